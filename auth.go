@@ -0,0 +1,69 @@
+package sprout
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+const (
+	basicAuthUserContextKey contextKey = "sprout:basic_auth_user"
+	claimsContextKey        contextKey = "sprout:claims"
+)
+
+// BasicAuth returns middleware that requires HTTP Basic credentials accepted
+// by verify. Rejected requests fail with ErrorKindUnauthorized (401 by default)
+// through the usual error pipeline, with a WWW-Authenticate challenge for
+// realm. On success the username is available via BasicAuthUser.
+//
+// verify should compare secrets with SecureCompare rather than ==.
+func BasicAuth(realm string, verify func(user, pass string) bool) Middleware {
+	challenge := `Basic realm="` + strings.ReplaceAll(realm, `"`, `\"`) + `", charset="UTF-8"`
+
+	return func(w http.ResponseWriter, req *http.Request, next Next) {
+		user, pass, ok := req.BasicAuth()
+		if !ok || verify == nil || !verify(user, pass) {
+			w.Header().Set("WWW-Authenticate", challenge)
+			next(&Error{
+				Kind:    ErrorKindUnauthorized,
+				Message: "invalid or missing credentials",
+			})
+			return
+		}
+
+		ctx := context.WithValue(req.Context(), basicAuthUserContextKey, user)
+		Continue(next, req.WithContext(ctx))
+	}
+}
+
+// BasicAuthUser returns the username authenticated by BasicAuth. It accepts
+// either a handler context or a request context.
+func BasicAuthUser(ctx context.Context) (string, bool) {
+	user, ok := ctx.Value(basicAuthUserContextKey).(string)
+	return user, ok
+}
+
+// SecureCompare reports whether given equals expected in constant time. Both
+// values are hashed first so the comparison does not leak their lengths.
+func SecureCompare(given, expected string) bool {
+	givenSum := sha256.Sum256([]byte(given))
+	expectedSum := sha256.Sum256([]byte(expected))
+	return subtle.ConstantTimeCompare(givenSum[:], expectedSum[:]) == 1
+}
+
+// WithClaims returns a copy of ctx carrying verified token claims. Auth
+// middleware calls it once the token checks out and passes the request on
+// with Continue; request fields tagged `claim:"name"` are then bound from
+// claims, after the body so clients cannot supply them.
+func WithClaims(ctx context.Context, claims map[string]any) context.Context {
+	return context.WithValue(ctx, claimsContextKey, claims)
+}
+
+// Claims returns the claims stored by WithClaims. It accepts either a handler
+// context or a request context.
+func Claims(ctx context.Context) (map[string]any, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(map[string]any)
+	return claims, ok
+}
@@ -0,0 +1,171 @@
+package sprout
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	router := New()
+	router.Use(BasicAuth("admin area", func(user, pass string) bool {
+		return SecureCompare(user, "ada") && SecureCompare(pass, "s3cret")
+	}))
+
+	GET(router, "/me", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		user, ok := BasicAuthUser(ctx)
+		if !ok {
+			t.Fatalf("expected authenticated user in handler context")
+		}
+		return &HelloResponse{Message: user}, nil
+	})
+
+	t.Run("valid credentials", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.SetBasicAuth("ada", "s3cret")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if body := strings.TrimSpace(rec.Body.String()); body != `{"message":"ada"}` {
+			t.Fatalf("expected username in response, got %s", body)
+		}
+		if rec.Header().Get("WWW-Authenticate") != "" {
+			t.Fatalf("did not expect a challenge on success")
+		}
+	})
+
+	for name, setup := range map[string]func(*http.Request){
+		"missing header": func(*http.Request) {},
+		"wrong password": func(r *http.Request) { r.SetBasicAuth("ada", "nope") },
+		"not basic":      func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			setup(req)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("expected status 401, got %d", rec.Code)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != `Basic realm="admin area", charset="UTF-8"` {
+				t.Fatalf("unexpected challenge %q", got)
+			}
+		})
+	}
+}
+
+func TestBasicAuthErrorHandler(t *testing.T) {
+	var kind ErrorKind
+	router := NewWithConfig(&Config{
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if sproutErr, ok := err.(*Error); ok {
+				kind = sproutErr.Kind
+			}
+			w.WriteHeader(http.StatusForbidden)
+		},
+	})
+	router.Use(BasicAuth("api", func(user, pass string) bool { return false }))
+
+	GET(router, "/me", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{}, nil
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/me", nil))
+
+	if kind != ErrorKindUnauthorized {
+		t.Fatalf("expected ErrorKindUnauthorized, got %q", kind)
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected custom handler status, got %d", rec.Code)
+	}
+}
+
+func TestSecureCompare(t *testing.T) {
+	if !SecureCompare("token", "token") {
+		t.Fatalf("expected equal values to match")
+	}
+	if SecureCompare("token", "tokens") || SecureCompare("", "token") {
+		t.Fatalf("expected different values not to match")
+	}
+}
+
+type claimScopedRequest struct {
+	Subject  string   `claim:"sub" validate:"required"`
+	TenantID int      `claim:"tenant"`
+	Roles    []string `claim:"roles"`
+	Trace    string   `header:"X-Trace"`
+	Name     string   `json:"name"`
+}
+
+func TestClaimBinding(t *testing.T) {
+	router := New()
+	router.Use(func(w http.ResponseWriter, r *http.Request, next Next) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer ada":
+			claims := map[string]any{"sub": "ada", "tenant": float64(7), "roles": []any{"admin", "ops"}}
+			Continue(next, r.WithContext(WithClaims(r.Context(), claims)))
+		case "Bearer broken":
+			Continue(next, r.WithContext(WithClaims(r.Context(), map[string]any{"sub": "ada", "tenant": "seven"})))
+		default:
+			next(nil)
+		}
+	})
+
+	var got claimScopedRequest
+	POST(router, "/notes", func(ctx context.Context, req *claimScopedRequest) (*HelloResponse, error) {
+		got = *req
+		return &HelloResponse{Message: req.Subject}, nil
+	})
+
+	serve := func(auth, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/notes", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Trace", "t-1")
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("bound from claims", func(t *testing.T) {
+		rec := serve("Bearer ada", `{"name":"todo","Subject":"mallory","TenantID":99}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if got.Subject != "ada" || got.TenantID != 7 || strings.Join(got.Roles, ",") != "admin,ops" {
+			t.Fatalf("expected fields bound from claims, got %+v", got)
+		}
+		if got.Trace != "t-1" || got.Name != "todo" {
+			t.Fatalf("expected header and body fields bound as usual, got %+v", got)
+		}
+	})
+
+	t.Run("missing claims", func(t *testing.T) {
+		rec := serve("", `{"name":"todo","Subject":"mallory"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400 without claims, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if !strings.Contains(rec.Body.String(), "Subject") {
+			t.Fatalf("expected required claim field in validation error, got %s", rec.Body.String())
+		}
+	})
+
+	t.Run("unconvertible claim", func(t *testing.T) {
+		rec := serve("Bearer broken", `{"name":"todo"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if !strings.Contains(rec.Body.String(), "invalid claim 'tenant'") {
+			t.Fatalf("expected claim parse error, got %s", rec.Body.String())
+		}
+	})
+}
@@ -86,7 +86,7 @@ func main() {
 		}, nil
 	})
 
-	log.Println("listening on http://localhost:8080 (swagger at /swagger)")
+	log.Println("listening on http://localhost:8080 (swagger at /swagger, UI at /swagger/ui)")
 	if err := http.ListenAndServe(":8080", router); err != nil {
 		log.Fatalf("server error: %v", err)
 	}
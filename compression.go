@@ -0,0 +1,330 @@
+package sprout
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultCompressionMinLength is the smallest body compressed when
+// CompressionOptions.MinLength is zero.
+const defaultCompressionMinLength = 1024
+
+// CompressionOptions configures the Compression middleware.
+type CompressionOptions struct {
+	// MinLength is the smallest body, in bytes, worth compressing. Shorter
+	// bodies are sent as is. Defaults to 1024; use a negative value to
+	// compress every body.
+	MinLength int
+
+	// Level is the gzip/zlib compression level, from gzip.BestSpeed (1) to
+	// gzip.BestCompression (9). Zero or an invalid level uses
+	// gzip.DefaultCompression.
+	Level int
+}
+
+// Compression returns middleware that compresses response bodies with gzip or
+// deflate, as negotiated from the request's Accept-Encoding header. Register it
+// before the routes it should cover so it wraps the writer before they run.
+//
+// Bodies are buffered until they reach MinLength; shorter ones are written
+// uncompressed. Responses to HEAD requests, 1xx, 204 and 304 responses, and
+// responses that already carry a Content-Encoding are never compressed.
+// Compressible responses get Vary: Accept-Encoding.
+func Compression(opts CompressionOptions) Middleware {
+	minLength := opts.MinLength
+	if minLength == 0 {
+		minLength = defaultCompressionMinLength
+	}
+	level := opts.Level
+	if level == 0 || level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+
+	return func(w http.ResponseWriter, req *http.Request, next Next) {
+		if req.Method == http.MethodHead {
+			next(nil)
+			return
+		}
+		encoding := negotiateEncoding(req.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			addVary(w.Header(), "Accept-Encoding")
+			next(nil)
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: w,
+			encoding:       encoding,
+			level:          level,
+			minLength:      minLength,
+		}
+		returned := false
+		defer func() {
+			if returned {
+				cw.finish()
+			} else {
+				cw.abort()
+			}
+		}()
+		continueWithWriter(next, cw, req)
+		returned = true
+	}
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header, or
+// returns "" when the client accepts neither. "*" stands for a coding the
+// header does not refuse with q=0.
+func negotiateEncoding(header string) string {
+	refused := refusedEncodings(header)
+	for _, coding := range parseAccept(header) {
+		switch normalizeEncoding(coding) {
+		case "gzip":
+			return "gzip"
+		case "deflate":
+			return "deflate"
+		case "*":
+			if !refused["gzip"] {
+				return "gzip"
+			}
+			if !refused["deflate"] {
+				return "deflate"
+			}
+		}
+	}
+	return ""
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows the
+// content coding encoding. "*" allows any coding not refused with q=0.
+func acceptsEncoding(header, encoding string) bool {
+	encoding = normalizeEncoding(encoding)
+	refused := refusedEncodings(header)
+	for _, coding := range parseAccept(header) {
+		coding = normalizeEncoding(coding)
+		if coding == encoding || (coding == "*" && !refused[encoding]) {
+			return true
+		}
+	}
+	return false
+}
+
+// refusedEncodings returns the codings an Accept-Encoding header lists with
+// q=0, which parseAccept drops.
+func refusedEncodings(header string) map[string]bool {
+	var refused map[string]bool
+	for _, part := range strings.Split(header, ",") {
+		coding, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q <= 0 {
+			if refused == nil {
+				refused = make(map[string]bool)
+			}
+			refused[normalizeEncoding(coding)] = true
+		}
+	}
+	return refused
+}
+
+// normalizeEncoding lowercases a content coding and maps x-gzip to gzip.
+func normalizeEncoding(coding string) string {
+	coding = strings.ToLower(coding)
+	if coding == "x-gzip" {
+		return "gzip"
+	}
+	return coding
+}
+
+// negotiateEncodedBody returns body as is when the client accepts the
+// Content-Encoding set in header. Otherwise gzip and deflate bodies are
+// decompressed and the Content-Encoding removed; other encodings the client
+// does not accept are an error.
+func negotiateEncodedBody(header http.Header, req *http.Request, body []byte) (encodedPayload, error) {
+	encoding := strings.ToLower(header.Get("Content-Encoding"))
+	if encoding == "" || encoding == "identity" {
+		return body, nil
+	}
+	addVary(header, "Accept-Encoding")
+	if acceptsEncoding(req.Header.Get("Accept-Encoding"), encoding) {
+		return body, nil
+	}
+
+	var reader io.ReadCloser
+	var err error
+	switch encoding {
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		reader, err = zlib.NewReader(bytes.NewReader(body))
+	default:
+		return nil, fmt.Errorf("client does not accept content encoding %q", encoding)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	header.Del("Content-Encoding")
+	return decoded, nil
+}
+
+// compressWriter buffers the start of a response to decide whether to
+// compress it, then streams the rest through the chosen encoder.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding  string
+	level     int
+	minLength int
+
+	status  int
+	buf     []byte
+	decided bool
+	encoder io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided || cw.status != 0 {
+		return
+	}
+	// Informational responses pass straight through.
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+	if !cw.compressible() {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.encoder != nil {
+			return cw.encoder.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.minLength {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush sends what has been buffered so far, compressing it only if it
+// already reached MinLength.
+func (cw *compressWriter) Flush() {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		_ = cw.decide(len(cw.buf) >= cw.minLength)
+	}
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		_ = flusher.Flush()
+	}
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// compressible reports whether the response may be compressed at all.
+func (cw *compressWriter) compressible() bool {
+	if cw.status == http.StatusNoContent || cw.status == http.StatusNotModified ||
+		cw.status == http.StatusSwitchingProtocols {
+		return false
+	}
+	return cw.Header().Get("Content-Encoding") == ""
+}
+
+// decide commits the headers, with compression when compress is set and the
+// response allows it, and writes out the buffered bytes.
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+	header := cw.Header()
+
+	if cw.compressible() {
+		addVary(header, "Accept-Encoding")
+	}
+	if compress && cw.compressible() {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		if cw.encoding == "gzip" {
+			cw.encoder, _ = gzip.NewWriterLevel(cw.ResponseWriter, cw.level)
+		} else {
+			cw.encoder, _ = zlib.NewWriterLevel(cw.ResponseWriter, cw.level)
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+
+	buffered := cw.buf
+	cw.buf = nil
+	var err error
+	if cw.encoder != nil {
+		_, err = cw.encoder.Write(buffered)
+	} else {
+		_, err = cw.ResponseWriter.Write(buffered)
+	}
+	return err
+}
+
+// finish writes a response that never reached MinLength and closes the
+// encoder of a compressed one.
+func (cw *compressWriter) finish() {
+	if cw.status == 0 {
+		return // nothing was written; leave the response to the server
+	}
+	if !cw.decided {
+		_ = cw.decide(false)
+	}
+	if cw.encoder != nil {
+		_ = cw.encoder.Close()
+	}
+}
+
+// abort ends a response whose chain panicked or exited early. A stream that
+// was already being compressed is closed; a buffered start is dropped
+// uncommitted, so a recoverer further out can still answer with an error.
+func (cw *compressWriter) abort() {
+	if cw.encoder != nil {
+		_ = cw.encoder.Close()
+	}
+	cw.buf = nil
+}
+
+// addVary appends value to the Vary header unless it is already listed.
+func addVary(header http.Header, value string) {
+	for _, existing := range header.Values("Vary") {
+		for _, part := range strings.Split(existing, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), value) || strings.TrimSpace(part) == "*" {
+				return
+			}
+		}
+	}
+	header.Add("Vary", value)
+}
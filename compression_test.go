@@ -0,0 +1,376 @@
+package sprout
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	router := New()
+	router.Use(Compression(CompressionOptions{MinLength: 64}))
+
+	long := strings.Repeat("sprout ", 40)
+	GET(router, "/long", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: long}, nil
+	})
+	HEAD(router, "/long", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: long}, nil
+	})
+	GET(router, "/short", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "hi"}, nil
+	})
+	writeStatus := func(status int) Middleware {
+		return func(w http.ResponseWriter, r *http.Request, next Next) {
+			w.WriteHeader(status)
+		}
+	}
+	GET(router, "/empty", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, nil
+	}, WithMiddleware(writeStatus(http.StatusNoContent)))
+	GET(router, "/cached", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, nil
+	}, WithMiddleware(writeStatus(http.StatusNotModified)))
+	GET(router, "/encoded", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, nil
+	}, WithMiddleware(func(w http.ResponseWriter, r *http.Request, next Next) {
+		w.Header().Set("Content-Encoding", "br")
+		_, _ = w.Write([]byte(long))
+	}))
+
+	serve := func(method, path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	decodeMessage := func(t *testing.T, r io.Reader) string {
+		t.Helper()
+		var resp HelloResponse
+		if err := json.NewDecoder(r).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode body: %v", err)
+		}
+		return resp.Message
+	}
+
+	t.Run("gzip", func(t *testing.T) {
+		rec := serve(http.MethodGet, "/long", "br;q=1, gzip;q=0.8")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("expected gzip encoding, got %q", got)
+		}
+		if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Fatalf("expected Vary: Accept-Encoding, got %q", got)
+		}
+		if got := rec.Header().Get("Content-Type"); got != "application/json" {
+			t.Fatalf("expected JSON content type to be kept, got %q", got)
+		}
+		zr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatalf("expected gzip body: %v", err)
+		}
+		if got := decodeMessage(t, zr); got != long {
+			t.Fatalf("unexpected decompressed message %q", got)
+		}
+	})
+
+	t.Run("deflate", func(t *testing.T) {
+		rec := serve(http.MethodGet, "/long", "deflate")
+		if got := rec.Header().Get("Content-Encoding"); got != "deflate" {
+			t.Fatalf("expected deflate encoding, got %q", got)
+		}
+		zr, err := zlib.NewReader(rec.Body)
+		if err != nil {
+			t.Fatalf("expected deflate body: %v", err)
+		}
+		if got := decodeMessage(t, zr); got != long {
+			t.Fatalf("unexpected decompressed message %q", got)
+		}
+	})
+
+	t.Run("below minimum length", func(t *testing.T) {
+		rec := serve(http.MethodGet, "/short", "gzip")
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Fatalf("did not expect short body to be compressed, got %q", got)
+		}
+		if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Fatalf("expected Vary: Accept-Encoding, got %q", got)
+		}
+		if got := decodeMessage(t, rec.Body); got != "hi" {
+			t.Fatalf("unexpected message %q", got)
+		}
+	})
+
+	t.Run("not accepted", func(t *testing.T) {
+		for _, accept := range []string{"", "br", "gzip;q=0, identity"} {
+			rec := serve(http.MethodGet, "/long", accept)
+			if got := rec.Header().Get("Content-Encoding"); got != "" {
+				t.Fatalf("Accept-Encoding %q: did not expect compression, got %q", accept, got)
+			}
+			if got := decodeMessage(t, rec.Body); got != long {
+				t.Fatalf("Accept-Encoding %q: unexpected message %q", accept, got)
+			}
+		}
+	})
+
+	t.Run("never compressed", func(t *testing.T) {
+		for _, tc := range []struct {
+			method, path string
+			status       int
+		}{
+			{http.MethodGet, "/empty", http.StatusNoContent},
+			{http.MethodGet, "/cached", http.StatusNotModified},
+			{http.MethodHead, "/long", http.StatusOK},
+		} {
+			rec := serve(tc.method, tc.path, "gzip")
+			if rec.Code != tc.status {
+				t.Fatalf("%s %s: expected status %d, got %d", tc.method, tc.path, tc.status, rec.Code)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != "" {
+				t.Fatalf("%s %s: did not expect compression, got %q", tc.method, tc.path, got)
+			}
+			if rec.Body.Len() != 0 {
+				t.Fatalf("%s %s: expected empty body, got %q", tc.method, tc.path, rec.Body.String())
+			}
+		}
+	})
+
+	t.Run("already encoded", func(t *testing.T) {
+		rec := serve(http.MethodGet, "/encoded", "gzip")
+		if got := rec.Header().Get("Content-Encoding"); got != "br" {
+			t.Fatalf("expected existing encoding to be kept, got %q", got)
+		}
+		if rec.Body.String() != long {
+			t.Fatalf("expected body to pass through untouched")
+		}
+	})
+}
+
+func TestCompressionStreamsNDJSON(t *testing.T) {
+	router := New()
+	router.Use(Compression(CompressionOptions{MinLength: -1}))
+	NDJSON(router, http.MethodGet, "/export", func(ctx context.Context, req *exportRequest, send func(*exportLine) error) error {
+		for i := 1; i <= req.Limit; i++ {
+			if err := send(&exportLine{ID: i, Name: "row"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/export?limit=3", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := &flushCountingRecorder{ResponseRecorder: httptest.NewRecorder()}
+	router.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", got)
+	}
+	if rec.flushes < 3 {
+		t.Fatalf("expected every line to be flushed, got %d flushes", rec.flushes)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("expected gzip body: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to decompress body: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(body)), "\n"); len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", body)
+	}
+}
+
+func TestCompressionClosesStreamOnPanic(t *testing.T) {
+	router := New()
+	router.Use(Recoverer())
+	router.Use(Compression(CompressionOptions{MinLength: -1}))
+	NDJSON(router, http.MethodGet, "/export", func(ctx context.Context, req *exportRequest, send func(*exportLine) error) error {
+		if err := send(&exportLine{ID: 1, Name: "row"}); err != nil {
+			return err
+		}
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/export?limit=1", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", got)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("expected gzip body: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("expected a closed gzip stream, got %v", err)
+	}
+	if !strings.Contains(string(body), `"id":1`) {
+		t.Fatalf("expected the streamed line, got %q", body)
+	}
+}
+
+func TestCompressionWithNegotiation(t *testing.T) {
+	router := New()
+	router.Use(Compression(CompressionOptions{MinLength: -1}))
+
+	GET(router, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*userV1, error) {
+		return &userV1{Name: "Ada Lovelace"}, nil
+	})
+	GET(router, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*userV2, error) {
+		return &userV2{FirstName: "Ada", LastName: "Lovelace"}, nil
+	}, WithProduces("application/vnd.myapi.v2+json"))
+
+	for _, acceptEncoding := range []string{"gzip", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		req.Header.Set("Accept", "application/vnd.myapi.v2+json")
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		got := rec.Header().Values("Vary")
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, []string{"Accept", "Accept-Encoding"}) {
+			t.Fatalf("expected Vary: Accept and Accept-Encoding with Accept-Encoding %q, got %q", acceptEncoding, got)
+		}
+	}
+}
+
+type cachedFeedResponse struct {
+	Encoding string `header:"Content-Encoding"`
+	Body     []byte `sprout:"encodedbody"`
+}
+
+func TestEncodedBodies(t *testing.T) {
+	var gzipped strings.Builder
+	zw := gzip.NewWriter(&gzipped)
+	_, _ = zw.Write([]byte(`{"message":"cached"}`))
+	_ = zw.Close()
+
+	router := New()
+	api := router.Mount("/api", nil)
+	api.Use(Compression(CompressionOptions{MinLength: -1}))
+	for _, r := range []*Sprout{router, api} {
+		GET(r, "/feed", func(ctx context.Context, req *EmptyRequest) (*cachedFeedResponse, error) {
+			return &cachedFeedResponse{Encoding: "gzip", Body: []byte(gzipped.String())}, nil
+		})
+		GET(r, "/brotli", func(ctx context.Context, req *EmptyRequest) (*cachedFeedResponse, error) {
+			return &cachedFeedResponse{Encoding: "br", Body: []byte("not really brotli")}, nil
+		})
+	}
+
+	serve := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("accepted encoding is written verbatim", func(t *testing.T) {
+		for _, path := range []string{"/feed", "/api/feed"} {
+			rec := serve(path, "gzip")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200 for %s, got %d", path, rec.Code)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+				t.Fatalf("expected gzip encoding for %s, got %q", path, got)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Fatalf("expected Vary: Accept-Encoding for %s, got %q", path, got)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Fatalf("expected JSON content type for %s, got %q", path, got)
+			}
+			if rec.Body.String() != gzipped.String() {
+				t.Fatalf("expected pre-encoded bytes to be written unchanged for %s", path)
+			}
+		}
+	})
+
+	t.Run("unaccepted gzip is decompressed", func(t *testing.T) {
+		rec := serve("/feed", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Fatalf("expected no Content-Encoding, got %q", got)
+		}
+		if got := rec.Body.String(); got != `{"message":"cached"}` {
+			t.Fatalf("expected decompressed body, got %q", got)
+		}
+	})
+
+	t.Run("unaccepted encoding without a decoder fails", func(t *testing.T) {
+		if rec := serve("/brotli", "gzip"); rec.Code != http.StatusInternalServerError {
+			t.Fatalf("expected status 500, got %d", rec.Code)
+		}
+		if rec := serve("/brotli", "br, gzip"); rec.Code != http.StatusOK || rec.Body.String() != "not really brotli" {
+			t.Fatalf("expected accepted brotli body, got %d %q", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	tests := []struct {
+		header, encoding string
+		want             bool
+	}{
+		{"gzip, deflate", "gzip", true},
+		{"x-gzip", "gzip", true},
+		{"deflate", "gzip", false},
+		{"gzip;q=0, deflate", "gzip", false},
+		{"*", "br", true},
+		{"*, gzip;q=0", "gzip", false},
+		{"gzip;q=0, *", "deflate", true},
+		{"", "gzip", false},
+	}
+	for _, tt := range tests {
+		if got := acceptsEncoding(tt.header, tt.encoding); got != tt.want {
+			t.Fatalf("acceptsEncoding(%q, %q) = %v, want %v", tt.header, tt.encoding, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header, want string
+	}{
+		{"gzip, deflate", "gzip"},
+		{"deflate, gzip;q=0.5", "deflate"},
+		{"*", "gzip"},
+		{"*, gzip;q=0", "deflate"},
+		{"*, gzip;q=0, deflate;q=0", ""},
+		{"br", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Fatalf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package sprout
+
+import (
+	"reflect"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// schemaMeta holds documentation attached to a schema through DescribeType.
+type schemaMeta struct {
+	description  string
+	format       string
+	example      any
+	hasExample   bool
+	defaultValue any
+	hasDefault   bool
+}
+
+func (m *schemaMeta) apply(schema *openapi3.Schema) {
+	if m == nil || schema == nil {
+		return
+	}
+	if m.description != "" {
+		schema.Description = m.description
+	}
+	if m.format != "" {
+		schema.Format = m.format
+	}
+	if m.hasExample {
+		schema.Example = m.example
+	}
+	if m.hasDefault {
+		schema.Default = m.defaultValue
+	}
+}
+
+// typeDescription is the metadata registered for one type.
+type typeDescription struct {
+	schemaMeta
+	fields map[string]*schemaMeta
+}
+
+// SchemaBuilder describes a type's generated schema; see DescribeType.
+type SchemaBuilder struct {
+	desc *typeDescription
+}
+
+// Description sets the schema description.
+func (b *SchemaBuilder) Description(text string) *SchemaBuilder {
+	b.desc.description = text
+	return b
+}
+
+// Format sets the schema format, e.g. "uuid" for a named string type.
+func (b *SchemaBuilder) Format(format string) *SchemaBuilder {
+	b.desc.format = format
+	return b
+}
+
+// Example sets an example value for the schema.
+func (b *SchemaBuilder) Example(value any) *SchemaBuilder {
+	b.desc.example, b.desc.hasExample = value, true
+	return b
+}
+
+// Default documents the schema's default value. It does not change binding.
+func (b *SchemaBuilder) Default(value any) *SchemaBuilder {
+	b.desc.defaultValue, b.desc.hasDefault = value, true
+	return b
+}
+
+// Field describes the property with the given JSON name. Names that do not
+// match a property of the type are ignored.
+func (b *SchemaBuilder) Field(jsonName string) *FieldBuilder {
+	if b.desc.fields == nil {
+		b.desc.fields = make(map[string]*schemaMeta)
+	}
+	meta, ok := b.desc.fields[jsonName]
+	if !ok {
+		meta = &schemaMeta{}
+		b.desc.fields[jsonName] = meta
+	}
+	return &FieldBuilder{meta: meta}
+}
+
+// FieldBuilder describes one property of a struct schema.
+type FieldBuilder struct {
+	meta *schemaMeta
+}
+
+// Description sets the property description.
+func (b *FieldBuilder) Description(text string) *FieldBuilder {
+	b.meta.description = text
+	return b
+}
+
+// Format sets the property format.
+func (b *FieldBuilder) Format(format string) *FieldBuilder {
+	b.meta.format = format
+	return b
+}
+
+// Example sets an example value for the property.
+func (b *FieldBuilder) Example(value any) *FieldBuilder {
+	b.meta.example, b.meta.hasExample = value, true
+	return b
+}
+
+// Default documents the property's default value. It does not change binding.
+func (b *FieldBuilder) Default(value any) *FieldBuilder {
+	b.meta.defaultValue, b.meta.hasDefault = value, true
+	return b
+}
+
+// DescribeType attaches documentation to the OpenAPI schema generated for the
+// type of instance (a value, pointer or reflect.Type), leaving the DTO free of
+// documentation tags. Metadata set here takes precedence over metadata derived
+// from struct tags. It applies to the router's own documents and should be
+// called before routes using the type are registered; an existing component
+// for the type is updated as well.
+func (s *Sprout) DescribeType(instance any, describe func(*SchemaBuilder)) {
+	t, ok := instance.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(instance)
+	}
+	t = derefType(t)
+	if t == nil || describe == nil {
+		return
+	}
+
+	if s.openapi != nil {
+		s.openapi.describeType(t, describe)
+	}
+	if s.internalOpenAPI != nil {
+		s.internalOpenAPI.describeType(t, describe)
+	}
+}
+
+func (d *openAPIDocument) describeType(t reflect.Type, describe func(*SchemaBuilder)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.descriptions == nil {
+		d.descriptions = make(map[reflect.Type]*typeDescription)
+	}
+	desc, ok := d.descriptions[t]
+	if !ok {
+		desc = &typeDescription{}
+		d.descriptions[t] = desc
+	}
+	describe(&SchemaBuilder{desc: desc})
+
+	if name, ok := d.typeNames[t]; ok {
+		for _, candidate := range []string{name, name + requestSchema.componentSuffix(), name + responseSchema.componentSuffix()} {
+			if component := d.doc.Components.Schemas[candidate]; component != nil {
+				d.applyDescriptionLocked(t, component.Value)
+			}
+		}
+	}
+	d.customized = nil
+}
+
+// applyDescriptionLocked applies DescribeType metadata for t to schema,
+// including per-property metadata for struct schemas.
+func (d *openAPIDocument) applyDescriptionLocked(t reflect.Type, schema *openapi3.Schema) {
+	desc := d.descriptions[derefType(t)]
+	if desc == nil || schema == nil {
+		return
+	}
+
+	desc.schemaMeta.apply(schema)
+	for name, meta := range desc.fields {
+		prop, ok := schema.Properties[name]
+		if !ok || prop == nil {
+			continue
+		}
+		if prop.Ref != "" {
+			// Siblings of $ref are ignored, so wrap the reference to document it.
+			prop = &openapi3.SchemaRef{Value: &openapi3.Schema{AllOf: openapi3.SchemaRefs{prop}}}
+			schema.Properties[name] = prop
+		}
+		meta.apply(prop.Value)
+	}
+}
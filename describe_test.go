@@ -0,0 +1,84 @@
+package sprout
+
+import (
+	"context"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+type describedID string
+
+type describedAddress struct {
+	City string `json:"city"`
+}
+
+type describedUser struct {
+	ID      describedID      `json:"id"`
+	Email   string           `json:"email" validate:"required,email"`
+	Role    string           `json:"role"`
+	Address describedAddress `json:"address"`
+}
+
+func TestDescribeType(t *testing.T) {
+	router := New()
+
+	router.DescribeType(describedID(""), func(b *SchemaBuilder) {
+		b.Format("uuid").Description("Opaque identifier")
+	})
+	router.DescribeType(&describedUser{}, func(b *SchemaBuilder) {
+		b.Description("A registered user").Example(map[string]any{"id": "u-1", "email": "ada@example.com"})
+		b.Field("email").Description("Login address").Format("idn-email")
+		b.Field("role").Default("member").Example("admin")
+		b.Field("address").Description("Postal address")
+		b.Field("unknown").Description("ignored")
+	})
+
+	GET(router, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*describedUser, error) {
+		return &describedUser{}, nil
+	})
+
+	// Describing a type after registration updates its existing component.
+	router.DescribeType(describedAddress{}, func(b *SchemaBuilder) {
+		b.Description("Where the user lives")
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	user := doc.Components.Schemas["sprout_describedUser"].Value
+	if user.Description != "A registered user" || user.Example == nil {
+		t.Fatalf("expected type-level metadata, got description %q example %v", user.Description, user.Example)
+	}
+
+	id := user.Properties["id"].Value
+	if id.Format != "uuid" || id.Description != "Opaque identifier" {
+		t.Fatalf("expected named scalar metadata on id, got format %q description %q", id.Format, id.Description)
+	}
+
+	email := user.Properties["email"].Value
+	if email.Description != "Login address" || email.Format != "idn-email" {
+		t.Fatalf("expected builder to take precedence over validate tags, got format %q description %q", email.Format, email.Description)
+	}
+
+	role := user.Properties["role"].Value
+	if role.Default != "member" || role.Example != "admin" {
+		t.Fatalf("expected default and example on role, got %v / %v", role.Default, role.Example)
+	}
+
+	address := user.Properties["address"]
+	if address.Ref != "" || address.Value.Description != "Postal address" || len(address.Value.AllOf) != 1 ||
+		address.Value.AllOf[0].Ref != "#/components/schemas/sprout_describedAddress" {
+		t.Fatalf("expected described reference to be wrapped in allOf, got %+v", address)
+	}
+
+	if desc := doc.Components.Schemas["sprout_describedAddress"].Value.Description; desc != "Where the user lives" {
+		t.Fatalf("expected late description on existing component, got %q", desc)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // ErrorKind represents the category of error that occurred during request processing.
@@ -42,6 +45,22 @@ const (
 	// ErrorKindSerialization indicates JSON serialization failed (internal error).
 	// This occurs when encoding a response or error to JSON fails.
 	ErrorKindSerialization ErrorKind = "serialization_error"
+
+	// ErrorKindRequestTooLarge indicates the request body exceeded Config.MaxBodyBytes.
+	// For compressed bodies the limit applies to the decompressed size.
+	ErrorKindRequestTooLarge ErrorKind = "request_too_large"
+
+	// ErrorKindUnauthorized indicates the request lacked valid credentials.
+	// This occurs when BasicAuth rejects a missing or incorrect Authorization header.
+	ErrorKindUnauthorized ErrorKind = "unauthorized"
+
+	// ErrorKindPanic indicates a handler or middleware panicked (internal error).
+	// This occurs when Recoverer recovers a panic; Err is a *PanicError.
+	ErrorKindPanic ErrorKind = "panic"
+
+	// ErrorKindTimeout indicates a handler did not return within its route's
+	// WithHardTimeout duration, or a request outlived a Timeout middleware.
+	ErrorKindTimeout ErrorKind = "timeout"
 )
 
 // Error represents an error from Sprout's request processing pipeline.
@@ -50,6 +69,12 @@ type Error struct {
 	Kind    ErrorKind // Category of error
 	Message string    // Human-readable message
 	Err     error     // Underlying error (can be nil)
+
+	// Meta carries structured request details for custom error handlers (can
+	// be nil). 404 and 405 errors set "method" and "path" to the request's
+	// method and URL path, and 405 errors also set "allowed" to the []string
+	// of methods registered for the path.
+	Meta map[string]any
 }
 
 // Error implements the error interface.
@@ -72,15 +97,18 @@ const (
 	ParameterSourcePath   ParameterSource = "path"
 	ParameterSourceQuery  ParameterSource = "query"
 	ParameterSourceHeader ParameterSource = "header"
+	ParameterSourceCookie ParameterSource = "cookie"
+	ParameterSourceForm   ParameterSource = "form"
+	ParameterSourceClaim  ParameterSource = "claim"
 )
 
-// ParseParameterError represents an error parsing a path, query, or header parameter.
+// ParseParameterError represents an error parsing a path, query, header, cookie, form, or claim parameter.
 // This provides structured information similar to json.UnmarshalTypeError.
 type ParseParameterError struct {
 	// Parameter is the name of the parameter that failed to parse (e.g., "page", "id").
 	Parameter string
 
-	// Source indicates where the parameter came from (path, query, or header).
+	// Source indicates where the parameter came from (path, query, header, cookie, form, or claim).
 	Source ParameterSource
 
 	// Value is the raw string value that failed to parse.
@@ -100,6 +128,32 @@ func (e *ParseParameterError) Unwrap() error {
 	return e.Err
 }
 
+// MethodNotAllowedError is the Err of an ErrorKindMethodNotAllowed error. It
+// lists the methods the path does support, as sent in the Allow header.
+type MethodNotAllowedError struct {
+	// Method is the request's method.
+	Method string
+
+	// Allowed lists the methods registered for the path, e.g. ["GET", "OPTIONS"].
+	Allowed []string
+}
+
+// Error implements the error interface.
+func (e *MethodNotAllowedError) Error() string {
+	return fmt.Sprintf("method %s not allowed, allowed: %s", e.Method, strings.Join(e.Allowed, ", "))
+}
+
+// splitAllowHeader splits an Allow header value into its methods.
+func splitAllowHeader(allow string) []string {
+	var methods []string
+	for _, method := range strings.Split(allow, ",") {
+		if method = strings.TrimSpace(method); method != "" {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
 // handleError routes errors to either the custom error handler or the default handler.
 func handleError(s *Sprout, w http.ResponseWriter, r *http.Request, err error) {
 	if err == nil {
@@ -107,6 +161,10 @@ func handleError(s *Sprout, w http.ResponseWriter, r *http.Request, err error) {
 	}
 
 	normalizedErr := normalizeError(s, err)
+	if state := chainStateFrom(r); state != nil {
+		state.setErr(normalizedErr)
+	}
+	s.applyDefaultHeaders(w)
 
 	if s.config.ErrorHandler != nil {
 		s.config.ErrorHandler(w, r, normalizedErr)
@@ -120,25 +178,165 @@ func handleError(s *Sprout, w http.ResponseWriter, r *http.Request, err error) {
 		return
 	}
 
+	status := http.StatusInternalServerError
 	var sproutErr *Error
 	if errors.As(normalizedErr, &sproutErr) {
-		switch sproutErr.Kind {
-		case ErrorKindParse, ErrorKindValidation:
-			http.Error(w, sproutErr.Error(), http.StatusBadRequest)
-		case ErrorKindNotFound:
-			http.Error(w, sproutErr.Error(), http.StatusNotFound)
-		case ErrorKindMethodNotAllowed:
-			http.Error(w, sproutErr.Error(), http.StatusMethodNotAllowed)
-		case ErrorKindResponseValidation, ErrorKindErrorValidation, ErrorKindUndeclaredError, ErrorKindSerialization:
-			http.Error(w, sproutErr.Error(), http.StatusInternalServerError)
-		default:
-			http.Error(w, sproutErr.Error(), http.StatusInternalServerError)
+		status = s.statusForKind(sproutErr.Kind)
+	}
+
+	if writeFormattedValidationError(s, w, r, status, normalizedErr) {
+		return
+	}
+	if writeEnvelopedError(s, w, r, status, normalizedErr) {
+		return
+	}
+
+	http.Error(w, normalizedErr.Error(), status)
+}
+
+// statusForKind resolves the status for kind from Config.StatusForKind,
+// falling back to the default mapping.
+func (s *Sprout) statusForKind(kind ErrorKind) int {
+	if status, ok := s.config.StatusForKind[kind]; ok && status >= 100 && status <= 599 {
+		return status
+	}
+	return statusForErrorKind(kind)
+}
+
+// statusForErrorKind maps a Sprout error kind to its default HTTP status.
+func statusForErrorKind(kind ErrorKind) int {
+	switch kind {
+	case ErrorKindParse, ErrorKindValidation:
+		return http.StatusBadRequest
+	case ErrorKindNotFound:
+		return http.StatusNotFound
+	case ErrorKindMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	case ErrorKindRequestTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case ErrorKindUnauthorized:
+		return http.StatusUnauthorized
+	case ErrorKindTimeout:
+		return http.StatusServiceUnavailable
+	default:
+		// Response/error validation, undeclared errors and serialization
+		// failures are internal errors.
+		return http.StatusInternalServerError
+	}
+}
+
+// StatusCoder is implemented by typed errors whose status is only known at
+// runtime, such as a generic APIError used for 400, 404 and 409 alike.
+// StatusCode takes precedence over the `http:"status=..."` tag; values outside
+// 300-599 are ignored, so an error is never answered with a success status.
+// The OpenAPI document cannot see runtime values and keeps documenting the
+// tag status.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// typedErrorStatus resolves the status of a typed error from StatusCoder, then
+// its status tag, then defaultStatus.
+func typedErrorStatus(err error, defaultStatus int) int {
+	if coder, ok := err.(StatusCoder); ok {
+		if code := coder.StatusCode(); code >= 300 && code <= 599 {
+			return code
 		}
+	}
+	return extractStatusCode(reflect.TypeOf(err), defaultStatus)
+}
+
+// RetryAfterer is implemented by typed errors, such as rate-limit errors, that
+// tell clients when to try again. A positive RetryAfter is sent as the
+// Retry-After header in whole seconds, rounded up, and takes precedence over a
+// `header:"Retry-After"` field.
+type RetryAfterer interface {
+	RetryAfter() time.Duration
+}
+
+// setRetryAfter writes the Retry-After header for errors implementing
+// RetryAfterer.
+func setRetryAfter(w http.ResponseWriter, err error) {
+	retry, ok := err.(RetryAfterer)
+	if !ok {
 		return
 	}
+	delay := retry.RetryAfter()
+	if delay <= 0 {
+		return
+	}
+	seconds := int64((delay + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+}
+
+// errorEnvelope applies Config.ErrorEnvelope, returning nil when none is configured.
+func (s *Sprout) errorEnvelope(status int, err error) any {
+	if s.config.ErrorEnvelope == nil {
+		return nil
+	}
+	return s.config.ErrorEnvelope(status, err)
+}
+
+// writeEnvelopedError writes a system error through Config.ErrorEnvelope.
+// It reports false when no envelope applies or it cannot be encoded.
+func writeEnvelopedError(s *Sprout, w http.ResponseWriter, r *http.Request, status int, err error) bool {
+	envelope := s.errorEnvelope(status, err)
+	if envelope == nil {
+		return false
+	}
+	return writeErrorBody(s, w, r, status, envelope)
+}
+
+// writeErrorBody writes envelope as the JSON body of a system error, applying
+// its `header:` fields. It reports false when envelope cannot be encoded.
+func writeErrorBody(s *Sprout, w http.ResponseWriter, r *http.Request, status int, envelope any) bool {
+	for name, value := range extractHeaders(reflect.ValueOf(envelope)) {
+		setResponseHeader(w.Header(), name, value)
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	body, encodeErr := encodeResponseBody(s, w, r, status, prepareResponseBody(envelope), false)
+	if encodeErr != nil {
+		w.Header().Del("Content-Type")
+		return false
+	}
 
-	// Fallback for non-Sprout errors (shouldn't normally happen)
-	http.Error(w, normalizedErr.Error(), http.StatusInternalServerError)
+	w.WriteHeader(status)
+	if shouldWriteBody(r.Method, status) {
+		_, _ = w.Write(body)
+	}
+	return true
+}
+
+// ProblemDetails is an RFC 9457 problem document. Return it from
+// Config.ErrorEnvelope (see NewProblemDetails) to serve errors as
+// application/problem+json.
+type ProblemDetails struct {
+	// ContentType is sent as the Content-Type header and excluded from the body.
+	ContentType string `header:"Content-Type"`
+
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// NewProblemDetails builds a problem document for status with err's message
+// as the detail. It has the signature expected by Config.ErrorEnvelope.
+func NewProblemDetails(status int, err error) any {
+	problem := &ProblemDetails{
+		ContentType: "application/problem+json",
+		Type:        "about:blank",
+		Title:       http.StatusText(status),
+		Status:      status,
+	}
+	if err != nil {
+		problem.Detail = err.Error()
+	}
+	return problem
 }
 
 func normalizeError(s *Sprout, err error) error {
@@ -159,7 +357,7 @@ func normalizeError(s *Sprout, err error) error {
 			return &Error{
 				Kind:    ErrorKindErrorValidation,
 				Message: "error response validation failed",
-				Err:     validationErr,
+				Err:     s.limitValidationErrors(validationErr),
 			}
 		}
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"reflect"
 	"sort"
 	"strings"
 	"sync"
@@ -22,6 +23,11 @@ type Next func(error)
 // ErrNext signals a typed handler should delegate to the next middleware.
 var ErrNext = errors.New("sprout: next")
 
+// ErrResponseWritten signals a typed handler has already written the response
+// itself, through the writer from ResponseWriter, so Sprout must not encode
+// the returned value or write a status of its own.
+var ErrResponseWritten = errors.New("sprout: response written")
+
 // middlewareLayer keeps the middleware function together with its registration
 // order so we can sort and partition layers relative to routes.
 type middlewareLayer struct {
@@ -36,6 +42,13 @@ type routeEntry struct {
 	order           int64
 	fn              Middleware
 	routeMiddleware []Middleware
+
+	method string
+	path   string
+	config *routeConfig
+
+	reqType  reflect.Type
+	respType reflect.Type
 }
 
 // orderSeq provides a monotonic counter shared by routers so we can determine
@@ -53,6 +66,11 @@ func (o *orderSeq) Next() int64 {
 type routerRegistry struct {
 	mu      sync.RWMutex
 	routers []*Sprout
+
+	// routes maps "METHOD path" to the handlers negotiated for that route.
+	routes map[string]*routeVariants
+	// entries lists every registered route in registration order.
+	entries []*routeEntry
 }
 
 func newRouterRegistry() *routerRegistry {
@@ -89,7 +107,10 @@ func (r *routerRegistry) matchingRouters(path string) []*Sprout {
 // it, inserting the typed handler between middleware registered before and
 // after the route.
 func (s *Sprout) dispatchRoute(w http.ResponseWriter, req *http.Request, ps httprouter.Params, entry *routeEntry) {
-	req = withParams(req, ps)
+	req = withRoutePattern(withParams(req, ps), entry.path)
+	if header := entry.owner.config.RequestIDHeader; header != "" {
+		req = withRequestID(w, req, header)
+	}
 
 	before, after := gatherRouteMiddleware(entry)
 
@@ -168,6 +189,9 @@ func runChain(chain []Middleware, owner *Sprout, w http.ResponseWriter, req *htt
 		return
 	}
 
+	state := &chainState{owner: owner, w: w}
+	state.req = req.WithContext(context.WithValue(req.Context(), chainStateContextKey, state))
+
 	var exec func(int, error)
 	exec = func(idx int, err error) {
 		if err != nil {
@@ -175,6 +199,7 @@ func runChain(chain []Middleware, owner *Sprout, w http.ResponseWriter, req *htt
 				err = nil
 			}
 			if err != nil {
+				w, req := state.current()
 				owner.handleChainError(w, req, err)
 				return
 			}
@@ -183,6 +208,7 @@ func runChain(chain []Middleware, owner *Sprout, w http.ResponseWriter, req *htt
 		if idx >= len(chain) {
 			return
 		}
+		w, req := state.current()
 		chain[idx](w, req, func(nextErr error) {
 			exec(idx+1, nextErr)
 		})
@@ -191,6 +217,97 @@ func runChain(chain []Middleware, owner *Sprout, w http.ResponseWriter, req *htt
 	exec(0, nil)
 }
 
+// chainState carries the request and writer handed to the remaining layers of
+// a chain, and the last error sent through the error pipeline. It is guarded
+// by mu because Timeout lets the rest of a chain outlive the layer that
+// started it.
+type chainState struct {
+	owner *Sprout
+
+	mu  sync.Mutex
+	req *http.Request
+	w   http.ResponseWriter
+	err error
+}
+
+// current returns the writer and request for the next layer.
+func (s *chainState) current() (http.ResponseWriter, *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w, s.req
+}
+
+func (s *chainState) setRequest(req *http.Request) {
+	s.mu.Lock()
+	s.req = req
+	s.mu.Unlock()
+}
+
+func (s *chainState) setWriter(w http.ResponseWriter) {
+	s.mu.Lock()
+	s.w = w
+	s.mu.Unlock()
+}
+
+func (s *chainState) setErr(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// lastErr returns the last error sent through the error pipeline.
+func (s *chainState) lastErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func chainStateFrom(req *http.Request) *chainState {
+	state, _ := req.Context().Value(chainStateContextKey).(*chainState)
+	return state
+}
+
+// Continue advances the chain like next(nil), but hands req to every later
+// middleware and the handler. Use it to pass along a request carrying extra
+// context values; req must be derived from the request the middleware received.
+func Continue(next Next, req *http.Request) {
+	if state := chainStateFrom(req); state != nil {
+		state.setRequest(req)
+	}
+	next(nil)
+}
+
+// continueWithWriter is Continue that also hands w to every later layer.
+func continueWithWriter(next Next, w http.ResponseWriter, req *http.Request) {
+	if state := chainStateFrom(req); state != nil {
+		state.setWriter(w)
+	}
+	Continue(next, req)
+}
+
+// WrapHTTP adapts classic net/http middleware, such as gorilla/handlers or
+// nosurf, for use with Use and WithMiddleware. mw is applied once; on each
+// request its inner handler advances the chain, handing later layers the
+// writer and request it was called with. If mw responds without calling the
+// inner handler, the rest of the chain is skipped. Code in mw that runs after
+// the inner handler returns sees the finished response of every later layer,
+// including errors reported through next(err).
+//
+// The request mw passes on must be derived from the one it received, so the
+// chain and route parameters travel with its context.
+func WrapHTTP(mw func(http.Handler) http.Handler) Middleware {
+	wrapped := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if next, ok := r.Context().Value(wrapHTTPNextContextKey).(Next); ok {
+			continueWithWriter(next, w, r)
+		}
+	}))
+
+	return func(w http.ResponseWriter, req *http.Request, next Next) {
+		ctx := context.WithValue(req.Context(), wrapHTTPNextContextKey, next)
+		wrapped.ServeHTTP(w, req.WithContext(ctx))
+	}
+}
+
 func (s *Sprout) handleChainError(w http.ResponseWriter, req *http.Request, err error) {
 	if err == nil {
 		return
@@ -204,6 +321,11 @@ type contextKey string
 const (
 	paramsContextKey      contextKey = "sprout:params"
 	httpRequestContextKey contextKey = "sprout:http_request"
+	responseWriterKey     contextKey = "sprout:response_writer"
+	chainStateContextKey  contextKey = "sprout:chain_state"
+	routePatternKey       contextKey = "sprout:route_pattern"
+
+	wrapHTTPNextContextKey contextKey = "sprout:wrap_http_next"
 )
 
 // withParams stores httprouter params on the request context so middleware and
@@ -222,6 +344,18 @@ func Params(r *http.Request) httprouter.Params {
 	return nil
 }
 
+func withRoutePattern(req *http.Request, pattern string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), routePatternKey, pattern))
+}
+
+// RoutePattern returns the registered path pattern, including any base path,
+// that matched the request, such as "/users/:id". It is empty when no route
+// matched, for example in fallback middleware for 404/405 responses.
+func RoutePattern(r *http.Request) string {
+	pattern, _ := r.Context().Value(routePatternKey).(string)
+	return pattern
+}
+
 func withHTTPRequest(ctx context.Context, req *http.Request) context.Context {
 	return context.WithValue(ctx, httpRequestContextKey, req)
 }
@@ -236,6 +370,20 @@ func HTTPRequest(ctx context.Context) *http.Request {
 	return nil
 }
 
+func withResponseWriter(ctx context.Context, w http.ResponseWriter) context.Context {
+	return context.WithValue(ctx, responseWriterKey, w)
+}
+
+// ResponseWriter returns the response writer for the current typed handler
+// context, for responses that do not fit Sprout's serialization such as files
+// or redirects. A handler that writes through it must return
+// ErrResponseWritten. On a WithHardTimeout route, writes made after the
+// deadline are dropped. It returns nil outside a typed handler.
+func ResponseWriter(ctx context.Context) http.ResponseWriter {
+	w, _ := ctx.Value(responseWriterKey).(http.ResponseWriter)
+	return w
+}
+
 // ancestorChain returns routers from root → current so we can evaluate
 // middleware inheritance in registration order.
 func (s *Sprout) ancestorChain() []*Sprout {
@@ -140,6 +140,78 @@ func TestMiddlewareAfterRouteWithNext(t *testing.T) {
 	}
 }
 
+type invoiceRequest struct {
+	ID string `query:"id" validate:"required"`
+}
+
+func TestErrResponseWritten(t *testing.T) {
+	router := New()
+	var events []string
+
+	GET(router, "/invoice", func(ctx context.Context, req *invoiceRequest) (*HelloResponse, error) {
+		events = append(events, "route")
+		w := ResponseWriter(ctx)
+		if w == nil {
+			t.Fatal("expected ResponseWriter(ctx) to return the writer")
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("%PDF-" + req.ID))
+		return nil, ErrResponseWritten
+	})
+	GET(router, "/old", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		http.Redirect(ResponseWriter(ctx), HTTPRequest(ctx), "/new", http.StatusFound)
+		return nil, ErrResponseWritten
+	})
+
+	router.Use(func(w http.ResponseWriter, r *http.Request, next Next) {
+		events = append(events, "global-after")
+	})
+
+	t.Run("raw body", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/invoice?id=7", nil))
+
+		if recorder.Code != http.StatusOK || recorder.Body.String() != "%PDF-7" {
+			t.Fatalf("expected raw body, got %d: %q", recorder.Code, recorder.Body.String())
+		}
+		if ct := recorder.Header().Get("Content-Type"); ct != "application/pdf" {
+			t.Fatalf("expected handler's content type, got %q", ct)
+		}
+		if diff := cmpStringSlices(events, []string{"route"}); diff != "" {
+			t.Fatalf("unexpected event order: %s", diff)
+		}
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/old", nil))
+
+		if recorder.Code != http.StatusFound || recorder.Header().Get("Location") != "/new" {
+			t.Fatalf("expected redirect, got %d with Location %q", recorder.Code, recorder.Header().Get("Location"))
+		}
+	})
+
+	t.Run("request is still validated", func(t *testing.T) {
+		events = nil
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/invoice", nil))
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		if len(events) != 0 {
+			t.Fatalf("did not expect the handler to run, got %v", events)
+		}
+	})
+
+	t.Run("outside a handler", func(t *testing.T) {
+		if ResponseWriter(context.Background()) != nil {
+			t.Fatal("expected no writer outside a typed handler")
+		}
+	})
+}
+
 func TestGlobalFallbackMiddlewareRunsOnNotFound(t *testing.T) {
 	router := New()
 	var events []string
@@ -375,3 +447,190 @@ func cmpStringSlices(actual, expected []string) string {
 	}
 	return ""
 }
+
+func TestContinuePassesRequest(t *testing.T) {
+	type traceKey struct{}
+
+	router := New()
+	router.Use(func(w http.ResponseWriter, r *http.Request, next Next) {
+		Continue(next, r.WithContext(context.WithValue(r.Context(), traceKey{}, "abc")))
+	})
+	router.Use(func(w http.ResponseWriter, r *http.Request, next Next) {
+		if r.Context().Value(traceKey{}) != "abc" {
+			t.Fatalf("expected later middleware to see the updated request")
+		}
+		next(nil)
+	})
+
+	GET(router, "/trace", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		trace, _ := ctx.Value(traceKey{}).(string)
+		return &HelloResponse{Message: trace}, nil
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/trace", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"message":"abc"}` {
+		t.Fatalf("expected handler to see the context value, got %s", body)
+	}
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *countingWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func TestWrapHTTP(t *testing.T) {
+	type tenantKey struct{}
+
+	var statuses []int
+	classic := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Tenant") == "" {
+				http.Error(w, "tenant required", http.StatusForbidden)
+				return
+			}
+			w.Header().Set("X-Classic", "1")
+			cw := &countingWriter{ResponseWriter: w}
+			h.ServeHTTP(cw, r.WithContext(context.WithValue(r.Context(), tenantKey{}, r.Header.Get("X-Tenant"))))
+			statuses = append(statuses, cw.status)
+		})
+	}
+
+	strict := false
+	router := NewWithConfig(&Config{StrictErrorTypes: &strict})
+	router.Use(WrapHTTP(classic))
+
+	GET(router, "/users/:id", func(ctx context.Context, req *tracedUserRequest) (*HelloResponse, error) {
+		if req.ID == "missing" {
+			return nil, &Error{Kind: ErrorKindNotFound, Message: "no such user"}
+		}
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		return &HelloResponse{Message: tenant + ":" + req.ID}, nil
+	})
+
+	serve := func(path, tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if tenant != "" {
+			req.Header.Set("X-Tenant", tenant)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("continues the chain", func(t *testing.T) {
+		rec := serve("/users/42", "acme")
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if body := strings.TrimSpace(rec.Body.String()); body != `{"message":"acme:42"}` {
+			t.Fatalf("expected context value and params preserved, got %s", body)
+		}
+		if rec.Header().Get("X-Classic") != "1" {
+			t.Fatalf("expected header set by classic middleware")
+		}
+		if statuses[len(statuses)-1] != http.StatusOK {
+			t.Fatalf("expected wrapped writer to see the handler's status, got %v", statuses)
+		}
+	})
+
+	t.Run("errors go through the wrapped writer", func(t *testing.T) {
+		rec := serve("/users/missing", "acme")
+
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if statuses[len(statuses)-1] != http.StatusNotFound {
+			t.Fatalf("expected wrapped writer to see the error status, got %v", statuses)
+		}
+	})
+
+	t.Run("short-circuits", func(t *testing.T) {
+		rec := serve("/users/42", "")
+
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("expected status 403, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if strings.Contains(rec.Body.String(), "message") {
+			t.Fatalf("did not expect the handler to run, got %s", rec.Body.String())
+		}
+	})
+}
+
+func TestHandleRedirectsRunsMiddleware(t *testing.T) {
+	router := NewWithConfig(&Config{HandleRedirects: true})
+	var seen []string
+	router.Use(func(w http.ResponseWriter, r *http.Request, next Next) {
+		seen = append(seen, "root "+r.URL.Path)
+		next(nil)
+	})
+
+	api := router.Mount("/api", nil)
+	api.Use(func(w http.ResponseWriter, r *http.Request, next Next) {
+		seen = append(seen, "api "+r.URL.Path)
+		next(nil)
+	})
+
+	GET(api, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	})
+
+	cases := []struct {
+		method   string
+		path     string
+		status   int
+		location string
+	}{
+		{http.MethodGet, "/api/users/", http.StatusMovedPermanently, "/api/users"},
+		{http.MethodGet, "/api//users?page=2", http.StatusMovedPermanently, "/api/users?page=2"},
+		{http.MethodGet, "/api/missing/", http.StatusNotFound, ""},
+	}
+
+	for _, tc := range cases {
+		seen = nil
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d", tc.path, tc.status, rec.Code)
+		}
+		if loc := rec.Header().Get("Location"); loc != tc.location {
+			t.Fatalf("%s: expected Location %q, got %q", tc.path, tc.location, loc)
+		}
+		if len(seen) != 2 {
+			t.Fatalf("%s: expected root and mount middleware to run, got %v", tc.path, seen)
+		}
+	}
+}
+
+func TestHandleRedirectsStopsInMiddleware(t *testing.T) {
+	router := NewWithConfig(&Config{HandleRedirects: true})
+	router.Use(func(w http.ResponseWriter, r *http.Request, next Next) {
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next(nil)
+	})
+
+	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/", nil))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected middleware to reject before redirecting, got %d", rec.Code)
+	}
+}
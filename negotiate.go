@@ -0,0 +1,142 @@
+package sprout
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// routeVariants holds every handler registered for one method and path, keyed
+// by the media type of its success response. The first registered variant is
+// the default.
+type routeVariants struct {
+	mu      sync.RWMutex
+	entries []*routeEntry
+	types   []string
+}
+
+// add registers entry as the variant producing mediaType. Registering the same
+// media type twice for a method and path is a programming error and panics.
+func (rv *routeVariants) add(method, path, mediaType string, entry *routeEntry) {
+	rv.mu.Lock()
+	defer rv.mu.Unlock()
+
+	for _, existing := range rv.types {
+		if strings.EqualFold(existing, baseMediaType(mediaType)) {
+			panic(fmt.Sprintf("sprout: a %s handler producing %q is already registered for path '%s'", method, mediaType, path))
+		}
+	}
+	rv.entries = append(rv.entries, entry)
+	rv.types = append(rv.types, baseMediaType(mediaType))
+}
+
+// selectEntry picks the variant named by the request's Accept header,
+// falling back to the default when nothing more specific matches. negotiated
+// reports whether there was a choice to make, so the response depends on
+// Accept.
+func (rv *routeVariants) selectEntry(req *http.Request) (entry *routeEntry, negotiated bool) {
+	rv.mu.RLock()
+	defer rv.mu.RUnlock()
+
+	if len(rv.entries) == 1 {
+		return rv.entries[0], false
+	}
+
+	for _, accepted := range parseAccept(req.Header.Get("Accept")) {
+		if strings.Contains(accepted, "*") {
+			break
+		}
+		for i, mediaType := range rv.types {
+			if strings.EqualFold(accepted, mediaType) {
+				return rv.entries[i], true
+			}
+		}
+	}
+	return rv.entries[0], true
+}
+
+// routeVariantsFor returns the variant set for method and path, creating it on
+// first use. created reports whether the caller must install the route.
+func (r *routerRegistry) routeVariantsFor(method, path string) (rv *routeVariants, created bool) {
+	key := method + " " + path
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.routes == nil {
+		r.routes = make(map[string]*routeVariants)
+	}
+	if rv, ok := r.routes[key]; ok {
+		return rv, false
+	}
+	rv = &routeVariants{}
+	r.routes[key] = rv
+	return rv, true
+}
+
+// baseMediaType strips parameters such as charset from a media type.
+func baseMediaType(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	return contentType
+}
+
+// prefersXML reports whether an Accept header ranks an XML media type ahead
+// of JSON and wildcards.
+func prefersXML(header string) bool {
+	for _, accepted := range parseAccept(header) {
+		switch strings.ToLower(accepted) {
+		case "application/xml", "text/xml":
+			return true
+		case "application/json", "*/*", "application/*":
+			return false
+		}
+	}
+	return false
+}
+
+// parseAccept returns the media ranges of an Accept header ordered by
+// preference. Parameters other than q are ignored and ranges with q=0 dropped.
+func parseAccept(header string) []string {
+	if header == "" {
+		return nil
+	}
+
+	type mediaRange struct {
+		value string
+		q     float64
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(raw, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		ranges = append(ranges, mediaRange{value: mediaType, q: q})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	values := make([]string, len(ranges))
+	for i, r := range ranges {
+		values[i] = r.value
+	}
+	return values
+}
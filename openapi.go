@@ -2,14 +2,18 @@ package sprout
 
 import (
 	"bytes"
+	"encoding"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"reflect"
+	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 
 	"github.com/getkin/kin-openapi/openapi3"
@@ -27,6 +31,23 @@ type openAPIDocument struct {
 	mu        sync.RWMutex
 	doc       *openapi3.T
 	typeNames map[reflect.Type]string
+	// nameOwners maps component names back to their type to detect collisions.
+	nameOwners map[string]reflect.Type
+
+	// dynamicServers derives a server entry from each /swagger request when
+	// no static servers are configured.
+	dynamicServers bool
+
+	// customize post-processes a copy of the document before it is marshaled.
+	// customized caches the result until another route is registered.
+	customize  func(*openapi3.T)
+	customized *openapi3.T
+
+	// descriptions holds DescribeType metadata keyed by type.
+	descriptions map[reflect.Type]*typeDescription
+
+	// directional caches which struct types are documented per direction.
+	directional map[reflect.Type]bool
 }
 
 // OpenAPIInfo configures high-level OpenAPI document metadata.
@@ -38,6 +59,41 @@ type OpenAPIInfo struct {
 	Contact     *OpenAPIContact
 	License     *OpenAPILicense
 	Servers     []OpenAPIServer
+
+	// Tags describe the tags routes are grouped under with WithTags or
+	// Config.OpenAPITags, in the order Swagger UI lists them.
+	Tags []OpenAPITag
+
+	// SecuritySchemes are documented under components.securitySchemes, keyed
+	// by the name routes refer to with WithSecurity.
+	SecuritySchemes map[string]OpenAPISecurityScheme
+}
+
+// OpenAPITag describes a tag operations are grouped under.
+type OpenAPITag struct {
+	Name        string
+	Description string
+}
+
+// OpenAPISecurityScheme describes how clients authenticate, e.g.
+// {Type: "http", Scheme: "bearer", BearerFormat: "JWT"} or
+// {Type: "apiKey", In: "header", Name: "X-API-Key"}.
+type OpenAPISecurityScheme struct {
+	// Type is "http", "apiKey" or "openIdConnect".
+	Type        string
+	Description string
+
+	// Scheme and BearerFormat apply to "http" schemes, e.g. "basic" or
+	// "bearer" with format "JWT".
+	Scheme       string
+	BearerFormat string
+
+	// In ("header", "query" or "cookie") and Name apply to "apiKey" schemes.
+	In   string
+	Name string
+
+	// OpenIDConnectURL applies to "openIdConnect" schemes.
+	OpenIDConnectURL string
 }
 
 // OpenAPIContact describes the API contact information.
@@ -79,6 +135,15 @@ func cloneOpenAPIInfo(info OpenAPIInfo) *OpenAPIInfo {
 	if len(info.Servers) > 0 {
 		clone.Servers = append([]OpenAPIServer(nil), info.Servers...)
 	}
+	if len(info.Tags) > 0 {
+		clone.Tags = append([]OpenAPITag(nil), info.Tags...)
+	}
+	if len(info.SecuritySchemes) > 0 {
+		clone.SecuritySchemes = make(map[string]OpenAPISecurityScheme, len(info.SecuritySchemes))
+		for name, scheme := range info.SecuritySchemes {
+			clone.SecuritySchemes[name] = scheme
+		}
+	}
 	return &clone
 }
 
@@ -126,6 +191,29 @@ func newOpenAPIDocument(info *OpenAPIInfo) *openAPIDocument {
 		Components: &components,
 	}
 
+	if info != nil && len(info.SecuritySchemes) > 0 {
+		components.SecuritySchemes = make(openapi3.SecuritySchemes, len(info.SecuritySchemes))
+		for name, scheme := range info.SecuritySchemes {
+			components.SecuritySchemes[name] = &openapi3.SecuritySchemeRef{
+				Value: &openapi3.SecurityScheme{
+					Type:             scheme.Type,
+					Description:      scheme.Description,
+					Scheme:           scheme.Scheme,
+					BearerFormat:     scheme.BearerFormat,
+					In:               scheme.In,
+					Name:             scheme.Name,
+					OpenIdConnectUrl: scheme.OpenIDConnectURL,
+				},
+			}
+		}
+	}
+
+	if info != nil {
+		for _, tag := range info.Tags {
+			doc.Tags = append(doc.Tags, &openapi3.Tag{Name: tag.Name, Description: tag.Description})
+		}
+	}
+
 	if info != nil && len(info.Servers) > 0 {
 		doc.Servers = make(openapi3.Servers, len(info.Servers))
 		for i, server := range info.Servers {
@@ -137,54 +225,102 @@ func newOpenAPIDocument(info *OpenAPIInfo) *openAPIDocument {
 	}
 
 	return &openAPIDocument{
-		doc:       doc,
-		typeNames: make(map[reflect.Type]string),
+		doc:        doc,
+		typeNames:  make(map[reflect.Type]string),
+		nameOwners: make(map[string]reflect.Type),
 	}
 }
 
-func (d *openAPIDocument) RegisterRoute(method, fullPath string, reqType, respType reflect.Type, expectedErrors []reflect.Type) {
+// hasSecurityScheme reports whether the document defines the security scheme
+// name.
+func (d *openAPIDocument) hasSecurityScheme(name string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	_, ok := d.doc.Components.SecuritySchemes[name]
+	return ok
+}
+
+func (d *openAPIDocument) RegisterRoute(method, fullPath string, reqType, respType reflect.Type, cfg *routeConfig) {
 	if d == nil {
 		return
 	}
 
+	if cfg == nil {
+		cfg = &routeConfig{}
+	}
+
 	normalizedPath := toOpenAPIPath(fullPath)
 
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	d.customized = nil
+
 	parameters, requestBody := d.buildRequestArtifactsLocked(reqType)
-	successStatus := extractStatusCode(respType, http.StatusOK)
-	successSchema := d.schemaRefLocked(respType)
+	if requestBody != nil && cfg.optionalBody {
+		requestBody.Value.Required = false
+	}
+	if requestBody != nil && cfg.requestEnvelopeKey != "" {
+		if media := requestBody.Value.Content["application/json"]; media != nil {
+			media.Schema = envelopeSchemaRef(cfg.requestEnvelopeKey, media.Schema)
+		}
+	}
+	if requestBody != nil && cfg.exampleRequest != nil {
+		if media := requestBody.Value.Content["application/json"]; media != nil {
+			media.Example = requestExample(cfg.exampleRequest, cfg.requestEnvelopeKey)
+		}
+	}
+	successStatus := extractStatusCode(respType, cfg.successStatus())
+	bodySchema := d.schemaRefLocked(respType, responseSchema)
+	enveloped := false
+	if field, ok := textBodyField(respType); ok {
+		bodySchema = d.fieldSchemaRefLocked(field, responseSchema)
+	} else if cfg.encodedResponse {
+		bodySchema = openapi3.NewStringSchema().WithFormat("binary").NewRef()
+	} else {
+		if cfg.responseEnvelopeKey != "" {
+			bodySchema = envelopeSchemaRef(cfg.responseEnvelopeKey, bodySchema)
+		}
+		enveloped = cfg.responseEnvelope != nil
+	}
 
 	responses := openapi3.NewResponses()
 
-	successResponse := openapi3.NewResponse().WithDescription("Successful response")
-	successResponse.Content = openapi3.Content{
-		"application/json": &openapi3.MediaType{
-			Schema: successSchema,
-		},
+	for _, status := range successStatuses(successStatus, cfg.extraStatuses) {
+		successSchema := bodySchema
+		if enveloped {
+			successSchema = d.responseEnvelopeSchemaLocked(cfg.responseEnvelope, status, bodySchema)
+		}
+		successResponse := openapi3.NewResponse().WithDescription("Successful response")
+		successResponse.Content = openapi3.Content{
+			cfg.responseContentType(): &openapi3.MediaType{
+				Schema: successSchema,
+			},
+		}
+		if cfg.exampleResponse != nil && !cfg.encodedResponse {
+			successResponse.Content[cfg.responseContentType()].Example = responseExample(cfg.exampleResponse, status, cfg)
+		}
+		successResponse.Headers = d.responseHeadersLocked(respType)
+		responses.Set(strconv.Itoa(status), &openapi3.ResponseRef{Value: successResponse})
 	}
-	responses.Set(strconv.Itoa(successStatus), &openapi3.ResponseRef{Value: successResponse})
 
-	for _, errType := range expectedErrors {
+	for _, errType := range cfg.expectedErrors {
 		if errType == nil {
 			continue
 		}
 		status := extractStatusCode(errType, http.StatusInternalServerError)
-		errResponse := openapi3.NewResponse().WithDescription(errType.Name())
-		errResponse.Content = openapi3.Content{
-			"application/json": &openapi3.MediaType{
-				Schema: d.schemaRefLocked(errType),
-			},
-		}
-		responses.Set(strconv.Itoa(status), &openapi3.ResponseRef{Value: errResponse})
+		responses.Set(strconv.Itoa(status), d.errorResponseLocked(errType, status))
+	}
+	if cfg.parentChecks {
+		notFoundType := typeOf[ResourceNotFoundError]()
+		responses.Set(strconv.Itoa(http.StatusNotFound), d.errorResponseLocked(notFoundType, http.StatusNotFound))
 	}
 
 	if responses.Default() == nil {
 		defaultResponse := openapi3.NewResponse().WithDescription("Unexpected error")
 		defaultResponse.Content = openapi3.Content{
 			"application/json": &openapi3.MediaType{
-				Schema: d.schemaRefLocked(typeOf[Error]()),
+				Schema: d.schemaRefLocked(typeOf[Error](), responseSchema),
 			},
 		}
 		responses.Set("default", &openapi3.ResponseRef{Value: defaultResponse})
@@ -192,6 +328,8 @@ func (d *openAPIDocument) RegisterRoute(method, fullPath string, reqType, respTy
 
 	op := &openapi3.Operation{
 		OperationID: buildOperationID(method, normalizedPath),
+		Summary:     cfg.summary,
+		Description: cfg.description,
 		Parameters:  parameters,
 		Responses:   responses,
 	}
@@ -199,6 +337,21 @@ func (d *openAPIDocument) RegisterRoute(method, fullPath string, reqType, respTy
 	if requestBody != nil {
 		op.RequestBody = requestBody
 	}
+	if len(cfg.tags) > 0 {
+		op.Tags = append([]string(nil), cfg.tags...)
+	}
+	op.Deprecated = cfg.deprecated
+	if cfg.security != nil {
+		security := make(openapi3.SecurityRequirements, len(cfg.security))
+		for i, names := range cfg.security {
+			requirement := openapi3.NewSecurityRequirement()
+			for _, name := range names {
+				requirement.Authenticate(name)
+			}
+			security[i] = requirement
+		}
+		op.Security = &security
+	}
 
 	pathItem := d.doc.Paths.Value(normalizedPath)
 	if pathItem == nil {
@@ -206,6 +359,13 @@ func (d *openAPIDocument) RegisterRoute(method, fullPath string, reqType, respTy
 		d.doc.Paths.Set(normalizedPath, pathItem)
 	}
 
+	// Another variant of this route was documented already: add this
+	// variant's responses alongside it, keeping the first variant's request.
+	if existing := pathItem.Operations()[strings.ToUpper(method)]; existing != nil {
+		mergeResponses(existing.Responses, op.Responses)
+		return
+	}
+
 	switch strings.ToUpper(method) {
 	case http.MethodGet:
 		pathItem.Get = op
@@ -224,6 +384,65 @@ func (d *openAPIDocument) RegisterRoute(method, fullPath string, reqType, respTy
 	}
 }
 
+// successStatuses lists the documented success statuses: the response type's
+// own status first, then the extras from WithResponses without duplicates.
+func successStatuses(status int, extra []int) []int {
+	statuses := []int{status}
+	for _, code := range extra {
+		if !slices.Contains(statuses, code) {
+			statuses = append(statuses, code)
+		}
+	}
+	return statuses
+}
+
+// requestExample returns the JSON body a client would send for example,
+// wrapped under envelopeKey when the route expects one.
+func requestExample(example any, envelopeKey string) any {
+	var body any = example
+	if isStructLike(reflect.ValueOf(example)) {
+		body = toJSONMap(example)
+	}
+	if envelopeKey != "" {
+		body = map[string]any{envelopeKey: body}
+	}
+	return body
+}
+
+// responseExample returns the body the route would send for example,
+// including any response envelope.
+func responseExample(example any, status int, cfg *routeConfig) any {
+	body := prepareResponseBody(example)
+	if cfg.responseEnvelopeKey != "" {
+		body = map[string]any{cfg.responseEnvelopeKey: body}
+	}
+	if cfg.responseEnvelope != nil {
+		if envelope := cfg.responseEnvelope(body, status); envelope != nil {
+			body = prepareResponseBody(envelope)
+		}
+	}
+	return body
+}
+
+// mergeResponses adds the responses and media types of src that dst lacks.
+func mergeResponses(dst, src *openapi3.Responses) {
+	for status, ref := range src.Map() {
+		current := dst.Value(status)
+		if current == nil || current.Value == nil {
+			dst.Set(status, ref)
+			continue
+		}
+		if current.Value.Content == nil {
+			current.Value.Content = openapi3.Content{}
+		}
+		for mediaType, media := range ref.Value.Content {
+			if _, ok := current.Value.Content[mediaType]; !ok {
+				current.Value.Content[mediaType] = media
+			}
+		}
+	}
+}
+
 func (d *openAPIDocument) buildRequestArtifactsLocked(reqType reflect.Type) (openapi3.Parameters, *openapi3.RequestBodyRef) {
 	reqType = derefType(reqType)
 	if reqType == nil || reqType.Kind() != reflect.Struct {
@@ -233,146 +452,460 @@ func (d *openAPIDocument) buildRequestArtifactsLocked(reqType reflect.Type) (ope
 	var params openapi3.Parameters
 	var bodyRequired bool
 	var hasBody bool
+	content := openapi3.Content{}
+	formSchema := openapi3.NewObjectSchema()
+	var hasFiles bool
 
 	for _, field := range exportedFields(reqType) {
-		switch {
-		case field.Tag.Get("path") != "":
-			params = append(params, d.parameterFromFieldLocked(field, "path", field.Tag.Get("path"), true))
-		case field.Tag.Get("query") != "":
-			required := hasRequiredValidation(field.Tag.Get("validate"))
-			params = append(params, d.parameterFromFieldLocked(field, "query", field.Tag.Get("query"), required))
-		case field.Tag.Get("header") != "":
-			required := hasRequiredValidation(field.Tag.Get("validate"))
-			params = append(params, d.parameterFromFieldLocked(field, "header", field.Tag.Get("header"), required))
-		default:
-			if shouldExcludeFromJSON(field) {
-				continue
+		if fieldParams := d.parametersFromFieldLocked(field); len(fieldParams) > 0 {
+			params = append(params, fieldParams...)
+			continue
+		}
+
+		if isTextBodyField(field) {
+			content["text/plain"] = &openapi3.MediaType{Schema: d.fieldSchemaRefLocked(field, requestSchema)}
+			if hasRequiredValidation(field.Tag.Get("validate")) {
+				bodyRequired = true
 			}
-			tagInfo := parseJSONTag(field)
-			if tagInfo.Name == "" || isUnwrapField(field) {
-				continue
+			continue
+		}
+
+		if isFormField(field) || isFileField(field) {
+			name := field.Tag.Get("form")
+			if isFileField(field) {
+				name = field.Tag.Get("file")
+				formSchema.Properties[name] = fileSchemaRef(field.Type)
+				hasFiles = true
+			} else {
+				formSchema.Properties[name] = d.fieldSchemaRefLocked(field, requestSchema)
 			}
-			if hasRequiredValidation(field.Tag.Get("validate")) && !tagInfo.OmitEmpty {
+			if hasRequiredValidation(field.Tag.Get("validate")) {
+				formSchema.Required = append(formSchema.Required, name)
 				bodyRequired = true
 			}
-			hasBody = true
+			continue
+		}
+
+		if shouldExcludeFromRequestJSON(field) {
+			continue
+		}
+		tagInfo := parseJSONTag(field)
+		if tagInfo.Name == "" || isUnwrapField(field) {
+			continue
 		}
+		if hasRequiredValidation(field.Tag.Get("validate")) && !tagInfo.OmitEmpty {
+			bodyRequired = true
+		}
+		hasBody = true
 	}
 
-	if len(params) > 1 {
-		sort.Slice(params, func(i, j int) bool {
-			pi := params[i].Value
-			pj := params[j].Value
-			if pi == nil || pj == nil {
-				return i < j
-			}
-			if pi.In == pj.In {
-				return pi.Name < pj.Name
-			}
-			return pi.In < pj.In
-		})
+	sortParameters(params)
+
+	if len(formSchema.Properties) > 0 {
+		sort.Strings(formSchema.Required)
+		mediaType := "application/x-www-form-urlencoded"
+		if hasFiles {
+			mediaType = "multipart/form-data"
+		}
+		content[mediaType] = &openapi3.MediaType{Schema: &openapi3.SchemaRef{Value: formSchema}}
 	}
 
-	if !hasBody {
+	if hasBody {
+		content["application/json"] = &openapi3.MediaType{
+			Schema: d.schemaRefLocked(reqType, requestSchema),
+		}
+	}
+	if len(content) == 0 {
 		return params, nil
 	}
 
-	schemaRef := d.schemaRefLocked(reqType)
-
 	return params, &openapi3.RequestBodyRef{
 		Value: &openapi3.RequestBody{
 			Required: bodyRequired,
-			Content: openapi3.Content{
-				"application/json": &openapi3.MediaType{
-					Schema: schemaRef,
-				},
-			},
+			Content:  content,
 		},
 	}
 }
 
+// envelopeSchemaRef documents a body nested under key, as produced by
+// Config.RequestEnvelopeKey and Config.ResponseEnvelopeKey.
+func envelopeSchemaRef(key string, inner *openapi3.SchemaRef) *openapi3.SchemaRef {
+	schema := openapi3.NewObjectSchema()
+	schema.Properties[key] = inner
+	schema.Required = []string{key}
+	return &openapi3.SchemaRef{Value: schema}
+}
+
+// envelopePlaceholder stands in for the response body when documenting a
+// Config.ResponseEnvelope.
+type envelopePlaceholder struct{}
+
+// responseEnvelopeSchemaLocked documents the value returned by envelope by
+// calling it with a placeholder body. Top-level properties holding the
+// placeholder are documented with body; the others from their Go types. An
+// envelope that ignores the placeholder or panics leaves body unchanged.
+func (d *openAPIDocument) responseEnvelopeSchemaLocked(envelope func(any, int) any, status int, body *openapi3.SchemaRef) (ref *openapi3.SchemaRef) {
+	placeholder := &envelopePlaceholder{}
+	defer func() {
+		if recover() != nil {
+			ref = body
+		}
+	}()
+
+	result := envelope(placeholder, status)
+	schema := openapi3.NewObjectSchema()
+	found := false
+
+	v := reflect.Indirect(reflect.ValueOf(result))
+	switch {
+	case !v.IsValid():
+		return body
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		for _, key := range v.MapKeys() {
+			value := v.MapIndex(key).Interface()
+			if value == any(placeholder) {
+				found = true
+			}
+			schema.Properties[key.String()] = d.envelopeValueSchemaLocked(value, placeholder, body)
+		}
+	case v.Kind() == reflect.Struct:
+		for _, field := range exportedFields(v.Type()) {
+			if shouldExcludeFromJSON(field) {
+				continue
+			}
+			tagInfo := parseJSONTag(field)
+			if tagInfo.Name == "" {
+				continue
+			}
+			fieldValue := v.FieldByIndex(field.Index)
+			if fieldValue.Kind() != reflect.Interface {
+				schema.Properties[tagInfo.Name] = d.propertySchemaRefLocked(field, responseSchema)
+				continue
+			}
+			if fieldValue.Interface() == any(placeholder) {
+				found = true
+			}
+			schema.Properties[tagInfo.Name] = d.envelopeValueSchemaLocked(fieldValue.Interface(), placeholder, body)
+		}
+	}
+
+	if !found {
+		return body
+	}
+	return &openapi3.SchemaRef{Value: schema}
+}
+
+// envelopeValueSchemaLocked documents one dynamically typed envelope value.
+func (d *openAPIDocument) envelopeValueSchemaLocked(value any, placeholder *envelopePlaceholder, body *openapi3.SchemaRef) *openapi3.SchemaRef {
+	switch {
+	case value == any(placeholder):
+		return body
+	case value == nil:
+		return &openapi3.SchemaRef{Value: openapi3.NewSchema()}
+	default:
+		return d.inlineSchemaRefLocked(reflect.TypeOf(value), responseSchema)
+	}
+}
+
+// fileSchemaRef documents a file part as binary data, or an array of them for
+// multi-file fields such as []*multipart.FileHeader.
+func fileSchemaRef(t reflect.Type) *openapi3.SchemaRef {
+	binary := openapi3.NewStringSchema()
+	binary.Format = "binary"
+
+	t = derefType(t)
+	if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8 {
+		schema := openapi3.NewArraySchema()
+		schema.Items = &openapi3.SchemaRef{Value: binary}
+		return &openapi3.SchemaRef{Value: schema}
+	}
+	return &openapi3.SchemaRef{Value: binary}
+}
+
+// parametersFromFieldLocked returns the parameters bound by a request field:
+// one for a path, query, header or cookie tag, or every parameter of a group struct.
+func (d *openAPIDocument) parametersFromFieldLocked(field reflect.StructField) openapi3.Parameters {
+	switch {
+	case field.Tag.Get("path") != "":
+		return openapi3.Parameters{d.parameterFromFieldLocked(field, "path", field.Tag.Get("path"), true)}
+	case field.Tag.Get("query") != "":
+		required := hasRequiredValidation(field.Tag.Get("validate"))
+		param := d.parameterFromFieldLocked(field, "query", field.Tag.Get("query"), required)
+		if isDeepObjectField(field) {
+			explode := true
+			param.Value.Style = openapi3.SerializationDeepObject
+			param.Value.Explode = &explode
+		} else if isQuerySliceField(field) && hasSproutOption(field, "nosplit") {
+			explode := true
+			param.Value.Style = openapi3.SerializationForm
+			param.Value.Explode = &explode
+		}
+		return openapi3.Parameters{param}
+	case field.Tag.Get("header") != "":
+		required := hasRequiredValidation(field.Tag.Get("validate"))
+		return openapi3.Parameters{d.parameterFromFieldLocked(field, "header", field.Tag.Get("header"), required)}
+	case field.Tag.Get("cookie") != "":
+		required := hasRequiredValidation(field.Tag.Get("validate"))
+		return openapi3.Parameters{d.parameterFromFieldLocked(field, "cookie", cookieTagName(field), required)}
+	case isParameterGroupField(field):
+		var params openapi3.Parameters
+		for _, nested := range exportedFields(field.Type) {
+			params = append(params, d.parametersFromFieldLocked(nested)...)
+		}
+		return params
+	}
+	return nil
+}
+
 func (d *openAPIDocument) parameterFromFieldLocked(field reflect.StructField, location, name string, required bool) *openapi3.ParameterRef {
 	if name == "" {
 		name = field.Name
 	}
 
+	schema := d.fieldSchemaRefLocked(field, requestSchema)
+	if def, ok := field.Tag.Lookup("default"); ok && schema.Value != nil && schema.Ref == "" {
+		schema.Value.Default = tagValue(field, def)
+	}
+
 	return &openapi3.ParameterRef{
 		Value: &openapi3.Parameter{
-			Name:     name,
-			In:       location,
-			Required: required || location == "path",
-			Schema:   d.inlineSchemaRefLocked(field.Type),
+			Name:       name,
+			In:         location,
+			Required:   required || location == "path",
+			Deprecated: isDeprecatedField(field),
+			Schema:     schema,
 		},
 	}
 }
 
-func (d *openAPIDocument) inlineSchemaRefLocked(t reflect.Type) *openapi3.SchemaRef {
+// tagValue converts a `default:` or `example:` tag to a value of the field's
+// type, so the document shows 20 rather than "20". Types documented as strings,
+// such as TextUnmarshalers, and values that do not parse are documented as the
+// raw string.
+func tagValue(field reflect.StructField, raw string) any {
+	if isTextType(derefType(field.Type)) {
+		return raw
+	}
+	value := reflect.New(derefType(field.Type)).Elem()
+	var err error
+	if value.Kind() == reflect.Slice {
+		_, _, err = setSliceValue(value, []string{raw}, !hasSproutOption(field, "nosplit"))
+	} else {
+		err = setFieldValue(value, raw)
+	}
+	if err != nil {
+		return raw
+	}
+	return value.Interface()
+}
+
+// errorResponseLocked documents declared error type errType, answered with
+// status.
+func (d *openAPIDocument) errorResponseLocked(errType reflect.Type, status int) *openapi3.ResponseRef {
+	errResponse := openapi3.NewResponse().WithDescription(errType.Name())
+	errResponse.Headers = d.responseHeadersLocked(errType)
+	if !isRedirectStatus(status) || hasBodyFields(errType) {
+		errResponse.Content = openapi3.Content{
+			"application/json": &openapi3.MediaType{
+				Schema: d.schemaRefLocked(errType, responseSchema),
+			},
+		}
+	}
+	return &openapi3.ResponseRef{Value: errResponse}
+}
+
+// responseHeadersLocked documents the `header:` fields of a response or error
+// type, which extractHeaders sends when they are non-empty strings, and the
+// Retry-After header of RetryAfterer errors. Content-Type is left to the media
+// type. It returns nil when t sets no headers.
+func (d *openAPIDocument) responseHeadersLocked(t reflect.Type) openapi3.Headers {
+	t = derefType(t)
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	headers := openapi3.Headers{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("header")
+		if name == "" || field.Type.Kind() != reflect.String || strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		headers[name] = &openapi3.HeaderRef{
+			Value: &openapi3.Header{
+				Parameter: openapi3.Parameter{
+					Required: hasRequiredValidation(field.Tag.Get("validate")),
+					Schema:   d.fieldSchemaRefLocked(field, responseSchema),
+				},
+			},
+		}
+	}
+	if t.Implements(retryAftererType) || reflect.PointerTo(t).Implements(retryAftererType) {
+		headers["Retry-After"] = &openapi3.HeaderRef{
+			Value: &openapi3.Header{
+				Parameter: openapi3.Parameter{
+					Description: "Seconds to wait before retrying",
+					Schema:      openapi3.NewIntegerSchema().NewRef(),
+				},
+			},
+		}
+	}
+
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
+
+var retryAftererType = reflect.TypeOf((*RetryAfterer)(nil)).Elem()
+
+// schemaDirection tells whether a schema documents a request or a response.
+// Struct types with readonly or writeonly fields get one component per
+// direction.
+type schemaDirection int
+
+const (
+	requestSchema schemaDirection = iota
+	responseSchema
+)
+
+// componentSuffix is appended to the component name of a type documented
+// separately per direction.
+func (dir schemaDirection) componentSuffix() string {
+	if dir == requestSchema {
+		return "_Request"
+	}
+	return "_Response"
+}
+
+// fieldSchemaRefLocked builds the schema for a struct field and applies metadata
+// carried by the field's tags. Component references are returned untouched.
+func (d *openAPIDocument) fieldSchemaRefLocked(field reflect.StructField, dir schemaDirection) *openapi3.SchemaRef {
+	ref := d.inlineSchemaRefLocked(field.Type, dir)
+	if ref.Ref != "" && isDeprecatedField(field) {
+		// Siblings of a $ref are ignored, so wrap it to carry the flag.
+		return &openapi3.SchemaRef{Value: &openapi3.Schema{AllOf: openapi3.SchemaRefs{ref}, Deprecated: true}}
+	}
+	if ref.Value == nil || ref.Ref != "" {
+		return ref
+	}
+
+	if isDeprecatedField(field) {
+		ref.Value.Deprecated = true
+	}
+
+	if isSensitiveField(field) {
+		if ref.Value.Type.Is("string") {
+			ref.Value.Format = "password"
+		}
+		ref.Value.WriteOnly = true
+	}
+	if isReadOnlyField(field) {
+		ref.Value.ReadOnly = true
+	}
+	if isWriteOnlyField(field) {
+		ref.Value.WriteOnly = true
+	}
+
+	if example, ok := field.Tag.Lookup("example"); ok {
+		ref.Value.Example = tagValue(field, example)
+	}
+
+	applyValidationRules(ref.Value, parseValidationRules(field.Tag.Get("validate")))
+	d.applyDescriptionLocked(field.Type, ref.Value)
+
+	return ref
+}
+
+// propertySchemaRefLocked documents a body property. Pointer fields accept and
+// marshal null, so they are nullable.
+func (d *openAPIDocument) propertySchemaRefLocked(field reflect.StructField, dir schemaDirection) *openapi3.SchemaRef {
+	ref := d.fieldSchemaRefLocked(field, dir)
+	if field.Type.Kind() == reflect.Ptr {
+		ref = nullableSchemaRef(ref)
+	}
+	return ref
+}
+
+// nullableSchemaRef marks ref as accepting null. A $ref cannot carry
+// siblings, so it is wrapped in allOf.
+func nullableSchemaRef(ref *openapi3.SchemaRef) *openapi3.SchemaRef {
+	if ref.Ref != "" {
+		return &openapi3.SchemaRef{Value: &openapi3.Schema{AllOf: openapi3.SchemaRefs{ref}, Nullable: true}}
+	}
+	if ref.Value != nil {
+		ref.Value.Nullable = true
+	}
+	return ref
+}
+
+func (d *openAPIDocument) inlineSchemaRefLocked(t reflect.Type, dir schemaDirection) *openapi3.SchemaRef {
 	t = derefType(t)
 	if t == nil {
 		return &openapi3.SchemaRef{Value: openapi3.NewObjectSchema()}
 	}
 
+	if isTextType(t) {
+		return textSchemaRef(t)
+	}
+
 	switch t.Kind() {
 	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
-		return d.schemaRefLocked(t)
+		return d.schemaRefLocked(t, dir)
 	default:
 		return d.scalarSchemaRef(t)
 	}
 }
 
-func (d *openAPIDocument) schemaRefLocked(t reflect.Type) *openapi3.SchemaRef {
+// elemSchemaRefLocked documents the elements of a slice, array or map.
+// Pointer elements are nullable; those referencing a component are wrapped in
+// allOf like pointer properties.
+func (d *openAPIDocument) elemSchemaRefLocked(t reflect.Type, dir schemaDirection) *openapi3.SchemaRef {
+	ref := d.schemaRefLocked(t, dir)
+	if t.Kind() == reflect.Ptr {
+		ref = nullableSchemaRef(ref)
+	}
+	return ref
+}
+
+func (d *openAPIDocument) schemaRefLocked(t reflect.Type, dir schemaDirection) *openapi3.SchemaRef {
 	t = derefType(t)
 	if t == nil {
 		return &openapi3.SchemaRef{Value: openapi3.NewObjectSchema()}
 	}
 
+	if isTextType(t) {
+		return textSchemaRef(t)
+	}
+
 	switch t.Kind() {
 	case reflect.Struct:
 		if unwrapType, ok := unwrapJSONFieldType(t); ok {
-			return d.schemaRefLocked(unwrapType)
+			return d.schemaRefLocked(unwrapType, dir)
+		}
+
+		if d.isDirectionalLocked(t) {
+			return d.directionalSchemaRefLocked(t, dir)
 		}
 
 		if ref, ok := d.typeNames[t]; ok {
 			return openapi3.NewSchemaRef("#/components/schemas/"+ref, nil)
 		}
 
-		name := schemaComponentName(t)
+		name := d.componentNameLocked(t)
 		d.typeNames[t] = name
-
-		if d.doc.Components.Schemas == nil {
-			d.doc.Components.Schemas = openapi3.Schemas{}
-		}
-
-		schema := openapi3.NewObjectSchema()
-		d.doc.Components.Schemas[name] = &openapi3.SchemaRef{Value: schema}
-
-		for _, field := range exportedFields(t) {
-			if shouldExcludeFromJSON(field) {
-				continue
-			}
-			tagInfo := parseJSONTag(field)
-			if tagInfo.Name == "" || isUnwrapField(field) {
-				continue
-			}
-			schema.Properties[tagInfo.Name] = d.inlineSchemaRefLocked(field.Type)
-			if hasRequiredValidation(field.Tag.Get("validate")) && !tagInfo.OmitEmpty {
-				schema.Required = append(schema.Required, tagInfo.Name)
-			}
-		}
-
-		if len(schema.Required) > 1 {
-			sort.Strings(schema.Required)
-		}
+		d.nameOwners[name] = t
+		d.buildStructComponentLocked(t, name, dir)
 
 		return openapi3.NewSchemaRef("#/components/schemas/"+name, nil)
 	case reflect.Slice, reflect.Array:
 		schema := openapi3.NewArraySchema()
-		schema.Items = d.schemaRefLocked(t.Elem())
+		schema.Items = d.elemSchemaRefLocked(t.Elem(), dir)
 		return &openapi3.SchemaRef{Value: schema}
 	case reflect.Map:
 		schema := openapi3.NewObjectSchema()
 		schema.AdditionalProperties = openapi3.AdditionalProperties{
-			Schema: d.schemaRefLocked(t.Elem()),
+			Schema: d.elemSchemaRefLocked(t.Elem(), dir),
 		}
 		return &openapi3.SchemaRef{Value: schema}
 	default:
@@ -380,6 +913,97 @@ func (d *openAPIDocument) schemaRefLocked(t reflect.Type) *openapi3.SchemaRef {
 	}
 }
 
+// directionalSchemaRefLocked references the dir variant of t, named after the
+// type's component name plus "_Request" or "_Response". The unsuffixed name
+// stays reserved for t.
+func (d *openAPIDocument) directionalSchemaRefLocked(t reflect.Type, dir schemaDirection) *openapi3.SchemaRef {
+	base, ok := d.typeNames[t]
+	if !ok {
+		base = d.componentNameLocked(t)
+		d.typeNames[t] = base
+		d.nameOwners[base] = t
+	}
+
+	name := base + dir.componentSuffix()
+	if _, ok := d.doc.Components.Schemas[name]; !ok {
+		d.nameOwners[name] = t
+		d.buildStructComponentLocked(t, name, dir)
+	}
+	return openapi3.NewSchemaRef("#/components/schemas/"+name, nil)
+}
+
+// buildStructComponentLocked documents struct t as component name. Requests
+// leave out parameter groups and leave readonly fields out of required;
+// responses omit writeonly fields.
+func (d *openAPIDocument) buildStructComponentLocked(t reflect.Type, name string, dir schemaDirection) {
+	if d.doc.Components.Schemas == nil {
+		d.doc.Components.Schemas = openapi3.Schemas{}
+	}
+
+	schema := openapi3.NewObjectSchema()
+	d.doc.Components.Schemas[name] = &openapi3.SchemaRef{Value: schema}
+
+	for _, field := range exportedFields(t) {
+		if shouldExcludeFromJSON(field) || (dir == requestSchema && isParameterGroupField(field)) {
+			continue
+		}
+		tagInfo := parseJSONTag(field)
+		if tagInfo.Name == "" || isUnwrapField(field) {
+			continue
+		}
+		if dir == responseSchema && isWriteOnlyField(field) {
+			continue
+		}
+		schema.Properties[tagInfo.Name] = d.propertySchemaRefLocked(field, dir)
+		if hasRequiredValidation(field.Tag.Get("validate")) && !tagInfo.OmitEmpty &&
+			!(dir == requestSchema && isReadOnlyField(field)) {
+			schema.Required = append(schema.Required, tagInfo.Name)
+		}
+	}
+
+	if len(schema.Required) > 1 {
+		sort.Strings(schema.Required)
+	}
+	d.applyDescriptionLocked(t, schema)
+}
+
+// isDirectionalLocked reports whether struct t, or a type reachable through
+// its JSON fields, has readonly, writeonly or parameter group fields and so is
+// documented once per direction.
+func (d *openAPIDocument) isDirectionalLocked(t reflect.Type) bool {
+	if directional, ok := d.directional[t]; ok {
+		return directional
+	}
+	if d.directional == nil {
+		d.directional = make(map[reflect.Type]bool)
+	}
+	d.directional[t] = false // guards recursive types
+
+	directional := false
+	for _, field := range exportedFields(t) {
+		if shouldExcludeFromJSON(field) || parseJSONTag(field).Name == "" {
+			continue
+		}
+		if isReadOnlyField(field) || isWriteOnlyField(field) || isParameterGroupField(field) {
+			directional = true
+			break
+		}
+		nested := derefType(field.Type)
+		for nested.Kind() == reflect.Slice || nested.Kind() == reflect.Array || nested.Kind() == reflect.Map {
+			nested = derefType(nested.Elem())
+		}
+		if unwrapType, ok := unwrapJSONFieldType(nested); ok {
+			nested = derefType(unwrapType)
+		}
+		if nested.Kind() == reflect.Struct && !isTextType(nested) && d.isDirectionalLocked(nested) {
+			directional = true
+			break
+		}
+	}
+	d.directional[t] = directional
+	return directional
+}
+
 func (d *openAPIDocument) scalarSchemaRef(t reflect.Type) *openapi3.SchemaRef {
 	switch t.Kind() {
 	case reflect.String:
@@ -398,26 +1022,49 @@ func (d *openAPIDocument) scalarSchemaRef(t reflect.Type) *openapi3.SchemaRef {
 	case reflect.Float64:
 		return &openapi3.SchemaRef{Value: openapi3.NewFloat64Schema()}
 	default:
-		// Special handling for time.Time
-		if t.PkgPath() == "time" && t.Name() == "Time" {
-			schema := openapi3.NewStringSchema()
-			schema.Format = "date-time"
-			return &openapi3.SchemaRef{Value: schema}
-		}
 		return &openapi3.SchemaRef{Value: openapi3.NewStringSchema()}
 	}
 }
 
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isTextType reports whether values of t are exchanged as strings: JSON
+// encodes TextMarshalers as strings, and parameters bind TextUnmarshalers.
+func isTextType(t reflect.Type) bool {
+	ptr := reflect.PointerTo(t)
+	if t.Implements(textMarshalerType) || ptr.Implements(textMarshalerType) {
+		return true
+	}
+	return t.Kind() == reflect.Struct && ptr.Implements(textUnmarshalerType)
+}
+
+// textSchemaRef documents a text type as a string; time.Time is a date-time.
+func textSchemaRef(t reflect.Type) *openapi3.SchemaRef {
+	schema := openapi3.NewStringSchema()
+	if t.PkgPath() == "time" && t.Name() == "Time" {
+		schema.Format = "date-time"
+	}
+	return &openapi3.SchemaRef{Value: schema}
+}
+
 func (d *openAPIDocument) ServeHTTP(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	if d == nil {
 		http.Error(w, "openapi unavailable", http.StatusInternalServerError)
 		return
 	}
 
+	var fallbackServers openapi3.Servers
+	if d.dynamicServers {
+		fallbackServers = openapi3.Servers{{URL: requestServerURL(r)}}
+	}
+
 	format := strings.ToLower(r.URL.Query().Get("format"))
 	switch format {
 	case "yaml", "yml":
-		bytes, err := d.marshalYAMLLocked()
+		bytes, err := d.marshalYAMLLocked(fallbackServers)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -425,7 +1072,7 @@ func (d *openAPIDocument) ServeHTTP(w http.ResponseWriter, r *http.Request, _ ht
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		_, _ = w.Write(bytes)
 	default:
-		data, err := d.marshalJSONLocked()
+		data, err := d.marshalJSONLocked(fallbackServers)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -439,30 +1086,106 @@ func (d *openAPIDocument) ServeHTTP(w http.ResponseWriter, r *http.Request, _ ht
 	}
 }
 
-func (d *openAPIDocument) marshalJSONLocked() ([]byte, error) {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-	return d.doc.MarshalJSON()
+// marshalJSONLocked and marshalYAMLLocked are byte-stable for identical route sets:
+// kin-openapi marshals every object (paths, components, properties, responses)
+// through maps, whose keys encoding/json and yaml.v3 both emit in sorted order,
+// and the slices Sprout builds (parameters, required lists) are sorted on insert.
+//
+// fallbackServers replace the document's servers only when none are configured.
+func (d *openAPIDocument) marshalJSONLocked(fallbackServers openapi3.Servers) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	doc, err := d.servedDocLocked()
+	if err != nil {
+		return nil, err
+	}
+	return withServers(doc, fallbackServers).MarshalJSON()
 }
 
-func (d *openAPIDocument) marshalYAMLLocked() ([]byte, error) {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-	return yaml.Marshal(d.doc)
+func (d *openAPIDocument) marshalYAMLLocked(fallbackServers openapi3.Servers) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	doc, err := d.servedDocLocked()
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(withServers(doc, fallbackServers))
+}
+
+// servedDocLocked returns the document to marshal. With a customize hook, the
+// hook runs on a deep copy the first time the document is needed after a
+// route was registered; the result is reused until the next registration.
+func (d *openAPIDocument) servedDocLocked() (*openapi3.T, error) {
+	if d.customize == nil {
+		return d.doc, nil
+	}
+	if d.customized != nil {
+		return d.customized, nil
+	}
+
+	data, err := d.doc.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	clone := &openapi3.T{}
+	if err := clone.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+
+	d.customize(clone)
+	d.customized = clone
+	return clone, nil
+}
+
+// withServers returns a shallow copy of doc carrying fallbackServers when it
+// has no static servers, and doc itself otherwise.
+func withServers(doc *openapi3.T, fallbackServers openapi3.Servers) *openapi3.T {
+	if len(fallbackServers) == 0 || len(doc.Servers) > 0 {
+		return doc
+	}
+	clone := *doc
+	clone.Servers = fallbackServers
+	return &clone
+}
+
+// requestServerURL derives the origin a client used to reach the server,
+// honouring X-Forwarded-Proto and X-Forwarded-Host set by reverse proxies.
+// Paths in the document already include the router's base path, so the
+// server URL deliberately stops at the host.
+func requestServerURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := firstHeaderValue(r.Header.Get("X-Forwarded-Proto")); proto != "" {
+		scheme = strings.ToLower(proto)
+	}
+
+	host := r.Host
+	if forwarded := firstHeaderValue(r.Header.Get("X-Forwarded-Host")); forwarded != "" {
+		host = forwarded
+	}
+
+	return scheme + "://" + host
+}
+
+// firstHeaderValue returns the first entry of a comma-separated header value.
+func firstHeaderValue(value string) string {
+	return strings.TrimSpace(strings.SplitN(value, ",", 2)[0])
 }
 
 func (s *Sprout) OpenAPIJSON() ([]byte, error) {
 	if s.openapi == nil {
 		return nil, fmt.Errorf("openapi not initialized")
 	}
-	return s.openapi.marshalJSONLocked()
+	return s.openapi.marshalJSONLocked(nil)
 }
 
 func (s *Sprout) OpenAPIYAML() ([]byte, error) {
 	if s.openapi == nil {
 		return nil, fmt.Errorf("openapi not initialized")
 	}
-	return s.openapi.marshalYAMLLocked()
+	return s.openapi.marshalYAMLLocked(nil)
 }
 
 func derefType(t reflect.Type) reflect.Type {
@@ -472,6 +1195,23 @@ func derefType(t reflect.Type) reflect.Type {
 	return t
 }
 
+// hasBodyFields reports whether values of t serialize any JSON body fields.
+func hasBodyFields(t reflect.Type) bool {
+	t = derefType(t)
+	if t == nil || t.Kind() != reflect.Struct {
+		return true
+	}
+	for _, field := range exportedFields(t) {
+		if shouldExcludeFromJSON(field) {
+			continue
+		}
+		if parseJSONTag(field).Name != "" || isUnwrapField(field) {
+			return true
+		}
+	}
+	return false
+}
+
 func exportedFields(t reflect.Type) []reflect.StructField {
 	var fields []reflect.StructField
 	for i := 0; i < t.NumField(); i++ {
@@ -484,6 +1224,269 @@ func exportedFields(t reflect.Type) []reflect.StructField {
 	return fields
 }
 
+// sortParameters orders parameters by location, then name. The sort is stable
+// so duplicate names keep registration order and the output never depends on
+// sort internals.
+func sortParameters(params openapi3.Parameters) {
+	sort.SliceStable(params, func(i, j int) bool {
+		pi := params[i].Value
+		pj := params[j].Value
+		if pi == nil || pj == nil {
+			return pi != nil && pj == nil
+		}
+		if pi.In == pj.In {
+			return pi.Name < pj.Name
+		}
+		return pi.In < pj.In
+	})
+}
+
+// validationRule is a single comma-separated entry of a validate tag, such as
+// "min=1" (Tag "min", Param "1") or "dive".
+type validationRule struct {
+	Tag   string
+	Param string
+}
+
+// parseValidationRules splits a validate tag into rules. Alternatives joined
+// with "|" cannot be expressed as one schema constraint and are skipped.
+func parseValidationRules(tag string) []validationRule {
+	if tag == "" {
+		return nil
+	}
+
+	var rules []validationRule
+	for _, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" || strings.Contains(part, "|") {
+			continue
+		}
+		name, param, _ := strings.Cut(part, "=")
+		rules = append(rules, validationRule{Tag: name, Param: param})
+	}
+	return rules
+}
+
+// splitDiveRules separates the rules that apply to a container from the rules
+// following "dive", which the validator applies to each element.
+func splitDiveRules(rules []validationRule) (container, elements []validationRule) {
+	for i, rule := range rules {
+		if rule.Tag == "dive" {
+			return rules[:i], rules[i+1:]
+		}
+	}
+	return rules, nil
+}
+
+// applyValidationRules documents validate-tag constraints on an inline schema.
+// Rules after "dive" are applied to the inline items (or map value) schema.
+func applyValidationRules(schema *openapi3.Schema, rules []validationRule) {
+	if schema == nil || len(rules) == 0 {
+		return
+	}
+
+	container, elements := splitDiveRules(rules)
+
+	for _, rule := range container {
+		switch {
+		case rule.Tag == "oneof":
+			applyEnumRule(schema, rule)
+		case schema.Type.Is("array"):
+			applyArrayRule(schema, rule)
+		case schema.Type.Is("string"):
+			applyStringRule(schema, rule)
+		case schema.Type.Is("integer") || schema.Type.Is("number"):
+			applyNumberRule(schema, rule)
+		}
+	}
+
+	if len(elements) == 0 {
+		return
+	}
+
+	var element *openapi3.SchemaRef
+	switch {
+	case schema.Type.Is("array"):
+		element = schema.Items
+	case schema.Type.Is("object"):
+		element = schema.AdditionalProperties.Schema
+		elements = skipKeyRules(elements)
+	}
+	if element != nil && element.Ref == "" {
+		applyValidationRules(element.Value, elements)
+	}
+}
+
+func applyArrayRule(schema *openapi3.Schema, rule validationRule) {
+	if minimum, maximum, ok := lengthBounds(rule); ok {
+		if minimum != nil {
+			schema.MinItems = *minimum
+		}
+		if maximum != nil {
+			schema.MaxItems = maximum
+		}
+	}
+}
+
+// lengthBounds converts a size rule on a string or slice into the inclusive
+// bounds it implies: min/gte and max/lte are bounds themselves, gt/lt are one
+// past them, and len fixes both.
+func lengthBounds(rule validationRule) (minimum, maximum *uint64, ok bool) {
+	n, err := strconv.ParseUint(rule.Param, 10, 64)
+	if err != nil {
+		return nil, nil, false
+	}
+	switch rule.Tag {
+	case "min", "gte":
+		return &n, nil, true
+	case "gt":
+		n++
+		return &n, nil, true
+	case "max", "lte":
+		return nil, &n, true
+	case "lt":
+		if n == 0 {
+			return nil, nil, false
+		}
+		n--
+		return nil, &n, true
+	case "len":
+		return &n, &n, true
+	}
+	return nil, nil, false
+}
+
+// applyNumberRule documents a bound on an integer or number: min/gte and
+// max/lte are inclusive, gt/lt exclusive, and len fixes the value.
+func applyNumberRule(schema *openapi3.Schema, rule validationRule) {
+	n, err := strconv.ParseFloat(rule.Param, 64)
+	if err != nil {
+		return
+	}
+	switch rule.Tag {
+	case "min", "gte":
+		schema.Min, schema.ExclusiveMin = &n, false
+	case "gt":
+		schema.Min, schema.ExclusiveMin = &n, true
+	case "max", "lte":
+		schema.Max, schema.ExclusiveMax = &n, false
+	case "lt":
+		schema.Max, schema.ExclusiveMax = &n, true
+	case "len", "eq":
+		schema.Min, schema.Max = &n, &n
+		schema.ExclusiveMin, schema.ExclusiveMax = false, false
+	}
+}
+
+// applyEnumRule documents a oneof rule's space-separated values as the enum
+// of a string, integer or number schema. Values that do not parse as the
+// schema's type leave the schema unchanged.
+func applyEnumRule(schema *openapi3.Schema, rule validationRule) {
+	values := splitOneOfValues(rule.Param)
+	if len(values) == 0 {
+		return
+	}
+
+	enum := make([]any, 0, len(values))
+	for _, value := range values {
+		switch {
+		case schema.Type.Is("string"):
+			enum = append(enum, value)
+		case schema.Type.Is("integer"):
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return
+			}
+			enum = append(enum, n)
+		case schema.Type.Is("number"):
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return
+			}
+			enum = append(enum, f)
+		default:
+			return
+		}
+	}
+	schema.Enum = enum
+}
+
+// oneOfValuePattern matches the values of a oneof rule the way the validator
+// reads them: single-quoted values may contain spaces.
+var oneOfValuePattern = regexp.MustCompile(`'[^']*'|\S+`)
+
+// splitOneOfValues splits a oneof parameter such as "'foo bar' baz" into its
+// values, without the quotes.
+func splitOneOfValues(param string) []string {
+	values := oneOfValuePattern.FindAllString(param, -1)
+	for i, value := range values {
+		values[i] = strings.ReplaceAll(value, "'", "")
+	}
+	return values
+}
+
+func applyStringRule(schema *openapi3.Schema, rule validationRule) {
+	if format, ok := validationFormats[rule.Tag]; ok && schema.Format == "" {
+		schema.Format = format
+	}
+	if format, ok := datetimeFormats[rule.Param]; ok && rule.Tag == "datetime" && schema.Format == "" {
+		schema.Format = format
+	}
+	if minimum, maximum, ok := lengthBounds(rule); ok {
+		if minimum != nil {
+			schema.MinLength = *minimum
+		}
+		if maximum != nil {
+			schema.MaxLength = maximum
+		}
+	}
+}
+
+// validationFormats maps validator tags to the OpenAPI string format they imply.
+var validationFormats = map[string]string{
+	"email":            "email",
+	"uuid":             "uuid",
+	"uuid3":            "uuid",
+	"uuid4":            "uuid",
+	"uuid5":            "uuid",
+	"uuid_rfc4122":     "uuid",
+	"uuid3_rfc4122":    "uuid",
+	"uuid4_rfc4122":    "uuid",
+	"uuid5_rfc4122":    "uuid",
+	"uri":              "uri",
+	"url":              "uri",
+	"http_url":         "uri",
+	"hostname":         "hostname",
+	"hostname_rfc1123": "hostname",
+	"ipv4":             "ipv4",
+	"ip4_addr":         "ipv4",
+	"ipv6":             "ipv6",
+	"ip6_addr":         "ipv6",
+	"base64":           "byte",
+}
+
+// datetimeFormats maps datetime=<layout> validator layouts to the OpenAPI
+// format they describe. Other layouts have no format equivalent.
+var datetimeFormats = map[string]string{
+	time.RFC3339:     "date-time",
+	time.RFC3339Nano: "date-time",
+	time.DateOnly:    "date",
+}
+
+// skipKeyRules drops a leading "keys ... endkeys" block, which the validator
+// applies to map keys rather than values.
+func skipKeyRules(rules []validationRule) []validationRule {
+	if len(rules) == 0 || rules[0].Tag != "keys" {
+		return rules
+	}
+	for i, rule := range rules {
+		if rule.Tag == "endkeys" {
+			return rules[i+1:]
+		}
+	}
+	return nil
+}
+
 func hasRequiredValidation(tag string) bool {
 	if tag == "" {
 		return false
@@ -509,6 +1512,34 @@ func schemaComponentName(t reflect.Type) string {
 	return sanitizeName(t.String())
 }
 
+// componentNameLocked returns a component name for t that no other type in the
+// document uses. Types whose short names collide are qualified with more of
+// their import path, and a numeric suffix settles any remaining clash (for
+// example two function-local types of the same name).
+func (d *openAPIDocument) componentNameLocked(t reflect.Type) string {
+	candidates := []string{schemaComponentName(t)}
+	if t.Name() != "" && t.PkgPath() != "" {
+		parts := strings.Split(t.PkgPath(), "/")
+		for i := len(parts) - 2; i >= 0; i-- {
+			candidates = append(candidates, sanitizeName(strings.Join(parts[i:], "_")+"_"+t.Name()))
+		}
+	}
+
+	for _, name := range candidates {
+		if _, taken := d.nameOwners[name]; !taken {
+			return name
+		}
+	}
+
+	base := candidates[0]
+	for i := 2; ; i++ {
+		name := base + "_" + strconv.Itoa(i)
+		if _, taken := d.nameOwners[name]; !taken {
+			return name
+		}
+	}
+}
+
 func sanitizeName(name string) string {
 	var builder strings.Builder
 	for _, r := range name {
@@ -2,11 +2,14 @@ package sprout
 
 import (
 	"context"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 )
@@ -326,6 +329,71 @@ func TestOpenAPIInfoOption(t *testing.T) {
 	}
 }
 
+func TestMountIsolatedOpenAPIDocuments(t *testing.T) {
+	router := New()
+
+	GET(router, "/health", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	})
+
+	v1 := router.Mount("/v1", &Config{IsolatedOpenAPI: true}, WithOpenAPIInfo(OpenAPIInfo{Title: "API v1", Version: "1"}))
+	v2 := router.Mount("/v2", &Config{IsolatedOpenAPI: true}, WithOpenAPIInfo(OpenAPIInfo{Title: "API v2", Version: "2"}))
+	admin := v2.Mount("/admin", nil)
+
+	GET(v1, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "v1"}, nil
+	})
+	GET(v2, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "v2"}, nil
+	})
+	GET(admin, "/stats", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "stats"}, nil
+	})
+
+	loadSpec := func(path string) *openapi3.T {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected %s to return 200, got %d", path, recorder.Code)
+		}
+		doc, err := openapi3.NewLoader().LoadFromData(recorder.Body.Bytes())
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", path, err)
+		}
+		return doc
+	}
+
+	root := loadSpec("/swagger")
+	if diff := cmpStringSlices(pathKeys(root.Paths), []string{"/health"}); diff != "" {
+		t.Fatalf("unexpected root paths: %s", diff)
+	}
+
+	first := loadSpec("/v1/swagger")
+	if first.Info.Title != "API v1" {
+		t.Fatalf("expected v1 title, got %q", first.Info.Title)
+	}
+	if diff := cmpStringSlices(pathKeys(first.Paths), []string{"/v1/users"}); diff != "" {
+		t.Fatalf("unexpected v1 paths: %s", diff)
+	}
+
+	second := loadSpec("/v2/swagger")
+	if second.Info.Title != "API v2" {
+		t.Fatalf("expected v2 title, got %q", second.Info.Title)
+	}
+	if diff := cmpStringSlices(pathKeys(second.Paths), []string{"/v2/admin/stats", "/v2/users"}); diff != "" {
+		t.Fatalf("unexpected v2 paths: %s", diff)
+	}
+
+	spec, err := v2.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal v2 spec: %v", err)
+	}
+	if !strings.Contains(string(spec), "/v2/users") || strings.Contains(string(spec), "/v1/users") {
+		t.Fatalf("expected OpenAPIJSON on the mount to return its own document, got %s", spec)
+	}
+}
+
 func pathKeys(paths *openapi3.Paths) []string {
 	if paths == nil {
 		return nil
@@ -338,3 +406,2288 @@ func pathKeys(paths *openapi3.Paths) []string {
 	sort.Strings(keys)
 	return keys
 }
+
+func TestOpenAPIOutputIsDeterministic(t *testing.T) {
+	type searchRequest struct {
+		Zeta   string `query:"zeta"`
+		Alpha  string `query:"alpha" validate:"required"`
+		Trace  string `header:"X-Trace"`
+		Tenant string `path:"tenant" validate:"required"`
+		Name   string `json:"name" validate:"required"`
+		Email  string `json:"email" validate:"required,email"`
+		Bio    string `json:"bio"`
+	}
+
+	type searchResponse struct {
+		Zulu  string `json:"zulu" validate:"required"`
+		Bravo string `json:"bravo" validate:"required"`
+		Kilo  []int  `json:"kilo"`
+	}
+
+	// Each build registers the same routes, components and errors in a
+	// different order.
+	build := func(reverse bool) *Sprout {
+		routes := []func(*Sprout){
+			func(router *Sprout) {
+				POST(router, "/t/:tenant/search", func(ctx context.Context, req *searchRequest) (*searchResponse, error) {
+					return nil, nil
+				}, WithErrors(&conflictError{}, &TeapotError{}))
+			},
+			func(router *Sprout) {
+				POST(router, "/t/:tenant/find", func(ctx context.Context, req *searchRequest) (*searchResponse, error) {
+					return nil, nil
+				}, WithErrors(&TeapotError{}, &conflictError{}))
+			},
+			func(router *Sprout) {
+				GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*openAPIUser, error) {
+					return nil, nil
+				})
+			},
+			func(router *Sprout) {
+				GET(router, "/t/:tenant/lookup", func(ctx context.Context, req *searchRequest) (*HelloResponse, error) {
+					return nil, nil
+				}, WithErrors(&conflictError{}))
+			},
+		}
+		if reverse {
+			for i, j := 0, len(routes)-1; i < j; i, j = i+1, j-1 {
+				routes[i], routes[j] = routes[j], routes[i]
+			}
+		}
+
+		router := New()
+		for _, register := range routes {
+			register(router)
+		}
+		return router
+	}
+
+	first, second := build(false), build(true)
+
+	firstJSON, err := first.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	secondJSON, err := second.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	if string(firstJSON) != string(secondJSON) {
+		t.Fatalf("expected identical JSON output regardless of registration order:\n%s\n%s", firstJSON, secondJSON)
+	}
+
+	firstYAML, err := first.OpenAPIYAML()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi yaml: %v", err)
+	}
+	secondYAML, err := second.OpenAPIYAML()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi yaml: %v", err)
+	}
+	if string(firstYAML) != string(secondYAML) {
+		t.Fatalf("expected identical YAML output regardless of registration order")
+	}
+
+	for i := 0; i < 10; i++ {
+		again, err := first.OpenAPIJSON()
+		if err != nil {
+			t.Fatalf("failed to marshal openapi json: %v", err)
+		}
+		if string(again) != string(firstJSON) {
+			t.Fatalf("expected repeated marshaling to be byte-stable")
+		}
+	}
+}
+
+type sensitiveLoginRequest struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required" sprout:"sensitive"`
+	PIN      int    `json:"pin" sprout:"sensitive"`
+	APIKey   string `header:"X-API-Key" sprout:"sensitive"`
+}
+
+func TestOpenAPISensitiveFields(t *testing.T) {
+	router := New()
+
+	POST(router, "/login", func(ctx context.Context, req *sensitiveLoginRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	schemaRef := doc.Components.Schemas["sprout_sensitiveLoginRequest"]
+	if schemaRef == nil || schemaRef.Value == nil {
+		t.Fatalf("expected sensitiveLoginRequest schema component")
+	}
+
+	password := schemaRef.Value.Properties["password"]
+	if password == nil || password.Value == nil {
+		t.Fatalf("expected password property")
+	}
+	if password.Value.Format != "password" || !password.Value.WriteOnly {
+		t.Fatalf("expected password to be writeOnly with format password, got format=%q writeOnly=%v", password.Value.Format, password.Value.WriteOnly)
+	}
+
+	pin := schemaRef.Value.Properties["pin"]
+	if pin == nil || pin.Value == nil || pin.Value.Format != "" || !pin.Value.WriteOnly {
+		t.Fatalf("expected a writeOnly pin without format password, got %#v", pin)
+	}
+
+	username := schemaRef.Value.Properties["username"]
+	if username == nil || username.Value == nil {
+		t.Fatalf("expected username property")
+	}
+	if username.Value.Format != "" || username.Value.WriteOnly {
+		t.Fatalf("did not expect username to be marked sensitive")
+	}
+
+	op := doc.Paths.Value("/login").Post
+	param := op.Parameters.GetByInAndName("header", "X-API-Key")
+	if param == nil || param.Schema == nil || param.Schema.Value == nil {
+		t.Fatalf("expected X-API-Key header parameter")
+	}
+	if param.Schema.Value.Format != "password" || !param.Schema.Value.WriteOnly {
+		t.Fatalf("expected X-API-Key schema to be marked sensitive")
+	}
+}
+
+type openAPICookieRequest struct {
+	Session string `cookie:"session_id" validate:"required"`
+	Theme   string `cookie:"theme"`
+	Name    string `json:"name"`
+}
+
+func TestOpenAPICookieParameters(t *testing.T) {
+	router := New()
+
+	POST(router, "/prefs", func(ctx context.Context, req *openAPICookieRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	op := doc.Paths.Value("/prefs").Post
+	session := op.Parameters.GetByInAndName("cookie", "session_id")
+	if session == nil || !session.Required {
+		t.Fatalf("expected required session_id cookie parameter, got %#v", session)
+	}
+	theme := op.Parameters.GetByInAndName("cookie", "theme")
+	if theme == nil || theme.Required {
+		t.Fatalf("expected optional theme cookie parameter, got %#v", theme)
+	}
+
+	schema := doc.Components.Schemas["sprout_openAPICookieRequest"]
+	if schema == nil || schema.Value == nil {
+		t.Fatalf("expected request body schema component")
+	}
+	if _, ok := schema.Value.Properties["Session"]; ok {
+		t.Fatalf("did not expect cookie fields in the body schema")
+	}
+	if _, ok := schema.Value.Properties["name"]; !ok {
+		t.Fatalf("expected name in the body schema")
+	}
+}
+
+type openAPIOptionalParamsRequest struct {
+	Page  *int    `query:"page"`
+	Limit *int    `query:"limit" validate:"required"`
+	Trace *string `header:"X-Trace"`
+}
+
+func TestOpenAPIPointerParameters(t *testing.T) {
+	router := New()
+
+	GET(router, "/items", func(ctx context.Context, req *openAPIOptionalParamsRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	op := doc.Paths.Value("/items").Get
+	page := op.Parameters.GetByInAndName("query", "page")
+	if page == nil || page.Required || page.Schema == nil || !page.Schema.Value.Type.Is("integer") {
+		t.Fatalf("expected optional integer page parameter, got %#v", page)
+	}
+	limit := op.Parameters.GetByInAndName("query", "limit")
+	if limit == nil || !limit.Required {
+		t.Fatalf("expected required limit parameter, got %#v", limit)
+	}
+	trace := op.Parameters.GetByInAndName("header", "X-Trace")
+	if trace == nil || trace.Required || !trace.Schema.Value.Type.Is("string") {
+		t.Fatalf("expected optional string header, got %#v", trace)
+	}
+}
+
+type directionalAccount struct {
+	ID       string             `json:"id" validate:"required" sprout:"readonly"`
+	Email    string             `json:"email" validate:"required"`
+	Password string             `json:"password" validate:"required" sprout:"writeonly"`
+	Profile  directionalProfile `json:"profile"`
+}
+
+type directionalProfile struct {
+	CreatedAt string `json:"created_at" validate:"required" sprout:"readonly"`
+	Bio       string `json:"bio"`
+}
+
+type directionalTeam struct {
+	Members []directionalAccount `json:"members"`
+}
+
+func TestOpenAPIDirectionalSchemas(t *testing.T) {
+	router := New()
+
+	POST(router, "/accounts", func(ctx context.Context, req *directionalAccount) (*directionalAccount, error) {
+		return req, nil
+	})
+	GET(router, "/team", func(ctx context.Context, req *EmptyRequest) (*directionalTeam, error) {
+		return &directionalTeam{}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	op := doc.Paths.Value("/accounts").Post
+	if ref := op.RequestBody.Value.Content["application/json"].Schema.Ref; ref != "#/components/schemas/sprout_directionalAccount_Request" {
+		t.Fatalf("expected request variant, got %q", ref)
+	}
+	if ref := op.Responses.Value("200").Value.Content["application/json"].Schema.Ref; ref != "#/components/schemas/sprout_directionalAccount_Response" {
+		t.Fatalf("expected response variant, got %q", ref)
+	}
+	if _, ok := doc.Components.Schemas["sprout_directionalAccount"]; ok {
+		t.Fatalf("did not expect an undirected component for a directional type")
+	}
+
+	request := doc.Components.Schemas["sprout_directionalAccount_Request"].Value
+	if !reflect.DeepEqual(request.Required, []string{"email", "password"}) {
+		t.Fatalf("expected readonly id left out of required, got %v", request.Required)
+	}
+	if id := request.Properties["id"]; id == nil || !id.Value.ReadOnly {
+		t.Fatalf("expected id documented as readOnly in requests")
+	}
+	if password := request.Properties["password"]; password == nil || !password.Value.WriteOnly {
+		t.Fatalf("expected password documented as writeOnly")
+	}
+	if ref := request.Properties["profile"].Ref; ref != "#/components/schemas/sprout_directionalProfile_Request" {
+		t.Fatalf("expected nested request variant, got %q", ref)
+	}
+
+	response := doc.Components.Schemas["sprout_directionalAccount_Response"].Value
+	if _, ok := response.Properties["password"]; ok {
+		t.Fatalf("expected writeonly password omitted from the response")
+	}
+	if !reflect.DeepEqual(response.Required, []string{"email", "id"}) {
+		t.Fatalf("expected id required in responses, got %v", response.Required)
+	}
+
+	profile := doc.Components.Schemas["sprout_directionalProfile_Request"].Value
+	if len(profile.Required) != 0 {
+		t.Fatalf("expected nested readonly field left out of required, got %v", profile.Required)
+	}
+
+	team := doc.Components.Schemas["sprout_directionalTeam_Response"]
+	if team == nil || team.Value.Properties["members"].Value.Items.Ref != "#/components/schemas/sprout_directionalAccount_Response" {
+		t.Fatalf("expected types containing directional types to be split too, got %#v", team)
+	}
+}
+
+type openAPISearchFilter struct {
+	Status string `query:"status" validate:"required"`
+	Owner  string `query:"owner"`
+}
+
+type openAPISearchRequest struct {
+	Filter  openAPISearchFilter
+	Tracing struct {
+		RequestID string `header:"X-Request-ID"`
+	}
+	Query string `json:"query"`
+}
+
+func TestOpenAPINestedParameterGroups(t *testing.T) {
+	router := New()
+
+	POST(router, "/search", func(ctx context.Context, req *openAPISearchRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	op := doc.Paths.Value("/search").Post
+	if op == nil {
+		t.Fatalf("expected POST operation for /search")
+	}
+
+	status := op.Parameters.GetByInAndName("query", "status")
+	if status == nil || !status.Required {
+		t.Fatalf("expected required status query parameter, got %+v", status)
+	}
+	if op.Parameters.GetByInAndName("query", "owner") == nil {
+		t.Fatalf("expected owner query parameter")
+	}
+	if op.Parameters.GetByInAndName("header", "X-Request-ID") == nil {
+		t.Fatalf("expected X-Request-ID header parameter")
+	}
+
+	schemaRef := doc.Components.Schemas["sprout_openAPISearchRequest_Request"]
+	if schemaRef == nil || schemaRef.Value == nil {
+		t.Fatalf("expected request body schema component")
+	}
+	if _, ok := schemaRef.Value.Properties["Filter"]; ok {
+		t.Fatalf("parameter group should not appear in the body schema")
+	}
+	if _, ok := schemaRef.Value.Properties["Tracing"]; ok {
+		t.Fatalf("parameter group should not appear in the body schema")
+	}
+	if _, ok := schemaRef.Value.Properties["query"]; !ok {
+		t.Fatalf("expected query body property")
+	}
+}
+
+func TestOpenAPIMapResponse(t *testing.T) {
+	router := New()
+
+	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*map[string]openAPIUser, error) {
+		return &map[string]openAPIUser{}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	resp := doc.Paths.Value("/users").Get.Responses.Value("200")
+	if resp == nil || resp.Value == nil {
+		t.Fatalf("expected 200 response in spec")
+	}
+
+	media := resp.Value.Content["application/json"]
+	if media == nil || media.Schema == nil || media.Schema.Value == nil {
+		t.Fatalf("expected application/json schema")
+	}
+
+	schema := media.Schema.Value
+	if !schema.Type.Is("object") {
+		t.Fatalf("expected object schema, got %v", schema.Type)
+	}
+	if schema.AdditionalProperties.Schema == nil {
+		t.Fatalf("expected additionalProperties schema")
+	}
+	if ref := schema.AdditionalProperties.Schema.Ref; ref != "#/components/schemas/sprout_openAPIUser" {
+		t.Fatalf("expected additionalProperties to reference sprout_openAPIUser, got %q", ref)
+	}
+}
+
+func TestOpenAPIListHandler(t *testing.T) {
+	router := New()
+
+	GET(router, "/users", List(func(ctx context.Context, req *EmptyRequest) ([]*openAPIUser, error) {
+		return nil, nil
+	}))
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	media := doc.Paths.Value("/users").Get.Responses.Value("200").Value.Content["application/json"]
+	if media == nil || media.Schema == nil || media.Schema.Value == nil {
+		t.Fatalf("expected application/json schema")
+	}
+
+	schema := media.Schema.Value
+	if !schema.Type.Is("array") {
+		t.Fatalf("expected array schema, got %v", schema.Type)
+	}
+	items := schema.Items.Value
+	if items == nil || !items.Nullable || len(items.AllOf) != 1 || items.AllOf[0].Ref != "#/components/schemas/sprout_openAPIUser" {
+		t.Fatalf("expected nullable items referencing sprout_openAPIUser, got %#v", schema.Items)
+	}
+}
+
+func TestSwaggerPath(t *testing.T) {
+	get := func(router *Sprout, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	t.Run("custom path", func(t *testing.T) {
+		router := NewWithConfig(&Config{BasePath: "/api", SwaggerPath: "/docs/openapi.json"})
+		isolated := router.Mount("/v2", &Config{IsolatedOpenAPI: true})
+		GET(isolated, "/ping", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+			return &HelloResponse{Message: "pong"}, nil
+		})
+
+		if rec := get(router, "/api/docs/openapi.json"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"openapi"`) {
+			t.Fatalf("expected document at the custom path, got %d", rec.Code)
+		}
+		if rec := get(router, "/api/swagger"); rec.Code != http.StatusNotFound {
+			t.Fatalf("expected default path to be unused, got %d", rec.Code)
+		}
+		if rec := get(router, "/api/v2/docs/openapi.json"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/api/v2/ping") {
+			t.Fatalf("expected isolated mount to inherit the path, got %d", rec.Code)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		router := NewWithConfig(&Config{DisableSwagger: true})
+		GET(router, "/ping", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+			return &HelloResponse{Message: "pong"}, nil
+		})
+		isolated := router.Mount("/v2", &Config{IsolatedOpenAPI: true})
+
+		if rec := get(router, "/swagger"); rec.Code != http.StatusNotFound {
+			t.Fatalf("expected no swagger route, got %d", rec.Code)
+		}
+		if rec := get(router, "/v2/swagger"); rec.Code != http.StatusNotFound {
+			t.Fatalf("expected mounts to inherit DisableSwagger, got %d", rec.Code)
+		}
+		if _, err := isolated.OpenAPIJSON(); err != nil {
+			t.Fatalf("expected the isolated document to still build: %v", err)
+		}
+		spec, err := router.OpenAPIJSON()
+		if err != nil || !strings.Contains(string(spec), "/ping") {
+			t.Fatalf("expected OpenAPIJSON to keep working, got %v", err)
+		}
+		if yaml, err := router.OpenAPIYAML(); err != nil || !strings.Contains(string(yaml), "/ping") {
+			t.Fatalf("expected OpenAPIYAML to keep working, got %v", err)
+		}
+	})
+}
+
+func TestSwaggerUI(t *testing.T) {
+	get := func(router *Sprout, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	router := NewWithConfig(&Config{BasePath: "/api"}, WithOpenAPIInfo(OpenAPIInfo{Title: "Pets <API>", Version: "1"}))
+	rec := get(router, "/api/swagger/ui")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Fatalf("expected an HTML page, got %q", ct)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "<title>Pets &lt;API&gt;</title>") {
+		t.Fatalf("expected the escaped document title, got %s", body)
+	}
+	if !strings.Contains(body, `url: "../swagger"`) || !strings.Contains(body, "swagger-ui-bundle.js") {
+		t.Fatalf("expected the page to load Swagger UI with the spec, got %s", body)
+	}
+
+	custom := NewWithConfig(&Config{SwaggerPath: "/docs/openapi.json"})
+	if rec := get(custom, "/docs/openapi.json/ui"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `url: "../openapi.json"`) {
+		t.Fatalf("expected the UI next to a custom spec path, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	disabled := NewWithConfig(&Config{DisableSwagger: true})
+	if rec := get(disabled, "/swagger/ui"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected DisableSwagger to skip the UI, got %d", rec.Code)
+	}
+}
+
+func TestOpenAPIDynamicServers(t *testing.T) {
+	fetchServers := func(t *testing.T, router *Sprout, req *http.Request) openapi3.Servers {
+		t.Helper()
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", recorder.Code)
+		}
+
+		doc, err := openapi3.NewLoader().LoadFromData(recorder.Body.Bytes())
+		if err != nil {
+			t.Fatalf("failed to parse openapi json: %v", err)
+		}
+		return doc.Servers
+	}
+
+	t.Run("derived from request", func(t *testing.T) {
+		router := NewWithConfig(&Config{DynamicServers: true, BasePath: "/api"})
+
+		servers := fetchServers(t, router, httptest.NewRequest(http.MethodGet, "http://docs.example.com/api/swagger", nil))
+		if len(servers) != 1 || servers[0].URL != "http://docs.example.com" {
+			t.Fatalf("expected request-derived server, got %+v", servers)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "http://internal:8080/api/swagger?format=yaml", nil)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set("X-Forwarded-Host", "api.example.com, proxy.local")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if !strings.Contains(recorder.Body.String(), "url: https://api.example.com") {
+			t.Fatalf("expected forwarded server in yaml output, got:\n%s", recorder.Body.String())
+		}
+
+		specBytes, err := router.OpenAPIJSON()
+		if err != nil {
+			t.Fatalf("failed to marshal openapi json: %v", err)
+		}
+		if strings.Contains(string(specBytes), "docs.example.com") {
+			t.Fatalf("programmatic output should not contain request-derived servers")
+		}
+	})
+
+	t.Run("static servers take precedence", func(t *testing.T) {
+		router := NewWithConfig(&Config{DynamicServers: true}, WithOpenAPIInfo(OpenAPIInfo{
+			Servers: []OpenAPIServer{{URL: "https://api.example.com"}},
+		}))
+
+		servers := fetchServers(t, router, httptest.NewRequest(http.MethodGet, "http://localhost/swagger", nil))
+		if len(servers) != 1 || servers[0].URL != "https://api.example.com" {
+			t.Fatalf("expected static server only, got %+v", servers)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		router := New()
+
+		servers := fetchServers(t, router, httptest.NewRequest(http.MethodGet, "http://localhost/swagger", nil))
+		if len(servers) != 0 {
+			t.Fatalf("expected no servers, got %+v", servers)
+		}
+	})
+}
+
+type openAPITaggedRequest struct {
+	Tags    []string `json:"tags" validate:"required,min=1,max=5"`
+	Labels  []string `json:"labels" validate:"omitempty,max=3,dive,min=2"`
+	Matrix  [][]int  `json:"matrix" validate:"len=2"`
+	Filters []string `query:"filter" validate:"max=4"`
+}
+
+type openAPITaggedResponse struct {
+	Items []openAPIUser `json:"items" validate:"min=2"`
+}
+
+func TestOpenAPIArrayLengthConstraints(t *testing.T) {
+	router := New()
+
+	POST(router, "/tagged", func(ctx context.Context, req *openAPITaggedRequest) (*openAPITaggedResponse, error) {
+		return nil, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	property := func(component, name string) *openapi3.Schema {
+		t.Helper()
+		schemaRef := doc.Components.Schemas[component]
+		if schemaRef == nil || schemaRef.Value == nil {
+			t.Fatalf("expected %s schema component", component)
+		}
+		prop := schemaRef.Value.Properties[name]
+		if prop == nil || prop.Value == nil {
+			t.Fatalf("expected %s property on %s", name, component)
+		}
+		return prop.Value
+	}
+
+	assertItems := func(name string, schema *openapi3.Schema, min uint64, max *uint64) {
+		t.Helper()
+		if schema.MinItems != min {
+			t.Fatalf("%s: expected minItems %d, got %d", name, min, schema.MinItems)
+		}
+		switch {
+		case max == nil && schema.MaxItems != nil:
+			t.Fatalf("%s: expected no maxItems, got %d", name, *schema.MaxItems)
+		case max != nil && (schema.MaxItems == nil || *schema.MaxItems != *max):
+			t.Fatalf("%s: expected maxItems %d, got %v", name, *max, schema.MaxItems)
+		}
+	}
+
+	assertItems("tags", property("sprout_openAPITaggedRequest", "tags"), 1, openapi3.Uint64Ptr(5))
+	assertItems("matrix", property("sprout_openAPITaggedRequest", "matrix"), 2, openapi3.Uint64Ptr(2))
+	assertItems("items", property("sprout_openAPITaggedResponse", "items"), 2, nil)
+
+	labels := property("sprout_openAPITaggedRequest", "labels")
+	assertItems("labels", labels, 0, openapi3.Uint64Ptr(3))
+	if labels.Items == nil || labels.Items.Value == nil || labels.Items.Value.MinItems != 0 {
+		t.Fatalf("element rules after dive must not become array constraints")
+	}
+
+	param := doc.Paths.Value("/tagged").Post.Parameters.GetByInAndName("query", "filter")
+	if param == nil || param.Schema == nil || param.Schema.Value == nil {
+		t.Fatalf("expected filter query parameter")
+	}
+	assertItems("filter", param.Schema.Value, 0, openapi3.Uint64Ptr(4))
+}
+
+type openAPIBoundedRequest struct {
+	Name    string  `json:"name" validate:"required,min=2,max=40"`
+	Code    string  `json:"code" validate:"len=3"`
+	Nick    string  `json:"nick" validate:"omitempty,gt=1,lt=11"`
+	Age     int     `json:"age" validate:"gte=18,lte=130"`
+	Score   float64 `json:"score" validate:"gt=0,lt=1"`
+	Version int     `json:"version" validate:"len=2"`
+	Tags    []int   `json:"tags" validate:"gte=1,dive,min=0,max=9"`
+	Limit   *int    `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+func TestOpenAPIScalarConstraints(t *testing.T) {
+	router := New()
+
+	POST(router, "/bounded", func(ctx context.Context, req *openAPIBoundedRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	props := doc.Components.Schemas["sprout_openAPIBoundedRequest"].Value.Properties
+
+	assertLength := func(name string, schema *openapi3.Schema, min uint64, max uint64) {
+		t.Helper()
+		if schema.MinLength != min || schema.MaxLength == nil || *schema.MaxLength != max {
+			t.Fatalf("%s: expected length %d..%d, got %d..%v", name, min, max, schema.MinLength, schema.MaxLength)
+		}
+	}
+	assertRange := func(name string, schema *openapi3.Schema, min, max float64, exclusive bool) {
+		t.Helper()
+		if schema.Min == nil || *schema.Min != min || schema.Max == nil || *schema.Max != max {
+			t.Fatalf("%s: expected range %v..%v, got %v..%v", name, min, max, schema.Min, schema.Max)
+		}
+		if schema.ExclusiveMin != exclusive || schema.ExclusiveMax != exclusive {
+			t.Fatalf("%s: expected exclusive bounds %v, got %v/%v", name, exclusive, schema.ExclusiveMin, schema.ExclusiveMax)
+		}
+	}
+
+	assertLength("name", props["name"].Value, 2, 40)
+	assertLength("code", props["code"].Value, 3, 3)
+	assertLength("nick", props["nick"].Value, 2, 10)
+	assertRange("age", props["age"].Value, 18, 130, false)
+	assertRange("score", props["score"].Value, 0, 1, true)
+	assertRange("version", props["version"].Value, 2, 2, false)
+
+	tags := props["tags"].Value
+	if tags.MinItems != 1 || tags.Min != nil {
+		t.Fatalf("expected gte=1 to bound the item count only, got minItems %d, minimum %v", tags.MinItems, tags.Min)
+	}
+	assertRange("tags items", tags.Items.Value, 0, 9, false)
+
+	limit := doc.Paths.Value("/bounded").Post.Parameters.GetByInAndName("query", "limit")
+	if limit == nil {
+		t.Fatalf("expected limit query parameter")
+	}
+	assertRange("limit", limit.Schema.Value, 1, 100, false)
+}
+
+type openAPIContactRequest struct {
+	Primary   string            `json:"primary" validate:"required,email"`
+	CC        []string          `json:"cc" validate:"max=10,dive,email"`
+	Groups    [][]string        `json:"groups" validate:"dive,max=3,dive,email"`
+	ByTeam    map[string]string `json:"by_team" validate:"dive,keys,alpha,endkeys,email"`
+	Referrals []openAPIUser     `json:"referrals" validate:"dive"`
+}
+
+func TestOpenAPIDiveConstraints(t *testing.T) {
+	router := New()
+
+	POST(router, "/contacts", func(ctx context.Context, req *openAPIContactRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	props := doc.Components.Schemas["sprout_openAPIContactRequest"].Value.Properties
+
+	if got := props["primary"].Value.Format; got != "email" {
+		t.Fatalf("expected primary format email, got %q", got)
+	}
+
+	cc := props["cc"].Value
+	if cc.Format != "" {
+		t.Fatalf("array itself should not carry a format, got %q", cc.Format)
+	}
+	if cc.MaxItems == nil || *cc.MaxItems != 10 {
+		t.Fatalf("expected cc maxItems 10, got %v", cc.MaxItems)
+	}
+	if got := cc.Items.Value.Format; got != "email" {
+		t.Fatalf("expected cc items format email, got %q", got)
+	}
+
+	groups := props["groups"].Value
+	if groups.MaxItems != nil {
+		t.Fatalf("outer groups array should not have maxItems")
+	}
+	inner := groups.Items.Value
+	if inner.MaxItems == nil || *inner.MaxItems != 3 {
+		t.Fatalf("expected inner groups maxItems 3, got %v", inner.MaxItems)
+	}
+	if got := inner.Items.Value.Format; got != "email" {
+		t.Fatalf("expected nested items format email, got %q", got)
+	}
+
+	byTeam := props["by_team"].Value
+	if got := byTeam.AdditionalProperties.Schema.Value.Format; got != "email" {
+		t.Fatalf("expected map values format email, got %q", got)
+	}
+
+	if ref := props["referrals"].Value.Items.Ref; ref != "#/components/schemas/sprout_openAPIUser" {
+		t.Fatalf("expected referral items to stay a component reference, got %q", ref)
+	}
+}
+
+func TestOpenAPIWithProduces(t *testing.T) {
+	router := New()
+
+	GET(router, "/orders", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	}, WithProduces("application/hal+json"), WithErrors(&conflictError{}))
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	responses := doc.Paths.Value("/orders").Get.Responses
+	success := responses.Value("200").Value.Content
+	if success["application/hal+json"] == nil || success["application/json"] != nil {
+		t.Fatalf("expected only application/hal+json success content, got %v", success)
+	}
+
+	conflict := responses.Value("409").Value.Content
+	if conflict["application/json"] == nil {
+		t.Fatalf("expected error responses to stay application/json, got %v", conflict)
+	}
+}
+
+func TestCustomizeOpenAPI(t *testing.T) {
+	runs := 0
+	router := NewWithConfig(&Config{
+		CustomizeOpenAPI: func(doc *openapi3.T) {
+			runs++
+			doc.Tags = append(doc.Tags, &openapi3.Tag{Name: "users"})
+			doc.Paths.Value("/users").Get.Tags = []string{"users"}
+		},
+	})
+
+	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	})
+
+	load := func(t *testing.T, data []byte) *openapi3.T {
+		t.Helper()
+		doc, err := openapi3.NewLoader().LoadFromData(data)
+		if err != nil {
+			t.Fatalf("failed to parse openapi document: %v", err)
+		}
+		return doc
+	}
+
+	first, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc := load(t, first)
+	if len(doc.Tags) != 1 || doc.Tags[0].Name != "users" {
+		t.Fatalf("expected customized tags, got %+v", doc.Tags)
+	}
+	if tags := doc.Paths.Value("/users").Get.Tags; len(tags) != 1 || tags[0] != "users" {
+		t.Fatalf("expected customized operation tags, got %v", tags)
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/swagger?format=yaml", nil))
+	if !strings.Contains(recorder.Body.String(), "name: users") {
+		t.Fatalf("expected served yaml to be customized, got:\n%s", recorder.Body.String())
+	}
+	if runs != 1 {
+		t.Fatalf("expected hook to run once while routes are unchanged, ran %d times", runs)
+	}
+
+	GET(router, "/orders", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	})
+
+	second, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	if runs != 2 {
+		t.Fatalf("expected hook to re-run after a new route, ran %d times", runs)
+	}
+	doc = load(t, second)
+	if len(doc.Tags) != 1 {
+		t.Fatalf("expected hook to run on a fresh copy, got tags %+v", doc.Tags)
+	}
+	if doc.Paths.Value("/orders") == nil {
+		t.Fatalf("expected new route in customized document")
+	}
+}
+
+func TestOpenAPIWithOptionalBody(t *testing.T) {
+	router := New()
+
+	type createRequest struct {
+		Name string `json:"name" validate:"required"`
+	}
+
+	POST(router, "/required", func(ctx context.Context, req *createRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	})
+	POST(router, "/optional", func(ctx context.Context, req *createRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	}, WithOptionalBody())
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	if body := doc.Paths.Value("/required").Post.RequestBody.Value; !body.Required {
+		t.Fatalf("expected request body to be required by default")
+	}
+	if body := doc.Paths.Value("/optional").Post.RequestBody.Value; body.Required {
+		t.Fatalf("expected WithOptionalBody to mark the request body optional")
+	}
+}
+
+func TestOpenAPIAcceptVersionedHandlers(t *testing.T) {
+	router := New()
+
+	type userV1 struct {
+		Name string `json:"name"`
+	}
+	type userV2 struct {
+		FirstName string `json:"first_name"`
+	}
+
+	GET(router, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*userV1, error) {
+		return &userV1{}, nil
+	})
+	GET(router, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*userV2, error) {
+		return &userV2{}, nil
+	}, WithProduces("application/vnd.myapi.v2+json"))
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	content := doc.Paths.Value("/users/{id}").Get.Responses.Value("200").Value.Content
+	if len(content) != 2 {
+		t.Fatalf("expected two success media types, got %v", content)
+	}
+	if ref := content["application/json"].Schema.Ref; ref != "#/components/schemas/sprout_userV1" {
+		t.Fatalf("expected v1 schema for application/json, got %q", ref)
+	}
+	if ref := content["application/vnd.myapi.v2+json"].Schema.Ref; ref != "#/components/schemas/sprout_userV2" {
+		t.Fatalf("expected v2 schema for the vendor media type, got %q", ref)
+	}
+}
+
+func TestOpenAPISchemaNameCollisions(t *testing.T) {
+	router := New()
+
+	// Two distinct types that both map to "sprout_User".
+	registerV1 := func() {
+		type User struct {
+			Name string `json:"name"`
+		}
+		GET(router, "/v1/user", func(ctx context.Context, req *EmptyRequest) (*User, error) {
+			return &User{}, nil
+		})
+	}
+	registerV2 := func() {
+		type User struct {
+			Email string `json:"email"`
+		}
+		GET(router, "/v2/user", func(ctx context.Context, req *EmptyRequest) (*User, error) {
+			return &User{}, nil
+		})
+	}
+	registerV1()
+	registerV2()
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	v1Ref := doc.Paths.Value("/v1/user").Get.Responses.Value("200").Value.Content["application/json"].Schema.Ref
+	v2Ref := doc.Paths.Value("/v2/user").Get.Responses.Value("200").Value.Content["application/json"].Schema.Ref
+	if v1Ref != "#/components/schemas/sprout_User" {
+		t.Fatalf("expected first type to keep the short name, got %q", v1Ref)
+	}
+	if v2Ref == v1Ref {
+		t.Fatalf("expected colliding types to get distinct components, both use %q", v1Ref)
+	}
+
+	v1 := doc.Components.Schemas[strings.TrimPrefix(v1Ref, "#/components/schemas/")].Value
+	v2 := doc.Components.Schemas[strings.TrimPrefix(v2Ref, "#/components/schemas/")].Value
+	if v1.Properties["name"] == nil || v1.Properties["email"] != nil {
+		t.Fatalf("first component was clobbered: %v", v1.Properties)
+	}
+	if v2.Properties["email"] == nil || v2.Properties["name"] != nil {
+		t.Fatalf("second component has wrong properties: %v", v2.Properties)
+	}
+}
+
+func TestComponentNameQualifiesImportPath(t *testing.T) {
+	d := newOpenAPIDocument(nil)
+	d.nameOwners["http_Header"] = typeOf[int]()
+
+	if name := d.componentNameLocked(typeOf[http.Header]()); name != "net_http_Header" {
+		t.Fatalf("expected import path qualified name, got %q", name)
+	}
+}
+
+func TestOpenAPIDefaultStatusByMethod(t *testing.T) {
+	router := NewWithConfig(&Config{
+		DefaultStatusByMethod: map[string]int{
+			http.MethodPost:   http.StatusCreated,
+			http.MethodDelete: http.StatusNoContent,
+		},
+	})
+
+	type acceptedResponse struct {
+		_     struct{} `http:"status=202"`
+		JobID string   `json:"job_id"`
+	}
+
+	POST(router, "/items", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{}, nil
+	})
+	POST(router, "/jobs", func(ctx context.Context, req *EmptyRequest) (*acceptedResponse, error) {
+		return &acceptedResponse{}, nil
+	})
+	DELETE(router, "/items/:id", func(ctx context.Context, req *EmptyRequest) (*struct{}, error) {
+		return nil, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	if doc.Paths.Value("/items").Post.Responses.Value("201") == nil {
+		t.Fatalf("expected documented 201 for POST /items")
+	}
+	if doc.Paths.Value("/jobs").Post.Responses.Value("202") == nil {
+		t.Fatalf("expected the status tag to win in the document")
+	}
+	if doc.Paths.Value("/items/{id}").Delete.Responses.Value("204") == nil {
+		t.Fatalf("expected documented 204 for DELETE /items/{id}")
+	}
+}
+
+func TestOpenAPIRedirectErrorHasNoContent(t *testing.T) {
+	router := New()
+
+	GET(router, "/old", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, nil
+	}, WithErrors(&MovedError{}))
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	moved := doc.Paths.Value("/old").Get.Responses.Value("301")
+	if moved == nil || moved.Value == nil {
+		t.Fatalf("expected documented 301 response")
+	}
+	if len(moved.Value.Content) != 0 {
+		t.Fatalf("expected redirect without body fields to have no content, got %v", moved.Value.Content)
+	}
+}
+
+func TestOpenAPIQueryRestExcluded(t *testing.T) {
+	router := New()
+
+	type searchRequest struct {
+		Page  int                 `query:"page"`
+		Rest  map[string][]string `sprout:"queryrest"`
+		Query string              `json:"query"`
+	}
+
+	POST(router, "/search", func(ctx context.Context, req *searchRequest) (*HelloResponse, error) {
+		return &HelloResponse{}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	op := doc.Paths.Value("/search").Post
+	if len(op.Parameters) != 1 || op.Parameters.GetByInAndName("query", "page") == nil {
+		t.Fatalf("expected only the page parameter, got %+v", op.Parameters)
+	}
+	schema := doc.Components.Schemas["sprout_searchRequest"].Value
+	if _, ok := schema.Properties["Rest"]; ok || len(schema.Properties) != 1 {
+		t.Fatalf("expected queryrest field to be excluded from the body, got %v", schema.Properties)
+	}
+}
+
+func TestOpenAPITextPlainBodies(t *testing.T) {
+	router := New()
+
+	type renderRequest struct {
+		Template string `sprout:"textbody" validate:"required"`
+	}
+	type renderResponse struct {
+		Text string `sprout:"textbody"`
+	}
+
+	POST(router, "/render", func(ctx context.Context, req *renderRequest) (*renderResponse, error) {
+		return &renderResponse{}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	op := doc.Paths.Value("/render").Post
+	body := op.RequestBody.Value
+	if !body.Required || len(body.Content) != 1 {
+		t.Fatalf("expected a required text body, got %+v", body)
+	}
+	if media := body.Content["text/plain"]; media == nil || !media.Schema.Value.Type.Is("string") {
+		t.Fatalf("expected text/plain string request body, got %v", body.Content)
+	}
+
+	success := op.Responses.Value("200").Value.Content["text/plain; charset=utf-8"]
+	if success == nil || !success.Schema.Value.Type.Is("string") {
+		t.Fatalf("expected text/plain string response, got %v", op.Responses.Value("200").Value.Content)
+	}
+}
+
+func TestOpenAPIFormRequestBodies(t *testing.T) {
+	router := New()
+
+	type loginRequest struct {
+		Username string `form:"username" validate:"required"`
+		Password string `form:"password" validate:"required" sprout:"sensitive"`
+		Remember bool   `form:"remember"`
+	}
+	type uploadRequest struct {
+		Owner       string                  `path:"owner"`
+		Title       string                  `form:"title"`
+		Document    *multipart.FileHeader   `file:"document" validate:"required"`
+		Attachments []*multipart.FileHeader `file:"attachments"`
+	}
+	type formResponse struct {
+		OK bool `json:"ok"`
+	}
+
+	POST(router, "/login", func(ctx context.Context, req *loginRequest) (*formResponse, error) {
+		return &formResponse{OK: true}, nil
+	}, WithRawRequest())
+	POST(router, "/owners/:owner/documents", func(ctx context.Context, req *uploadRequest) (*formResponse, error) {
+		return &formResponse{OK: true}, nil
+	}, WithRawRequest())
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	login := doc.Paths.Value("/login").Post.RequestBody.Value
+	if !login.Required || len(login.Content) != 1 {
+		t.Fatalf("expected a single required form body, got %v", login.Content)
+	}
+	form := login.Content["application/x-www-form-urlencoded"]
+	if form == nil {
+		t.Fatalf("expected urlencoded media type, got %v", login.Content)
+	}
+	if got := strings.Join(form.Schema.Value.Required, ","); got != "password,username" {
+		t.Fatalf("expected required form fields, got %q", got)
+	}
+	if prop := form.Schema.Value.Properties["password"]; prop == nil || prop.Value.Format != "password" {
+		t.Fatalf("expected field metadata to apply to form fields, got %+v", prop)
+	}
+	if prop := form.Schema.Value.Properties["remember"]; prop == nil || !prop.Value.Type.Is("boolean") {
+		t.Fatalf("expected boolean remember field, got %+v", prop)
+	}
+
+	upload := doc.Paths.Value("/owners/{owner}/documents").Post
+	if len(upload.Parameters) != 1 || upload.Parameters[0].Value.Name != "owner" {
+		t.Fatalf("expected path parameter to stay a parameter, got %v", upload.Parameters)
+	}
+	multi := upload.RequestBody.Value.Content["multipart/form-data"]
+	if multi == nil || len(upload.RequestBody.Value.Content) != 1 {
+		t.Fatalf("expected multipart media type only, got %v", upload.RequestBody.Value.Content)
+	}
+	document := multi.Schema.Value.Properties["document"]
+	if document == nil || !document.Value.Type.Is("string") || document.Value.Format != "binary" {
+		t.Fatalf("expected binary file field, got %+v", document)
+	}
+	attachments := multi.Schema.Value.Properties["attachments"]
+	if attachments == nil || !attachments.Value.Type.Is("array") || attachments.Value.Items.Value.Format != "binary" {
+		t.Fatalf("expected array of binary files, got %+v", attachments)
+	}
+	if _, ok := multi.Schema.Value.Properties["title"]; !ok {
+		t.Fatalf("expected form fields alongside files, got %v", multi.Schema.Value.Properties)
+	}
+}
+
+func TestOpenAPIDeepObjectQueryParameters(t *testing.T) {
+	router := New()
+
+	type filterRequest struct {
+		Filter map[string]string `query:"filter"`
+	}
+	type filterResponse struct {
+		Count int `json:"count"`
+	}
+
+	GET(router, "/users", func(ctx context.Context, req *filterRequest) (*filterResponse, error) {
+		return &filterResponse{}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	params := doc.Paths.Value("/users").Get.Parameters
+	if len(params) != 1 {
+		t.Fatalf("expected one parameter, got %d", len(params))
+	}
+	param := params[0].Value
+	if param.Style != "deepObject" || param.Explode == nil || !*param.Explode {
+		t.Fatalf("expected exploded deepObject parameter, got style %q explode %v", param.Style, param.Explode)
+	}
+	schema := param.Schema.Value
+	if !schema.Type.Is("object") || schema.AdditionalProperties.Schema == nil ||
+		!schema.AdditionalProperties.Schema.Value.Type.Is("string") {
+		t.Fatalf("expected object schema with string values, got %+v", schema)
+	}
+}
+
+func TestOpenAPIEnvelopeKeys(t *testing.T) {
+	router := NewWithConfig(&Config{RequestEnvelopeKey: "data", ResponseEnvelopeKey: "result"})
+
+	type envelopeCreateRequest struct {
+		Name string `json:"name"`
+	}
+	type envelopeCreateResponse struct {
+		ID string `json:"id"`
+	}
+
+	POST(router, "/items", func(ctx context.Context, req *envelopeCreateRequest) (*envelopeCreateResponse, error) {
+		return &envelopeCreateResponse{}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	op := doc.Paths.Value("/items").Post
+	reqSchema := op.RequestBody.Value.Content["application/json"].Schema.Value
+	if inner := reqSchema.Properties["data"]; inner == nil || inner.Ref != "#/components/schemas/sprout_envelopeCreateRequest" {
+		t.Fatalf("expected request envelope around the DTO schema, got %+v", reqSchema.Properties["data"])
+	}
+	respSchema := op.Responses.Value("200").Value.Content["application/json"].Schema.Value
+	if inner := respSchema.Properties["result"]; inner == nil || inner.Ref != "#/components/schemas/sprout_envelopeCreateResponse" {
+		t.Fatalf("expected response envelope around the DTO schema, got %+v", respSchema.Properties)
+	}
+	if len(respSchema.Required) != 1 || respSchema.Required[0] != "result" {
+		t.Fatalf("expected envelope key to be required, got %v", respSchema.Required)
+	}
+}
+
+func TestOpenAPIResponseEnvelope(t *testing.T) {
+	type pageMeta struct {
+		Total int `json:"total"`
+	}
+	router := NewWithConfig(&Config{
+		ResponseEnvelope: func(body any, status int) any {
+			return map[string]any{"data": body, "meta": pageMeta{}, "links": nil}
+		},
+	})
+
+	type itemResponse struct {
+		ID string `json:"id"`
+	}
+
+	GET(router, "/items", func(ctx context.Context, req *EmptyRequest) (*itemResponse, error) {
+		return &itemResponse{}, nil
+	}, WithErrors(ConflictError{}))
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	responses := doc.Paths.Value("/items").Get.Responses
+	schema := responses.Value("200").Value.Content["application/json"].Schema.Value
+	if data := schema.Properties["data"]; data == nil || data.Ref != "#/components/schemas/sprout_itemResponse" {
+		t.Fatalf("expected body schema under data, got %+v", schema.Properties)
+	}
+	if meta := schema.Properties["meta"]; meta == nil || meta.Ref != "#/components/schemas/sprout_pageMeta" {
+		t.Fatalf("expected meta schema from its Go type, got %+v", schema.Properties["meta"])
+	}
+	if _, ok := schema.Properties["links"]; !ok {
+		t.Fatalf("expected nil values to be documented as any, got %+v", schema.Properties)
+	}
+
+	errSchema := responses.Value("409").Value.Content["application/json"].Schema
+	if errSchema.Ref != "#/components/schemas/sprout_ConflictError" {
+		t.Fatalf("expected error responses to stay unwrapped, got %+v", errSchema)
+	}
+}
+
+func TestInternalOpenAPIDocument(t *testing.T) {
+	router := NewWithConfig(&Config{
+		BasePath:            "/api",
+		InternalOpenAPIPath: "/internal/swagger",
+		InternalOpenAPIMiddleware: []Middleware{BasicAuth("docs", func(user, pass string) bool {
+			return user == "ops" && SecureCompare(pass, "secret")
+		})},
+	})
+
+	type statusResponse struct {
+		OK bool `json:"ok"`
+	}
+
+	GET(router, "/status", func(ctx context.Context, req *EmptyRequest) (*statusResponse, error) {
+		return &statusResponse{OK: true}, nil
+	})
+	GET(router, "/debug/cache", func(ctx context.Context, req *EmptyRequest) (*statusResponse, error) {
+		return &statusResponse{OK: true}, nil
+	}, WithInternal())
+
+	admin := router.Mount("/admin", nil)
+	POST(admin, "/reindex", func(ctx context.Context, req *EmptyRequest) (*statusResponse, error) {
+		return &statusResponse{OK: true}, nil
+	}, WithInternal())
+
+	load := func(t *testing.T, rec *httptest.ResponseRecorder) *openapi3.T {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		doc, err := openapi3.NewLoader().LoadFromData(rec.Body.Bytes())
+		if err != nil {
+			t.Fatalf("failed to parse openapi json: %v", err)
+		}
+		return doc
+	}
+
+	t.Run("public document omits internal routes", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/swagger", nil))
+		doc := load(t, rec)
+		if doc.Paths.Value("/api/status") == nil {
+			t.Fatalf("expected public route in public document")
+		}
+		if doc.Paths.Value("/api/debug/cache") != nil || doc.Paths.Value("/api/admin/reindex") != nil {
+			t.Fatalf("expected internal routes to be omitted, got %v", doc.Paths.InMatchingOrder())
+		}
+	})
+
+	t.Run("internal document requires credentials", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/internal/swagger", nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401, got %d", rec.Code)
+		}
+	})
+
+	t.Run("internal document lists internal routes", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/internal/swagger", nil)
+		req.SetBasicAuth("ops", "secret")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		doc := load(t, rec)
+		if doc.Paths.Value("/api/debug/cache") == nil || doc.Paths.Value("/api/admin/reindex") == nil {
+			t.Fatalf("expected internal routes, got %v", doc.Paths.InMatchingOrder())
+		}
+		if doc.Paths.Value("/api/status") != nil {
+			t.Fatalf("expected public routes to be omitted from the internal document")
+		}
+	})
+
+	t.Run("internal routes are still served", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/debug/cache", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+	})
+}
+
+func TestOpenAPIOneOfEnums(t *testing.T) {
+	router := New()
+
+	type orderState string
+	type orderRequest struct {
+		State orderState `path:"state" validate:"oneof=open closed"`
+		Level int        `query:"level" validate:"omitempty,oneof=1 2 3"`
+		Ratio float64    `query:"ratio" validate:"omitempty,oneof=0.5 1"`
+		Tags  []string   `query:"tags" validate:"omitempty,dive,oneof=a b"`
+		Sort  string     `json:"sort" validate:"oneof=asc desc"`
+	}
+	type orderResponse struct {
+		OK bool `json:"ok"`
+	}
+
+	POST(router, "/orders/:state", func(ctx context.Context, req *orderRequest) (*orderResponse, error) {
+		return &orderResponse{}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	op := doc.Paths.Value("/orders/{state}").Post
+	params := map[string]*openapi3.Schema{}
+	for _, param := range op.Parameters {
+		params[param.Value.Name] = param.Value.Schema.Value
+	}
+
+	assertEnum := func(name string, schema *openapi3.Schema, want ...any) {
+		t.Helper()
+		if schema == nil || len(schema.Enum) != len(want) {
+			t.Fatalf("%s: expected enum %v, got %+v", name, want, schema)
+		}
+		for i := range want {
+			if schema.Enum[i] != want[i] {
+				t.Fatalf("%s: expected enum %v, got %v", name, want, schema.Enum)
+			}
+		}
+	}
+
+	assertEnum("state", params["state"], "open", "closed")
+	assertEnum("level", params["level"], float64(1), float64(2), float64(3))
+	assertEnum("ratio", params["ratio"], 0.5, float64(1))
+	assertEnum("tags items", params["tags"].Items.Value, "a", "b")
+	if len(params["tags"].Enum) != 0 {
+		t.Fatalf("expected no enum on the array itself, got %v", params["tags"].Enum)
+	}
+
+	body := op.RequestBody.Value.Content["application/json"].Schema
+	component := doc.Components.Schemas[strings.TrimPrefix(body.Ref, "#/components/schemas/")].Value
+	assertEnum("sort", component.Properties["sort"].Value, "asc", "desc")
+}
+
+type openAPICurrency struct {
+	code string
+}
+
+func (c *openAPICurrency) UnmarshalText(text []byte) error {
+	c.code = string(text)
+	return nil
+}
+
+func (c openAPICurrency) MarshalText() ([]byte, error) {
+	return []byte(c.code), nil
+}
+
+func TestOpenAPITextTypes(t *testing.T) {
+	router := New()
+
+	type quoteRequest struct {
+		Currency openAPICurrency `query:"currency" validate:"required"`
+		Since    time.Time       `query:"since"`
+	}
+	type quoteResponse struct {
+		Currency openAPICurrency `json:"currency"`
+		QuotedAt time.Time       `json:"quotedAt"`
+	}
+
+	GET(router, "/quotes", func(ctx context.Context, req *quoteRequest) (*quoteResponse, error) {
+		return &quoteResponse{}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	for _, param := range doc.Paths.Value("/quotes").Get.Parameters {
+		schema := param.Value.Schema
+		if schema.Ref != "" || !schema.Value.Type.Is("string") {
+			t.Fatalf("expected %s to be a string parameter, got %+v", param.Value.Name, schema)
+		}
+		if param.Value.Name == "since" && schema.Value.Format != "date-time" {
+			t.Fatalf("expected date-time format for time.Time, got %q", schema.Value.Format)
+		}
+	}
+
+	component := doc.Components.Schemas["sprout_quoteResponse"]
+	if component == nil {
+		t.Fatalf("expected response component, got %v", doc.Components.Schemas)
+	}
+	if prop := component.Value.Properties["currency"]; prop.Ref != "" || !prop.Value.Type.Is("string") {
+		t.Fatalf("expected text marshaler property to be a string, got %+v", prop)
+	}
+	if prop := component.Value.Properties["quotedAt"]; prop.Ref != "" || prop.Value.Format != "date-time" {
+		t.Fatalf("expected time.Time property to be a date-time string, got %+v", prop)
+	}
+	for name := range doc.Components.Schemas {
+		if strings.Contains(name, "Time") || strings.Contains(name, "Currency") {
+			t.Fatalf("expected no component for text types, got %q", name)
+		}
+	}
+}
+
+func TestOpenAPIParameterDefaults(t *testing.T) {
+	router := New()
+
+	GET(router, "/items", func(ctx context.Context, req *defaultedListRequest) (*defaultedListResponse, error) {
+		return &defaultedListResponse{}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	expected := map[string]any{
+		"page":            float64(1),
+		"limit":           float64(20),
+		"tag":             []any{"new", "hot"},
+		"Accept-Language": "en",
+		"theme":           "light",
+	}
+	params := doc.Paths.Value("/items").Get.Parameters
+	if len(params) != len(expected) {
+		t.Fatalf("expected %d parameters, got %d", len(expected), len(params))
+	}
+	for _, param := range params {
+		want, ok := expected[param.Value.Name]
+		if !ok {
+			t.Fatalf("unexpected parameter %q", param.Value.Name)
+		}
+		if got := param.Value.Schema.Value.Default; !reflect.DeepEqual(got, want) {
+			t.Fatalf("expected default %#v for %s, got %#v", want, param.Value.Name, got)
+		}
+	}
+}
+
+func TestOpenAPITextUnmarshalerSlices(t *testing.T) {
+	router := New()
+
+	GET(router, "/quotes", func(ctx context.Context, req *quoteRequest) (*quoteResponse, error) {
+		return &quoteResponse{}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	op := doc.Paths.Value("/quotes").Get
+	for _, name := range []string{"item", "note"} {
+		param := op.Parameters.GetByInAndName("query", name)
+		if param == nil {
+			t.Fatalf("expected %s parameter", name)
+		}
+		schema := param.Schema.Value
+		if !schema.Type.Is("array") || schema.Items == nil || !schema.Items.Value.Type.Is("string") {
+			t.Fatalf("expected %s documented as an array of strings, got %#v", name, schema)
+		}
+	}
+
+	if item := op.Parameters.GetByInAndName("query", "item"); item.Style != "" || item.Explode != nil {
+		t.Fatalf("expected default serialization for item, got style %q", item.Style)
+	}
+	note := op.Parameters.GetByInAndName("query", "note")
+	if note.Style != openapi3.SerializationForm || note.Explode == nil || !*note.Explode {
+		t.Fatalf("expected nosplit parameter to be form exploded, got style %q explode %v", note.Style, note.Explode)
+	}
+}
+
+func TestOpenAPIEncodedBody(t *testing.T) {
+	router := New()
+	GET(router, "/feed", func(ctx context.Context, req *EmptyRequest) (*cachedFeedResponse, error) {
+		return &cachedFeedResponse{}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	media := doc.Paths.Value("/feed").Get.Responses.Value("200").Value.Content["application/json"]
+	if media == nil {
+		t.Fatalf("expected application/json response content")
+	}
+	if schema := media.Schema.Value; !schema.Type.Is("string") || schema.Format != "binary" {
+		t.Fatalf("expected binary string schema, got %+v", schema)
+	}
+}
+
+func TestOpenAPIQuotedOneOfEnums(t *testing.T) {
+	router := New()
+
+	type paletteRequest struct {
+		Color string `query:"color" validate:"omitempty,oneof='sky blue' red"`
+	}
+	type paletteResponse struct {
+		Color string `json:"color" validate:"oneof='sky blue' red"`
+	}
+
+	GET(router, "/palette", func(ctx context.Context, req *paletteRequest) (*paletteResponse, error) {
+		return &paletteResponse{Color: req.Color}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	want := []any{"sky blue", "red"}
+	op := doc.Paths.Value("/palette").Get
+	if got := op.Parameters[0].Value.Schema.Value.Enum; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected request enum %v, got %v", want, got)
+	}
+	component := doc.Components.Schemas["sprout_paletteResponse"].Value
+	if got := component.Properties["color"].Value.Enum; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected response enum %v, got %v", want, got)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/palette?color=sky+blue", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected documented value to validate, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestOpenAPIStringFormats(t *testing.T) {
+	router := New()
+
+	type accountRequest struct {
+		ID       string   `path:"id" validate:"uuid4"`
+		Email    string   `json:"email" validate:"required,email"`
+		Website  string   `json:"website" validate:"omitempty,url"`
+		Callback string   `json:"callback" validate:"omitempty,uri"`
+		Host     string   `json:"host" validate:"omitempty,hostname"`
+		Address  string   `json:"address" validate:"omitempty,ipv4"`
+		Born     string   `json:"born" validate:"omitempty,datetime=2006-01-02"`
+		SeenAt   string   `json:"seenAt" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+		Clock    string   `json:"clock" validate:"omitempty,datetime=15:04"`
+		Nickname string   `json:"nickname" validate:"omitempty,alphanum,min=2"`
+		Aliases  []string `json:"aliases" validate:"dive,email"`
+	}
+
+	PUT(router, "/accounts/:id", func(ctx context.Context, req *accountRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	op := doc.Paths.Value("/accounts/{id}").Put
+	if got := op.Parameters.GetByInAndName("path", "id").Schema.Value.Format; got != "uuid" {
+		t.Fatalf("expected uuid format on path parameter, got %q", got)
+	}
+
+	body := op.RequestBody.Value.Content["application/json"].Schema
+	props := doc.Components.Schemas[strings.TrimPrefix(body.Ref, "#/components/schemas/")].Value.Properties
+	expected := map[string]string{
+		"email":    "email",
+		"website":  "uri",
+		"callback": "uri",
+		"host":     "hostname",
+		"address":  "ipv4",
+		"born":     "date",
+		"seenAt":   "date-time",
+		"clock":    "",
+		"nickname": "",
+	}
+	for name, want := range expected {
+		if got := props[name].Value.Format; got != want {
+			t.Fatalf("%s: expected format %q, got %q", name, want, got)
+		}
+	}
+	if got := props["aliases"].Value.Items.Value.Format; got != "email" {
+		t.Fatalf("expected email format on items, got %q", got)
+	}
+}
+
+func TestOpenAPIMultiStatus(t *testing.T) {
+	router := New()
+	POST(router, "/users/batch-delete", func(ctx context.Context, req *batchDeleteRequest) (*MultiStatus[batchItemResult], error) {
+		return &MultiStatus[batchItemResult]{}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	responses := doc.Paths.Value("/users/batch-delete").Post.Responses
+	if responses.Value("200") != nil {
+		t.Fatalf("did not expect a 200 response")
+	}
+	resp := responses.Value("207")
+	if resp == nil {
+		t.Fatalf("expected 207 response to be documented")
+	}
+	schema := resp.Value.Content["application/json"].Schema.Value
+	if !schema.Type.Is("array") || schema.Items.Ref != "#/components/schemas/sprout_batchItemResult" {
+		t.Fatalf("expected array of batchItemResult, got %+v", schema)
+	}
+}
+
+func TestOpenAPISecurity(t *testing.T) {
+	router := NewWithConfig(nil, WithOpenAPIInfo(OpenAPIInfo{
+		SecuritySchemes: map[string]OpenAPISecurityScheme{
+			"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			"apiKey":     {Type: "apiKey", In: "header", Name: "X-API-Key", Description: "Partner key"},
+		},
+	}))
+
+	handler := func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	}
+	GET(router, "/public", handler)
+	GET(router, "/me", handler, WithSecurity("bearerAuth"))
+	GET(router, "/reports", handler, WithSecurity("bearerAuth"), WithSecurity("apiKey"))
+	GET(router, "/feed", handler, WithSecurity("bearerAuth"), WithSecurity())
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("expected valid document, got %v", err)
+	}
+
+	bearer := doc.Components.SecuritySchemes["bearerAuth"]
+	if bearer == nil || bearer.Value.Type != "http" || bearer.Value.Scheme != "bearer" || bearer.Value.BearerFormat != "JWT" {
+		t.Fatalf("unexpected bearer scheme: %+v", bearer)
+	}
+	apiKey := doc.Components.SecuritySchemes["apiKey"]
+	if apiKey == nil || apiKey.Value.In != "header" || apiKey.Value.Name != "X-API-Key" || apiKey.Value.Description != "Partner key" {
+		t.Fatalf("unexpected api key scheme: %+v", apiKey)
+	}
+
+	security := func(path string) *openapi3.SecurityRequirements {
+		return doc.Paths.Value(path).Get.Security
+	}
+	if got := security("/public"); got != nil {
+		t.Fatalf("expected no security on public route, got %v", *got)
+	}
+	if got := security("/me"); got == nil || len(*got) != 1 || (*got)[0]["bearerAuth"] == nil {
+		t.Fatalf("expected bearerAuth requirement, got %v", got)
+	}
+	if got := security("/reports"); got == nil || len(*got) != 2 || (*got)[1]["apiKey"] == nil {
+		t.Fatalf("expected bearerAuth or apiKey, got %v", got)
+	}
+	if got := security("/feed"); got == nil || len(*got) != 2 || len((*got)[1]) != 0 {
+		t.Fatalf("expected an anonymous alternative, got %v", got)
+	}
+
+	t.Run("unknown scheme panics at registration", func(t *testing.T) {
+		defer func() {
+			recovered := recover()
+			msg, _ := recovered.(string)
+			if !strings.Contains(msg, `security scheme "bearer" for GET /typo`) {
+				t.Fatalf("expected panic naming the scheme and route, got %v", recovered)
+			}
+		}()
+		GET(router, "/typo", handler, WithSecurity("bearerAuth", "bearer"))
+	})
+}
+
+type exampleUserRequest struct {
+	Tenant string `header:"X-Tenant"`
+	Name   string `json:"name" validate:"required,min=3"`
+	Email  string `json:"email" validate:"required,email"`
+}
+
+func TestOpenAPIExamples(t *testing.T) {
+	router := New()
+	POST(router, "/users", func(ctx context.Context, req *exampleUserRequest) (*CreateUserResponse, error) {
+		return &CreateUserResponse{}, nil
+	},
+		WithExampleRequest(exampleUserRequest{Tenant: "acme", Name: "Ada Lovelace", Email: "ada@example.com"}),
+		WithExampleResponse(&CreateUserResponse{ID: 7, Name: "Ada Lovelace", Email: "ada@example.com"}),
+	)
+
+	load := func(router *Sprout) *openapi3.T {
+		t.Helper()
+		specBytes, err := router.OpenAPIJSON()
+		if err != nil {
+			t.Fatalf("failed to marshal openapi json: %v", err)
+		}
+		doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+		if err != nil {
+			t.Fatalf("failed to parse openapi json: %v", err)
+		}
+		return doc
+	}
+
+	op := load(router).Paths.Value("/users").Post
+	wantRequest := map[string]any{"name": "Ada Lovelace", "email": "ada@example.com"}
+	if got := op.RequestBody.Value.Content["application/json"].Example; !reflect.DeepEqual(got, wantRequest) {
+		t.Fatalf("expected request example %#v, got %#v", wantRequest, got)
+	}
+	wantResponse := map[string]any{"id": float64(7), "name": "Ada Lovelace", "email": "ada@example.com"}
+	if got := op.Responses.Value("200").Value.Content["application/json"].Example; !reflect.DeepEqual(got, wantResponse) {
+		t.Fatalf("expected response example %#v, got %#v", wantResponse, got)
+	}
+
+	t.Run("envelopes", func(t *testing.T) {
+		router := NewWithConfig(&Config{RequestEnvelopeKey: "data", ResponseEnvelopeKey: "data"})
+		POST(router, "/users", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+			return &CreateUserResponse{}, nil
+		},
+			WithExampleRequest(CreateUserRequest{Name: "Ada", Email: "ada@example.com"}),
+			WithExampleResponse(CreateUserResponse{ID: 1, Name: "Ada", Email: "ada@example.com"}),
+		)
+
+		op := load(router).Paths.Value("/users").Post
+		request, _ := op.RequestBody.Value.Content["application/json"].Example.(map[string]any)
+		if _, ok := request["data"]; !ok {
+			t.Fatalf("expected request example under the envelope key, got %#v", request)
+		}
+		response, _ := op.Responses.Value("200").Value.Content["application/json"].Example.(map[string]any)
+		if _, ok := response["data"]; !ok {
+			t.Fatalf("expected response example under the envelope key, got %#v", response)
+		}
+	})
+
+	t.Run("invalid examples panic at registration", func(t *testing.T) {
+		tests := []struct {
+			name string
+			opt  RouteOption
+			want string
+		}{
+			{"request", WithExampleRequest(CreateUserRequest{Name: "Al", Email: "ada@example.com"}), "example request for POST /users is invalid"},
+			{"response", WithExampleResponse(&CreateUserResponse{Name: "Ada", Email: "not-an-email"}), "example response for POST /users is invalid"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				defer func() {
+					recovered := recover()
+					msg, _ := recovered.(string)
+					if !strings.Contains(msg, tt.want) {
+						t.Fatalf("expected panic containing %q, got %v", tt.want, recovered)
+					}
+				}()
+				POST(New(), "/users", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+					return &CreateUserResponse{}, nil
+				}, tt.opt)
+			})
+		}
+	})
+
+	t.Run("validation can be skipped", func(t *testing.T) {
+		router := New()
+		POST(router, "/users", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+			return &CreateUserResponse{}, nil
+		}, WithExampleRequest(CreateUserRequest{Name: "Al"}), WithUnvalidatedExamples())
+
+		example := load(router).Paths.Value("/users").Post.RequestBody.Value.Content["application/json"].Example
+		if !reflect.DeepEqual(example, map[string]any{"name": "Al", "email": ""}) {
+			t.Fatalf("expected the invalid example to be documented, got %#v", example)
+		}
+	})
+}
+
+type exampleTaggedRequest struct {
+	Page     int       `query:"page" example:"2"`
+	Tags     []string  `query:"tag" example:"new,hot"`
+	Name     string    `json:"name" example:"Ada Lovelace"`
+	Age      int       `json:"age" example:"36"`
+	Ratio    float64   `json:"ratio" example:"0.75"`
+	Active   bool      `json:"active" example:"true"`
+	Since    time.Time `json:"since" example:"2024-01-02T15:04:05Z"`
+	Weight   int       `json:"weight" example:"heavy"`
+	Untagged string    `json:"untagged"`
+}
+
+func TestOpenAPIExampleTags(t *testing.T) {
+	router := New()
+	router.DescribeType(&exampleTaggedRequest{}, func(b *SchemaBuilder) {
+		b.Field("age").Example(40)
+	})
+	POST(router, "/people", func(ctx context.Context, req *exampleTaggedRequest) (*HelloResponse, error) {
+		return &HelloResponse{}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	op := doc.Paths.Value("/people").Post
+	if got := op.Parameters.GetByInAndName("query", "page").Schema.Value.Example; got != float64(2) {
+		t.Fatalf("expected typed parameter example 2, got %#v", got)
+	}
+	if got := op.Parameters.GetByInAndName("query", "tag").Schema.Value.Example; !reflect.DeepEqual(got, []any{"new", "hot"}) {
+		t.Fatalf("expected list example split on commas, got %#v", got)
+	}
+
+	schema := op.RequestBody.Value.Content["application/json"].Schema
+	if schema.Ref != "" {
+		schema = doc.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	expected := map[string]any{
+		"name":   "Ada Lovelace",
+		"age":    float64(40), // DescribeType wins over the tag
+		"ratio":  0.75,
+		"active": true,
+		"since":  "2024-01-02T15:04:05Z",
+		"weight": "heavy", // does not parse as int, kept as written
+	}
+	for name, want := range expected {
+		if got := schema.Value.Properties[name].Value.Example; !reflect.DeepEqual(got, want) {
+			t.Fatalf("expected example %#v for %s, got %#v", want, name, got)
+		}
+	}
+	if got := schema.Value.Properties["untagged"].Value.Example; got != nil {
+		t.Fatalf("expected no example without a tag, got %#v", got)
+	}
+}
+
+type nullablePersonResponse struct {
+	First    string              `json:"first"`
+	Middle   *string             `json:"middle"`
+	Age      *int                `json:"age"`
+	Address  *describedAddress   `json:"address"`
+	Nicks    []*string           `json:"nicks"`
+	Friends  []*describedAddress `json:"friends"`
+	Scores   map[string]*int     `json:"scores"`
+	Verified *time.Time          `json:"verified"`
+}
+
+func TestOpenAPINullablePointers(t *testing.T) {
+	router := New()
+	GET(router, "/people/:id", func(ctx context.Context, req *tracedUserRequest) (*nullablePersonResponse, error) {
+		return &nullablePersonResponse{}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	props := doc.Components.Schemas["sprout_nullablePersonResponse"].Value.Properties
+	for _, name := range []string{"middle", "age", "verified"} {
+		if !props[name].Value.Nullable {
+			t.Fatalf("expected pointer field %s to be nullable", name)
+		}
+	}
+	if props["first"].Value.Nullable {
+		t.Fatalf("did not expect a non-pointer field to be nullable")
+	}
+
+	address := props["address"].Value
+	if !address.Nullable || len(address.AllOf) != 1 || address.AllOf[0].Ref != "#/components/schemas/sprout_describedAddress" {
+		t.Fatalf("expected nullable component property wrapped in allOf, got %#v", address)
+	}
+
+	if props["nicks"].Value.Nullable || !props["nicks"].Value.Items.Value.Nullable {
+		t.Fatalf("expected nullable items for a slice of pointers")
+	}
+	if !props["scores"].Value.AdditionalProperties.Schema.Value.Nullable {
+		t.Fatalf("expected nullable values for a map of pointers")
+	}
+	friends := props["friends"].Value.Items.Value
+	if !friends.Nullable || len(friends.AllOf) != 1 || friends.AllOf[0].Ref != "#/components/schemas/sprout_describedAddress" {
+		t.Fatalf("expected nullable component items wrapped in allOf, got %#v", friends)
+	}
+}
+
+func TestOpenAPISummaryAndDescription(t *testing.T) {
+	router := New()
+	GET(router, "/users/:id", func(ctx context.Context, req *tracedUserRequest) (*HelloResponse, error) {
+		return &HelloResponse{}, nil
+	}, WithSummary("Get a user"), WithDescription("Returns the user with the given ID.\n\nDeleted users are **not** returned."))
+	GET(router, "/health", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	op := doc.Paths.Value("/users/{id}").Get
+	if op.Summary != "Get a user" || !strings.HasPrefix(op.Description, "Returns the user") {
+		t.Fatalf("expected summary and description, got %q / %q", op.Summary, op.Description)
+	}
+	if health := doc.Paths.Value("/health").Get; health.Summary != "" || health.Description != "" {
+		t.Fatalf("expected no summary or description by default, got %q / %q", health.Summary, health.Description)
+	}
+	if strings.Contains(string(specBytes), `"summary":""`) {
+		t.Fatalf("expected empty summaries to be omitted")
+	}
+}
+
+func TestOpenAPIWithResponses(t *testing.T) {
+	router := New()
+	PUT(router, "/users/:id", func(ctx context.Context, req *upsertUserRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: req.ID}, nil
+	}, WithResponses(http.StatusCreated, http.StatusOK, http.StatusCreated))
+	POST(router, "/jobs", func(ctx context.Context, req *EmptyRequest) (*AcceptedResponse, error) {
+		return &AcceptedResponse{}, nil
+	}, WithResponses(http.StatusOK))
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	put := doc.Paths.Value("/users/{id}").Put
+	for _, status := range []int{http.StatusOK, http.StatusCreated} {
+		resp := put.Responses.Status(status)
+		if resp == nil || resp.Value.Content["application/json"] == nil {
+			t.Fatalf("expected documented %d response, got %v", status, put.Responses.Map())
+		}
+		if ref := resp.Value.Content["application/json"].Schema.Ref; !strings.HasSuffix(ref, "HelloResponse") {
+			t.Fatalf("expected %d response to reference HelloResponse, got %q", status, ref)
+		}
+	}
+	if put.Responses.Len() != 3 {
+		t.Fatalf("expected 200, 201 and default responses, got %v", put.Responses.Map())
+	}
+
+	post := doc.Paths.Value("/jobs").Post
+	if post.Responses.Status(http.StatusAccepted) == nil || post.Responses.Status(http.StatusOK) == nil {
+		t.Fatalf("expected tagged 202 and extra 200 responses, got %v", post.Responses.Map())
+	}
+}
+
+type deprecatedFieldsRequest struct {
+	Sort    string           `query:"sort" sprout:"deprecated"`
+	Order   string           `query:"order"`
+	Name    string           `json:"name"`
+	Legacy  string           `json:"legacy" sprout:"deprecated"`
+	Address describedAddress `json:"address" sprout:"deprecated"`
+}
+
+func TestOpenAPIDeprecated(t *testing.T) {
+	router := New()
+	POST(router, "/v1/users", func(ctx context.Context, req *deprecatedFieldsRequest) (*HelloResponse, error) {
+		return &HelloResponse{}, nil
+	}, WithDeprecated())
+	POST(router, "/v2/users", func(ctx context.Context, req *CreateUserRequest) (*HelloResponse, error) {
+		return &HelloResponse{}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	v1 := doc.Paths.Value("/v1/users").Post
+	if !v1.Deprecated || doc.Paths.Value("/v2/users").Post.Deprecated {
+		t.Fatalf("expected only the WithDeprecated operation to be deprecated")
+	}
+	if !v1.Parameters.GetByInAndName("query", "sort").Deprecated || v1.Parameters.GetByInAndName("query", "order").Deprecated {
+		t.Fatalf("expected only the tagged parameter to be deprecated")
+	}
+
+	schema := v1.RequestBody.Value.Content["application/json"].Schema
+	if schema.Ref != "" {
+		schema = doc.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	props := schema.Value.Properties
+	if !props["legacy"].Value.Deprecated || props["name"].Value.Deprecated {
+		t.Fatalf("expected only the tagged property to be deprecated")
+	}
+	address := props["address"].Value
+	if !address.Deprecated || len(address.AllOf) != 1 || address.AllOf[0].Ref == "" {
+		t.Fatalf("expected deprecated component property wrapped in allOf, got %#v", address)
+	}
+}
+
+func TestOpenAPITags(t *testing.T) {
+	router := NewWithConfig(nil, WithOpenAPIInfo(OpenAPIInfo{
+		Tags: []OpenAPITag{
+			{Name: "users", Description: "User management"},
+			{Name: "admin", Description: "Operator endpoints"},
+		},
+	}))
+
+	handler := func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	}
+	GET(router, "/health", handler)
+	GET(router, "/users", handler, WithTags("users"))
+
+	admin := router.Mount("/admin", &Config{OpenAPITags: []string{"admin"}})
+	GET(admin, "/stats", handler)
+	GET(admin, "/users", handler, WithTags("admin", "users"))
+	GET(admin, "/ping", handler, WithTags())
+	nested := admin.Mount("/audit", nil)
+	GET(nested, "/log", handler)
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	if len(doc.Tags) != 2 || doc.Tags[0].Name != "users" || doc.Tags[0].Description != "User management" || doc.Tags[1].Name != "admin" {
+		t.Fatalf("unexpected document tags: %+v", doc.Tags)
+	}
+
+	expected := map[string][]string{
+		"/health":          nil,
+		"/users":           {"users"},
+		"/admin/stats":     {"admin"},
+		"/admin/users":     {"admin", "users"},
+		"/admin/ping":      nil,
+		"/admin/audit/log": {"admin"},
+	}
+	for path, want := range expected {
+		got := doc.Paths.Value(path).Get.Tags
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("%s: expected tags %v, got %v", path, want, got)
+		}
+	}
+}
+
+type createdDocumentResponse struct {
+	_           struct{} `http:"status=201"`
+	Location    string   `header:"Location" validate:"required"`
+	ETag        string   `header:"ETag"`
+	ContentType string   `header:"Content-Type"`
+	ID          string   `json:"id"`
+}
+
+type throttledDocumentError struct {
+	_       struct{} `http:"status=429"`
+	Message string   `json:"message"`
+	Limit   string   `header:"X-RateLimit-Limit"`
+}
+
+func (e *throttledDocumentError) Error() string             { return e.Message }
+func (e *throttledDocumentError) RetryAfter() time.Duration { return time.Minute }
+
+func TestOpenAPIResponseHeaders(t *testing.T) {
+	router := New()
+	POST(router, "/documents", func(ctx context.Context, req *EmptyRequest) (*createdDocumentResponse, error) {
+		return &createdDocumentResponse{}, nil
+	}, WithErrors(&throttledDocumentError{}))
+	GET(router, "/plain", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{}, nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("expected valid document, got %v", err)
+	}
+
+	responses := doc.Paths.Value("/documents").Post.Responses
+	created := responses.Value("201").Value.Headers
+	if len(created) != 2 {
+		t.Fatalf("expected Location and ETag headers, got %v", created)
+	}
+	if location := created["Location"]; location == nil || !location.Value.Required || !location.Value.Schema.Value.Type.Is("string") {
+		t.Fatalf("expected required string Location header, got %+v", location)
+	}
+	if etag := created["ETag"]; etag == nil || etag.Value.Required {
+		t.Fatalf("expected optional ETag header, got %+v", etag)
+	}
+
+	throttled := responses.Value("429").Value.Headers
+	if throttled["X-RateLimit-Limit"] == nil {
+		t.Fatalf("expected error header, got %v", throttled)
+	}
+	if retry := throttled["Retry-After"]; retry == nil || !retry.Value.Schema.Value.Type.Is("integer") {
+		t.Fatalf("expected integer Retry-After header, got %+v", retry)
+	}
+
+	if headers := doc.Paths.Value("/plain").Get.Responses.Value("200").Value.Headers; len(headers) != 0 {
+		t.Fatalf("expected no headers on a plain response, got %v", headers)
+	}
+}
@@ -0,0 +1,64 @@
+package sprout
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// ResourceNotFoundError is returned for a route declared WithParent when a
+// parent resource does not exist. It is a typed error answered with 404 Not
+// Found, naming the missing resource and the ID from the path.
+type ResourceNotFoundError struct {
+	_        struct{} `http:"status=404"`
+	Resource string   `json:"resource"`
+	ID       string   `json:"id"`
+	Message  string   `json:"message"`
+}
+
+func (e *ResourceNotFoundError) Error() string {
+	return e.Message
+}
+
+// WithParent checks that the parent resource named by path parameter param
+// exists before the route's request is bound. exists receives the request
+// context and the parameter's value; when it reports false the request fails
+// with a *ResourceNotFoundError for resource. An error from exists goes
+// through the error pipeline like next(err).
+//
+// Declare one WithParent per level, outermost first; the checks run in that
+// order and stop at the first missing parent. ResourceNotFoundError is
+// declared for the route and always documented as its 404 response, even
+// when WithErrors declares another 404 type for the handler.
+func WithParent(param, resource string, exists func(ctx context.Context, id string) (bool, error)) RouteOption {
+	check := func(w http.ResponseWriter, req *http.Request, next Next) {
+		id := Params(req).ByName(param)
+		found, err := exists(req.Context(), id)
+		if err != nil {
+			next(err)
+			return
+		}
+		if !found {
+			next(&ResourceNotFoundError{
+				Resource: resource,
+				ID:       id,
+				Message:  fmt.Sprintf("%s '%s' not found", resource, id),
+			})
+			return
+		}
+		next(nil)
+	}
+
+	return func(cfg *routeConfig) {
+		cfg.middlewares = append(cfg.middlewares, check)
+		cfg.parentChecks = true
+
+		notFoundType := typeOf[ResourceNotFoundError]()
+		for _, declared := range cfg.expectedErrors {
+			if declared == notFoundType {
+				return
+			}
+		}
+		cfg.expectedErrors = append(cfg.expectedErrors, notFoundType)
+	}
+}
@@ -0,0 +1,150 @@
+package sprout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+type teamMemberRequest struct {
+	Org  string `path:"org"`
+	Team string `path:"team"`
+	ID   string `path:"id"`
+}
+
+func TestWithParent(t *testing.T) {
+	orgs := map[string]bool{"acme": true}
+	teams := map[string]bool{"platform": true}
+	var checked []string
+
+	router := New()
+	GET(router, "/orgs/:org/teams/:team/users/:id", func(ctx context.Context, req *teamMemberRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: req.Org + "/" + req.Team + "/" + req.ID}, nil
+	},
+		WithParent("org", "organization", func(ctx context.Context, id string) (bool, error) {
+			checked = append(checked, "org:"+id)
+			if id == "broken" {
+				return false, errors.New("database unavailable")
+			}
+			return orgs[id], nil
+		}),
+		WithParent("team", "team", func(ctx context.Context, id string) (bool, error) {
+			checked = append(checked, "team:"+id)
+			return teams[id], nil
+		}),
+	)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		checked = nil
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	t.Run("all parents exist", func(t *testing.T) {
+		rec := serve("/orgs/acme/teams/platform/users/7")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if !reflect.DeepEqual(checked, []string{"org:acme", "team:platform"}) {
+			t.Fatalf("expected checks outermost first, got %v", checked)
+		}
+	})
+
+	t.Run("missing parent", func(t *testing.T) {
+		rec := serve("/orgs/initech/teams/platform/users/7")
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("expected JSON body: %v", err)
+		}
+		expected := map[string]any{"resource": "organization", "id": "initech", "message": "organization 'initech' not found"}
+		if !reflect.DeepEqual(body, expected) {
+			t.Fatalf("expected %v, got %v", expected, body)
+		}
+		if !reflect.DeepEqual(checked, []string{"org:initech"}) {
+			t.Fatalf("expected checks to stop at the first missing parent, got %v", checked)
+		}
+	})
+
+	t.Run("missing inner parent", func(t *testing.T) {
+		rec := serve("/orgs/acme/teams/sales/users/7")
+		if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"resource":"team"`) {
+			t.Fatalf("expected team 404, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("check error", func(t *testing.T) {
+		rec := serve("/orgs/broken/teams/platform/users/7")
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("expected status 500, got %d", rec.Code)
+		}
+	})
+}
+
+type customMissingError struct {
+	_      struct{} `http:"status=404"`
+	Detail string   `json:"detail"`
+}
+
+func (e *customMissingError) Error() string { return e.Detail }
+
+func TestWithParentAndDeclaredNotFound(t *testing.T) {
+	router := New()
+	GET(router, "/orgs/:org/projects/:id", func(ctx context.Context, req *teamMemberRequest) (*HelloResponse, error) {
+		return nil, &customMissingError{Detail: "no project " + req.ID}
+	}, WithErrors(&customMissingError{}), WithParent("org", "organization", func(ctx context.Context, id string) (bool, error) {
+		return id == "acme", nil
+	}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := serve("/orgs/initech/projects/7"); rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"resource":"organization"`) {
+		t.Fatalf("expected ResourceNotFoundError for the missing parent, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve("/orgs/acme/projects/7"); rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"detail":"no project 7"`) {
+		t.Fatalf("expected the handler's declared 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestOpenAPIWithParent(t *testing.T) {
+	exists := func(ctx context.Context, id string) (bool, error) { return true, nil }
+	router := New()
+	GET(router, "/orgs/:org/teams/:team", func(ctx context.Context, req *teamMemberRequest) (*HelloResponse, error) {
+		return &HelloResponse{}, nil
+	}, WithParent("org", "organization", exists))
+	GET(router, "/orgs/:org/projects/:id", func(ctx context.Context, req *teamMemberRequest) (*HelloResponse, error) {
+		return &HelloResponse{}, nil
+	}, WithErrors(&customMissingError{}), WithParent("org", "organization", exists))
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	notFound := doc.Paths.Value("/orgs/{org}/teams/{team}").Get.Responses.Value("404")
+	if notFound == nil || notFound.Value.Content["application/json"].Schema.Ref != "#/components/schemas/sprout_ResourceNotFoundError" {
+		t.Fatalf("expected ResourceNotFoundError documented as 404, got %#v", notFound)
+	}
+	custom := doc.Paths.Value("/orgs/{org}/projects/{id}").Get.Responses.Value("404")
+	if custom == nil || custom.Value.Content["application/json"].Schema.Ref != "#/components/schemas/sprout_ResourceNotFoundError" {
+		t.Fatalf("expected ResourceNotFoundError documented over the declared 404 type, got %#v", custom)
+	}
+}
@@ -0,0 +1,82 @@
+package sprout
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicError carries a value recovered by Recoverer and the stack of the
+// goroutine that panicked. It is the Err of an ErrorKindPanic error.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+
+	// Stack is the stack trace captured when the panic was recovered.
+	Stack []byte
+}
+
+// Error implements the error interface. The stack is left out.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value when it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Recoverer returns middleware that recovers panics in later middleware and
+// handlers. The panic becomes an *Error of kind ErrorKindPanic wrapping a
+// *PanicError, which goes through the usual error pipeline: ErrorHandler and
+// ErrorEnvelope apply, and the default response is 500 Internal Server Error.
+// Register it first so it covers everything after it.
+//
+// If the response was already started when the panic happened, its status
+// can no longer change; the error still reaches the pipeline, so ErrorHandler
+// can log it. http.ErrAbortHandler is re-raised for net/http to handle.
+func Recoverer() Middleware {
+	return func(w http.ResponseWriter, req *http.Request, next Next) {
+		rec := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(recovered)
+			}
+
+			panicErr := &Error{
+				Kind:    ErrorKindPanic,
+				Message: "recovered from panic",
+				Err:     &PanicError{Value: recovered, Stack: debug.Stack()},
+			}
+			if state := chainStateFrom(req); state != nil {
+				// Later layers may have swapped in writers of their own, such
+				// as an unfinished compressor; answer through ours instead.
+				if rec.status != 0 {
+					// Headers are committed; report the panic without writing.
+					state.setWriter(committedWriter{rec})
+				} else {
+					state.setWriter(rec)
+				}
+			}
+			next(panicErr)
+		}()
+
+		continueWithWriter(next, rec, req)
+	}
+}
+
+// committedWriter discards what the error pipeline writes for a response that
+// has already been started.
+type committedWriter struct {
+	http.ResponseWriter
+}
+
+func (committedWriter) WriteHeader(int) {}
+
+func (committedWriter) Write(b []byte) (int, error) { return len(b), nil }
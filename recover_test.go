@@ -0,0 +1,138 @@
+package sprout
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecoverer(t *testing.T) {
+	var handled error
+	router := NewWithConfig(&Config{
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			handled = err
+			var sproutErr *Error
+			if errors.As(err, &sproutErr) && sproutErr.Kind == ErrorKindPanic {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte("something broke"))
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+		},
+	})
+	router.Use(Recoverer())
+
+	errBoom := errors.New("boom")
+	GET(router, "/panic", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		panic(errBoom)
+	})
+	GET(router, "/partial", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, nil
+	}, WithMiddleware(func(w http.ResponseWriter, r *http.Request, next Next) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("started"))
+		panic("late")
+	}))
+	GET(router, "/next", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, ErrNext
+	})
+	router.Use(func(w http.ResponseWriter, r *http.Request, next Next) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	t.Run("panic becomes error", func(t *testing.T) {
+		handled = nil
+		rec := serve("/panic")
+		if rec.Code != http.StatusInternalServerError || rec.Body.String() != "something broke" {
+			t.Fatalf("expected error handler response, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var panicErr *PanicError
+		if !errors.As(handled, &panicErr) {
+			t.Fatalf("expected *PanicError, got %v", handled)
+		}
+		if panicErr.Value != errBoom || !errors.Is(handled, errBoom) {
+			t.Fatalf("expected recovered value to be kept, got %v", panicErr.Value)
+		}
+		if !strings.Contains(string(panicErr.Stack), "recover_test.go") {
+			t.Fatalf("expected stack of the panicking goroutine, got %s", panicErr.Stack)
+		}
+	})
+
+	t.Run("response already started", func(t *testing.T) {
+		handled = nil
+		rec := serve("/partial")
+		if rec.Code != http.StatusAccepted || rec.Body.String() != "started" {
+			t.Fatalf("expected committed response untouched, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var sproutErr *Error
+		if !errors.As(handled, &sproutErr) || sproutErr.Kind != ErrorKindPanic {
+			t.Fatalf("expected panic reported to the error handler, got %v", handled)
+		}
+	})
+
+	t.Run("ErrNext still falls through", func(t *testing.T) {
+		handled = nil
+		rec := serve("/next")
+		if rec.Code != http.StatusTeapot || handled != nil {
+			t.Fatalf("expected fallthrough to later middleware, got %d (error %v)", rec.Code, handled)
+		}
+	})
+}
+
+func TestRecovererDefaultResponse(t *testing.T) {
+	router := New()
+	router.Use(Recoverer())
+	GET(router, "/panic", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		panic("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "panic") {
+		t.Fatalf("expected panic error message, got %s", rec.Body.String())
+	}
+
+	defer func() {
+		if recover() != http.ErrAbortHandler {
+			t.Fatalf("expected http.ErrAbortHandler to be re-raised")
+		}
+	}()
+	GET(router, "/abort", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		panic(http.ErrAbortHandler)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+}
+
+func TestRecovererWithCompression(t *testing.T) {
+	router := New()
+	router.Use(Recoverer())
+	router.Use(Compression(CompressionOptions{MinLength: -1}))
+	GET(router, "/panic", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "panic") {
+		t.Fatalf("expected an uncompressed panic error body, got %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,65 @@
+package sprout
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// maxRequestIDLength bounds the client-sent request IDs that are kept; longer
+// ones are replaced with a generated ID.
+const maxRequestIDLength = 128
+
+type requestIDContextKey struct{}
+
+// RequestID returns the ID of the current request, as assigned through
+// Config.RequestIDHeader. It accepts the handler context as well as
+// r.Context() in middleware, and returns "" when no ID was assigned.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// withRequestID keeps the request ID sent in header, or generates one, echoes
+// it on the response and stores it on the request context. A generated ID
+// also replaces the request header, on a copy of the headers.
+func withRequestID(w http.ResponseWriter, req *http.Request, header string) *http.Request {
+	id := req.Header.Get(header)
+	generated := !validRequestID(id)
+	if generated {
+		id = newRequestID()
+	}
+	w.Header().Set(header, id)
+
+	req = req.WithContext(context.WithValue(req.Context(), requestIDContextKey{}, id))
+	if generated {
+		req.Header = req.Header.Clone()
+		if req.Header == nil {
+			req.Header = make(http.Header)
+		}
+		req.Header.Set(header, id)
+	}
+	return req
+}
+
+// validRequestID reports whether a client-sent ID is safe to echo: non-empty,
+// reasonably short and made of visible ASCII characters.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns 16 random bytes, hex encoded.
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
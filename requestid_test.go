@@ -0,0 +1,96 @@
+package sprout
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDHeader(t *testing.T) {
+	router := NewWithConfig(&Config{RequestIDHeader: "X-Request-ID"})
+
+	var fromMiddleware, fromHeader string
+	router.Use(func(w http.ResponseWriter, r *http.Request, next Next) {
+		fromMiddleware = RequestID(r.Context())
+		fromHeader = r.Header.Get("X-Request-ID")
+		next(nil)
+	})
+	GET(router, "/hello", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: RequestID(ctx)}, nil
+	})
+	GET(router, "/fail", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, &TeapotError{Msg: "short and stout"}
+	}, WithErrors(&TeapotError{}))
+
+	serve := func(path, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if id != "" {
+			req.Header.Set("X-Request-ID", id)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("echoes the client's ID", func(t *testing.T) {
+		rec := serve("/hello", "req-42")
+		if got := rec.Header().Get("X-Request-ID"); got != "req-42" {
+			t.Fatalf("expected echoed ID, got %q", got)
+		}
+		if body := strings.TrimSpace(rec.Body.String()); body != `{"message":"req-42"}` || fromMiddleware != "req-42" {
+			t.Fatalf("expected ID in handler and middleware context, got %s / %q", body, fromMiddleware)
+		}
+	})
+
+	t.Run("generates a missing or unsafe ID", func(t *testing.T) {
+		for _, id := range []string{"", "has space", strings.Repeat("a", maxRequestIDLength+1)} {
+			rec := serve("/hello", id)
+			got := rec.Header().Get("X-Request-ID")
+			if len(got) != 32 || got == id {
+				t.Fatalf("sent %q: expected a generated ID, got %q", id, got)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != `{"message":"`+got+`"}` {
+				t.Fatalf("sent %q: expected the generated ID in context, got %s", id, body)
+			}
+			if fromHeader != got {
+				t.Fatalf("sent %q: expected the generated ID in the request header, got %q", id, fromHeader)
+			}
+		}
+		if serve("/hello", "").Header().Get("X-Request-ID") == serve("/hello", "").Header().Get("X-Request-ID") {
+			t.Fatalf("expected a fresh ID per request")
+		}
+	})
+
+	t.Run("error responses", func(t *testing.T) {
+		rec := serve("/fail", "req-7")
+		if rec.Code != http.StatusTeapot || rec.Header().Get("X-Request-ID") != "req-7" {
+			t.Fatalf("expected the ID on error responses, got %d %v", rec.Code, rec.Header())
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		plain := New()
+		GET(plain, "/hello", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+			return &HelloResponse{Message: "id=" + RequestID(ctx)}, nil
+		})
+		req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+		req.Header.Set("X-Request-ID", "req-1")
+		rec := httptest.NewRecorder()
+		plain.ServeHTTP(rec, req)
+		if rec.Header().Get("X-Request-ID") != "" || strings.TrimSpace(rec.Body.String()) != `{"message":"id="}` {
+			t.Fatalf("expected no request ID handling, got %v %s", rec.Header(), rec.Body.String())
+		}
+	})
+
+	t.Run("inherited by mounts", func(t *testing.T) {
+		api := router.Mount("/api", nil)
+		GET(api, "/ping", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+			return &HelloResponse{Message: "pong"}, nil
+		})
+		if got := serve("/api/ping", "req-9").Header().Get("X-Request-ID"); got != "req-9" {
+			t.Fatalf("expected mount to inherit RequestIDHeader, got %q", got)
+		}
+	})
+}
@@ -1,17 +1,26 @@
 package sprout
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"mime"
 	"net/http"
+	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-playground/validator/v10"
 	"github.com/julienschmidt/httprouter"
 )
@@ -25,6 +34,10 @@ type Sprout struct {
 	order    *orderSeq
 	registry *routerRegistry
 
+	// internalOpenAPI collects routes registered WithInternal; nil when
+	// Config.InternalOpenAPIPath is unset.
+	internalOpenAPI *openAPIDocument
+
 	mwMu        sync.RWMutex
 	middlewares []middlewareLayer
 }
@@ -50,9 +63,243 @@ type Config struct {
 	// Leading and trailing slashes are handled automatically.
 	BasePath string
 
+	// NilIsNotFound makes a GET or HEAD handler that returns (nil, nil) answer
+	// 404 Not Found through the error pipeline, instead of sending an empty
+	// response struct. Other methods keep the default. Mounted routers
+	// inherit the setting when the parent enables it.
+	NilIsNotFound bool
+
+	// NilNotFoundError is the error reported for NilIsNotFound responses. A
+	// typed error is written like a declared handler error, so give it an
+	// `http:"status=404"` tag. Defaults to an ErrorKindNotFound *Error.
+	// Inherited by mounts unless set.
+	NilNotFoundError error
+
+	// SwaggerPath is where the OpenAPI document is served, relative to
+	// BasePath. Defaults to "/swagger". Inherited by mounts unless set.
+	SwaggerPath string
+
+	// DisableSwagger skips registering the OpenAPI document endpoint, e.g. in
+	// production. The document is still built, so OpenAPIJSON and OpenAPIYAML
+	// keep working for applications that serve it themselves. Mounted routers
+	// inherit the setting when the parent enables it.
+	DisableSwagger bool
+
+	// IsolatedOpenAPI gives a mounted router its own OpenAPI document instead of
+	// contributing to the parent's. The document is served at <BasePath><SwaggerPath>
+	// and collects routes registered on the mounted router and its descendants.
+	// Ignored by New/NewWithConfig, which always create a fresh document.
+	IsolatedOpenAPI bool
+
+	// HeadContentLength makes HEAD requests serialize the would-be response body
+	// to report its size in the Content-Length header. No body is written.
+	// Disabled by default to avoid the serialization cost. Mounted routers
+	// inherit the setting when the parent enables it.
+	HeadContentLength bool
+
+	// DynamicServers makes the /swagger endpoint add a server entry derived from
+	// the request's scheme and host when WithOpenAPIInfo configures no Servers.
+	// Configured Servers always take precedence. Mounted routers inherit the
+	// setting when the parent enables it.
+	DynamicServers bool
+
+	// RequestValidationError converts request validation failures into a typed
+	// error, which is rendered like an error declared with WithErrors (status and
+	// headers from its tags, defaulting to 400 Bad Request). Returning nil falls
+	// back to the default ErrorKindValidation handling. Inherited by mounts.
+	RequestValidationError func(validator.ValidationErrors) error
+
+	// ValidationErrorFormatter shapes the body of request validation failures
+	// (ErrorKindValidation) in the default error handling, e.g. with
+	// ValidationErrorFields for a per-field list. The result is written as
+	// JSON with the validation status and takes precedence over ErrorEnvelope;
+	// returning nil falls back to the usual response. RequestValidationError
+	// and a custom ErrorHandler take precedence over it. Inherited by mounts.
+	ValidationErrorFormatter func(validator.ValidationErrors) any
+
+	// PathValidationErrorKind reports path parameters that fail to parse or
+	// validate with this kind instead of ErrorKindParse or ErrorKindValidation
+	// (400), typically ErrorKindNotFound: a malformed ID in the URL cannot
+	// name any resource. Query, header and body failures keep their kinds.
+	// WithPathValidationAs overrides it per route. Inherited by mounts unless
+	// set.
+	PathValidationErrorKind ErrorKind
+
+	// DefaultResponseHeaders are added to every response written by Sprout,
+	// including typed errors and 404/405 fallbacks, e.g. security headers such
+	// as X-Content-Type-Options. They are applied first and only when absent, so
+	// headers set by middleware or `header:` response fields take precedence.
+	// Mounted routers inherit the parent's headers merged with their own.
+	DefaultResponseHeaders map[string]string
+
+	// EnablePreflight answers OPTIONS requests for every registered path with
+	// 204 No Content and permissive CORS headers, unless a route registers its
+	// own OPTIONS handler. It relies on httprouter's automatic OPTIONS handling
+	// and is only honoured by New/NewWithConfig, since mounts share the router.
+	EnablePreflight bool
+
+	// HandleRedirects performs trailing-slash and path-cleaning redirects
+	// ("/users/" → "/users", "/a//b" → "/a/b") inside Sprout's fallback dispatch
+	// instead of httprouter, so middleware for the path runs before the redirect
+	// is written. httprouter's RedirectTrailingSlash and RedirectFixedPath are
+	// turned off; case-insensitive path correction is not performed. Only
+	// honoured by New/NewWithConfig, since mounts share the router.
+	HandleRedirects bool
+
+	// MaxBodyBytes caps the size of JSON request bodies. Larger bodies fail with
+	// ErrorKindRequestTooLarge (413). For gzip-encoded bodies the limit applies to
+	// the decompressed size. Zero means no limit. WithMaxBodyBytes overrides it
+	// per route. Inherited by mounts unless set.
+	MaxBodyBytes int64
+
+	// RequestIDHeader names a header, such as "X-Request-ID", carrying a
+	// request ID. When set, each routed request keeps the ID its client sent
+	// or gets a generated one; the ID is echoed on the response and available
+	// to middleware and handlers through RequestID. Inherited by mounts unless
+	// set.
+	RequestIDHeader string
+
+	// ValuelessQueryFlags treats a bool query parameter given without a value
+	// ("?active") as true. An explicit value still wins ("?active=false" is
+	// false), and "?active=" remains unset. Inherited by mounts when enabled.
+	ValuelessQueryFlags bool
+
+	// InternalOpenAPIPath serves a second OpenAPI document, relative to
+	// BasePath, that collects the routes registered WithInternal. Those routes
+	// are left out of the public /swagger document either way; without a path
+	// they are not documented at all. Mounted routers share the parent's
+	// internal document unless IsolatedOpenAPI is set.
+	InternalOpenAPIPath string
+
+	// InternalOpenAPIMiddleware guards the internal document endpoint, e.g.
+	// with BasicAuth. It runs only for that endpoint, and a middleware error is
+	// handled like any other.
+	InternalOpenAPIMiddleware []Middleware
+
+	// ResponseValidationMode sets how routes without WithResponseValidationMode
+	// handle response validation failures. The zero value is
+	// ResponseValidationStrict. Inherited by mounts unless set.
+	ResponseValidationMode ResponseValidationMode
+
+	// ResponseValidationWarning is called for each response validation failure
+	// on a route in ResponseValidationWarn mode, e.g. to count failures in
+	// metrics. route is the registered path pattern. When nil, failures are
+	// logged with slog.WarnContext instead. Inherited by mounts unless set.
+	ResponseValidationWarning func(r *http.Request, route string, err error)
+
+	// DisallowUnknownFields rejects JSON request bodies containing keys that no
+	// body field declares, at any depth, with ErrorKindParse (400) naming the
+	// key. Keys matching path, query or header fields count as unknown too.
+	// json.RawMessage fields accept any payload. Inherited by mounts when
+	// enabled.
+	DisallowUnknownFields bool
+
+	// TreatEmptyAsPresent treats query parameters and headers that are sent
+	// with an empty value ("?name=") as present: they bind the zero value
+	// (pointers point to it) and satisfy `required`. Their other rules are
+	// still checked against the zero value. By default an empty value is
+	// skipped and behaves exactly like an absent one. Inherited by mounts when
+	// enabled.
+	TreatEmptyAsPresent bool
+
+	// CustomizeOpenAPI post-processes the generated OpenAPI document, e.g. to add
+	// webhooks, tags or components the built-in options do not cover. It runs
+	// lazily on a copy of the document when it is first served or marshaled,
+	// and again (on a fresh copy) only after further routes are registered, so
+	// it need not be idempotent. It applies to the router's own document: the
+	// root's, or a mount's when IsolatedOpenAPI is set.
+	CustomizeOpenAPI func(*openapi3.T)
+
+	// StopOnFirstValidationError reports only the first failed rule of request,
+	// response and typed-error validation. Responses stay small and do not
+	// reveal every constrained field, at the cost of clients fixing one problem
+	// per round trip. Validation itself still runs in full. Inherited by mounts
+	// when enabled.
+	StopOnFirstValidationError bool
+
+	// ErrorEnvelope transforms every error body Sprout writes—typed errors and
+	// system errors alike—into a uniform shape, such as NewProblemDetails. It
+	// receives the response status and the error; returning nil keeps the
+	// default body. `header:` fields on the returned value are applied, but
+	// header fields on a typed error still take precedence. Inherited by mounts.
+	// A custom ErrorHandler replaces the envelope for system errors.
+	ErrorEnvelope func(status int, err error) any
+
+	// EnableXML encodes success responses with encoding/xml for clients that
+	// prefer application/xml (or text/xml) over JSON in their Accept header.
+	// Only response types with `xml:` tags take part; only their tagged fields
+	// are encoded, and responses are sent as application/xml. Routes using
+	// WithProduces, text bodies or a response envelope keep their encoding.
+	// Errors are still JSON. Inherited by mounts when enabled.
+	EnableXML bool
+
+	// StatusForKind overrides the status written for Sprout's system errors,
+	// keyed by kind, e.g. {ErrorKindValidation: 422} to tell well-formed but
+	// invalid requests apart from unparseable ones (ErrorKindParse, still 400).
+	// Kinds not listed keep their default. It applies to the default error
+	// response and ErrorEnvelope alike; a custom ErrorHandler picks its own
+	// status. Inherited by mounts unless set.
+	StatusForKind map[ErrorKind]int
+
+	// ResponseDecorator adjusts every JSON object success body, e.g. to add
+	// hypermedia _links. It receives the handler context (see HTTPRequest),
+	// the route pattern with base path, and the body built from the response
+	// struct, and returns the body to send; nil keeps it unchanged. It runs
+	// before response envelopes. Unwrapped, slice, text, XML and custom
+	// MarshalJSON bodies, NDJSON and SSE streams and errors are not decorated.
+	// Added fields are not part of the OpenAPI schema. Inherited by mounts
+	// unless set.
+	ResponseDecorator func(ctx context.Context, routePattern string, body map[string]any) map[string]any
+
+	// HandlerDecorator wraps every typed handler, for retries, caching or
+	// instrumentation that needs the decoded request and the handler's result.
+	// It is called once per route at registration with the route pattern
+	// (including base path) and the handler, and returns the handler to use.
+	// The decorated handler runs after middleware, binding and request
+	// validation, and its result goes through response validation and error
+	// handling as usual. NDJSON and SSE handlers are not decorated. Inherited
+	// by mounts unless set.
+	HandlerDecorator func(routePattern string, next AnyHandle) AnyHandle
+
+	// DefaultStatusByMethod sets the success status for response types without
+	// an `http:"status=..."` tag, keyed by HTTP method, e.g.
+	// {"POST": 201, "DELETE": 204}. Methods not listed default to 200 OK, and a
+	// status tag always wins. The OpenAPI document uses the same default.
+	// Inherited by mounts unless set.
+	DefaultStatusByMethod map[string]int
+
+	// RequestEnvelopeKey re-roots JSON request bodies at the named key before
+	// binding, so {"data": {...}} binds the inner object to the DTO. Bodies
+	// lacking the key fail with ErrorKindParse. Text and raw request bodies are
+	// not affected. Inherited by mounts unless set.
+	RequestEnvelopeKey string
+
+	// ResponseEnvelopeKey wraps every JSON success body under the named key,
+	// typically the same one as RequestEnvelopeKey. Error responses, text
+	// bodies and NDJSON streams are not wrapped; see ErrorEnvelope for errors.
+	// Inherited by mounts unless set.
+	ResponseEnvelopeKey string
+
+	// ResponseEnvelope wraps every JSON success body in a uniform shape, such as
+	// {"data": ..., "meta": ...}. It receives the prepared body (after unwrap
+	// and ResponseEnvelopeKey) and the success status; returning nil keeps the
+	// body as is. `header:` fields on the returned value are applied, but header
+	// fields on the response DTO take precedence, and the status is unchanged.
+	// Error responses, text bodies and NDJSON streams are not wrapped. The
+	// OpenAPI document reflects the envelope by calling the function once per
+	// route with a placeholder body. Inherited by mounts unless set.
+	ResponseEnvelope func(body any, status int) any
+
+	// OpenAPITags are the OpenAPI tags of routes registered without WithTags,
+	// typically set on a mount to group its routes in Swagger UI. Describe
+	// them in OpenAPIInfo.Tags. Inherited by mounts unless set.
+	OpenAPITags []string
+
 	openapiInfo *OpenAPIInfo
 }
 
+const textContentType = "text/plain; charset=utf-8"
+
 // Option mutates router configuration before the Sprout instance is constructed.
 type Option func(*Config)
 
@@ -85,8 +332,9 @@ func NewWithConfig(config *Config, opts ...Option) *Sprout {
 
 	// Use JSON tag names in validation errors so error messages match the HTTP request field names
 	validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
+		// Report fields by their JSON names; an empty name (no json tag, or
+		// json:"-") makes the validator fall back to the Go field name.
 		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
-		// skip if tag key says it should be ignored
 		if name == "-" {
 			return ""
 		}
@@ -106,32 +354,229 @@ func NewWithConfig(config *Config, opts ...Option) *Sprout {
 	// Route 404 Not Found errors through ErrorHandler for consistent error handling
 	s.Router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		s.dispatchFallback(w, r, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if s.config.HandleRedirects && s.redirectPath(w, r) {
+				return
+			}
 			handleError(s, w, r, &Error{
 				Kind:    ErrorKindNotFound,
 				Message: fmt.Sprintf("route not found: %s %s", r.Method, r.URL.Path),
+				Meta: map[string]any{
+					"method": r.Method,
+					"path":   r.URL.Path,
+				},
 			})
 		}))
 	})
 
-	// Route 405 Method Not Allowed errors through ErrorHandler for consistent error handling
+	// Route 405 Method Not Allowed errors through ErrorHandler for consistent error handling.
+	// httprouter sets Allow on the original writer; it is captured here and set
+	// again in case middleware swapped the writer.
 	s.Router.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allow := w.Header().Get("Allow")
 		s.dispatchFallback(w, r, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", allow)
+			allowed := splitAllowHeader(allow)
 			handleError(s, w, r, &Error{
 				Kind:    ErrorKindMethodNotAllowed,
 				Message: fmt.Sprintf("method not allowed: %s %s", r.Method, r.URL.Path),
+				Err: &MethodNotAllowedError{
+					Method:  r.Method,
+					Allowed: allowed,
+				},
+				Meta: map[string]any{
+					"method":  r.Method,
+					"path":    r.URL.Path,
+					"allowed": allowed,
+				},
 			})
 		}))
 	})
 
-	// Expose generated OpenAPI specification
-	swaggerPath := joinPath(s.config.BasePath, "/swagger")
-	s.Router.GET(swaggerPath, s.openapi.ServeHTTP)
+	if config.HandleRedirects {
+		s.Router.RedirectTrailingSlash = false
+		s.Router.RedirectFixedPath = false
+	}
+
+	if config.EnablePreflight {
+		s.Router.HandleOPTIONS = true
+		s.Router.GlobalOPTIONS = http.HandlerFunc(s.servePreflight)
+	}
+
+	s.registerOpenAPIRoutes()
+	s.registerInternalOpenAPIRoutes()
 
 	return s
 }
 
+// servePreflight answers automatic OPTIONS requests when Config.EnablePreflight
+// is set. httprouter has already set the Allow header for the matched path.
+// Default headers come from the deepest router whose BasePath covers the path,
+// so mounts add their own.
+func (s *Sprout) servePreflight(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	header.Set("Access-Control-Allow-Origin", "*")
+	header.Set("Access-Control-Allow-Methods", header.Get("Allow"))
+	if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		header.Set("Access-Control-Allow-Headers", requested)
+		addVary(header, "Access-Control-Request-Headers")
+	}
+	header.Set("Access-Control-Max-Age", "600")
+
+	owner := s
+	if routers := s.registry.matchingRouters(r.URL.Path); len(routers) > 0 {
+		owner = routers[len(routers)-1]
+	}
+	owner.applyDefaultHeaders(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// redirectPath redirects requests whose path only misses a route by a trailing
+// slash or by uncleaned segments, mirroring httprouter's redirect behaviour.
+// It reports whether a redirect was written.
+func (s *Sprout) redirectPath(w http.ResponseWriter, r *http.Request) bool {
+	path := r.URL.Path
+	if r.Method == http.MethodConnect || path == "/" {
+		return false
+	}
+
+	target := ""
+	if _, _, tsr := s.Router.Lookup(r.Method, path); tsr {
+		target = toggleTrailingSlash(path)
+	} else if cleaned := httprouter.CleanPath(path); cleaned != path {
+		if handle, _, tsr := s.Router.Lookup(r.Method, cleaned); handle != nil {
+			target = cleaned
+		} else if tsr {
+			target = toggleTrailingSlash(cleaned)
+		}
+	}
+	if target == "" {
+		return false
+	}
+
+	code := http.StatusMovedPermanently
+	if r.Method != http.MethodGet {
+		code = http.StatusTemporaryRedirect
+	}
+
+	u := *r.URL
+	u.Path = target
+	u.RawPath = ""
+	s.applyDefaultHeaders(w)
+	http.Redirect(w, r, u.String(), code)
+	return true
+}
+
+func toggleTrailingSlash(path string) string {
+	if len(path) > 1 && strings.HasSuffix(path, "/") {
+		return path[:len(path)-1]
+	}
+	return path + "/"
+}
+
+// registerOpenAPIRoutes exposes the router's OpenAPI document under its base
+// path, with a Swagger UI page at <spec path>/ui.
+func (s *Sprout) registerOpenAPIRoutes() {
+	s.openapi.dynamicServers = s.config.DynamicServers
+	s.openapi.customize = s.config.CustomizeOpenAPI
+	if s.config.DisableSwagger {
+		return
+	}
+
+	swaggerPath := s.config.SwaggerPath
+	if swaggerPath == "" {
+		swaggerPath = "/swagger"
+	}
+	specPath := joinPath(s.config.BasePath, swaggerPath)
+	s.Router.GET(specPath, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		s.applyDefaultHeaders(w)
+		s.openapi.ServeHTTP(w, r, ps)
+	})
+	s.Router.GET(joinPath(specPath, "/ui"), func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		s.applyDefaultHeaders(w)
+		s.openapi.serveSwaggerUI(w, specPath)
+	})
+}
+
+// registerInternalOpenAPIRoutes exposes the internal OpenAPI document at
+// Config.InternalOpenAPIPath, behind Config.InternalOpenAPIMiddleware.
+func (s *Sprout) registerInternalOpenAPIRoutes() {
+	if s.config.InternalOpenAPIPath == "" {
+		return
+	}
+
+	s.internalOpenAPI = newOpenAPIDocument(s.config.openapiInfo)
+	s.internalOpenAPI.dynamicServers = s.config.DynamicServers
+	doc := s.internalOpenAPI
+
+	chain := append([]Middleware(nil), s.config.InternalOpenAPIMiddleware...)
+	s.Router.GET(joinPath(s.config.BasePath, s.config.InternalOpenAPIPath), func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		serve := func(w http.ResponseWriter, r *http.Request, _ Next) {
+			s.applyDefaultHeaders(w)
+			doc.ServeHTTP(w, r, ps)
+		}
+		runChain(append(chain[:len(chain):len(chain)], serve), s, w, r)
+	})
+}
+
 type Handle[Req, Resp any] func(context.Context, *Req) (*Resp, error)
 
+// AnyHandle is a typed handler with its types erased, as seen by
+// Config.HandlerDecorator. req is the handler's *Req and the response its
+// *Resp; a decorator that replaces them must keep those types.
+type AnyHandle func(ctx context.Context, req any) (any, error)
+
+// decorateHandler applies decorate to h through its AnyHandle form.
+func decorateHandler[Req, Resp any](decorate func(string, AnyHandle) AnyHandle, routePattern string, h Handle[Req, Resp]) Handle[Req, Resp] {
+	next := func(ctx context.Context, req any) (any, error) {
+		typed, ok := req.(*Req)
+		if !ok {
+			return nil, fmt.Errorf("sprout: handler decorator for %s passed request %T, want %T", routePattern, req, typed)
+		}
+		resp, err := h(ctx, typed)
+		if resp == nil {
+			return nil, err
+		}
+		return resp, err
+	}
+	decorated := decorate(routePattern, next)
+	return func(ctx context.Context, req *Req) (*Resp, error) {
+		resp, err := decorated(ctx, req)
+		if resp == nil {
+			return nil, err
+		}
+		typed, ok := resp.(*Resp)
+		if !ok {
+			return nil, fmt.Errorf("sprout: handler decorator for %s returned response %T, want %T", routePattern, resp, typed)
+		}
+		return typed, err
+	}
+}
+
+// ListHandle is a handler that returns a slice directly instead of a pointer
+// to a response DTO. Adapt it with List to register it.
+type ListHandle[Req, Elem any] func(context.Context, *Req) ([]Elem, error)
+
+// List adapts h to a Handle so it can be passed to GET, POST and the other
+// registration functions. The slice is serialized as a JSON array ([] when h
+// returns nil) and its struct elements are validated like any response DTO.
+func List[Req, Elem any](h ListHandle[Req, Elem]) Handle[Req, []Elem] {
+	return func(ctx context.Context, req *Req) (*[]Elem, error) {
+		items, err := h(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return &items, nil
+	}
+}
+
+// MultiStatus is the response of a batch operation whose items succeed or
+// fail independently. It is sent as 207 Multi-Status with Results as a bare
+// JSON array, and struct results are validated like any response DTO.
+type MultiStatus[T any] struct {
+	_       struct{} `http:"status=207"`
+	Results []T      `json:"results" sprout:"unwrap" validate:"dive"`
+}
+
 // joinPath joins base path and route path, handling slashes correctly
 func joinPath(basePath, routePath string) string {
 	// Clean up base path
@@ -173,42 +618,171 @@ func combineBasePath(paths ...string) string {
 
 // handle is a helper that applies route config and registers a handler
 func handle[Req, Resp any](s *Sprout, method, path string, h Handle[Req, Resp], opts ...RouteOption) {
+	cfg := newRouteConfig(opts)
+	_, cfg.textResponse = textBodyField(typeOf[Resp]())
+	_, cfg.encodedResponse = encodedBodyField(typeOf[Resp]())
+	if !cfg.textResponse && !cfg.encodedResponse {
+		cfg.responseEnvelopeKey = s.config.ResponseEnvelopeKey
+		cfg.responseEnvelope = s.config.ResponseEnvelope
+	}
+	s.registerRoute(method, path, typeOf[Req](), typeOf[Resp](), cfg, func(entry *routeEntry) Middleware {
+		return wrap(entry, h, cfg)
+	})
+}
+
+func newRouteConfig(opts []RouteOption) *routeConfig {
 	cfg := &routeConfig{}
 	for _, opt := range opts {
 		opt(cfg)
 	}
+	return cfg
+}
+
+// registerRoute documents a route and installs it on the underlying router.
+// build produces the terminal middleware once the route entry exists.
+func (s *Sprout) registerRoute(method, path string, reqType, respType reflect.Type, cfg *routeConfig, build func(*routeEntry) Middleware) {
+	if cfg.disabled {
+		return
+	}
 
 	// Prepend base path if configured
 	fullPath := joinPath(s.config.BasePath, path)
+	cfg.defaultStatus = s.config.DefaultStatusByMethod[strings.ToUpper(method)]
+	cfg.requestEnvelopeKey = s.config.RequestEnvelopeKey
+	if cfg.responseValidation == 0 {
+		cfg.responseValidation = s.config.ResponseValidationMode
+	}
+	if cfg.tags == nil {
+		cfg.tags = s.config.OpenAPITags
+	}
+	if cfg.maxBodyBytes == 0 {
+		cfg.maxBodyBytes = s.config.MaxBodyBytes
+	}
+	if cfg.pathValidationKind == "" {
+		cfg.pathValidationKind = s.config.PathValidationErrorKind
+	}
+	s.validateExamples(method, fullPath, cfg)
 
-	if s.openapi != nil {
-		s.openapi.RegisterRoute(method, fullPath, typeOf[Req](), typeOf[Resp](), cfg.expectedErrors)
+	doc := s.openapi
+	if cfg.internal {
+		doc = s.internalOpenAPI
 	}
+	validateSecurity(doc, method, fullPath, cfg)
 
 	entry := &routeEntry{
 		owner:           s,
 		order:           s.order.Next(),
 		routeMiddleware: cfg.middlewares,
+		method:          method,
+		path:            fullPath,
+		config:          cfg,
+		reqType:         reqType,
+		respType:        respType,
+	}
+	entry.fn = build(entry)
+
+	// Handlers sharing a method and path are variants selected by Accept.
+	variants, created := s.registry.routeVariantsFor(method, fullPath)
+	variants.add(method, fullPath, cfg.responseContentType(), entry)
+	s.registry.addEntry(entry)
+
+	if doc != nil {
+		doc.RegisterRoute(method, fullPath, reqType, respType, cfg)
 	}
-	entry.fn = wrap(entry, h, cfg)
 
+	if !created {
+		return
+	}
 	s.Router.Handle(method, fullPath, func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		entry, negotiated := variants.selectEntry(req)
+		if negotiated {
+			addVary(w.Header(), "Accept")
+		}
 		entry.owner.dispatchRoute(w, req, ps, entry)
 	})
 }
 
 // Mount creates a child router that shares the underlying router and validator.
 // The child inherits configuration such as error handlers, while applying an additional base path prefix.
-func (s *Sprout) Mount(prefix string, config *Config) *Sprout {
+// Set Config.IsolatedOpenAPI to give the child its own OpenAPI document; opts such as
+// WithOpenAPIInfo then configure that document's metadata.
+func (s *Sprout) Mount(prefix string, config *Config, opts ...Option) *Sprout {
 	var childConfig Config
 	if config != nil {
 		childConfig = *config
 	}
 
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&childConfig)
+		}
+	}
+
 	if childConfig.ErrorHandler == nil {
 		childConfig.ErrorHandler = s.config.ErrorHandler
 	}
 
+	if childConfig.MaxBodyBytes == 0 {
+		childConfig.MaxBodyBytes = s.config.MaxBodyBytes
+	}
+
+	if childConfig.PathValidationErrorKind == "" {
+		childConfig.PathValidationErrorKind = s.config.PathValidationErrorKind
+	}
+
+	childConfig.DefaultResponseHeaders = mergeHeaders(s.config.DefaultResponseHeaders, childConfig.DefaultResponseHeaders)
+
+	if childConfig.ErrorEnvelope == nil {
+		childConfig.ErrorEnvelope = s.config.ErrorEnvelope
+	}
+
+	if childConfig.RequestValidationError == nil {
+		childConfig.RequestValidationError = s.config.RequestValidationError
+	}
+	if childConfig.ValidationErrorFormatter == nil {
+		childConfig.ValidationErrorFormatter = s.config.ValidationErrorFormatter
+	}
+
+	if childConfig.RequestEnvelopeKey == "" {
+		childConfig.RequestEnvelopeKey = s.config.RequestEnvelopeKey
+	}
+	if childConfig.ResponseEnvelopeKey == "" {
+		childConfig.ResponseEnvelopeKey = s.config.ResponseEnvelopeKey
+	}
+	if childConfig.ResponseEnvelope == nil {
+		childConfig.ResponseEnvelope = s.config.ResponseEnvelope
+	}
+	if childConfig.ResponseValidationMode == 0 {
+		childConfig.ResponseValidationMode = s.config.ResponseValidationMode
+	}
+	if childConfig.ResponseValidationWarning == nil {
+		childConfig.ResponseValidationWarning = s.config.ResponseValidationWarning
+	}
+	if childConfig.DefaultStatusByMethod == nil {
+		childConfig.DefaultStatusByMethod = s.config.DefaultStatusByMethod
+	}
+	if childConfig.ResponseDecorator == nil {
+		childConfig.ResponseDecorator = s.config.ResponseDecorator
+	}
+	if childConfig.NilNotFoundError == nil {
+		childConfig.NilNotFoundError = s.config.NilNotFoundError
+	}
+	if childConfig.SwaggerPath == "" {
+		childConfig.SwaggerPath = s.config.SwaggerPath
+	}
+	if childConfig.RequestIDHeader == "" {
+		childConfig.RequestIDHeader = s.config.RequestIDHeader
+	}
+	if childConfig.HandlerDecorator == nil {
+		childConfig.HandlerDecorator = s.config.HandlerDecorator
+	}
+	if childConfig.StatusForKind == nil {
+		childConfig.StatusForKind = s.config.StatusForKind
+	}
+	if childConfig.OpenAPITags == nil {
+		childConfig.OpenAPITags = s.config.OpenAPITags
+	}
+
 	if childConfig.StrictErrorTypes == nil {
 		strict := *s.config.StrictErrorTypes
 		childConfig.StrictErrorTypes = &strict
@@ -218,6 +792,16 @@ func (s *Sprout) Mount(prefix string, config *Config) *Sprout {
 		childConfig.openapiInfo = s.config.openapiInfo
 	}
 
+	childConfig.HeadContentLength = childConfig.HeadContentLength || s.config.HeadContentLength
+	childConfig.DynamicServers = childConfig.DynamicServers || s.config.DynamicServers
+	childConfig.DisableSwagger = childConfig.DisableSwagger || s.config.DisableSwagger
+	childConfig.NilIsNotFound = childConfig.NilIsNotFound || s.config.NilIsNotFound
+	childConfig.ValuelessQueryFlags = childConfig.ValuelessQueryFlags || s.config.ValuelessQueryFlags
+	childConfig.StopOnFirstValidationError = childConfig.StopOnFirstValidationError || s.config.StopOnFirstValidationError
+	childConfig.TreatEmptyAsPresent = childConfig.TreatEmptyAsPresent || s.config.TreatEmptyAsPresent
+	childConfig.DisallowUnknownFields = childConfig.DisallowUnknownFields || s.config.DisallowUnknownFields
+	childConfig.EnableXML = childConfig.EnableXML || s.config.EnableXML
+
 	childConfig.BasePath = combineBasePath(s.config.BasePath, prefix, childConfig.BasePath)
 
 	child := &Sprout{
@@ -228,12 +812,60 @@ func (s *Sprout) Mount(prefix string, config *Config) *Sprout {
 		parent:   s,
 		order:    s.order,
 		registry: s.registry,
+
+		internalOpenAPI: s.internalOpenAPI,
+	}
+	if childConfig.IsolatedOpenAPI {
+		child.openapi = newOpenAPIDocument(childConfig.openapiInfo)
+		child.internalOpenAPI = nil
+		child.registerOpenAPIRoutes()
+		child.registerInternalOpenAPIRoutes()
 	}
+
 	s.registry.add(child)
 
 	return child
 }
 
+// mergeHeaders returns a copy of parent overlaid with child. It returns nil when both are empty.
+func mergeHeaders(parent, child map[string]string) map[string]string {
+	if len(parent) == 0 && len(child) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(parent)+len(child))
+	for name, value := range parent {
+		merged[name] = value
+	}
+	for name, value := range child {
+		merged[name] = value
+	}
+	return merged
+}
+
+// applyDefaultHeaders sets Config.DefaultResponseHeaders that are not already present.
+func (s *Sprout) applyDefaultHeaders(w http.ResponseWriter) {
+	for name, value := range s.config.DefaultResponseHeaders {
+		if w.Header().Get(name) == "" || strings.EqualFold(name, "Vary") {
+			setResponseHeader(w.Header(), name, value)
+		}
+	}
+}
+
+// setResponseHeader sets a header from a response field or default. Vary
+// values are merged into the existing list instead of replacing it, so the
+// Vary entries added by content negotiation and compression survive.
+func setResponseHeader(header http.Header, name, value string) {
+	if !strings.EqualFold(name, "Vary") {
+		header.Set(name, value)
+		return
+	}
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			addVary(header, part)
+		}
+	}
+}
+
 // RegisterCustomTypeFunc exposes validator.RegisterCustomTypeFunc to allow custom type handling.
 func (s *Sprout) RegisterCustomTypeFunc(fn validator.CustomTypeFunc, types ...any) {
 	s.validate.RegisterCustomTypeFunc(fn, types...)
@@ -268,6 +900,96 @@ type routeConfig struct {
 	expectedErrors []reflect.Type
 	middlewares    []Middleware
 	rawRequestBody bool
+
+	// successContentType overrides the media type of the success response.
+	successContentType string
+	prettyJSON         bool
+	optionalBody       bool
+	cacheControl       string
+	hardTimeout        time.Duration
+
+	// parentChecks is set by WithParent, whose ResourceNotFoundError is then
+	// documented as the route's 404 response.
+	parentChecks bool
+
+	// maxBodyBytes is the route's body limit, falling back to
+	// Config.MaxBodyBytes at registration. Negative means no limit.
+	maxBodyBytes int64
+
+	// pathValidationKind is the kind reported for path parameters that fail
+	// to parse or validate, falling back to Config.PathValidationErrorKind at
+	// registration. Empty keeps the usual 400 kinds.
+	pathValidationKind ErrorKind
+
+	// tags are the route's OpenAPI tags, falling back to Config.OpenAPITags
+	// at registration.
+	tags []string
+
+	// security lists the route's alternative OpenAPI security requirements,
+	// each naming the schemes that must all be satisfied.
+	security [][]string
+
+	// exampleRequest and exampleResponse are documented on the request body
+	// and success response. They are validated at registration unless
+	// unvalidatedExamples is set.
+	exampleRequest      any
+	exampleResponse     any
+	unvalidatedExamples bool
+
+	// responseValidation is the route's mode, falling back to
+	// Config.ResponseValidationMode at registration.
+	responseValidation ResponseValidationMode
+
+	// summary and description document the operation in OpenAPI.
+	summary     string
+	description string
+
+	// deprecated routes are flagged in the OpenAPI document.
+	deprecated bool
+
+	// extraStatuses are additional success statuses documented with
+	// WithResponses, for handlers that pick their status with WithStatus.
+	extraStatuses []int
+
+	// disabled routes are skipped at registration.
+	disabled bool
+
+	// internal routes are documented in the internal OpenAPI document only.
+	internal bool
+
+	// textResponse is set when the response type has a textbody field.
+	textResponse bool
+	// encodedResponse is set when the response type has an encodedbody field.
+	encodedResponse bool
+
+	// defaultStatus is the success status for untagged response types,
+	// resolved from Config.DefaultStatusByMethod at registration.
+	defaultStatus int
+
+	// requestEnvelopeKey, responseEnvelopeKey and responseEnvelope are
+	// resolved from the router's Config at registration.
+	requestEnvelopeKey  string
+	responseEnvelopeKey string
+	responseEnvelope    func(body any, status int) any
+}
+
+// successStatus returns the status used when a response type has no status tag.
+func (cfg *routeConfig) successStatus() int {
+	if cfg.defaultStatus != 0 {
+		return cfg.defaultStatus
+	}
+	return http.StatusOK
+}
+
+// responseContentType returns the media type of the route's success response.
+func (cfg *routeConfig) responseContentType() string {
+	if cfg.successContentType != "" {
+		return cfg.successContentType
+	}
+	if cfg.textResponse {
+		return textContentType
+	}
+	return "application/json"
 }
 
 // WithErrors registers expected error types for validation and documentation
@@ -304,12 +1026,300 @@ func WithRawRequest() RouteOption {
 	}
 }
 
+// WithOptionalBody marks the request body as optional. The body is documented
+// with requestBody.required=false, and when a request arrives without one the
+// validation rules of body fields are skipped; path, query, and header fields
+// are validated as usual. A body that is present is validated in full.
+func WithOptionalBody() RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.optionalBody = true
+	}
+}
+
+// ResponseValidationMode controls what happens when a response fails validation.
+type ResponseValidationMode int
+
+const (
+	// ResponseValidationStrict fails the request with ErrorKindResponseValidation
+	// (500). This is the default.
+	ResponseValidationStrict ResponseValidationMode = iota + 1
+	// ResponseValidationWarn reports the failure through
+	// Config.ResponseValidationWarning (or slog) and sends the response anyway.
+	ResponseValidationWarn
+)
+
+// WithResponseValidationMode overrides Config.ResponseValidationMode for the
+// route, e.g. to roll out validation on legacy handlers in warn mode first.
+func WithResponseValidationMode(mode ResponseValidationMode) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.responseValidation = mode
+	}
+}
+
+// WithEnabled registers the route only when enabled is true, e.g. behind a
+// feature flag. A disabled route is skipped entirely: it is not served, not
+// documented and does not reserve its path, so another handler may take it.
+func WithEnabled(enabled bool) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.disabled = !enabled
+	}
+}
+
+// WithInternal documents the route in the internal OpenAPI document served at
+// Config.InternalOpenAPIPath instead of the public one. The route itself is
+// served like any other; protect it with middleware as needed.
+func WithInternal() RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.internal = true
+	}
+}
+
+// CacheOption adjusts the Cache-Control policy set by WithCache.
+type CacheOption func(*cachePolicy)
+
+type cachePolicy struct {
+	maxAge  time.Duration
+	private bool
+	noStore bool
+}
+
+// CachePrivate restricts caching to the client ("private") so shared caches
+// such as CDNs do not store the response.
+func CachePrivate() CacheOption {
+	return func(p *cachePolicy) {
+		p.private = true
+	}
+}
+
+// CacheNoStore forbids caching altogether ("no-store"); maxAge is ignored.
+func CacheNoStore() CacheOption {
+	return func(p *cachePolicy) {
+		p.noStore = true
+	}
+}
+
+func (p cachePolicy) header() string {
+	if p.noStore {
+		return "no-store"
+	}
+	visibility := "public"
+	if p.private {
+		visibility = "private"
+	}
+	return fmt.Sprintf("%s, max-age=%d", visibility, int64(p.maxAge/time.Second))
+}
+
+// WithCache sets Cache-Control on successful GET and HEAD responses, by default
+// "public, max-age=N" with N in whole seconds. A Cache-Control header set by
+// middleware or a `header:"Cache-Control"` response field takes precedence,
+// while Config.DefaultResponseHeaders does not.
+func WithCache(maxAge time.Duration, opts ...CacheOption) RouteOption {
+	policy := cachePolicy{maxAge: maxAge}
+	for _, opt := range opts {
+		opt(&policy)
+	}
+	return func(cfg *routeConfig) {
+		cfg.cacheControl = policy.header()
+	}
+}
+
+// WithProduces sets the Content-Type of the route's success responses, e.g.
+// "application/hal+json", and documents it in OpenAPI. The body is still
+// encoded as JSON, and a `header:"Content-Type"` response field takes precedence.
+func WithProduces(contentType string) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.successContentType = contentType
+	}
+}
+
+// WithPrettyJSON indents the route's JSON success responses for human readers.
+// Responses are compact by default.
+func WithPrettyJSON() RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.prettyJSON = true
+	}
+}
+
+// WithTags groups the route under the given OpenAPI tags, replacing the
+// router's Config.OpenAPITags for this route.
+func WithTags(tags ...string) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.tags = append([]string{}, tags...)
+	}
+}
+
+// WithSummary sets the one-line summary of the route's OpenAPI operation.
+func WithSummary(summary string) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.summary = summary
+	}
+}
+
+// WithDescription sets the longer description of the route's OpenAPI
+// operation. CommonMark is allowed.
+func WithDescription(description string) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.description = description
+	}
+}
+
+// WithDeprecated marks the route as deprecated in the OpenAPI document. The
+// route keeps serving requests; to flag a single field or parameter instead,
+// tag it `sprout:"deprecated"`.
+func WithDeprecated() RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.deprecated = true
+	}
+}
+
+// WithResponses documents additional success statuses for a route whose
+// handler picks its status at runtime with WithStatus. Each status is listed
+// in the OpenAPI document with the response type's schema, next to the
+// status the type would get by default. It panics on codes outside 2xx.
+func WithResponses(statuses ...int) RouteOption {
+	for _, status := range statuses {
+		if status < 200 || status > 299 {
+			panic(fmt.Sprintf("sprout: WithResponses status %d is not a success status", status))
+		}
+	}
+	return func(cfg *routeConfig) {
+		cfg.extraStatuses = append(cfg.extraStatuses, statuses...)
+	}
+}
+
+// WithSecurity documents that the route requires the named security schemes,
+// as registered in OpenAPIInfo.SecuritySchemes. All schemes of one call must
+// be satisfied together; repeated calls add alternatives. Calling it without
+// names documents the route as also open to anonymous clients. A name the
+// route's document does not define panics when the route is registered. It
+// only affects the OpenAPI document; enforce authentication with middleware
+// such as BasicAuth.
+func WithSecurity(schemes ...string) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.security = append(cfg.security, append([]string(nil), schemes...))
+	}
+}
+
+// validateSecurity panics when the route names a security scheme that doc
+// does not define, so a typo fails at startup instead of producing a
+// dangling reference.
+func validateSecurity(doc *openAPIDocument, method, path string, cfg *routeConfig) {
+	if doc == nil {
+		return
+	}
+	for _, names := range cfg.security {
+		for _, name := range names {
+			if !doc.hasSecurityScheme(name) {
+				panic(fmt.Sprintf("sprout: security scheme %q for %s %s is not defined in OpenAPIInfo.SecuritySchemes", name, method, path))
+			}
+		}
+	}
+}
+
+// WithHardTimeout answers 503 Service Unavailable when the route's handler has
+// not returned within d. The handler runs in its own goroutine with a context
+// that is cancelled at the deadline; it cannot be stopped, so it must watch
+// ctx.Done() to release its resources. Its late result, or panic, is discarded.
+func WithHardTimeout(d time.Duration) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.hardTimeout = d
+	}
+}
+
+// WithExampleRequest documents example as the route's request body example.
+// Struct examples are checked against their validation rules when the route is
+// registered, and an invalid one panics, so a stale example fails at startup
+// rather than misleading API consumers. Only body fields appear in the
+// document.
+func WithExampleRequest(example any) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.exampleRequest = example
+	}
+}
+
+// WithExampleResponse documents example as the route's success response example,
+// serialized the way the route would send it. Like WithExampleRequest, struct
+// examples must pass validation when the route is registered.
+func WithExampleResponse(example any) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.exampleResponse = example
+	}
+}
+
+// WithUnvalidatedExamples skips the registration-time validation of the route's
+// examples, for routes that deliberately document an invalid payload.
+func WithUnvalidatedExamples() RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.unvalidatedExamples = true
+	}
+}
+
+// validateExamples panics when a struct example of the route fails validation.
+func (s *Sprout) validateExamples(method, path string, cfg *routeConfig) {
+	if cfg.unvalidatedExamples {
+		return
+	}
+	examples := []struct {
+		kind    string
+		example any
+	}{
+		{"request", cfg.exampleRequest},
+		{"response", cfg.exampleResponse},
+	}
+	for _, e := range examples {
+		if !isStructLike(reflect.ValueOf(e.example)) {
+			continue
+		}
+		if err := s.validate.Struct(e.example); err != nil {
+			panic(fmt.Sprintf("sprout: example %s for %s %s is invalid: %v", e.kind, method, path, err))
+		}
+	}
+}
+
+// WithMaxBodyBytes sets the route's request body limit, overriding
+// Config.MaxBodyBytes in either direction, e.g. to let an upload route accept
+// more than the router's default. Larger bodies fail with
+// ErrorKindRequestTooLarge (413) naming this limit. A negative n removes the
+// limit for the route.
+func WithMaxBodyBytes(n int64) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.maxBodyBytes = n
+	}
+}
+
+// WithPathValidationAs reports the route's path parameters that fail to parse
+// or validate with kind, overriding Config.PathValidationErrorKind. Use
+// ErrorKindNotFound to answer a malformed ID with 404 rather than 400, or
+// ErrorKindValidation to keep a 400 on one route of a router that opts in.
+func WithPathValidationAs(kind ErrorKind) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.pathValidationKind = kind
+	}
+}
+
 // setFieldValue sets a reflect.Value from a string value, handling type conversion
 func setFieldValue(fieldValue reflect.Value, value string) error {
 	if value == "" {
 		return nil // Skip empty values
 	}
 
+	// Pointer fields stay nil until a value is present
+	if fieldValue.Kind() == reflect.Ptr {
+		target := reflect.New(fieldValue.Type().Elem())
+		if err := setFieldValue(target.Elem(), value); err != nil {
+			return err
+		}
+		fieldValue.Set(target)
+		return nil
+	}
+
+	// Domain types parse themselves
+	if fieldValue.CanAddr() {
+		if u, ok := fieldValue.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(value))
+		}
+	}
+
 	switch fieldValue.Kind() {
 	case reflect.String:
 		fieldValue.SetString(value)
@@ -344,166 +1354,1014 @@ func setFieldValue(fieldValue reflect.Value, value string) error {
 	return nil
 }
 
-func wrap[Req, Resp any](entry *routeEntry, handle Handle[Req, Resp], cfg *routeConfig) Middleware {
-	return func(w http.ResponseWriter, req *http.Request, next Next) {
-		s := entry.owner
-		ctx := withHTTPRequest(req.Context(), req)
+// bindParameters populates path, query and header fields of v, descending into
+// named struct fields that group further parameters.
+func bindParameters(s *Sprout, v reflect.Value, req *http.Request, params httprouter.Params) *Error {
+	t := v.Type()
 
-		// Parse request into the typed DTO
-		var reqDTO Req
-		reqValue := reflect.ValueOf(&reqDTO).Elem()
-		reqType := reqValue.Type()
-		params := Params(req)
+	// Iterate through struct fields and populate from different sources
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldValue := v.Field(i)
 
-		// Iterate through struct fields and populate from different sources
-		for i := 0; i < reqType.NumField(); i++ {
-			field := reqType.Field(i)
-			fieldValue := reqValue.Field(i)
+		if !fieldValue.CanSet() {
+			continue
+		}
 
-			if !fieldValue.CanSet() {
-				continue
+		// Descend into structs grouping related parameters
+		if isParameterGroupField(field) {
+			if err := bindParameters(s, fieldValue, req, params); err != nil {
+				return err
 			}
+			continue
+		}
 
-			// Handle path parameters
-			if pathTag := field.Tag.Get("path"); pathTag != "" {
-				paramValue := ""
-				if params != nil {
-					paramValue = params.ByName(pathTag)
+		// Handle path parameters
+		if pathTag := field.Tag.Get("path"); pathTag != "" {
+			paramValue := ""
+			if params != nil {
+				paramValue = params.ByName(pathTag)
+			}
+			paramValue = withDefault(field, paramValue)
+			if err := setFieldValue(fieldValue, paramValue); err != nil {
+				return &Error{
+					Kind:    ErrorKindParse,
+					Message: fmt.Sprintf("invalid path parameter '%s'", pathTag),
+					Err: &ParseParameterError{
+						Parameter: pathTag,
+						Source:    ParameterSourcePath,
+						Value:     paramValue,
+						Err:       err,
+					},
 				}
-				if err := setFieldValue(fieldValue, paramValue); err != nil {
-					handleError(s, w, req, &Error{
-						Kind:    ErrorKindParse,
-						Message: fmt.Sprintf("invalid path parameter '%s'", pathTag),
-						Err: &ParseParameterError{
-							Parameter: pathTag,
-							Source:    ParameterSourcePath,
-							Value:     paramValue,
-							Err:       err,
-						},
-					})
-					return
+			}
+		}
+
+		// Handle query parameters
+		if queryTag := field.Tag.Get("query"); queryTag != "" && isDeepObjectField(field) {
+			if key, value, err := bindDeepObject(fieldValue, req.URL.Query(), queryTag); err != nil {
+				return &Error{
+					Kind:    ErrorKindParse,
+					Message: fmt.Sprintf("invalid query parameter '%s[%s]'", queryTag, key),
+					Err: &ParseParameterError{
+						Parameter: queryTag + "[" + key + "]",
+						Source:    ParameterSourceQuery,
+						Value:     value,
+						Err:       err,
+					},
 				}
 			}
+		} else if queryTag != "" && isQuerySliceField(field) {
+			values := req.URL.Query()[queryTag]
+			if def, ok := field.Tag.Lookup("default"); ok && (len(values) == 0 || (len(values) == 1 && values[0] == "")) {
+				values = []string{def}
+			}
+			if index, value, err := setSliceValue(fieldValue, values, !hasSproutOption(field, "nosplit")); err != nil {
+				return &Error{
+					Kind:    ErrorKindParse,
+					Message: fmt.Sprintf("invalid query parameter '%s' at index %d", queryTag, index),
+					Err: &ParseParameterError{
+						Parameter: queryTag,
+						Source:    ParameterSourceQuery,
+						Value:     value,
+						Err:       err,
+					},
+				}
+			}
+		} else if queryTag != "" {
+			queryValue := req.URL.Query().Get(queryTag)
+			if queryValue == "" && s.config.ValuelessQueryFlags && derefType(field.Type).Kind() == reflect.Bool &&
+				hasValuelessQueryKey(req.URL.RawQuery, queryTag) {
+				queryValue = "true"
+			}
+			queryValue = withDefault(field, queryValue)
+			if err := setFieldValue(fieldValue, queryValue); err != nil {
+				return &Error{
+					Kind:    ErrorKindParse,
+					Message: fmt.Sprintf("invalid query parameter '%s'", queryTag),
+					Err: &ParseParameterError{
+						Parameter: queryTag,
+						Source:    ParameterSourceQuery,
+						Value:     queryValue,
+						Err:       err,
+					},
+				}
+			}
+		}
 
-			// Handle query parameters
-			if queryTag := field.Tag.Get("query"); queryTag != "" {
-				queryValue := req.URL.Query().Get(queryTag)
-				if err := setFieldValue(fieldValue, queryValue); err != nil {
-					handleError(s, w, req, &Error{
-						Kind:    ErrorKindParse,
-						Message: fmt.Sprintf("invalid query parameter '%s'", queryTag),
-						Err: &ParseParameterError{
-							Parameter: queryTag,
-							Source:    ParameterSourceQuery,
-							Value:     queryValue,
-							Err:       err,
-						},
-					})
-					return
+		// Handle headers
+		if headerTag := field.Tag.Get("header"); headerTag != "" {
+			headerValue := withDefault(field, req.Header.Get(headerTag))
+			if err := setFieldValue(fieldValue, headerValue); err != nil {
+				return &Error{
+					Kind:    ErrorKindParse,
+					Message: fmt.Sprintf("invalid header '%s'", headerTag),
+					Err: &ParseParameterError{
+						Parameter: headerTag,
+						Source:    ParameterSourceHeader,
+						Value:     headerValue,
+						Err:       err,
+					},
 				}
 			}
+		}
 
-			// Handle headers
-			if headerTag := field.Tag.Get("header"); headerTag != "" {
-				headerValue := req.Header.Get(headerTag)
-				if err := setFieldValue(fieldValue, headerValue); err != nil {
-					handleError(s, w, req, &Error{
+		// Handle cookies; a missing cookie without a default leaves the zero value
+		if cookieTag := cookieTagName(field); cookieTag != "" {
+			cookieValue, found := "", false
+			if cookie, err := req.Cookie(cookieTag); err == nil {
+				cookieValue, found = cookie.Value, true
+			}
+			if def, ok := field.Tag.Lookup("default"); ok && cookieValue == "" {
+				cookieValue, found = def, true
+			}
+			if found {
+				if err := setFieldValue(fieldValue, cookieValue); err != nil {
+					return &Error{
 						Kind:    ErrorKindParse,
-						Message: fmt.Sprintf("invalid header '%s'", headerTag),
+						Message: fmt.Sprintf("invalid cookie '%s'", cookieTag),
 						Err: &ParseParameterError{
-							Parameter: headerTag,
-							Source:    ParameterSourceHeader,
-							Value:     headerValue,
+							Parameter: cookieTag,
+							Source:    ParameterSourceCookie,
+							Value:     cookieValue,
 							Err:       err,
 						},
-					})
-					return
+					}
 				}
 			}
 		}
+	}
 
-		// Parse JSON body into struct (excluding tagged fields)
-		if !cfg.rawRequestBody && req.Body != nil && req.ContentLength > 0 {
-			body, err := io.ReadAll(req.Body)
-			if err != nil {
-				handleError(s, w, req, &Error{
-					Kind:    ErrorKindParse,
-					Message: "failed to read request body",
-					Err:     err,
-				})
-				return
+	return nil
+}
+
+// withDefault returns the `default:` tag of field when value is empty, and
+// value otherwise.
+func withDefault(field reflect.StructField, value string) string {
+	if value == "" {
+		if def, ok := field.Tag.Lookup("default"); ok {
+			return def
+		}
+	}
+	return value
+}
+
+// bindQueryRest fills `sprout:"queryrest"` fields with every query parameter
+// that no `query:` tag in the request type binds. Fields must have a type with
+// map[string][]string as underlying type, such as url.Values. They stay nil
+// when there are no unbound parameters.
+func bindQueryRest(v reflect.Value, req *http.Request) {
+	if v.Kind() != reflect.Struct || !hasQueryRestField(v.Type()) {
+		return
+	}
+
+	bound := make(map[string]bool)
+	collectQueryTags(v.Type(), bound)
+
+	var rest map[string][]string
+	for key, values := range req.URL.Query() {
+		if bound[key] {
+			continue
+		}
+		if name, _, ok := splitDeepObjectKey(key); ok && bound[name+"[]"] {
+			continue
+		}
+		if rest == nil {
+			rest = make(map[string][]string)
+		}
+		rest[key] = values
+	}
+	if rest == nil {
+		return
+	}
+
+	setQueryRestFields(v, reflect.ValueOf(rest))
+}
+
+func hasQueryRestField(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if isQueryRestField(field) || (isParameterGroupField(field) && hasQueryRestField(field.Type)) {
+			return true
+		}
+	}
+	return false
+}
+
+func collectQueryTags(t reflect.Type, bound map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if queryTag := field.Tag.Get("query"); queryTag != "" && isDeepObjectField(field) {
+			bound[queryTag+"[]"] = true
+		} else if queryTag != "" {
+			bound[queryTag] = true
+		} else if isParameterGroupField(field) {
+			collectQueryTags(field.Type, bound)
+		}
+	}
+}
+
+func setQueryRestFields(v reflect.Value, rest reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldValue := v.Field(i)
+		if !fieldValue.CanSet() {
+			continue
+		}
+		switch {
+		case isQueryRestField(field):
+			if rest.Type().ConvertibleTo(field.Type) && field.Type.Kind() == reflect.Map {
+				fieldValue.Set(rest.Convert(field.Type))
 			}
-			defer req.Body.Close()
+		case isParameterGroupField(field):
+			setQueryRestFields(fieldValue, rest)
+		}
+	}
+}
 
-			if len(body) > 0 {
-				if err := json.Unmarshal(body, &reqDTO); err != nil {
+// bindPathFields fills `sprout:"fullpath"` fields with the request path and
+// `sprout:"pathrest"` fields with the path after basePath, the base path of
+// the router owning the route. Both are decoded paths that start with "/".
+func bindPathFields(v reflect.Value, req *http.Request, basePath string) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldValue := v.Field(i)
+		if !fieldValue.CanSet() {
+			continue
+		}
+		switch {
+		case isFullPathField(field) && fieldValue.Kind() == reflect.String:
+			fieldValue.SetString(req.URL.Path)
+		case isPathRestField(field) && fieldValue.Kind() == reflect.String:
+			rest := strings.TrimPrefix(req.URL.Path, basePath)
+			if !strings.HasPrefix(rest, "/") {
+				rest = "/" + rest
+			}
+			fieldValue.SetString(rest)
+		case isParameterGroupField(field):
+			bindPathFields(fieldValue, req, basePath)
+		}
+	}
+}
+
+// bindClaims fills `claim:` fields from the claims stored by WithClaims.
+// Fields whose claim is missing are reset to their zero value, so a value
+// decoded from the body never survives. String claims are converted like
+// other parameters; other values are decoded as JSON into the field.
+func bindClaims(v reflect.Value, claims map[string]any) *Error {
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldValue := v.Field(i)
+		if !fieldValue.CanSet() {
+			continue
+		}
+		if isParameterGroupField(field) {
+			if err := bindClaims(fieldValue, claims); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := field.Tag.Get("claim")
+		if name == "" {
+			continue
+		}
+		fieldValue.Set(reflect.Zero(field.Type))
+		claim, ok := claims[name]
+		if !ok || claim == nil {
+			continue
+		}
+
+		var err error
+		if str, isString := claim.(string); isString {
+			err = setFieldValue(fieldValue, str)
+		} else {
+			var raw []byte
+			if raw, err = json.Marshal(claim); err == nil {
+				err = json.Unmarshal(raw, fieldValue.Addr().Interface())
+			}
+		}
+		if err != nil {
+			return &Error{
+				Kind:    ErrorKindParse,
+				Message: fmt.Sprintf("invalid claim '%s'", name),
+				Err: &ParseParameterError{
+					Parameter: name,
+					Source:    ParameterSourceClaim,
+					Value:     fmt.Sprint(claim),
+					Err:       err,
+				},
+			}
+		}
+	}
+	return nil
+}
+
+// setSliceValue fills a slice field from repeated query values
+// (?tag=a&tag=b), splitting a single value on commas (?ids=1,2,3) when split
+// is set. Empty elements are dropped, so empty input leaves the slice nil. On
+// failure it returns the position and text of the element that did not parse.
+func setSliceValue(fieldValue reflect.Value, values []string, split bool) (int, string, error) {
+	if split && len(values) == 1 {
+		values = strings.Split(values[0], ",")
+	}
+
+	slice := reflect.Zero(fieldValue.Type())
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+		elem := reflect.New(fieldValue.Type().Elem()).Elem()
+		if err := setFieldValue(elem, value); err != nil {
+			return i, value, err
+		}
+		slice = reflect.Append(slice, elem)
+	}
+	fieldValue.Set(slice)
+	return 0, "", nil
+}
+
+// bindDeepObject collects name[key]=value query entries into a map field. The
+// map is left nil when no such entries are present. Keys are bound in sorted
+// order, so the first failing key, which is returned with its value, does not
+// depend on map iteration.
+func bindDeepObject(fieldValue reflect.Value, query url.Values, name string) (string, string, error) {
+	rawKeys := make([]string, 0, len(query))
+	for rawKey := range query {
+		rawKeys = append(rawKeys, rawKey)
+	}
+	sort.Strings(rawKeys)
+
+	mapType := fieldValue.Type()
+	for _, rawKey := range rawKeys {
+		values := query[rawKey]
+		prefix, key, ok := splitDeepObjectKey(rawKey)
+		if !ok || prefix != name || len(values) == 0 {
+			continue
+		}
+
+		elem := reflect.New(mapType.Elem()).Elem()
+		if err := setFieldValue(elem, values[0]); err != nil {
+			return key, values[0], err
+		}
+		if fieldValue.IsNil() {
+			fieldValue.Set(reflect.MakeMap(mapType))
+		}
+		fieldValue.SetMapIndex(reflect.ValueOf(key).Convert(mapType.Key()), elem)
+	}
+	return "", "", nil
+}
+
+// splitDeepObjectKey splits a query key such as "filter[status]" into its name
+// and property. Keys without a single non-empty bracketed property are rejected.
+func splitDeepObjectKey(rawKey string) (name, key string, ok bool) {
+	open := strings.IndexByte(rawKey, '[')
+	if open <= 0 || !strings.HasSuffix(rawKey, "]") {
+		return "", "", false
+	}
+	key = rawKey[open+1 : len(rawKey)-1]
+	if key == "" || strings.ContainsAny(key, "[]") {
+		return "", "", false
+	}
+	return rawKey[:open], key, true
+}
+
+// hasValuelessQueryKey reports whether key appears in rawQuery without an "="
+// (as in "?active"), as opposed to with an empty value ("?active=").
+func hasValuelessQueryKey(rawQuery, key string) bool {
+	for _, part := range strings.Split(rawQuery, "&") {
+		if part == "" || strings.Contains(part, "=") {
+			continue
+		}
+		if name, err := url.QueryUnescape(part); err == nil && name == key {
+			return true
+		}
+	}
+	return false
+}
+
+// bindRequest populates a request DTO from path, query, header and body sources
+// and validates it. On failure the error is reported through handleError and
+// ok is false; the caller must not write to w.
+func bindRequest[Req any](s *Sprout, w http.ResponseWriter, req *http.Request, cfg *routeConfig) (*Req, bool) {
+	// Parse request into the typed DTO
+	var reqDTO Req
+	if err := bindParameters(s, reflect.ValueOf(&reqDTO).Elem(), req, Params(req)); err != nil {
+		handleError(s, w, req, pathParamFailure(cfg.pathValidationKind, reflect.TypeOf(reqDTO), err))
+		return nil, false
+	}
+	bindQueryRest(reflect.ValueOf(&reqDTO).Elem(), req)
+
+	// Parse JSON body into struct (excluding tagged fields)
+	hasBody := false
+	if !cfg.rawRequestBody && req.Body != nil && req.ContentLength > 0 {
+		body, err := readRequestBody(req, cfg.maxBodyBytes)
+		if err != nil {
+			handleError(s, w, req, err)
+			return nil, false
+		}
+
+		// text/plain bodies fill a textbody field and urlencoded forms the
+		// form fields; everything else is JSON
+		formBody, formErr := setFormBody(reflect.ValueOf(&reqDTO).Elem(), req, body)
+		if formErr != nil {
+			handleError(s, w, req, formErr)
+			return nil, false
+		}
+		if len(body) > 0 && !formBody && !setTextBody(reflect.ValueOf(&reqDTO).Elem(), req, body) {
+			if cfg.requestEnvelopeKey != "" {
+				inner, err := unwrapRequestEnvelope(body, cfg.requestEnvelopeKey)
+				if err != nil {
 					handleError(s, w, req, &Error{
 						Kind:    ErrorKindParse,
-						Message: "invalid JSON",
+						Message: fmt.Sprintf("request body must be wrapped in %q", cfg.requestEnvelopeKey),
 						Err:     err,
 					})
-					return
+					return nil, false
 				}
+				body = inner
+			}
+			if err := decodeRequestJSON(body, &reqDTO, s.config.DisallowUnknownFields); err != nil {
+				handleError(s, w, req, &Error{
+					Kind:    ErrorKindParse,
+					Message: "invalid JSON",
+					Err:     err,
+				})
+				return nil, false
+			}
+			clearTextBody(reflect.ValueOf(&reqDTO).Elem())
+			clearReadOnlyFields(reflect.ValueOf(&reqDTO).Elem())
+
+			// A custom UnmarshalJSON may reset the whole DTO, so bind the
+			// parameters again on top of whatever it decoded
+			if _, custom := any(&reqDTO).(json.Unmarshaler); custom {
+				_ = bindParameters(s, reflect.ValueOf(&reqDTO).Elem(), req, Params(req))
+				bindQueryRest(reflect.ValueOf(&reqDTO).Elem(), req)
 			}
 		}
+		hasBody = len(body) > 0
+	}
 
-		// Validate request DTO
-		if err := s.validate.Struct(reqDTO); err != nil {
-			handleError(s, w, req, &Error{
-				Kind:    ErrorKindValidation,
-				Message: "request validation failed",
-				Err:     err,
-			})
-			return
+	// Bound after the body so untagged keys cannot override the URL
+	bindPathFields(reflect.ValueOf(&reqDTO).Elem(), req, s.config.BasePath)
+	claims, _ := Claims(req.Context())
+	if err := bindClaims(reflect.ValueOf(&reqDTO).Elem(), claims); err != nil {
+		handleError(s, w, req, err)
+		return nil, false
+	}
+
+	// Validate request DTO
+	var filters []validator.FilterFunc
+	if cfg.optionalBody && !hasBody {
+		filters = append(filters, skipBodyFields(reflect.TypeOf(reqDTO)))
+	}
+	if skip := skipReadOnlyFields(reflect.TypeOf(reqDTO)); skip != nil {
+		filters = append(filters, skip)
+	}
+	var emptyParams []emptyParam
+	if s.config.TreatEmptyAsPresent {
+		emptyParams = presentEmptyParams(s, reflect.ValueOf(&reqDTO).Elem(), req)
+		if len(emptyParams) > 0 {
+			filters = append(filters, skipEmptyParams(emptyParams))
+		}
+	}
+	var err error
+	if len(filters) > 0 {
+		err = s.validate.StructFiltered(reqDTO, anyFilter(filters))
+	} else {
+		err = s.validate.Struct(reqDTO)
+	}
+	err = s.validateEmptyParams(err, emptyParams)
+	if err != nil {
+		err = s.limitValidationErrors(err)
+		validationErr := &Error{
+			Kind:    ErrorKindValidation,
+			Message: "request validation failed",
+			Err:     err,
+		}
+		if pathErr := pathParamFailure(cfg.pathValidationKind, reflect.TypeOf(reqDTO), validationErr); pathErr != validationErr {
+			handleError(s, w, req, pathErr)
+			return nil, false
 		}
+		if s.writeRequestValidationError(w, req, err) {
+			return nil, false
+		}
+		handleError(s, w, req, validationErr)
+		return nil, false
+	}
 
-		// Call the handler
-		respDTO, err := handle(ctx, &reqDTO)
+	return &reqDTO, true
+}
+
+// setFormBody fills the `form:` fields of v from a body sent as
+// application/x-www-form-urlencoded. It reports whether the body was consumed;
+// requests without that content type, or DTOs without form fields, are left
+// to the other decoders.
+func setFormBody(v reflect.Value, req *http.Request, body []byte) (bool, *Error) {
+	if v.Kind() != reflect.Struct || !hasFormField(v.Type()) {
+		return false, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/x-www-form-urlencoded" {
+		return false, nil
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return true, &Error{
+			Kind:    ErrorKindParse,
+			Message: "invalid form body",
+			Err:     err,
+		}
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldValue := v.Field(i)
+		if !isFormField(field) || !fieldValue.CanSet() {
+			continue
+		}
+
+		name := field.Tag.Get("form")
+		value := values.Get(name)
+		if fieldValue.Kind() == reflect.Slice {
+			_, value, err = setSliceValue(fieldValue, values[name], !hasSproutOption(field, "nosplit"))
+		} else {
+			err = setFieldValue(fieldValue, value)
+		}
 		if err != nil {
-			if errors.Is(err, ErrNext) {
-				next(nil)
-				return
+			return true, &Error{
+				Kind:    ErrorKindParse,
+				Message: fmt.Sprintf("invalid form field '%s'", name),
+				Err: &ParseParameterError{
+					Parameter: name,
+					Source:    ParameterSourceForm,
+					Value:     value,
+					Err:       err,
+				},
 			}
+		}
+	}
+	return true, nil
+}
 
-			errType := reflect.TypeOf(err)
-			if errType.Kind() == reflect.Ptr {
-				errType = errType.Elem()
+func hasFormField(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if isFormField(t.Field(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// setTextBody stores body in the request's `sprout:"textbody"` field when the
+// request is sent as text/plain. It reports whether the body was consumed.
+func setTextBody(v reflect.Value, req *http.Request, body []byte) bool {
+	field, ok := textBodyField(v.Type())
+	if !ok {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "text/plain" {
+		return false
+	}
+	fieldValue := v.FieldByIndex(field.Index)
+	fieldValue.SetString(string(body))
+	return true
+}
+
+// clearTextBody resets the textbody field after a JSON decode, which would
+// otherwise fill it from a key matching the Go field name.
+func clearTextBody(v reflect.Value) {
+	if field, ok := textBodyField(v.Type()); ok {
+		v.FieldByIndex(field.Index).SetString("")
+	}
+}
+
+// clearReadOnlyFields resets the top-level `sprout:"readonly"` body fields
+// after a JSON decode, so a value sent by the client never reaches the
+// handler. Their validation is skipped for the same reason.
+func clearReadOnlyFields(v reflect.Value) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.IsExported() && isReadOnlyField(field) && !shouldExcludeFromRequestJSON(field) {
+			v.Field(i).SetZero()
+		}
+	}
+}
+
+// emptyParam is a query, header or cookie field sent with an empty value.
+type emptyParam struct {
+	namespace string
+	field     reflect.StructField
+	value     reflect.Value
+}
+
+// presentEmptyParams returns the query, header and cookie fields of v sent
+// with an empty value, in field order. Nil pointer fields among them are set
+// to the zero value, so a handler can tell them from absent ones.
+func presentEmptyParams(s *Sprout, v reflect.Value, req *http.Request) []emptyParam {
+	// The validator namespaces top-level fields as "TypeName.Field".
+	prefix := ""
+	if name := v.Type().Name(); name != "" {
+		prefix = name + "."
+	}
+	return collectEmptyParams(s, v, req, prefix, nil)
+}
+
+func collectEmptyParams(s *Sprout, v reflect.Value, req *http.Request, prefix string, empty []emptyParam) []emptyParam {
+	t := v.Type()
+	query := req.URL.Query()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		present := false
+		switch {
+		case isParameterGroupField(field):
+			empty = collectEmptyParams(s, v.Field(i), req, prefix+field.Name+".", empty)
+			continue
+		case field.Tag.Get("query") != "" && !isDeepObjectField(field):
+			name := field.Tag.Get("query")
+			values, ok := query[name]
+			present = ok && len(values) > 0 && values[0] == ""
+			if present && s.config.ValuelessQueryFlags && derefType(field.Type).Kind() == reflect.Bool &&
+				hasValuelessQueryKey(req.URL.RawQuery, name) {
+				present = false // bound as true instead
 			}
+		case field.Tag.Get("header") != "":
+			values := req.Header.Values(field.Tag.Get("header"))
+			present = len(values) > 0 && values[0] == ""
+		case field.Tag.Get("cookie") != "":
+			cookie, err := req.Cookie(cookieTagName(field))
+			present = err == nil && cookie.Value == ""
+		}
+		if _, ok := field.Tag.Lookup("default"); ok {
+			present = false // bound from the default instead
+		}
+		if !present {
+			continue
+		}
+		fieldValue := v.Field(i)
+		if fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil() && fieldValue.CanSet() {
+			fieldValue.Set(reflect.New(field.Type.Elem()))
+		}
+		empty = append(empty, emptyParam{namespace: prefix + field.Name, field: field, value: fieldValue})
+	}
+	return empty
+}
 
-			declared := false
-			for _, expected := range cfg.expectedErrors {
-				if errType == expected {
-					declared = true
-					break
-				}
+// skipEmptyParams returns a validator filter that skips the empty parameters,
+// which validateEmptyParams checks on their own.
+func skipEmptyParams(params []emptyParam) validator.FilterFunc {
+	skip := make(map[string]struct{}, len(params))
+	for _, param := range params {
+		skip[param.namespace] = struct{}{}
+	}
+	return func(ns []byte) bool {
+		_, ok := skip[string(ns)]
+		return ok
+	}
+}
+
+// validateEmptyParams checks the zero values of empty parameters against their
+// rules, except the presence rules an explicitly empty value satisfies, and
+// adds the failures to err.
+func (s *Sprout) validateEmptyParams(err error, params []emptyParam) error {
+	var errs validator.ValidationErrors
+	if err != nil && !errors.As(err, &errs) {
+		return err
+	}
+	for _, param := range params {
+		tag := withoutPresenceRules(param.field.Tag.Get("validate"))
+		if tag == "" {
+			continue
+		}
+		var fieldErrs validator.ValidationErrors
+		if !errors.As(s.validate.Var(param.value.Interface(), tag), &fieldErrs) {
+			continue
+		}
+		for _, fe := range fieldErrs {
+			errs = append(errs, namedFieldError{FieldError: fe, namespace: param.namespace, name: param.field.Name})
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// withoutPresenceRules removes the required rules from a validate tag.
+func withoutPresenceRules(tag string) string {
+	var kept []string
+	for _, rule := range strings.Split(tag, ",") {
+		name, _, _ := strings.Cut(rule, "=")
+		if name == "" || name == "required" || strings.HasPrefix(name, "required_") {
+			continue
+		}
+		kept = append(kept, rule)
+	}
+	return strings.Join(kept, ",")
+}
+
+// namedFieldError names a field error from validator.Var after the struct
+// field it was checked for.
+type namedFieldError struct {
+	validator.FieldError
+	namespace string
+	name      string
+}
+
+func (e namedFieldError) Namespace() string       { return e.namespace }
+func (e namedFieldError) StructNamespace() string { return e.namespace }
+func (e namedFieldError) Field() string           { return e.name }
+func (e namedFieldError) StructField() string     { return e.name }
+
+func (e namedFieldError) Error() string {
+	return fmt.Sprintf("Key: '%s' Error:Field validation for '%s' failed on the '%s' tag", e.namespace, e.name, e.Tag())
+}
+
+// anyFilter skips a field when any of filters does.
+func anyFilter(filters []validator.FilterFunc) validator.FilterFunc {
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	return func(ns []byte) bool {
+		for _, filter := range filters {
+			if filter(ns) {
+				return true
 			}
+		}
+		return false
+	}
+}
 
-			if declared {
-				enforceValidation := true
-				if s.config.StrictErrorTypes != nil && !*s.config.StrictErrorTypes {
-					enforceValidation = false
-				}
+// skipBodyFields returns a validator filter that skips the top-level JSON body
+// fields of t, leaving path, query, and header fields to be validated.
+func skipBodyFields(t reflect.Type) validator.FilterFunc {
+	// The validator namespaces top-level fields as "TypeName.Field".
+	prefix := ""
+	if t.Name() != "" {
+		prefix = t.Name() + "."
+	}
 
-				if handled, fallbackErr := writeTypedErrorResponse(s, w, req, err, http.StatusInternalServerError, enforceValidation); handled {
-					if fallbackErr != nil {
-						handleError(s, w, req, fallbackErr)
-					}
-					return
-				} else if fallbackErr != nil {
-					handleError(s, w, req, fallbackErr)
-					return
-				}
+	bodyFields := make(map[string]struct{})
+	if t.Kind() == reflect.Struct {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.IsExported() && (!shouldExcludeFromRequestJSON(field) || isTextBodyField(field)) {
+				bodyFields[prefix+field.Name] = struct{}{}
 			}
+		}
+	}
+	return func(ns []byte) bool {
+		_, ok := bodyFields[string(ns)]
+		return ok
+	}
+}
 
-			if *s.config.StrictErrorTypes {
-				handleError(s, w, req, &Error{
-					Kind:    ErrorKindUndeclaredError,
-					Message: fmt.Sprintf("handler returned undeclared error type: %T", err),
-					Err:     err,
-				})
-				return
+// skipReadOnlyFields returns a validator filter that skips the top-level
+// `sprout:"readonly"` fields of t, which clients do not send, or nil when t
+// has none.
+func skipReadOnlyFields(t reflect.Type) validator.FilterFunc {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	prefix := ""
+	if t.Name() != "" {
+		prefix = t.Name() + "."
+	}
+
+	readOnly := make(map[string]struct{})
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.IsExported() && isReadOnlyField(field) {
+			readOnly[prefix+field.Name] = struct{}{}
+		}
+	}
+	if len(readOnly) == 0 {
+		return nil
+	}
+	return func(ns []byte) bool {
+		_, ok := readOnly[string(ns)]
+		return ok
+	}
+}
+
+// readRequestBody reads the request body, transparently decompressing
+// gzip-encoded bodies. When limit is positive, bodies larger than limit bytes
+// (after decompression) fail with ErrorKindRequestTooLarge.
+func readRequestBody(req *http.Request, limit int64) ([]byte, *Error) {
+	defer req.Body.Close()
+
+	var reader io.Reader = req.Body
+	compressed := strings.EqualFold(strings.TrimSpace(req.Header.Get("Content-Encoding")), "gzip")
+	if compressed {
+		gz, err := gzip.NewReader(req.Body)
+		if err != nil {
+			return nil, &Error{
+				Kind:    ErrorKindParse,
+				Message: "invalid gzip request body",
+				Err:     err,
 			}
-			handleError(s, w, req, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	if limit > 0 {
+		reader = io.LimitReader(reader, limit+1)
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		message := "failed to read request body"
+		if compressed {
+			message = "invalid gzip request body"
+		}
+		return nil, &Error{
+			Kind:    ErrorKindParse,
+			Message: message,
+			Err:     err,
+		}
+	}
+
+	if limit > 0 && int64(len(body)) > limit {
+		return nil, &Error{
+			Kind:    ErrorKindRequestTooLarge,
+			Message: fmt.Sprintf("request body exceeds %d bytes", limit),
+		}
+	}
+
+	return body, nil
+}
+
+// limitValidationErrors trims validator.ValidationErrors to the first entry
+// when Config.StopOnFirstValidationError is set.
+func (s *Sprout) limitValidationErrors(err error) error {
+	var validationErrs validator.ValidationErrors
+	if !s.config.StopOnFirstValidationError || !errors.As(err, &validationErrs) || len(validationErrs) <= 1 {
+		return err
+	}
+	return validationErrs[:1]
+}
+
+// writeRequestValidationError renders validation failures through
+// Config.RequestValidationError. It reports whether the error was handled.
+func (s *Sprout) writeRequestValidationError(w http.ResponseWriter, req *http.Request, err error) bool {
+	if s.config.RequestValidationError == nil {
+		return false
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return false
+	}
+
+	typedErr := s.config.RequestValidationError(validationErrs)
+	if typedErr == nil {
+		return false
+	}
+
+	enforceValidation := s.config.StrictErrorTypes == nil || *s.config.StrictErrorTypes
+	handled, fallbackErr := writeTypedErrorResponse(s, w, req, typedErr, http.StatusBadRequest, enforceValidation)
+	switch {
+	case fallbackErr != nil:
+		handleError(s, w, req, fallbackErr)
+	case !handled:
+		handleError(s, w, req, typedErr)
+	}
+	return true
+}
+
+// handleHandlerError reports an error returned by a handler, writing declared
+// error types as typed responses and enforcing StrictErrorTypes for the rest.
+func handleHandlerError(s *Sprout, w http.ResponseWriter, req *http.Request, next Next, cfg *routeConfig, err error) {
+	if errors.Is(err, ErrNext) {
+		next(nil)
+		return
+	}
+	if errors.Is(err, ErrResponseWritten) {
+		return
+	}
+
+	errType := reflect.TypeOf(err)
+	if errType.Kind() == reflect.Ptr {
+		errType = errType.Elem()
+	}
+
+	declared := false
+	for _, expected := range cfg.expectedErrors {
+		if errType == expected {
+			declared = true
+			break
+		}
+	}
+
+	if declared {
+		enforceValidation := true
+		if s.config.StrictErrorTypes != nil && !*s.config.StrictErrorTypes {
+			enforceValidation = false
+		}
+
+		if handled, fallbackErr := writeTypedErrorResponse(s, w, req, err, http.StatusInternalServerError, enforceValidation); handled {
+			if fallbackErr != nil {
+				handleError(s, w, req, fallbackErr)
+			}
+			return
+		} else if fallbackErr != nil {
+			handleError(s, w, req, fallbackErr)
+			return
+		}
+	}
+
+	if *s.config.StrictErrorTypes {
+		handleError(s, w, req, &Error{
+			Kind:    ErrorKindUndeclaredError,
+			Message: fmt.Sprintf("handler returned undeclared error type: %T", err),
+			Err:     err,
+		})
+		return
+	}
+	handleError(s, w, req, err)
+}
+
+func wrap[Req, Resp any](entry *routeEntry, handle Handle[Req, Resp], cfg *routeConfig) Middleware {
+	if decorate := entry.owner.config.HandlerDecorator; decorate != nil {
+		handle = decorateHandler(decorate, entry.path, handle)
+	}
+	return func(w http.ResponseWriter, req *http.Request, next Next) {
+		s := entry.owner
+		ctx, chosenStatus := withResponseStatus(withHTTPRequest(req.Context(), req))
+
+		reqDTO, ok := bindRequest[Req](s, w, req, cfg)
+		if !ok {
+			return
+		}
+
+		// A handler that may be abandoned at its hard timeout writes through
+		// a guard that drops its writes after the deadline.
+		handlerWriter := w
+		var guard *timeoutWriter
+		if cfg.hardTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cfg.hardTimeout)
+			defer cancel()
+			guard = &timeoutWriter{ctx: ctx, w: w, header: w.Header().Clone()}
+			handlerWriter = guard
+		}
+		ctx = withResponseWriter(ctx, handlerWriter)
+
+		// Call the handler
+		respDTO, err := callHandler(ctx, handle, reqDTO, guard != nil)
+		if guard != nil && err != errHandlerTimeout && guard.missedDeadline() {
+			err = errHandlerTimeout
+		}
+		if err == errHandlerTimeout {
+			errWriter := w
+			if guard.timeout() {
+				// The handler started the response; report without writing.
+				errWriter = committedWriter{w}
+			}
+			handleError(s, errWriter, req, &Error{
+				Kind:    ErrorKindTimeout,
+				Message: "handler timed out",
+				Err:     err,
+			})
+			return
+		}
+		if guard != nil {
+			guard.finish()
+		}
+		if err != nil {
+			handleHandlerError(s, w, req, next, cfg, err)
+			return
+		}
+
+		if respDTO == nil && s.config.NilIsNotFound && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+			notFound := s.config.NilNotFoundError
+			if notFound == nil {
+				notFound = &Error{Kind: ErrorKindNotFound, Message: "resource not found"}
+			}
+			handleError(s, w, req, notFound)
 			return
 		}
 
@@ -513,50 +2371,151 @@ func wrap[Req, Resp any](entry *routeEntry, handle Handle[Req, Resp], cfg *route
 		}
 
 		// Validate response DTO
-		if err := s.validate.Struct(respDTO); err != nil {
-			handleError(s, w, req, &Error{
-				Kind:    ErrorKindResponseValidation,
-				Message: "response validation failed",
-				Err:     err,
-			})
+		if err := s.checkResponse(req, entry, respDTO); err != nil {
+			handleError(s, w, req, err)
 			return
 		}
 
 		// Extract status code and headers from response struct tags
-		statusCode := http.StatusOK
+		statusCode := cfg.successStatus()
 		var customHeaders map[string]string
 		if respDTO != nil {
 			respType := reflect.TypeOf(respDTO)
-			statusCode = extractStatusCode(respType, http.StatusOK)
+			statusCode = extractStatusCode(respType, cfg.successStatus())
 			customHeaders = extractHeaders(reflect.ValueOf(respDTO))
 		}
+		if chosenStatus.code != 0 {
+			statusCode = chosenStatus.code
+		}
 
-		// Set custom headers from struct tags
+		// Prepare the body, decorate object bodies and apply the router's envelopes
+		payload := prepareResponseBody(respDTO)
+		if fields, ok := payload.(map[string]interface{}); ok && s.config.ResponseDecorator != nil {
+			if decorated := s.config.ResponseDecorator(ctx, entry.path, fields); decorated != nil {
+				payload = decorated
+			}
+		}
+		contentType := cfg.responseContentType()
+		if s.config.EnableXML && cfg.successContentType == "" && cfg.responseEnvelopeKey == "" &&
+			cfg.responseEnvelope == nil && hasXMLTags(reflect.TypeOf(respDTO)) {
+			addVary(w.Header(), "Accept")
+			if prefersXML(req.Header.Get("Accept")) {
+				payload = toXMLPayload(respDTO)
+				contentType = xmlContentType
+			}
+		}
+		if cfg.responseEnvelopeKey != "" {
+			payload = map[string]any{cfg.responseEnvelopeKey: payload}
+		}
+		var envelopeHeaders map[string]string
+		if cfg.responseEnvelope != nil {
+			if envelope := cfg.responseEnvelope(payload, statusCode); envelope != nil {
+				envelopeHeaders = extractHeaders(reflect.ValueOf(envelope))
+				payload = prepareResponseBody(envelope)
+			}
+		}
+
+		// Set the route's cache policy, default headers, then custom headers from struct tags
+		if cfg.cacheControl != "" && isCacheableMethod(req.Method) && w.Header().Get("Cache-Control") == "" {
+			w.Header().Set("Cache-Control", cfg.cacheControl)
+		}
+		s.applyDefaultHeaders(w)
+		for name, value := range envelopeHeaders {
+			setResponseHeader(w.Header(), name, value)
+		}
 		for name, value := range customHeaders {
-			w.Header().Set(name, value)
+			setResponseHeader(w.Header(), name, value)
+		}
+		for _, cookie := range extractCookies(reflect.ValueOf(respDTO)) {
+			http.SetCookie(w, cookie)
 		}
 
-		// Set Content-Type to application/json if not already set
-		if w.Header().Get("Content-Type") == "" {
-			w.Header().Set("Content-Type", "application/json")
+		// Send a pre-encoded body only to clients accepting its encoding
+		if encoded, ok := payload.(encodedPayload); ok {
+			decoded, err := negotiateEncodedBody(w.Header(), req, encoded)
+			if err != nil {
+				handleError(s, w, req, &Error{
+					Kind:    ErrorKindSerialization,
+					Message: "failed to decode pre-encoded response",
+					Err:     err,
+				})
+				return
+			}
+			payload = decoded
 		}
 
-		// Serialize response
-		w.WriteHeader(statusCode)
-		if !shouldWriteBody(req.Method, statusCode) {
-			return
+		// Set the route's Content-Type (application/json unless overridden or
+		// negotiated to XML) if not already set
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", contentType)
 		}
-		payload := prepareResponseBody(respDTO)
-		if encodeErr := json.NewEncoder(w).Encode(payload); encodeErr != nil {
-			// Note: headers already written, so handleError can't change the status code
+
+		// Serialize response before committing the status so encoding failures
+		// can still be reported as a 500
+		body, err := encodeResponseBody(s, w, req, statusCode, payload, cfg.prettyJSON)
+		if err != nil {
 			handleError(s, w, req, &Error{
 				Kind:    ErrorKindSerialization,
 				Message: "failed to encode response",
-				Err:     encodeErr,
+				Err:     err,
 			})
 			return
 		}
+
+		w.WriteHeader(statusCode)
+		if !shouldWriteBody(req.Method, statusCode) {
+			return
+		}
+		_, _ = w.Write(body)
+	}
+}
+
+// errHandlerTimeout is reported by callHandler when a handler outlives its
+// route's hard timeout.
+var errHandlerTimeout = errors.New("sprout: handler exceeded its hard timeout")
+
+// callHandler runs handle. When abandon is set, ctx carries the route's hard
+// timeout and callHandler gives up once its deadline passes. The handler then
+// keeps running in the background, but only ever hands its result to a
+// buffered channel nobody reads, so the response is written once.
+func callHandler[Req, Resp any](ctx context.Context, handle Handle[Req, Resp], req *Req, abandon bool) (*Resp, error) {
+	if !abandon {
+		return handle(ctx, req)
+	}
+
+	type result struct {
+		resp      *Resp
+		err       error
+		panicked  bool
+		recovered any
+	}
+	done := make(chan result, 1)
+	go func() {
+		res := result{panicked: true}
+		defer func() {
+			if res.panicked {
+				res.recovered = recover()
+			}
+			done <- res
+		}()
+		res.resp, res.err = handle(ctx, req)
+		res.panicked = false
+	}()
+
+	var res result
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, errHandlerTimeout
+		}
+		// The client went away; let the handler wind down as usual.
+		res = <-done
 	}
+	if res.panicked {
+		panic(res.recovered)
+	}
+	return res.resp, res.err
 }
 
 // GET is a shortcut for handle(s, http.MethodGet, path, h, opts...)
@@ -614,27 +2573,56 @@ func writeTypedErrorResponse(s *Sprout, w http.ResponseWriter, req *http.Request
 			return false, &Error{
 				Kind:    ErrorKindErrorValidation,
 				Message: "error response validation failed",
-				Err:     validationErr,
+				Err:     s.limitValidationErrors(validationErr),
 			}
 		}
 	}
 
-	statusCode := extractStatusCode(reflect.TypeOf(err), defaultStatus)
+	statusCode := typedErrorStatus(err, defaultStatus)
 	customHeaders := extractHeaders(reflect.ValueOf(err))
-	for name, value := range customHeaders {
-		w.Header().Set(name, value)
+	s.applyDefaultHeaders(w)
+
+	// Redirects are not failures, so they bypass the error envelope.
+	redirect := isRedirectStatus(statusCode)
+
+	fields := toJSONMap(err)
+	var payload any = fields
+	contentType := "application/json"
+	if envelope := s.errorEnvelope(statusCode, err); envelope != nil && !redirect {
+		for name, value := range extractHeaders(reflect.ValueOf(envelope)) {
+			setResponseHeader(w.Header(), name, value)
+		}
+		payload = prepareResponseBody(envelope)
+	} else if s.config.EnableXML && hasXMLTags(errValue.Type()) {
+		// Errors follow the same negotiation as success responses.
+		addVary(w.Header(), "Accept")
+		if prefersXML(req.Header.Get("Accept")) {
+			payload = toXMLPayload(err)
+			contentType = xmlContentType
+		}
 	}
 
-	if w.Header().Get("Content-Type") == "" {
-		w.Header().Set("Content-Type", "application/json")
+	for name, value := range customHeaders {
+		setResponseHeader(w.Header(), name, value)
 	}
+	for _, cookie := range extractCookies(reflect.ValueOf(err)) {
+		http.SetCookie(w, cookie)
+	}
+	setRetryAfter(w, err)
 
-	w.WriteHeader(statusCode)
-	if !shouldWriteBody(req.Method, statusCode) {
+	// A redirect without body fields (typically just a Location header) is
+	// sent without a body rather than as an empty JSON object.
+	if redirect && len(fields) == 0 {
+		w.WriteHeader(statusCode)
 		return true, nil
 	}
 
-	if encodeErr := json.NewEncoder(w).Encode(toJSONMap(err)); encodeErr != nil {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	body, encodeErr := encodeResponseBody(s, w, req, statusCode, payload, false)
+	if encodeErr != nil {
 		return false, &Error{
 			Kind:    ErrorKindSerialization,
 			Message: "failed to encode error response",
@@ -642,6 +2630,11 @@ func writeTypedErrorResponse(s *Sprout, w http.ResponseWriter, req *http.Request
 		}
 	}
 
+	w.WriteHeader(statusCode)
+	if shouldWriteBody(req.Method, statusCode) {
+		_, _ = w.Write(body)
+	}
+
 	return true, nil
 }
 
@@ -680,6 +2673,65 @@ func isStructLike(v reflect.Value) bool {
 	return false
 }
 
+// checkResponse validates resp for the route. It returns the error to report,
+// or nil when the response may be sent; in ResponseValidationWarn mode a
+// failure is reported as a warning and nil is returned.
+func (s *Sprout) checkResponse(req *http.Request, entry *routeEntry, resp any) *Error {
+	err := validateResponse(s.validate, resp)
+	if err == nil {
+		return nil
+	}
+	err = s.limitValidationErrors(err)
+
+	if entry.config.responseValidation == ResponseValidationWarn {
+		if s.config.ResponseValidationWarning != nil {
+			s.config.ResponseValidationWarning(req, entry.path, err)
+		} else {
+			slog.WarnContext(req.Context(), "sprout: response validation failed",
+				"method", req.Method, "route", entry.path, "error", err)
+		}
+		return nil
+	}
+
+	return &Error{
+		Kind:    ErrorKindResponseValidation,
+		Message: "response validation failed",
+		Err:     err,
+	}
+}
+
+// validateResponse validates a response DTO. Maps, slices and arrays returned
+// directly have their struct elements validated; other non-struct values have
+// no tags to check.
+func validateResponse(validate *validator.Validate, resp any) error {
+	v := reflect.ValueOf(resp)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return validate.Struct(resp)
+	case reflect.Map, reflect.Slice, reflect.Array:
+		if derefType(v.Type().Elem()).Kind() != reflect.Struct {
+			return nil
+		}
+		return validate.Var(v.Interface(), "dive")
+	default:
+		return nil
+	}
+}
+
+// isCacheableMethod reports whether responses to method may carry WithCache headers.
+func isCacheableMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// isRedirectStatus reports whether status is a 3xx redirection.
+func isRedirectStatus(status int) bool {
+	return status >= 300 && status < 400
+}
+
 // shouldWriteBody determines whether a response body is allowed for the given method/status combination.
 func shouldWriteBody(method string, status int) bool {
 	if method == http.MethodHead {
@@ -690,6 +2742,8 @@ func shouldWriteBody(method string, status int) bool {
 		return false
 	}
 
+	// Other 3xx responses may carry a body (e.g. a link for old clients);
+	// 304 never does.
 	switch status {
 	case http.StatusNoContent, http.StatusResetContent, http.StatusNotModified:
 		return false
@@ -698,15 +2752,70 @@ func shouldWriteBody(method string, status int) bool {
 	return true
 }
 
+// encodeResponseBody serializes payload ahead of WriteHeader. It returns nil
+// when no body will be sent. For HEAD requests with Config.HeadContentLength
+// enabled, the body a GET would have returned is encoded to set Content-Length.
+func encodeResponseBody(s *Sprout, w http.ResponseWriter, req *http.Request, status int, payload any, pretty bool) ([]byte, error) {
+	headLength := req.Method == http.MethodHead && s.config.HeadContentLength &&
+		shouldWriteBody(http.MethodGet, status) && w.Header().Get("Content-Length") == ""
+	if !headLength && !shouldWriteBody(req.Method, status) {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	if text, ok := payload.(textPayload); ok {
+		buf.WriteString(string(text))
+	} else if encoded, ok := payload.(encodedPayload); ok {
+		buf.Write(encoded)
+	} else if doc, ok := payload.(xmlPayload); ok {
+		if err := encodeXML(&buf, doc, pretty); err != nil {
+			return nil, err
+		}
+	} else {
+		enc := json.NewEncoder(&buf)
+		if pretty {
+			enc.SetIndent("", "  ")
+		}
+		if err := enc.Encode(payload); err != nil {
+			return nil, err
+		}
+	}
+
+	if headLength {
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	}
+	return buf.Bytes(), nil
+}
+
 func prepareResponseBody(resp any) any {
 	if resp == nil {
 		return nil
 	}
+	if field, ok := textBodyField(reflect.TypeOf(resp)); ok {
+		v := reflect.Indirect(reflect.ValueOf(resp))
+		if v.IsValid() {
+			return textPayload(v.FieldByIndex(field.Index).String())
+		}
+	}
+	if field, ok := encodedBodyField(reflect.TypeOf(resp)); ok {
+		v := reflect.Indirect(reflect.ValueOf(resp))
+		if v.IsValid() {
+			return encodedPayload(v.FieldByIndex(field.Index).Bytes())
+		}
+	}
 	if unwrapped, ok := unwrapJSONFieldValue(reflect.ValueOf(resp)); ok {
 		return unwrapped
 	}
+	// Types with their own MarshalJSON control their encoding entirely.
+	if implementsJSONMarshaler(reflect.TypeOf(resp)) {
+		return resp
+	}
 	if isStructLike(reflect.ValueOf(resp)) {
 		return toJSONMap(resp)
 	}
+	// A nil slice returned as the whole response is an empty list, not null.
+	if v := reflect.Indirect(reflect.ValueOf(resp)); v.Kind() == reflect.Slice && v.IsNil() {
+		return reflect.MakeSlice(v.Type(), 0, 0).Interface()
+	}
 	return resp
 }
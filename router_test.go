@@ -2,16 +2,22 @@ package sprout
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -180,6 +186,124 @@ func TestSproutSliceResponseValidationFailure(t *testing.T) {
 	}
 }
 
+type businessesController struct {
+	businesses []*ListUsersResponse
+}
+
+func (c *businessesController) GetBusinesses(ctx context.Context, req *EmptyRequest) ([]*ListUsersResponse, error) {
+	return c.businesses, nil
+}
+
+func TestListHandlers(t *testing.T) {
+	serve := func(businesses []*ListUsersResponse) *httptest.ResponseRecorder {
+		router := New()
+		GET(router, "/businesses", List((&businessesController{businesses: businesses}).GetBusinesses))
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/businesses", nil))
+		return recorder
+	}
+
+	t.Run("serializes a JSON array", func(t *testing.T) {
+		recorder := serve([]*ListUsersResponse{
+			{ID: 1, Email: "alice@example.com"},
+			{ID: 2, Email: "bob@example.com"},
+		})
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected status OK, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		expected := `[{"id":1,"email":"alice@example.com"},{"id":2,"email":"bob@example.com"}]`
+		if body := strings.TrimSpace(recorder.Body.String()); body != expected {
+			t.Fatalf("expected %s, got %s", expected, body)
+		}
+	})
+
+	t.Run("nil slice is an empty array", func(t *testing.T) {
+		recorder := serve(nil)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected status OK, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		if body := strings.TrimSpace(recorder.Body.String()); body != "[]" {
+			t.Fatalf("expected empty array, got %s", body)
+		}
+	})
+
+	t.Run("validates each element", func(t *testing.T) {
+		recorder := serve([]*ListUsersResponse{
+			{ID: 1, Email: "alice@example.com"},
+			{ID: 2, Email: "invalid-email"},
+		})
+
+		if recorder.Code != http.StatusInternalServerError {
+			t.Fatalf("expected status InternalServerError, got %d", recorder.Code)
+		}
+		if !strings.Contains(recorder.Body.String(), "response validation failed") {
+			t.Fatalf("expected response validation error message, got %q", recorder.Body.String())
+		}
+	})
+}
+
+type UsersByEmailEnvelope struct {
+	Users map[string]ListUsersResponse `json:"users" sprout:"unwrap" validate:"dive"`
+}
+
+func TestSproutMapResponse(t *testing.T) {
+	users := map[string]ListUsersResponse{
+		"alice": {ID: 1, Email: "alice@example.com"},
+		"bob":   {ID: 2, Email: "bob@example.com"},
+	}
+
+	router := New()
+	GET(router, "/direct", func(ctx context.Context, req *EmptyRequest) (*map[string]ListUsersResponse, error) {
+		return &users, nil
+	})
+	GET(router, "/unwrapped", func(ctx context.Context, req *EmptyRequest) (*UsersByEmailEnvelope, error) {
+		return &UsersByEmailEnvelope{Users: users}, nil
+	})
+
+	for _, path := range []string{"/direct", "/unwrapped"} {
+		t.Run(path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("expected status OK, got %d: %s", recorder.Code, recorder.Body.String())
+			}
+
+			var resp map[string]ListUsersResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if !reflect.DeepEqual(resp, users) {
+				t.Fatalf("expected %+v, got %+v", users, resp)
+			}
+		})
+	}
+}
+
+func TestSproutMapResponseValidationFailure(t *testing.T) {
+	router := New()
+	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*map[string]ListUsersResponse, error) {
+		return &map[string]ListUsersResponse{
+			"alice": {ID: 1, Email: "invalid-email"},
+		}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/users", nil))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status InternalServerError, got %d", recorder.Code)
+	}
+
+	if !strings.Contains(recorder.Body.String(), "response validation failed") {
+		t.Fatalf("expected response validation error message, got %q", recorder.Body.String())
+	}
+}
+
 func TestSproutValidationFailure(t *testing.T) {
 	router := New()
 	POST(router, "/users", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
@@ -270,6 +394,114 @@ func TestValidationErrorUsesJSONTagNames(t *testing.T) {
 	}
 }
 
+type FieldValidationError struct {
+	_      struct{} `http:"status=422"`
+	Code   string   `json:"code" validate:"required"`
+	Fields []string `json:"fields" validate:"required,min=1"`
+}
+
+func (e *FieldValidationError) Error() string {
+	return fmt.Sprintf("invalid fields: %v", e.Fields)
+}
+
+func TestRequestValidationErrorConstructor(t *testing.T) {
+	newRouter := func(handlerHit *bool) *Sprout {
+		router := NewWithConfig(&Config{
+			RequestValidationError: func(errs validator.ValidationErrors) error {
+				fields := make([]string, 0, len(errs))
+				for _, fe := range errs {
+					fields = append(fields, fe.Field())
+				}
+				return &FieldValidationError{Code: "INVALID_REQUEST", Fields: fields}
+			},
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				*handlerHit = true
+				w.WriteHeader(http.StatusTeapot)
+			},
+		})
+		POST(router, "/users", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+			return &CreateUserResponse{ID: 1, Name: req.Name, Email: req.Email}, nil
+		})
+		return router
+	}
+
+	t.Run("validation failures use the typed error", func(t *testing.T) {
+		handlerHit := false
+		router := newRouter(&handlerHit)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/users", strings.NewReader(`{"name":"Jo","email":"nope"}`)))
+
+		if recorder.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("expected status 422, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		if handlerHit {
+			t.Fatalf("typed validation errors should bypass the ErrorHandler like declared errors")
+		}
+
+		var body struct {
+			Code   string   `json:"code"`
+			Fields []string `json:"fields"`
+		}
+		if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if body.Code != "INVALID_REQUEST" || !reflect.DeepEqual(body.Fields, []string{"name", "email"}) {
+			t.Fatalf("unexpected error body: %+v", body)
+		}
+	})
+
+	t.Run("parse errors keep default handling", func(t *testing.T) {
+		handlerHit := false
+		router := newRouter(&handlerHit)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/users", strings.NewReader(`{"name":`)))
+
+		if !handlerHit {
+			t.Fatalf("expected parse error to reach the ErrorHandler")
+		}
+	})
+
+	t.Run("nil falls back to default handling", func(t *testing.T) {
+		var captured error
+		router := NewWithConfig(&Config{
+			RequestValidationError: func(validator.ValidationErrors) error { return nil },
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				captured = err
+				w.WriteHeader(http.StatusBadRequest)
+			},
+		})
+		POST(router, "/users", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+			return &CreateUserResponse{ID: 1}, nil
+		})
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/users", strings.NewReader(`{"name":"Jo","email":"john@example.com"}`)))
+
+		var sproutErr *Error
+		if !errors.As(captured, &sproutErr) || sproutErr.Kind != ErrorKindValidation {
+			t.Fatalf("expected default validation error, got %v", captured)
+		}
+	})
+
+	t.Run("inherited by mounts", func(t *testing.T) {
+		handlerHit := false
+		router := newRouter(&handlerHit)
+		api := router.Mount("/api", nil)
+		POST(api, "/users", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+			return &CreateUserResponse{ID: 1}, nil
+		})
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/api/users", strings.NewReader(`{}`)))
+
+		if recorder.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("expected status 422, got %d", recorder.Code)
+		}
+	})
+}
+
 // Test with path, query, and header parameters
 type GetUserRequest struct {
 	UserID    string `path:"id" validate:"required"`
@@ -348,6 +580,344 @@ func TestSproutMissingRequiredHeader(t *testing.T) {
 	}
 }
 
+type cookieRequest struct {
+	Session string `cookie:"session_id" validate:"required"`
+	Theme   string `cookie:"theme"`
+	Visits  int    `cookie:"visits"`
+}
+
+func TestCookieParameters(t *testing.T) {
+	router := New()
+	GET(router, "/prefs", func(ctx context.Context, req *cookieRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: fmt.Sprintf("%s/%s/%d", req.Session, req.Theme, req.Visits)}, nil
+	})
+
+	serve := func(cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/prefs", nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("binds cookies", func(t *testing.T) {
+		rec := serve(&http.Cookie{Name: "session_id", Value: "abc"}, &http.Cookie{Name: "visits", Value: "3"})
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if body := strings.TrimSpace(rec.Body.String()); body != `{"message":"abc//3"}` {
+			t.Fatalf("expected cookies bound with missing ones left empty, got %s", body)
+		}
+	})
+
+	t.Run("missing required cookie", func(t *testing.T) {
+		rec := serve(&http.Cookie{Name: "theme", Value: "dark"})
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("invalid cookie value", func(t *testing.T) {
+		var captured error
+		router := NewWithConfig(&Config{
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				captured = err
+				w.WriteHeader(http.StatusBadRequest)
+			},
+		})
+		GET(router, "/prefs", func(ctx context.Context, req *cookieRequest) (*HelloResponse, error) {
+			return &HelloResponse{Message: "ok"}, nil
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/prefs", nil)
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: "abc"})
+		req.AddCookie(&http.Cookie{Name: "visits", Value: "many"})
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		var paramErr *ParseParameterError
+		if !errors.As(captured, &paramErr) {
+			t.Fatalf("expected ParseParameterError, got %v", captured)
+		}
+		if paramErr.Source != ParameterSourceCookie || paramErr.Parameter != "visits" || paramErr.Value != "many" {
+			t.Fatalf("unexpected parse error: %+v", paramErr)
+		}
+	})
+}
+
+type loginResponse struct {
+	Session string       `cookie:"session_id,httponly,secure,path=/,maxage=3600,samesite=lax"`
+	Theme   string       `cookie:"theme"`
+	Tracker *http.Cookie `cookie:"tracker"`
+	Message string       `json:"message"`
+}
+
+type expiredSessionError struct {
+	Session string `cookie:"session_id,path=/,maxage=-1"`
+	Message string `json:"message"`
+}
+
+func (e expiredSessionError) Error() string { return e.Message }
+
+func TestResponseCookies(t *testing.T) {
+	router := New()
+	POST(router, "/login", func(ctx context.Context, req *EmptyRequest) (*loginResponse, error) {
+		return &loginResponse{
+			Session: "abc",
+			Tracker: &http.Cookie{Value: "t1", Domain: "example.com"},
+			Message: "welcome",
+		}, nil
+	})
+	POST(router, "/refresh", func(ctx context.Context, req *EmptyRequest) (*loginResponse, error) {
+		return nil, expiredSessionError{Session: "gone", Message: "session expired"}
+	}, WithErrors(expiredSessionError{}))
+
+	t.Run("success response", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/login", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if body := strings.TrimSpace(rec.Body.String()); body != `{"message":"welcome"}` {
+			t.Fatalf("expected cookie fields excluded from the body, got %s", body)
+		}
+
+		cookies := rec.Header().Values("Set-Cookie")
+		expected := []string{
+			"session_id=abc; Path=/; Max-Age=3600; HttpOnly; Secure; SameSite=Lax",
+			"tracker=t1; Domain=example.com",
+		}
+		if !reflect.DeepEqual(cookies, expected) {
+			t.Fatalf("expected cookies %q, got %q", expected, cookies)
+		}
+	})
+
+	t.Run("typed error", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/refresh", nil))
+
+		if got := rec.Header().Get("Set-Cookie"); got != "session_id=gone; Path=/; Max-Age=0" {
+			t.Fatalf("expected expiring cookie on error, got %q", got)
+		}
+		if body := strings.TrimSpace(rec.Body.String()); body != `{"message":"session expired"}` {
+			t.Fatalf("expected cookie field excluded from the error body, got %s", body)
+		}
+	})
+}
+
+type optionalParamsRequest struct {
+	Page   *int       `query:"page" validate:"omitempty,gte=1"`
+	Active *bool      `query:"active"`
+	Name   *string    `query:"name"`
+	Since  *time.Time `query:"since"`
+	Trace  *string    `header:"X-Trace"`
+}
+
+func TestPointerParameters(t *testing.T) {
+	var got optionalParamsRequest
+	router := NewWithConfig(&Config{ValuelessQueryFlags: true})
+	GET(router, "/items", func(ctx context.Context, req *optionalParamsRequest) (*HelloResponse, error) {
+		got = *req
+		return &HelloResponse{Message: "ok"}, nil
+	})
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if strings.Contains(target, "trace") {
+			req.Header.Set("X-Trace", "t-1")
+		}
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("absent parameters stay nil", func(t *testing.T) {
+		got = optionalParamsRequest{}
+		if rec := serve("/items"); rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if got.Page != nil || got.Active != nil || got.Name != nil || got.Since != nil || got.Trace != nil {
+			t.Fatalf("expected nil pointers, got %+v", got)
+		}
+	})
+
+	t.Run("present parameters are allocated", func(t *testing.T) {
+		got = optionalParamsRequest{}
+		if rec := serve("/items?page=2&active=false&name=x&since=2024-01-02T03:04:05Z&trace"); rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if got.Page == nil || *got.Page != 2 {
+			t.Fatalf("expected page 2, got %v", got.Page)
+		}
+		if got.Active == nil || *got.Active {
+			t.Fatalf("expected explicit false to be distinguishable from absent, got %v", got.Active)
+		}
+		if got.Name == nil || *got.Name != "x" || got.Trace == nil || *got.Trace != "t-1" {
+			t.Fatalf("expected name and header bound, got %+v", got)
+		}
+		if got.Since == nil || got.Since.Year() != 2024 {
+			t.Fatalf("expected since parsed, got %v", got.Since)
+		}
+	})
+
+	t.Run("valueless flag", func(t *testing.T) {
+		got = optionalParamsRequest{}
+		serve("/items?active")
+		if got.Active == nil || !*got.Active {
+			t.Fatalf("expected valueless flag to set *bool true, got %v", got.Active)
+		}
+	})
+
+	t.Run("pointer targets are validated", func(t *testing.T) {
+		if rec := serve("/items?page=0"); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if rec := serve("/items?page=abc"); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400 for unparsable value, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+type readOnlyUser struct {
+	ID   string `json:"id" validate:"required" sprout:"readonly"`
+	Name string `json:"name" validate:"required"`
+}
+
+func TestReadOnlyFieldsSkipRequestValidation(t *testing.T) {
+	router := New()
+	POST(router, "/users", func(ctx context.Context, req *readOnlyUser) (*readOnlyUser, error) {
+		req.ID = "u1"
+		return req, nil
+	})
+	PUT(router, "/users", func(ctx context.Context, req *readOnlyUser) (*readOnlyUser, error) {
+		return req, nil
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"Ada"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected readonly id not required in the request, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"id":"u1","name":"Ada"}` {
+		t.Fatalf("unexpected response: %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/users", strings.NewReader(`{"name":"Ada"}`)))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected readonly id still required in the response, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+type readOnlyAccount struct {
+	ID   string `json:"id" validate:"required,uuid4" sprout:"readonly"`
+	Name string `json:"name" validate:"required"`
+}
+
+func TestReadOnlyFieldsAreNotBound(t *testing.T) {
+	router := New()
+	var seen string
+	POST(router, "/accounts", func(ctx context.Context, req *readOnlyAccount) (*HelloResponse, error) {
+		seen = req.ID
+		return &HelloResponse{Message: req.Name}, nil
+	})
+
+	seen = "unset"
+	rec := httptest.NewRecorder()
+	body := `{"id":"not-a-uuid; DROP","name":"Ada"}`
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/accounts", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if seen != "" {
+		t.Fatalf("expected the handler to see a zero readonly id, got %q", seen)
+	}
+}
+
+type signupFormRequest struct {
+	Team   string   `path:"team"`
+	Email  string   `form:"email" validate:"required,email"`
+	Age    int      `form:"age" validate:"omitempty,gte=18"`
+	Tags   []string `form:"tags"`
+	Notes  []string `form:"notes" sprout:"nosplit"`
+	Method string   `json:"method"`
+}
+
+type signupFormResponse struct {
+	Team  string   `json:"team"`
+	Email string   `json:"email"`
+	Age   int      `json:"age"`
+	Tags  []string `json:"tags"`
+	Notes []string `json:"notes,omitempty"`
+}
+
+func TestFormBodies(t *testing.T) {
+	router := New()
+	POST(router, "/teams/:team/signup", func(ctx context.Context, req *signupFormRequest) (*signupFormResponse, error) {
+		return &signupFormResponse{Team: req.Team, Email: req.Email, Age: req.Age, Tags: req.Tags, Notes: req.Notes}, nil
+	})
+
+	post := func(contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/teams/core/signup", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("binds form fields", func(t *testing.T) {
+		rec := post("application/x-www-form-urlencoded; charset=utf-8", "email=ada%40example.com&age=36&tags=a&tags=b")
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		expected := `{"age":36,"email":"ada@example.com","tags":["a","b"],"team":"core"}`
+		if body := strings.TrimSpace(rec.Body.String()); body != expected {
+			t.Fatalf("expected %s, got %s", expected, body)
+		}
+	})
+
+	t.Run("nosplit keeps commas", func(t *testing.T) {
+		rec := post("application/x-www-form-urlencoded", "email=ada%40example.com&tags=a,b&notes=a,b")
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		expected := `{"age":0,"email":"ada@example.com","notes":["a,b"],"tags":["a","b"],"team":"core"}`
+		if body := strings.TrimSpace(rec.Body.String()); body != expected {
+			t.Fatalf("expected %s, got %s", expected, body)
+		}
+	})
+
+	t.Run("validates after binding", func(t *testing.T) {
+		rec := post("application/x-www-form-urlencoded", "email=not-an-email")
+
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "request validation failed") {
+			t.Fatalf("expected validation failure, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		rec := post("application/x-www-form-urlencoded", "email=ada%40example.com&age=old")
+
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid form field 'age'") {
+			t.Fatalf("expected parse failure naming the field, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("JSON bodies unchanged", func(t *testing.T) {
+		rec := post("application/json", `{"method":"json"}`)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected form fields not bound from JSON, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
 // Test combining body with path/query/headers
 type UpdateUserRequest struct {
 	UserID    string `path:"id" validate:"required"`
@@ -407,25 +977,196 @@ func TestSproutWithBodyAndParams(t *testing.T) {
 	}
 }
 
-type RawUploadRequest struct {
-	AccountID string `path:"account_id" validate:"required"`
-	AuthToken string `header:"Authorization" validate:"required"`
+type searchFilter struct {
+	Status string `query:"status" validate:"omitempty,oneof=open closed"`
+	Owner  string `query:"owner"`
 }
 
-type RawUploadResponse struct {
-	AccountID string `json:"account_id"`
-	Mapping   string `json:"mapping"`
-	File      string `json:"file"`
+type searchPaging struct {
+	Limit  int `query:"limit" validate:"required,min=1"`
+	Offset int `query:"offset"`
+	Cursor struct {
+		Token string `header:"X-Cursor"`
+	}
 }
 
-func newMultipartUploadRequest(t *testing.T, path string) *http.Request {
-	t.Helper()
+type searchRequest struct {
+	TenantID string `path:"tenant"`
+	Filter   searchFilter
+	Paging   searchPaging
+	Query    string `json:"query"`
+}
 
-	var body bytes.Buffer
-	writer := multipart.NewWriter(&body)
+type searchResponse struct {
+	TenantID string `json:"tenant"`
+	Status   string `json:"status"`
+	Owner    string `json:"owner"`
+	Offset   int    `json:"offset"`
+	Cursor   string `json:"cursor"`
+	Query    string `json:"query"`
+}
 
-	if err := writer.WriteField("mapping", `{"amount":2}`); err != nil {
-		t.Fatalf("failed to write mapping field: %v", err)
+func TestNestedParameterGroups(t *testing.T) {
+	router := New()
+	POST(router, "/tenants/:tenant/search", func(ctx context.Context, req *searchRequest) (*searchResponse, error) {
+		return &searchResponse{
+			TenantID: req.TenantID,
+			Status:   req.Filter.Status,
+			Owner:    req.Filter.Owner,
+			Offset:   req.Paging.Offset,
+			Cursor:   req.Paging.Cursor.Token,
+			Query:    req.Query,
+		}, nil
+	})
+
+	t.Run("binds nested groups", func(t *testing.T) {
+		httpReq := httptest.NewRequest("POST", "/tenants/acme/search?status=open&owner=bob&limit=10&offset=20", strings.NewReader(`{"query":"printer"}`))
+		httpReq.Header.Set("X-Cursor", "abc")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httpReq)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		var resp searchResponse
+		if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		expected := searchResponse{TenantID: "acme", Status: "open", Owner: "bob", Offset: 20, Cursor: "abc", Query: "printer"}
+		if resp != expected {
+			t.Fatalf("expected %+v, got %+v", expected, resp)
+		}
+	})
+
+	t.Run("validates nested groups", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/tenants/acme/search?status=pending&limit=10", nil))
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", recorder.Code)
+		}
+
+		recorder = httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/tenants/acme/search", nil))
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400 for missing nested required query, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("reports nested parse errors", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/tenants/acme/search?limit=ten", nil))
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("serializes groups in responses", func(t *testing.T) {
+		type savedSearch struct {
+			Filter searchFilter
+			Name   string `json:"name"`
+		}
+		GET(router, "/saved", func(ctx context.Context, req *EmptyRequest) (*savedSearch, error) {
+			return &savedSearch{Filter: searchFilter{Status: "open", Owner: "bob"}, Name: "mine"}, nil
+		})
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/saved", nil))
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		var resp savedSearch
+		if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Filter.Status != "open" || resp.Filter.Owner != "bob" || resp.Name != "mine" {
+			t.Fatalf("expected the group to be serialized, got %+v", resp)
+		}
+	})
+}
+
+type FlagRequest struct {
+	Active  bool   `query:"active"`
+	Verbose bool   `query:"verbose"`
+	Name    string `query:"name"`
+}
+
+type FlagResponse struct {
+	Active  bool   `json:"active"`
+	Verbose bool   `json:"verbose"`
+	Name    string `json:"name"`
+}
+
+func TestValuelessQueryFlags(t *testing.T) {
+	handler := func(ctx context.Context, req *FlagRequest) (*FlagResponse, error) {
+		return &FlagResponse{Active: req.Active, Verbose: req.Verbose, Name: req.Name}, nil
+	}
+
+	enabled := NewWithConfig(&Config{ValuelessQueryFlags: true})
+	GET(enabled, "/flags", handler)
+
+	disabled := New()
+	GET(disabled, "/flags", handler)
+
+	tests := []struct {
+		name     string
+		router   *Sprout
+		query    string
+		expected FlagResponse
+	}{
+		{"valueless flag", enabled, "active", FlagResponse{Active: true}},
+		{"several flags", enabled, "active&verbose&name=x", FlagResponse{Active: true, Verbose: true, Name: "x"}},
+		{"explicit false", enabled, "active=false", FlagResponse{}},
+		{"empty value", enabled, "active=", FlagResponse{}},
+		{"string fields ignore flags", enabled, "name", FlagResponse{}},
+		{"disabled by default", disabled, "active", FlagResponse{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			tt.router.ServeHTTP(recorder, httptest.NewRequest("GET", "/flags?"+tt.query, nil))
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+			}
+
+			var resp FlagResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp != tt.expected {
+				t.Fatalf("expected %+v, got %+v", tt.expected, resp)
+			}
+		})
+	}
+}
+
+type RawUploadRequest struct {
+	AccountID string `path:"account_id" validate:"required"`
+	AuthToken string `header:"Authorization" validate:"required"`
+}
+
+type RawUploadResponse struct {
+	AccountID string `json:"account_id"`
+	Mapping   string `json:"mapping"`
+	File      string `json:"file"`
+}
+
+func newMultipartUploadRequest(t *testing.T, path string) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	if err := writer.WriteField("mapping", `{"amount":2}`); err != nil {
+		t.Fatalf("failed to write mapping field: %v", err)
 	}
 
 	filePart, err := writer.CreateFormFile("file", "payments.csv")
@@ -1269,6 +2010,50 @@ func TestCustomContentType(t *testing.T) {
 	})
 }
 
+func TestWithProduces(t *testing.T) {
+	router := New()
+
+	GET(router, "/json", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "plain"}, nil
+	})
+	GET(router, "/hal", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "hal"}, nil
+	}, WithProduces("application/hal+json"))
+	GET(router, "/field-wins", func(ctx context.Context, req *EmptyRequest) (*CustomContentTypeResponse, error) {
+		return &CustomContentTypeResponse{ContentType: "application/vnd.api+json", Message: "field"}, nil
+	}, WithProduces("application/hal+json"))
+	GET(router, "/error", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, &TeapotError{Msg: "short and stout"}
+	}, WithProduces("application/hal+json"), WithErrors(&TeapotError{}))
+
+	tests := []struct {
+		path        string
+		contentType string
+	}{
+		{"/json", "application/json"},
+		{"/hal", "application/hal+json"},
+		{"/field-wins", "application/vnd.api+json"},
+		{"/error", "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest("GET", tt.path, nil))
+
+			if ct := recorder.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Fatalf("expected Content-Type %q, got %q", tt.contentType, ct)
+			}
+		})
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/hal", nil))
+	if got := recorder.Body.String(); got != "{\"message\":\"hal\"}\n" {
+		t.Fatalf("expected JSON body, got %q", got)
+	}
+}
+
 type NoBodyError struct {
 	_ struct{} `http:"status=204"`
 }
@@ -1323,453 +2108,665 @@ func TestHeadResponseSkipsBody(t *testing.T) {
 	}
 }
 
-// Test automatic exclusion of routing/metadata fields from JSON
-func TestJSONAutoExclusion(t *testing.T) {
-	router := New()
+func TestHeadContentLength(t *testing.T) {
+	t.Run("enabled", func(t *testing.T) {
+		router := NewWithConfig(&Config{HeadContentLength: true})
 
-	type ResponseWithAllTags struct {
-		_           struct{} `http:"status=200"`
-		PathField   string   `path:"id"`
-		QueryField  string   `query:"page"`
-		HeaderField string   `header:"X-Custom"`
-		HTTPField   struct{} `http:"status=200"`
-		JSONField   string   `json:"data"`
-		NormalField string   // No tags
-	}
+		handler := func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+			return &HelloResponse{Message: "hello"}, nil
+		}
+		HEAD(router, "/head", handler)
+		GET(router, "/head", handler)
 
-	GET(router, "/test/:id", func(ctx context.Context, req *EmptyRequest) (*ResponseWithAllTags, error) {
-		return &ResponseWithAllTags{
-			PathField:   "should-not-appear",
-			QueryField:  "should-not-appear",
-			HeaderField: "header-value",
-			JSONField:   "should-appear",
-			NormalField: "should-appear-as-NormalField",
-		}, nil
+		getRecorder := httptest.NewRecorder()
+		router.ServeHTTP(getRecorder, httptest.NewRequest("GET", "/head", nil))
+
+		recorder := newBodyTrackingRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("HEAD", "/head", nil))
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", recorder.Code)
+		}
+		if recorder.wroteBody {
+			t.Fatalf("expected no body to be written for HEAD responses")
+		}
+		if got, want := recorder.Header().Get("Content-Length"), strconv.Itoa(getRecorder.Body.Len()); got != want {
+			t.Fatalf("expected Content-Length %s, got %q", want, got)
+		}
 	})
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/test/123", nil))
+	t.Run("typed error", func(t *testing.T) {
+		router := NewWithConfig(&Config{HeadContentLength: true})
 
-	if recorder.Code != http.StatusOK {
-		t.Fatalf("expected status 200, got %d", recorder.Code)
-	}
+		HEAD(router, "/head", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+			return nil, &TeapotError{Msg: "short and stout"}
+		}, WithErrors(&TeapotError{}))
 
-	// Verify header was set
-	if header := recorder.Header().Get("X-Custom"); header != "header-value" {
-		t.Errorf("expected X-Custom header 'header-value', got '%s'", header)
-	}
+		recorder := newBodyTrackingRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("HEAD", "/head", nil))
 
-	// Parse JSON response
-	var result map[string]interface{}
-	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
-		t.Fatalf("failed to unmarshal JSON: %v", err)
-	}
+		if recorder.Code != http.StatusTeapot {
+			t.Fatalf("expected status 418, got %d", recorder.Code)
+		}
+		if recorder.wroteBody {
+			t.Fatalf("expected no body to be written for HEAD responses")
+		}
+		if got := recorder.Header().Get("Content-Length"); got != strconv.Itoa(len(`{"message":"short and stout"}`+"\n")) {
+			t.Fatalf("unexpected Content-Length %q", got)
+		}
+	})
 
-	// Verify excluded fields are NOT in JSON
-	if _, exists := result["PathField"]; exists {
-		t.Errorf("PathField should be excluded from JSON, but was present")
-	}
-	if _, exists := result["QueryField"]; exists {
-		t.Errorf("QueryField should be excluded from JSON, but was present")
-	}
-	if _, exists := result["HeaderField"]; exists {
-		t.Errorf("HeaderField should be excluded from JSON, but was present")
-	}
-	if _, exists := result["HTTPField"]; exists {
-		t.Errorf("HTTPField should be excluded from JSON, but was present")
-	}
+	t.Run("disabled by default", func(t *testing.T) {
+		router := New()
 
-	// Verify included fields ARE in JSON
-	if data, exists := result["data"]; !exists {
-		t.Errorf("'data' should be in JSON")
-	} else if data != "should-appear" {
-		t.Errorf("expected 'data' to be 'should-appear', got '%v'", data)
-	}
+		HEAD(router, "/head", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+			return &HelloResponse{Message: "hello"}, nil
+		})
 
-	if normalField, exists := result["NormalField"]; !exists {
-		t.Errorf("'NormalField' should be in JSON")
-	} else if normalField != "should-appear-as-NormalField" {
-		t.Errorf("expected 'NormalField' to be 'should-appear-as-NormalField', got '%v'", normalField)
-	}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("HEAD", "/head", nil))
+
+		if got := recorder.Header().Get("Content-Length"); got != "" {
+			t.Fatalf("expected no Content-Length by default, got %q", got)
+		}
+	})
+
+	t.Run("inherited by mounts", func(t *testing.T) {
+		router := NewWithConfig(&Config{HeadContentLength: true})
+		api := router.Mount("/api", nil)
+
+		HEAD(api, "/head", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+			return &HelloResponse{Message: "hello"}, nil
+		})
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("HEAD", "/api/head", nil))
+
+		if got := recorder.Header().Get("Content-Length"); got == "" {
+			t.Fatalf("expected mounted router to inherit HeadContentLength")
+		}
+	})
 }
 
-// Test JSON exclusion with omitempty
-func TestJSONAutoExclusionWithOmitempty(t *testing.T) {
+func TestWithPrettyJSON(t *testing.T) {
 	router := New()
 
-	type ResponseWithOmitempty struct {
-		Required    string `json:"required"`
-		Optional    string `json:"optional,omitempty"`
-		EmptyString string `json:"empty_string,omitempty"`
-		HeaderField string `header:"X-Test"`
-	}
-
-	GET(router, "/omitempty-test", func(ctx context.Context, req *EmptyRequest) (*ResponseWithOmitempty, error) {
-		return &ResponseWithOmitempty{
-			Required:    "present",
-			Optional:    "also-present",
-			EmptyString: "", // Should be omitted
-			HeaderField: "test-header",
-		}, nil
+	GET(router, "/pretty", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "hello"}, nil
+	}, WithPrettyJSON())
+	GET(router, "/compact", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "hello"}, nil
 	})
 
 	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/omitempty-test", nil))
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/pretty", nil))
 
-	if recorder.Code != http.StatusOK {
-		t.Fatalf("expected status 200, got %d", recorder.Code)
+	if got, want := recorder.Body.String(), "{\n  \"message\": \"hello\"\n}\n"; got != want {
+		t.Fatalf("expected indented body %q, got %q", want, got)
 	}
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
-		t.Fatalf("failed to unmarshal JSON: %v", err)
+	if ct := recorder.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected application/json content type, got %q", ct)
 	}
 
-	// Verify required field is present
-	if _, exists := result["required"]; !exists {
-		t.Errorf("'required' should be in JSON")
-	}
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/compact", nil))
 
-	// Verify optional non-empty field is present
-	if _, exists := result["optional"]; !exists {
-		t.Errorf("'optional' should be in JSON")
+	if got, want := recorder.Body.String(), "{\"message\":\"hello\"}\n"; got != want {
+		t.Fatalf("expected compact body %q, got %q", want, got)
 	}
+}
 
-	// Verify empty field with omitempty is NOT present
-	if _, exists := result["empty_string"]; exists {
-		t.Errorf("'empty_string' should be omitted from JSON due to omitempty")
-	}
+type unencodableResponse struct {
+	Updates chan int `json:"updates"`
+}
 
-	// Verify header field is NOT in JSON
-	if _, exists := result["HeaderField"]; exists {
-		t.Errorf("'HeaderField' should be excluded from JSON")
+func TestResponseSerializationFailureReturns500(t *testing.T) {
+	var captured error
+	router := NewWithConfig(&Config{
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			captured = err
+			w.WriteHeader(http.StatusInternalServerError)
+		},
+	})
+
+	GET(router, "/broken", func(ctx context.Context, req *EmptyRequest) (*unencodableResponse, error) {
+		return &unencodableResponse{Updates: make(chan int)}, nil
+	}, WithPrettyJSON())
+
+	recorder := newBodyTrackingRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/broken", nil))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", recorder.Code)
+	}
+	if recorder.wroteBody {
+		t.Fatalf("expected no partial body, got %q", recorder.Body.String())
 	}
 
-	// Verify header was set
-	if header := recorder.Header().Get("X-Test"); header != "test-header" {
-		t.Errorf("expected X-Test header 'test-header', got '%s'", header)
+	var sproutErr *Error
+	if !errors.As(captured, &sproutErr) || sproutErr.Kind != ErrorKindSerialization {
+		t.Fatalf("expected serialization error, got %v", captured)
 	}
 }
 
-// Test JSON exclusion with explicit json:"-" tag
-func TestJSONAutoExclusionWithExplicitJsonDash(t *testing.T) {
-	router := New()
+type FramedResponse struct {
+	FrameOptions string `header:"X-Frame-Options"`
+	Message      string `json:"message"`
+}
 
-	type ResponseWithExplicitExclusion struct {
-		PublicField  string `json:"public"`
-		PrivateField string `json:"-"`        // Explicitly excluded
-		HeaderField  string `header:"X-Test"` // Auto-excluded
-	}
-
-	GET(router, "/explicit-test", func(ctx context.Context, req *EmptyRequest) (*ResponseWithExplicitExclusion, error) {
-		return &ResponseWithExplicitExclusion{
-			PublicField:  "visible",
-			PrivateField: "invisible",
-			HeaderField:  "header-value",
-		}, nil
+func TestDefaultResponseHeaders(t *testing.T) {
+	router := NewWithConfig(&Config{
+		DefaultResponseHeaders: map[string]string{
+			"X-Content-Type-Options": "nosniff",
+			"X-Frame-Options":        "DENY",
+		},
 	})
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/explicit-test", nil))
+	GET(router, "/ok", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "hi"}, nil
+	})
+	GET(router, "/framed", func(ctx context.Context, req *EmptyRequest) (*FramedResponse, error) {
+		return &FramedResponse{FrameOptions: "SAMEORIGIN", Message: "hi"}, nil
+	})
+	GET(router, "/teapot", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, &TeapotError{Msg: "short and stout"}
+	}, WithErrors(&TeapotError{}))
+	GET(router, "/middleware", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "hi"}, nil
+	}, WithMiddleware(func(w http.ResponseWriter, r *http.Request, next Next) {
+		w.Header().Set("X-Frame-Options", "ALLOW-FROM https://example.com")
+		next(nil)
+	}))
+
+	api := router.Mount("/api", &Config{
+		DefaultResponseHeaders: map[string]string{"X-Frame-Options": "SAMEORIGIN", "X-API": "1"},
+	})
+	GET(api, "/ok", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "hi"}, nil
+	})
 
-	if recorder.Code != http.StatusOK {
-		t.Fatalf("expected status 200, got %d", recorder.Code)
+	tests := []struct {
+		name     string
+		path     string
+		status   int
+		expected map[string]string
+	}{
+		{"success", "/ok", http.StatusOK, map[string]string{"X-Content-Type-Options": "nosniff", "X-Frame-Options": "DENY"}},
+		{"field header overrides", "/framed", http.StatusOK, map[string]string{"X-Frame-Options": "SAMEORIGIN"}},
+		{"typed error", "/teapot", http.StatusTeapot, map[string]string{"X-Content-Type-Options": "nosniff", "X-Frame-Options": "DENY"}},
+		{"not found", "/missing", http.StatusNotFound, map[string]string{"X-Frame-Options": "DENY"}},
+		{"middleware header wins", "/middleware", http.StatusOK, map[string]string{"X-Frame-Options": "ALLOW-FROM https://example.com"}},
+		{"mount merges", "/api/ok", http.StatusOK, map[string]string{"X-Content-Type-Options": "nosniff", "X-Frame-Options": "SAMEORIGIN", "X-API": "1"}},
+		{"swagger", "/swagger", http.StatusOK, map[string]string{"X-Frame-Options": "DENY"}},
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
-		t.Fatalf("failed to unmarshal JSON: %v", err)
-	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest("GET", tt.path, nil))
 
-	// Verify public field is present
-	if val, exists := result["public"]; !exists {
-		t.Errorf("'public' should be in JSON")
-	} else if val != "visible" {
-		t.Errorf("expected 'public' to be 'visible', got '%v'", val)
+			if recorder.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, recorder.Code)
+			}
+			for name, value := range tt.expected {
+				if got := recorder.Header().Get(name); got != value {
+					t.Fatalf("expected %s %q, got %q", name, value, got)
+				}
+			}
+		})
 	}
 
-	// Verify private field with json:"-" is NOT present
-	if _, exists := result["PrivateField"]; exists {
-		t.Errorf("'PrivateField' should be excluded from JSON due to json:\"-\" tag")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/ok", nil))
+	if got := recorder.Header().Get("X-API"); got != "" {
+		t.Fatalf("mount headers must not leak to the parent, got %q", got)
 	}
+}
 
-	// Verify header field is NOT present
-	if _, exists := result["HeaderField"]; exists {
-		t.Errorf("'HeaderField' should be excluded from JSON due to header tag")
+func TestEnablePreflight(t *testing.T) {
+	router := NewWithConfig(&Config{EnablePreflight: true})
+
+	handler := func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "hi"}, nil
 	}
-}
+	GET(router, "/items", handler)
+	POST(router, "/items", handler)
+	GET(router, "/custom", handler)
+	OPTIONS(router, "/custom", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "custom options"}, nil
+	})
 
-// Test JSON exclusion in error responses
-type ErrorWithMetadata struct {
-	_           struct{} `http:"status=400"`
-	HeaderField string   `header:"X-Error-Code"`
-	ErrorCode   string   `json:"error_code"`
-	Message     string   `json:"message"`
-}
+	t.Run("preflight", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/items", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		req.Header.Set("Access-Control-Request-Headers", "Content-Type, Authorization")
 
-func (e ErrorWithMetadata) Error() string { return e.Message }
+		recorder := newBodyTrackingRecorder()
+		router.ServeHTTP(recorder, req)
 
-func TestJSONAutoExclusionInErrors(t *testing.T) {
-	router := New()
+		if recorder.Code != http.StatusNoContent {
+			t.Fatalf("expected status 204, got %d", recorder.Code)
+		}
+		if recorder.wroteBody {
+			t.Fatalf("expected no body for preflight")
+		}
 
-	GET(router, "/error-test", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
-		return nil, ErrorWithMetadata{
-			HeaderField: "BAD_REQUEST",
-			ErrorCode:   "invalid_input",
-			Message:     "Something went wrong",
+		expected := map[string]string{
+			"Access-Control-Allow-Origin":  "*",
+			"Access-Control-Allow-Methods": "GET, OPTIONS, POST",
+			"Access-Control-Allow-Headers": "Content-Type, Authorization",
+			"Allow":                        "GET, OPTIONS, POST",
 		}
-	}, WithErrors(ErrorWithMetadata{}))
+		for name, value := range expected {
+			if got := recorder.Header().Get(name); got != value {
+				t.Fatalf("expected %s %q, got %q", name, value, got)
+			}
+		}
+	})
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/error-test", nil))
+	t.Run("user options handler wins", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodOptions, "/custom", nil))
 
-	if recorder.Code != http.StatusBadRequest {
-		t.Fatalf("expected status 400, got %d", recorder.Code)
-	}
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected status 200 from user handler, got %d", recorder.Code)
+		}
+		if !strings.Contains(recorder.Body.String(), "custom options") {
+			t.Fatalf("expected user OPTIONS response, got %q", recorder.Body.String())
+		}
+	})
 
-	// Verify header was set
-	if header := recorder.Header().Get("X-Error-Code"); header != "BAD_REQUEST" {
-		t.Errorf("expected X-Error-Code header 'BAD_REQUEST', got '%s'", header)
-	}
+	t.Run("unknown path", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodOptions, "/missing", nil))
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
-		t.Fatalf("failed to unmarshal JSON: %v", err)
-	}
+		if recorder.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404, got %d", recorder.Code)
+		}
+	})
 
-	// Verify header field is NOT in JSON
-	if _, exists := result["HeaderField"]; exists {
-		t.Errorf("'HeaderField' should be excluded from JSON")
-	}
+	t.Run("mount default headers", func(t *testing.T) {
+		router := NewWithConfig(&Config{
+			EnablePreflight:        true,
+			DefaultResponseHeaders: map[string]string{"X-Frame-Options": "DENY"},
+		})
+		GET(router, "/items", handler)
+		api := router.Mount("/api", &Config{
+			DefaultResponseHeaders: map[string]string{"X-Frame-Options": "SAMEORIGIN", "X-API": "1"},
+		})
+		GET(api, "/items", handler)
 
-	// Verify error fields are present
-	if _, exists := result["error_code"]; !exists {
-		t.Errorf("'error_code' should be in JSON")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodOptions, "/api/items", nil))
+		if recorder.Code != http.StatusNoContent || recorder.Header().Get("X-API") != "1" || recorder.Header().Get("X-Frame-Options") != "SAMEORIGIN" {
+			t.Fatalf("expected the mount's default headers, got %d %v", recorder.Code, recorder.Header())
+		}
+
+		recorder = httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodOptions, "/items", nil))
+		if recorder.Header().Get("X-API") != "" || recorder.Header().Get("X-Frame-Options") != "DENY" {
+			t.Fatalf("expected only the root's default headers, got %v", recorder.Header())
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		router := New()
+		GET(router, "/items", handler)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodOptions, "/items", nil))
+
+		if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Fatalf("expected no CORS headers by default, got %q", got)
+		}
+	})
+}
+
+func gzipBody(t *testing.T, payload []byte) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(payload); err != nil {
+		t.Fatalf("failed to gzip payload: %v", err)
 	}
-	if _, exists := result["message"]; !exists {
-		t.Errorf("'message' should be in JSON")
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to close gzip writer: %v", err)
 	}
+	return &buf
 }
 
-// Test corner case: struct with only routing tags
-func TestJSONAutoExclusionAllFieldsExcluded(t *testing.T) {
-	router := New()
+func TestGzipRequestBody(t *testing.T) {
+	var captured error
+	router := NewWithConfig(&Config{
+		MaxBodyBytes: 1024,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			captured = err
+			var sproutErr *Error
+			if errors.As(err, &sproutErr) && sproutErr.Kind == ErrorKindRequestTooLarge {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+		},
+	})
+	POST(router, "/users", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+		return &CreateUserResponse{ID: 1, Name: req.Name, Email: req.Email}, nil
+	})
 
-	type ResponseOnlyRoutingFields struct {
-		_           struct{} `http:"status=204"`
-		HeaderField string   `header:"X-Custom"`
-	}
+	t.Run("decompresses body", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/users", gzipBody(t, []byte(`{"name":"Jane","email":"jane@example.com"}`)))
+		req.Header.Set("Content-Encoding", "gzip")
 
-	GET(router, "/only-routing", func(ctx context.Context, req *EmptyRequest) (*ResponseOnlyRoutingFields, error) {
-		return &ResponseOnlyRoutingFields{
-			HeaderField: "test",
-		}, nil
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		var resp CreateUserResponse
+		if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Name != "Jane" {
+			t.Fatalf("expected decompressed name, got %q", resp.Name)
+		}
 	})
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/only-routing", nil))
+	t.Run("invalid gzip", func(t *testing.T) {
+		captured = nil
+		req := httptest.NewRequest("POST", "/users", strings.NewReader(`{"name":"Jane"}`))
+		req.Header.Set("Content-Encoding", "gzip")
 
-	if recorder.Code != http.StatusNoContent {
-		t.Fatalf("expected status 204, got %d", recorder.Code)
-	}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
 
-	// Verify header was set
-	if header := recorder.Header().Get("X-Custom"); header != "test" {
-		t.Errorf("expected X-Custom header 'test', got '%s'", header)
-	}
+		var sproutErr *Error
+		if !errors.As(captured, &sproutErr) || sproutErr.Kind != ErrorKindParse {
+			t.Fatalf("expected parse error, got %v", captured)
+		}
+	})
 
-	// 204 responses must not include a body
-	if recorder.Body.Len() != 0 {
-		t.Fatalf("expected empty body for 204 response, got %q", recorder.Body.String())
-	}
-}
+	t.Run("limit applies to decompressed size", func(t *testing.T) {
+		captured = nil
+		payload := []byte(`{"name":"` + strings.Repeat("a", 4096) + `","email":"jane@example.com"}`)
+		compressed := gzipBody(t, payload)
+		if compressed.Len() >= 1024 {
+			t.Fatalf("test payload should compress below the limit, got %d bytes", compressed.Len())
+		}
 
-// Test nested request objects
-type Address struct {
-	Street  string `json:"street" validate:"required"`
-	City    string `json:"city" validate:"required"`
-	ZipCode string `json:"zip_code" validate:"required,len=5"`
-}
+		req := httptest.NewRequest("POST", "/users", compressed)
+		req.Header.Set("Content-Encoding", "gzip")
 
-type CreateUserWithAddressRequest struct {
-	Name    string  `json:"name" validate:"required,min=3"`
-	Email   string  `json:"email" validate:"required,email"`
-	Address Address `json:"address" validate:"required"`
-}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
 
-type CreateUserWithAddressResponse struct {
-	ID      int     `json:"id" validate:"required,gt=0"`
-	Name    string  `json:"name" validate:"required"`
-	Address Address `json:"address" validate:"required"`
-}
+		if recorder.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("expected status 413, got %d", recorder.Code)
+		}
+	})
 
-func TestNestedRequestObjects(t *testing.T) {
-	router := New()
-	POST(router, "/users", func(ctx context.Context, req *CreateUserWithAddressRequest) (*CreateUserWithAddressResponse, error) {
-		return &CreateUserWithAddressResponse{
-			ID:      1,
-			Name:    req.Name,
-			Address: req.Address,
-		}, nil
+	t.Run("limit applies to plain bodies", func(t *testing.T) {
+		payload := `{"name":"` + strings.Repeat("a", 2048) + `","email":"jane@example.com"}`
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/users", strings.NewReader(payload)))
+
+		if recorder.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("expected status 413, got %d", recorder.Code)
+		}
 	})
+}
 
-	// Valid nested request
-	reqBody := map[string]interface{}{
-		"name":  "John Doe",
-		"email": "john@example.com",
-		"address": map[string]string{
-			"street":   "123 Main St",
-			"city":     "New York",
-			"zip_code": "10001",
-		},
-	}
-	body, _ := json.Marshal(reqBody)
+func TestRequestTooLargeDefaultStatus(t *testing.T) {
+	router := NewWithConfig(&Config{MaxBodyBytes: 8})
+	POST(router, "/users", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+		return &CreateUserResponse{ID: 1}, nil
+	})
 
 	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/users", bytes.NewReader(body)))
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/users", strings.NewReader(`{"name":"Jane Doe"}`)))
 
-	if recorder.Code != http.StatusOK {
-		t.Fatalf("expected status OK, got %d: %s", recorder.Code, recorder.Body.String())
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status 413, got %d", recorder.Code)
 	}
+}
 
-	var resp CreateUserWithAddressResponse
-	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
+func TestRouteMaxBodyBytes(t *testing.T) {
+	router := NewWithConfig(&Config{MaxBodyBytes: 16})
+	handler := func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+		return &CreateUserResponse{ID: 1, Name: req.Name, Email: req.Email}, nil
 	}
+	POST(router, "/users", handler)
+	POST(router, "/imports", handler, WithMaxBodyBytes(1024))
+	POST(router, "/tiny", handler, WithMaxBodyBytes(4))
+	POST(router, "/unlimited", handler, WithMaxBodyBytes(-1))
 
-	if resp.ID != 1 {
-		t.Errorf("expected ID 1, got %d", resp.ID)
+	body := `{"name":"Jane Doe","email":"jane@example.com"}`
+	tests := []struct {
+		path    string
+		status  int
+		message string
+	}{
+		{"/users", http.StatusRequestEntityTooLarge, "request body exceeds 16 bytes"},
+		{"/imports", http.StatusOK, ""},
+		{"/tiny", http.StatusRequestEntityTooLarge, "request body exceeds 4 bytes"},
+		{"/unlimited", http.StatusOK, ""},
 	}
-	if resp.Address.City != "New York" {
-		t.Errorf("expected City 'New York', got '%s'", resp.Address.City)
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(body)))
+
+		if rec.Code != tt.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.path, tt.status, rec.Code, rec.Body.String())
+		}
+		if tt.message != "" && !strings.Contains(rec.Body.String(), tt.message) {
+			t.Fatalf("%s: expected %q in body, got %s", tt.path, tt.message, rec.Body.String())
+		}
 	}
 }
 
-func TestNestedRequestValidationFailure(t *testing.T) {
-	router := New()
-	POST(router, "/users", func(ctx context.Context, req *CreateUserWithAddressRequest) (*CreateUserWithAddressResponse, error) {
-		return &CreateUserWithAddressResponse{
-			ID:      1,
-			Name:    req.Name,
-			Address: req.Address,
-		}, nil
-	})
+type JSONAPIError struct {
+	_           struct{} `http:"status=409"`
+	ContentType string   `header:"Content-Type"`
+	Msg         string   `json:"message" validate:"required"`
+}
 
-	// Invalid nested request (invalid zip code)
-	reqBody := map[string]interface{}{
-		"name":  "John Doe",
-		"email": "john@example.com",
-		"address": map[string]string{
-			"street":   "123 Main St",
-			"city":     "New York",
-			"zip_code": "123", // Invalid: must be 5 digits
-		},
+func (e *JSONAPIError) Error() string {
+	return e.Msg
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	type envelope struct {
+		Status int    `json:"status"`
+		Error  string `json:"error"`
 	}
-	body, _ := json.Marshal(reqBody)
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/users", bytes.NewReader(body)))
+	router := NewWithConfig(&Config{
+		ErrorEnvelope: func(status int, err error) any {
+			return &envelope{Status: status, Error: err.Error()}
+		},
+	})
+	GET(router, "/teapot", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, &TeapotError{Msg: "short and stout"}
+	}, WithErrors(&TeapotError{}))
+	POST(router, "/users", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+		return &CreateUserResponse{ID: 1}, nil
+	})
 
-	if recorder.Code != http.StatusBadRequest {
-		t.Errorf("expected status BadRequest, got %d", recorder.Code)
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		status  int
+		message string
+	}{
+		{"typed error", "GET", "/teapot", "", http.StatusTeapot, "short and stout"},
+		{"validation error", "POST", "/users", `{"name":"Jo"}`, http.StatusBadRequest, "request validation failed"},
+		{"not found", "GET", "/missing", "", http.StatusNotFound, "route not found"},
 	}
-}
 
-// Test nested response objects
-type ContactInfo struct {
-	Email string `json:"email" validate:"required,email"`
-	Phone string `json:"phone" validate:"required"`
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+
+			if recorder.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, recorder.Code)
+			}
+			if ct := recorder.Header().Get("Content-Type"); ct != "application/json" {
+				t.Fatalf("expected application/json, got %q", ct)
+			}
+
+			var body envelope
+			if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode envelope: %v", err)
+			}
+			if body.Status != tt.status || !strings.Contains(body.Error, tt.message) {
+				t.Fatalf("unexpected envelope: %+v", body)
+			}
+		})
+	}
 }
 
-type UserDetailResponse struct {
-	_       struct{}    `http:"status=200"`
-	UserID  string      `json:"user_id" validate:"required"`
-	Name    string      `json:"name" validate:"required"`
-	Contact ContactInfo `json:"contact" validate:"required"`
+func TestErrorEnvelopeProblemDetails(t *testing.T) {
+	router := NewWithConfig(&Config{ErrorEnvelope: NewProblemDetails})
+	GET(router, "/teapot", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, &TeapotError{Msg: "short and stout"}
+	}, WithErrors(&TeapotError{}))
+	GET(router, "/conflict", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, &JSONAPIError{ContentType: "application/vnd.api+json", Msg: "conflict"}
+	}, WithErrors(&JSONAPIError{}))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/teapot", nil))
+
+	if recorder.Code != http.StatusTeapot {
+		t.Fatalf("expected status 418, got %d", recorder.Code)
+	}
+	if ct := recorder.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("expected application/problem+json, got %q", ct)
+	}
+
+	var problem map[string]any
+	if err := json.NewDecoder(recorder.Body).Decode(&problem); err != nil {
+		t.Fatalf("failed to decode problem: %v", err)
+	}
+	expected := map[string]any{
+		"type":   "about:blank",
+		"title":  "I'm a teapot",
+		"status": float64(http.StatusTeapot),
+		"detail": "short and stout",
+	}
+	if !reflect.DeepEqual(problem, expected) {
+		t.Fatalf("expected %v, got %v", expected, problem)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/conflict", nil))
+
+	if ct := recorder.Header().Get("Content-Type"); ct != "application/vnd.api+json" {
+		t.Fatalf("expected the error's Content-Type field to win, got %q", ct)
+	}
 }
 
-func TestNestedResponseObjects(t *testing.T) {
+// Test automatic exclusion of routing/metadata fields from JSON
+func TestJSONAutoExclusion(t *testing.T) {
 	router := New()
-	GET(router, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*UserDetailResponse, error) {
-		return &UserDetailResponse{
-			UserID: "user-123",
-			Name:   "John Doe",
-			Contact: ContactInfo{
-				Email: "john@example.com",
-				Phone: "+1234567890",
-			},
+
+	type ResponseWithAllTags struct {
+		_           struct{} `http:"status=200"`
+		PathField   string   `path:"id"`
+		QueryField  string   `query:"page"`
+		HeaderField string   `header:"X-Custom"`
+		HTTPField   struct{} `http:"status=200"`
+		JSONField   string   `json:"data"`
+		NormalField string   // No tags
+	}
+
+	GET(router, "/test/:id", func(ctx context.Context, req *EmptyRequest) (*ResponseWithAllTags, error) {
+		return &ResponseWithAllTags{
+			PathField:   "should-not-appear",
+			QueryField:  "should-not-appear",
+			HeaderField: "header-value",
+			JSONField:   "should-appear",
+			NormalField: "should-appear-as-NormalField",
 		}, nil
 	})
 
 	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/users/123", nil))
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/test/123", nil))
 
 	if recorder.Code != http.StatusOK {
-		t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+		t.Fatalf("expected status 200, got %d", recorder.Code)
+	}
+
+	// Verify header was set
+	if header := recorder.Header().Get("X-Custom"); header != "header-value" {
+		t.Errorf("expected X-Custom header 'header-value', got '%s'", header)
 	}
 
+	// Parse JSON response
 	var result map[string]interface{}
 	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
 		t.Fatalf("failed to unmarshal JSON: %v", err)
 	}
 
-	// Verify nested contact object exists
-	contact, ok := result["contact"].(map[string]interface{})
-	if !ok {
-		t.Fatalf("expected 'contact' to be an object, got %T", result["contact"])
+	// Verify excluded fields are NOT in JSON
+	if _, exists := result["PathField"]; exists {
+		t.Errorf("PathField should be excluded from JSON, but was present")
 	}
-
-	// Verify email and phone are present
-	if email, exists := contact["email"]; !exists || email != "john@example.com" {
-		t.Errorf("expected email 'john@example.com', got '%v'", email)
+	if _, exists := result["QueryField"]; exists {
+		t.Errorf("QueryField should be excluded from JSON, but was present")
 	}
-	if phone, exists := contact["phone"]; !exists || phone != "+1234567890" {
-		t.Errorf("expected phone '+1234567890', got '%v'", phone)
+	if _, exists := result["HeaderField"]; exists {
+		t.Errorf("HeaderField should be excluded from JSON, but was present")
+	}
+	if _, exists := result["HTTPField"]; exists {
+		t.Errorf("HTTPField should be excluded from JSON, but was present")
 	}
-}
-
-// Test deeply nested structures
-type Metadata struct {
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
-	Version   int    `json:"version"`
-}
 
-type FullAddress struct {
-	Street   string   `json:"street"`
-	City     string   `json:"city"`
-	Metadata Metadata `json:"metadata"`
-}
+	// Verify included fields ARE in JSON
+	if data, exists := result["data"]; !exists {
+		t.Errorf("'data' should be in JSON")
+	} else if data != "should-appear" {
+		t.Errorf("expected 'data' to be 'should-appear', got '%v'", data)
+	}
 
-type ComplexUserResponse struct {
-	ID      int         `json:"id"`
-	Name    string      `json:"name"`
-	Address FullAddress `json:"address"`
+	if normalField, exists := result["NormalField"]; !exists {
+		t.Errorf("'NormalField' should be in JSON")
+	} else if normalField != "should-appear-as-NormalField" {
+		t.Errorf("expected 'NormalField' to be 'should-appear-as-NormalField', got '%v'", normalField)
+	}
 }
 
-func TestDeeplyNestedStructures(t *testing.T) {
+// Test JSON exclusion with omitempty
+func TestJSONAutoExclusionWithOmitempty(t *testing.T) {
 	router := New()
-	GET(router, "/complex", func(ctx context.Context, req *EmptyRequest) (*ComplexUserResponse, error) {
-		return &ComplexUserResponse{
-			ID:   1,
-			Name: "Test User",
-			Address: FullAddress{
-				Street: "123 Main St",
-				City:   "New York",
-				Metadata: Metadata{
-					CreatedAt: "2024-01-01",
-					UpdatedAt: "2024-01-02",
-					Version:   1,
-				},
-			},
+
+	type ResponseWithOmitempty struct {
+		Required    string `json:"required"`
+		Optional    string `json:"optional,omitempty"`
+		EmptyString string `json:"empty_string,omitempty"`
+		HeaderField string `header:"X-Test"`
+	}
+
+	GET(router, "/omitempty-test", func(ctx context.Context, req *EmptyRequest) (*ResponseWithOmitempty, error) {
+		return &ResponseWithOmitempty{
+			Required:    "present",
+			Optional:    "also-present",
+			EmptyString: "", // Should be omitted
+			HeaderField: "test-header",
 		}, nil
 	})
 
 	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/complex", nil))
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/omitempty-test", nil))
 
 	if recorder.Code != http.StatusOK {
-		t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+		t.Fatalf("expected status 200, got %d", recorder.Code)
 	}
 
 	var result map[string]interface{}
@@ -1777,54 +2774,55 @@ func TestDeeplyNestedStructures(t *testing.T) {
 		t.Fatalf("failed to unmarshal JSON: %v", err)
 	}
 
-	// Navigate to deeply nested metadata
-	address, ok := result["address"].(map[string]interface{})
-	if !ok {
-		t.Fatalf("expected 'address' to be an object")
+	// Verify required field is present
+	if _, exists := result["required"]; !exists {
+		t.Errorf("'required' should be in JSON")
 	}
 
-	metadata, ok := address["metadata"].(map[string]interface{})
-	if !ok {
-		t.Fatalf("expected 'metadata' to be an object")
+	// Verify optional non-empty field is present
+	if _, exists := result["optional"]; !exists {
+		t.Errorf("'optional' should be in JSON")
 	}
 
-	// Verify nested fields are present and correct
-	if createdAt, exists := metadata["created_at"]; !exists || createdAt != "2024-01-01" {
-		t.Errorf("expected created_at '2024-01-01', got '%v'", createdAt)
-	}
-	if version, exists := metadata["version"]; !exists || version != float64(1) {
-		t.Errorf("expected version 1, got '%v'", version)
+	// Verify empty field with omitempty is NOT present
+	if _, exists := result["empty_string"]; exists {
+		t.Errorf("'empty_string' should be omitted from JSON due to omitempty")
 	}
-}
 
-// Test arrays of nested objects
-type Item struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
-}
+	// Verify header field is NOT in JSON
+	if _, exists := result["HeaderField"]; exists {
+		t.Errorf("'HeaderField' should be excluded from JSON")
+	}
 
-type ItemListResponse struct {
-	Items []Item `json:"items"`
-	Count int    `json:"count"`
+	// Verify header was set
+	if header := recorder.Header().Get("X-Test"); header != "test-header" {
+		t.Errorf("expected X-Test header 'test-header', got '%s'", header)
+	}
 }
 
-func TestArrayOfNestedObjects(t *testing.T) {
+// Test JSON exclusion with explicit json:"-" tag
+func TestJSONAutoExclusionWithExplicitJsonDash(t *testing.T) {
 	router := New()
-	GET(router, "/items", func(ctx context.Context, req *EmptyRequest) (*ItemListResponse, error) {
-		return &ItemListResponse{
-			Items: []Item{
-				{ID: 1, Name: "Item 1"},
-				{ID: 2, Name: "Item 2"},
-			},
-			Count: 2,
+
+	type ResponseWithExplicitExclusion struct {
+		PublicField  string `json:"public"`
+		PrivateField string `json:"-"`        // Explicitly excluded
+		HeaderField  string `header:"X-Test"` // Auto-excluded
+	}
+
+	GET(router, "/explicit-test", func(ctx context.Context, req *EmptyRequest) (*ResponseWithExplicitExclusion, error) {
+		return &ResponseWithExplicitExclusion{
+			PublicField:  "visible",
+			PrivateField: "invisible",
+			HeaderField:  "header-value",
 		}, nil
 	})
 
 	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/items", nil))
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/explicit-test", nil))
 
 	if recorder.Code != http.StatusOK {
-		t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+		t.Fatalf("expected status 200, got %d", recorder.Code)
 	}
 
 	var result map[string]interface{}
@@ -1832,1428 +2830,3943 @@ func TestArrayOfNestedObjects(t *testing.T) {
 		t.Fatalf("failed to unmarshal JSON: %v", err)
 	}
 
-	items, ok := result["items"].([]interface{})
-	if !ok {
-		t.Fatalf("expected 'items' to be an array")
+	// Verify public field is present
+	if val, exists := result["public"]; !exists {
+		t.Errorf("'public' should be in JSON")
+	} else if val != "visible" {
+		t.Errorf("expected 'public' to be 'visible', got '%v'", val)
 	}
 
-	if len(items) != 2 {
-		t.Errorf("expected 2 items, got %d", len(items))
+	// Verify private field with json:"-" is NOT present
+	if _, exists := result["PrivateField"]; exists {
+		t.Errorf("'PrivateField' should be excluded from JSON due to json:\"-\" tag")
 	}
 
-	// Check first item
-	item1, ok := items[0].(map[string]interface{})
-	if !ok {
-		t.Fatalf("expected item to be an object")
+	// Verify header field is NOT present
+	if _, exists := result["HeaderField"]; exists {
+		t.Errorf("'HeaderField' should be excluded from JSON due to header tag")
 	}
+}
 
-	if id, exists := item1["id"]; !exists || id != float64(1) {
-		t.Errorf("expected id 1, got '%v'", id)
-	}
-	if name, exists := item1["name"]; !exists || name != "Item 1" {
-		t.Errorf("expected name 'Item 1', got '%v'", name)
-	}
-}
-
-// Test nested error objects
-type ErrorDetails struct {
-	Field   string `json:"field"`
-	Message string `json:"message"`
-}
-
-type DetailedError struct {
-	_       struct{}     `http:"status=400"`
-	Type    string       `json:"type"`
-	Details ErrorDetails `json:"details"`
+// Test JSON exclusion in error responses
+type ErrorWithMetadata struct {
+	_           struct{} `http:"status=400"`
+	HeaderField string   `header:"X-Error-Code"`
+	ErrorCode   string   `json:"error_code"`
+	Message     string   `json:"message"`
 }
 
-func (e DetailedError) Error() string { return e.Type }
+func (e ErrorWithMetadata) Error() string { return e.Message }
 
-func TestNestedErrorObjects(t *testing.T) {
+func TestJSONAutoExclusionInErrors(t *testing.T) {
 	router := New()
-	POST(router, "/validate", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
-		return nil, DetailedError{
-			Type: "validation_error",
-			Details: ErrorDetails{
-				Field:   "email",
-				Message: "invalid email format",
-			},
+
+	GET(router, "/error-test", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, ErrorWithMetadata{
+			HeaderField: "BAD_REQUEST",
+			ErrorCode:   "invalid_input",
+			Message:     "Something went wrong",
 		}
-	}, WithErrors(DetailedError{}))
+	}, WithErrors(ErrorWithMetadata{}))
 
 	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/validate", nil))
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/error-test", nil))
 
 	if recorder.Code != http.StatusBadRequest {
 		t.Fatalf("expected status 400, got %d", recorder.Code)
 	}
 
+	// Verify header was set
+	if header := recorder.Header().Get("X-Error-Code"); header != "BAD_REQUEST" {
+		t.Errorf("expected X-Error-Code header 'BAD_REQUEST', got '%s'", header)
+	}
+
 	var result map[string]interface{}
 	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
 		t.Fatalf("failed to unmarshal JSON: %v", err)
 	}
 
-	// Verify type field is present
-	if typ, exists := result["type"]; !exists || typ != "validation_error" {
-		t.Errorf("expected type 'validation_error', got '%v'", typ)
-	}
-
-	// Verify nested details object
-	details, ok := result["details"].(map[string]interface{})
-	if !ok {
-		t.Fatalf("expected 'details' to be an object")
+	// Verify header field is NOT in JSON
+	if _, exists := result["HeaderField"]; exists {
+		t.Errorf("'HeaderField' should be excluded from JSON")
 	}
 
-	if field, exists := details["field"]; !exists || field != "email" {
-		t.Errorf("expected field 'email', got '%v'", field)
+	// Verify error fields are present
+	if _, exists := result["error_code"]; !exists {
+		t.Errorf("'error_code' should be in JSON")
 	}
-	if message, exists := details["message"]; !exists || message != "invalid email format" {
-		t.Errorf("expected message 'invalid email format', got '%v'", message)
+	if _, exists := result["message"]; !exists {
+		t.Errorf("'message' should be in JSON")
 	}
 }
 
-// Test custom error handler functionality
-func TestCustomErrorHandler(t *testing.T) {
-	var capturedError error
-	var capturedWriter http.ResponseWriter
-	var capturedRequest *http.Request
+// Test corner case: struct with only routing tags
+func TestJSONAutoExclusionAllFieldsExcluded(t *testing.T) {
+	router := New()
 
-	config := &Config{
-		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
-			capturedError = err
-			capturedWriter = w
-			capturedRequest = r
+	type ResponseOnlyRoutingFields struct {
+		_           struct{} `http:"status=204"`
+		HeaderField string   `header:"X-Custom"`
+	}
 
-			// Return custom JSON error response
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusTeapot) // Use 418 to distinguish from default
-			json.NewEncoder(w).Encode(map[string]string{
-				"custom_error": "true",
-				"message":      err.Error(),
-			})
-		},
+	GET(router, "/only-routing", func(ctx context.Context, req *EmptyRequest) (*ResponseOnlyRoutingFields, error) {
+		return &ResponseOnlyRoutingFields{
+			HeaderField: "test",
+		}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/only-routing", nil))
+
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", recorder.Code)
 	}
 
-	router := NewWithConfig(config)
+	// Verify header was set
+	if header := recorder.Header().Get("X-Custom"); header != "test" {
+		t.Errorf("expected X-Custom header 'test', got '%s'", header)
+	}
 
-	// Test handler that triggers validation error
-	POST(router, "/test", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
-		return &CreateUserResponse{
-			ID:    1,
-			Name:  req.Name,
-			Email: req.Email,
+	// 204 responses must not include a body
+	if recorder.Body.Len() != 0 {
+		t.Fatalf("expected empty body for 204 response, got %q", recorder.Body.String())
+	}
+}
+
+// Test nested request objects
+type Address struct {
+	Street  string `json:"street" validate:"required"`
+	City    string `json:"city" validate:"required"`
+	ZipCode string `json:"zip_code" validate:"required,len=5"`
+}
+
+type CreateUserWithAddressRequest struct {
+	Name    string  `json:"name" validate:"required,min=3"`
+	Email   string  `json:"email" validate:"required,email"`
+	Address Address `json:"address" validate:"required"`
+}
+
+type CreateUserWithAddressResponse struct {
+	ID      int     `json:"id" validate:"required,gt=0"`
+	Name    string  `json:"name" validate:"required"`
+	Address Address `json:"address" validate:"required"`
+}
+
+func TestNestedRequestObjects(t *testing.T) {
+	router := New()
+	POST(router, "/users", func(ctx context.Context, req *CreateUserWithAddressRequest) (*CreateUserWithAddressResponse, error) {
+		return &CreateUserWithAddressResponse{
+			ID:      1,
+			Name:    req.Name,
+			Address: req.Address,
 		}, nil
 	})
 
-	// Invalid request (name too short) - should trigger validation error
-	reqBody := CreateUserRequest{
-		Name:  "Jo",
-		Email: "john@example.com",
+	// Valid nested request
+	reqBody := map[string]interface{}{
+		"name":  "John Doe",
+		"email": "john@example.com",
+		"address": map[string]string{
+			"street":   "123 Main St",
+			"city":     "New York",
+			"zip_code": "10001",
+		},
 	}
 	body, _ := json.Marshal(reqBody)
 
 	recorder := httptest.NewRecorder()
-	httpReq := httptest.NewRequest("POST", "/test", bytes.NewReader(body))
-	router.ServeHTTP(recorder, httpReq)
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/users", bytes.NewReader(body)))
 
-	// Verify custom error handler was called
-	if capturedError == nil {
-		t.Fatal("expected error handler to be called")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %d: %s", recorder.Code, recorder.Body.String())
 	}
 
-	if capturedWriter == nil {
-		t.Error("expected ResponseWriter to be passed to error handler")
+	var resp CreateUserWithAddressResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
 	}
 
-	if capturedRequest == nil {
-		t.Error("expected Request to be passed to error handler")
+	if resp.ID != 1 {
+		t.Errorf("expected ID 1, got %d", resp.ID)
 	}
-
-	// Verify custom status code
-	if recorder.Code != http.StatusTeapot {
-		t.Errorf("expected status 418 (custom), got %d", recorder.Code)
+	if resp.Address.City != "New York" {
+		t.Errorf("expected City 'New York', got '%s'", resp.Address.City)
 	}
+}
 
-	// Verify custom response body
-	var resp map[string]string
-	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
+func TestNestedRequestValidationFailure(t *testing.T) {
+	router := New()
+	POST(router, "/users", func(ctx context.Context, req *CreateUserWithAddressRequest) (*CreateUserWithAddressResponse, error) {
+		return &CreateUserWithAddressResponse{
+			ID:      1,
+			Name:    req.Name,
+			Address: req.Address,
+		}, nil
+	})
+
+	// Invalid nested request (invalid zip code)
+	reqBody := map[string]interface{}{
+		"name":  "John Doe",
+		"email": "john@example.com",
+		"address": map[string]string{
+			"street":   "123 Main St",
+			"city":     "New York",
+			"zip_code": "123", // Invalid: must be 5 digits
+		},
 	}
+	body, _ := json.Marshal(reqBody)
 
-	if resp["custom_error"] != "true" {
-		t.Errorf("expected custom_error 'true', got '%s'", resp["custom_error"])
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/users", bytes.NewReader(body)))
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected status BadRequest, got %d", recorder.Code)
 	}
 }
 
-// Test error kinds with custom handler
-func TestCustomErrorHandlerWithErrorKinds(t *testing.T) {
-	tests := []struct {
-		name          string
-		setup         func(*Sprout)
-		request       func() *http.Request
-		expectedKind  ErrorKind
-		expectedError string
-	}{
-		{
-			name: "ParseError",
-			setup: func(s *Sprout) {
-				GET(s, "/users/:id", func(ctx context.Context, req *GetUserRequest) (*GetUserResponse, error) {
-					return &GetUserResponse{
-						UserID:    req.UserID,
-						Page:      req.Page,
-						Limit:     req.Limit,
-						AuthToken: req.AuthToken,
-					}, nil
-				})
+// Test nested response objects
+type ContactInfo struct {
+	Email string `json:"email" validate:"required,email"`
+	Phone string `json:"phone" validate:"required"`
+}
+
+type UserDetailResponse struct {
+	_       struct{}    `http:"status=200"`
+	UserID  string      `json:"user_id" validate:"required"`
+	Name    string      `json:"name" validate:"required"`
+	Contact ContactInfo `json:"contact" validate:"required"`
+}
+
+func TestNestedResponseObjects(t *testing.T) {
+	router := New()
+	GET(router, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*UserDetailResponse, error) {
+		return &UserDetailResponse{
+			UserID: "user-123",
+			Name:   "John Doe",
+			Contact: ContactInfo{
+				Email: "john@example.com",
+				Phone: "+1234567890",
 			},
-			request: func() *http.Request {
-				// Invalid query param (page should be int)
-				return httptest.NewRequest("GET", "/users/123?page=invalid&limit=10", nil)
+		}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/users/123", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to unmarshal JSON: %v", err)
+	}
+
+	// Verify nested contact object exists
+	contact, ok := result["contact"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected 'contact' to be an object, got %T", result["contact"])
+	}
+
+	// Verify email and phone are present
+	if email, exists := contact["email"]; !exists || email != "john@example.com" {
+		t.Errorf("expected email 'john@example.com', got '%v'", email)
+	}
+	if phone, exists := contact["phone"]; !exists || phone != "+1234567890" {
+		t.Errorf("expected phone '+1234567890', got '%v'", phone)
+	}
+}
+
+// Test deeply nested structures
+type Metadata struct {
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	Version   int    `json:"version"`
+}
+
+type FullAddress struct {
+	Street   string   `json:"street"`
+	City     string   `json:"city"`
+	Metadata Metadata `json:"metadata"`
+}
+
+type ComplexUserResponse struct {
+	ID      int         `json:"id"`
+	Name    string      `json:"name"`
+	Address FullAddress `json:"address"`
+}
+
+func TestDeeplyNestedStructures(t *testing.T) {
+	router := New()
+	GET(router, "/complex", func(ctx context.Context, req *EmptyRequest) (*ComplexUserResponse, error) {
+		return &ComplexUserResponse{
+			ID:   1,
+			Name: "Test User",
+			Address: FullAddress{
+				Street: "123 Main St",
+				City:   "New York",
+				Metadata: Metadata{
+					CreatedAt: "2024-01-01",
+					UpdatedAt: "2024-01-02",
+					Version:   1,
+				},
 			},
-			expectedKind:  ErrorKindParse,
-			expectedError: "invalid query parameter 'page'",
+		}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/complex", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to unmarshal JSON: %v", err)
+	}
+
+	// Navigate to deeply nested metadata
+	address, ok := result["address"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected 'address' to be an object")
+	}
+
+	metadata, ok := address["metadata"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected 'metadata' to be an object")
+	}
+
+	// Verify nested fields are present and correct
+	if createdAt, exists := metadata["created_at"]; !exists || createdAt != "2024-01-01" {
+		t.Errorf("expected created_at '2024-01-01', got '%v'", createdAt)
+	}
+	if version, exists := metadata["version"]; !exists || version != float64(1) {
+		t.Errorf("expected version 1, got '%v'", version)
+	}
+}
+
+// Test arrays of nested objects
+type Item struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type ItemListResponse struct {
+	Items []Item `json:"items"`
+	Count int    `json:"count"`
+}
+
+func TestArrayOfNestedObjects(t *testing.T) {
+	router := New()
+	GET(router, "/items", func(ctx context.Context, req *EmptyRequest) (*ItemListResponse, error) {
+		return &ItemListResponse{
+			Items: []Item{
+				{ID: 1, Name: "Item 1"},
+				{ID: 2, Name: "Item 2"},
+			},
+			Count: 2,
+		}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/items", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to unmarshal JSON: %v", err)
+	}
+
+	items, ok := result["items"].([]interface{})
+	if !ok {
+		t.Fatalf("expected 'items' to be an array")
+	}
+
+	if len(items) != 2 {
+		t.Errorf("expected 2 items, got %d", len(items))
+	}
+
+	// Check first item
+	item1, ok := items[0].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected item to be an object")
+	}
+
+	if id, exists := item1["id"]; !exists || id != float64(1) {
+		t.Errorf("expected id 1, got '%v'", id)
+	}
+	if name, exists := item1["name"]; !exists || name != "Item 1" {
+		t.Errorf("expected name 'Item 1', got '%v'", name)
+	}
+}
+
+// Test nested error objects
+type ErrorDetails struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type DetailedError struct {
+	_       struct{}     `http:"status=400"`
+	Type    string       `json:"type"`
+	Details ErrorDetails `json:"details"`
+}
+
+func (e DetailedError) Error() string { return e.Type }
+
+func TestNestedErrorObjects(t *testing.T) {
+	router := New()
+	POST(router, "/validate", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, DetailedError{
+			Type: "validation_error",
+			Details: ErrorDetails{
+				Field:   "email",
+				Message: "invalid email format",
+			},
+		}
+	}, WithErrors(DetailedError{}))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/validate", nil))
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", recorder.Code)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to unmarshal JSON: %v", err)
+	}
+
+	// Verify type field is present
+	if typ, exists := result["type"]; !exists || typ != "validation_error" {
+		t.Errorf("expected type 'validation_error', got '%v'", typ)
+	}
+
+	// Verify nested details object
+	details, ok := result["details"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected 'details' to be an object")
+	}
+
+	if field, exists := details["field"]; !exists || field != "email" {
+		t.Errorf("expected field 'email', got '%v'", field)
+	}
+	if message, exists := details["message"]; !exists || message != "invalid email format" {
+		t.Errorf("expected message 'invalid email format', got '%v'", message)
+	}
+}
+
+// Test custom error handler functionality
+func TestCustomErrorHandler(t *testing.T) {
+	var capturedError error
+	var capturedWriter http.ResponseWriter
+	var capturedRequest *http.Request
+
+	config := &Config{
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			capturedError = err
+			capturedWriter = w
+			capturedRequest = r
+
+			// Return custom JSON error response
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTeapot) // Use 418 to distinguish from default
+			json.NewEncoder(w).Encode(map[string]string{
+				"custom_error": "true",
+				"message":      err.Error(),
+			})
+		},
+	}
+
+	router := NewWithConfig(config)
+
+	// Test handler that triggers validation error
+	POST(router, "/test", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+		return &CreateUserResponse{
+			ID:    1,
+			Name:  req.Name,
+			Email: req.Email,
+		}, nil
+	})
+
+	// Invalid request (name too short) - should trigger validation error
+	reqBody := CreateUserRequest{
+		Name:  "Jo",
+		Email: "john@example.com",
+	}
+	body, _ := json.Marshal(reqBody)
+
+	recorder := httptest.NewRecorder()
+	httpReq := httptest.NewRequest("POST", "/test", bytes.NewReader(body))
+	router.ServeHTTP(recorder, httpReq)
+
+	// Verify custom error handler was called
+	if capturedError == nil {
+		t.Fatal("expected error handler to be called")
+	}
+
+	if capturedWriter == nil {
+		t.Error("expected ResponseWriter to be passed to error handler")
+	}
+
+	if capturedRequest == nil {
+		t.Error("expected Request to be passed to error handler")
+	}
+
+	// Verify custom status code
+	if recorder.Code != http.StatusTeapot {
+		t.Errorf("expected status 418 (custom), got %d", recorder.Code)
+	}
+
+	// Verify custom response body
+	var resp map[string]string
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp["custom_error"] != "true" {
+		t.Errorf("expected custom_error 'true', got '%s'", resp["custom_error"])
+	}
+}
+
+// Test error kinds with custom handler
+func TestCustomErrorHandlerWithErrorKinds(t *testing.T) {
+	tests := []struct {
+		name          string
+		setup         func(*Sprout)
+		request       func() *http.Request
+		expectedKind  ErrorKind
+		expectedError string
+	}{
+		{
+			name: "ParseError",
+			setup: func(s *Sprout) {
+				GET(s, "/users/:id", func(ctx context.Context, req *GetUserRequest) (*GetUserResponse, error) {
+					return &GetUserResponse{
+						UserID:    req.UserID,
+						Page:      req.Page,
+						Limit:     req.Limit,
+						AuthToken: req.AuthToken,
+					}, nil
+				})
+			},
+			request: func() *http.Request {
+				// Invalid query param (page should be int)
+				return httptest.NewRequest("GET", "/users/123?page=invalid&limit=10", nil)
+			},
+			expectedKind:  ErrorKindParse,
+			expectedError: "invalid query parameter 'page'",
+		},
+		{
+			name: "ValidationError",
+			setup: func(s *Sprout) {
+				POST(s, "/users", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+					return &CreateUserResponse{
+						ID:    1,
+						Name:  req.Name,
+						Email: req.Email,
+					}, nil
+				})
+			},
+			request: func() *http.Request {
+				// Invalid body (name too short)
+				reqBody := CreateUserRequest{
+					Name:  "Jo",
+					Email: "john@example.com",
+				}
+				body, _ := json.Marshal(reqBody)
+				return httptest.NewRequest("POST", "/users", bytes.NewReader(body))
+			},
+			expectedKind:  ErrorKindValidation,
+			expectedError: "request validation failed",
+		},
+		{
+			name: "ResponseValidationError",
+			setup: func(s *Sprout) {
+				GET(s, "/invalid-response", func(ctx context.Context, req *EmptyRequest) (*CreateUserResponse, error) {
+					// Return response with invalid ID (must be > 0)
+					return &CreateUserResponse{
+						ID:    -1, // Invalid!
+						Name:  "Test",
+						Email: "test@example.com",
+					}, nil
+				})
+			},
+			request: func() *http.Request {
+				return httptest.NewRequest("GET", "/invalid-response", nil)
+			},
+			expectedKind:  ErrorKindResponseValidation,
+			expectedError: "response validation failed",
+		},
+		{
+			name: "ErrorValidationError",
+			setup: func(s *Sprout) {
+				GET(s, "/invalid-error", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+					// Return error with missing required field
+					return nil, NotFoundError{
+						Resource: "user",
+						Message:  "", // Invalid! Message is required
+					}
+				}, WithErrors(NotFoundError{}))
+			},
+			request: func() *http.Request {
+				return httptest.NewRequest("GET", "/invalid-error", nil)
+			},
+			expectedKind:  ErrorKindErrorValidation,
+			expectedError: "error response validation failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var capturedError error
+
+			config := &Config{
+				ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+					capturedError = err
+					w.WriteHeader(http.StatusTeapot)
+				},
+			}
+
+			router := NewWithConfig(config)
+			tt.setup(router)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, tt.request())
+
+			if capturedError == nil {
+				t.Fatal("expected error handler to be called")
+			}
+
+			// Extract Error using errors.As
+			var sproutErr *Error
+			if !errors.As(capturedError, &sproutErr) {
+				t.Fatalf("expected *Error, got %T", capturedError)
+			}
+
+			if sproutErr.Kind != tt.expectedKind {
+				t.Errorf("expected kind %s, got %s", tt.expectedKind, sproutErr.Kind)
+			}
+
+			if !bytes.Contains([]byte(sproutErr.Message), []byte(tt.expectedError)) {
+				t.Errorf("expected error message to contain '%s', got '%s'", tt.expectedError, sproutErr.Message)
+			}
+
+			// Verify custom status code was used
+			if recorder.Code != http.StatusTeapot {
+				t.Errorf("expected status 418 (custom handler), got %d", recorder.Code)
+			}
+		})
+	}
+}
+
+// Test default error handling (no custom handler)
+func TestDefaultErrorHandling(t *testing.T) {
+	router := New() // No custom config
+
+	// Test handler that triggers validation error
+	POST(router, "/test", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+		return &CreateUserResponse{
+			ID:    1,
+			Name:  req.Name,
+			Email: req.Email,
+		}, nil
+	})
+
+	// Invalid request (name too short)
+	reqBody := CreateUserRequest{
+		Name:  "Jo",
+		Email: "john@example.com",
+	}
+	body, _ := json.Marshal(reqBody)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/test", bytes.NewReader(body)))
+
+	// Default handler should return 400 for validation errors
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", recorder.Code)
+	}
+
+	// Default handler returns plain text error
+	if !bytes.Contains(recorder.Body.Bytes(), []byte("validation_error")) {
+		t.Logf("Response body: %s", recorder.Body.String())
+	}
+}
+
+// Test unwrapping Error
+func TestErrorUnwrap(t *testing.T) {
+	underlyingErr := errors.New("underlying error")
+	sproutErr := &Error{
+		Kind:    ErrorKindParse,
+		Message: "parse failed",
+		Err:     underlyingErr,
+	}
+
+	unwrapped := sproutErr.Unwrap()
+	if unwrapped != underlyingErr {
+		t.Errorf("expected unwrapped error to be %v, got %v", underlyingErr, unwrapped)
+	}
+}
+
+// Test Error string formatting
+func TestErrorString(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      *Error
+		expected string
+	}{
+		{
+			name: "WithUnderlyingError",
+			err: &Error{
+				Kind:    ErrorKindValidation,
+				Message: "validation failed",
+				Err:     errors.New("field 'name' is required"),
+			},
+			expected: "validation_error: validation failed: field 'name' is required",
+		},
+		{
+			name: "WithoutUnderlyingError",
+			err: &Error{
+				Kind:    ErrorKindParse,
+				Message: "parse failed",
+			},
+			expected: "parse_error: parse failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.err.Error()
+			if result != tt.expected {
+				t.Errorf("expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+}
+
+// Test strict error type checking (default behavior)
+func TestStrictErrorTypesDefault(t *testing.T) {
+	router := New() // Default: strict = true
+
+	// Handler that returns undeclared error type
+	POST(router, "/test", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+		if req.Name == "trigger" {
+			// Return NotFoundError, but only ConflictError is declared
+			return nil, NotFoundError{Resource: "user", Message: "user not found"}
+		}
+		return &CreateUserResponse{ID: 1, Name: req.Name, Email: req.Email}, nil
+	}, WithErrors(ConflictError{})) // Only ConflictError declared, NOT NotFoundError
+
+	reqBody := CreateUserRequest{
+		Name:  "trigger",
+		Email: "test@example.com",
+	}
+	body, _ := json.Marshal(reqBody)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/test", bytes.NewReader(body)))
+
+	// Should return 500 because error type not declared and strict mode is on
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 (strict mode), got %d", recorder.Code)
+	}
+
+	if !bytes.Contains(recorder.Body.Bytes(), []byte("undeclared_error_type")) {
+		t.Errorf("expected 'undeclared_error_type' in response, got: %s", recorder.Body.String())
+	}
+}
+
+// Test strict error type checking disabled
+func TestStrictErrorTypesDisabled(t *testing.T) {
+	falseVal := false
+	config := &Config{
+		StrictErrorTypes: &falseVal,
+	}
+	router := NewWithConfig(config)
+
+	// Handler that returns undeclared error type
+	POST(router, "/test", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+		if req.Name == "trigger" {
+			// Return NotFoundError, but only ConflictError is declared
+			return nil, NotFoundError{Resource: "user", Message: "user not found"}
+		}
+		return &CreateUserResponse{ID: 1, Name: req.Name, Email: req.Email}, nil
+	}, WithErrors(ConflictError{})) // Only ConflictError declared
+
+	reqBody := CreateUserRequest{
+		Name:  "trigger",
+		Email: "test@example.com",
+	}
+	body, _ := json.Marshal(reqBody)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/test", bytes.NewReader(body)))
+
+	// Should return 404 (error's status code) because strict mode is off
+	// The undeclared error is allowed with just a warning
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 (error's status), got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	// Should still get valid error response
+	var errResp NotFoundError
+	if err := json.NewDecoder(recorder.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+
+	if errResp.Resource != "user" {
+		t.Errorf("expected resource 'user', got '%s'", errResp.Resource)
+	}
+}
+
+// Test declared errors work in strict mode
+func TestStrictErrorTypesDeclared(t *testing.T) {
+	router := New() // Default: strict = true
+
+	// Handler with properly declared error types
+	POST(router, "/test", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+		if req.Name == "notfound" {
+			return nil, NotFoundError{Resource: "user", Message: "user not found"}
+		}
+		if req.Name == "conflict" {
+			return nil, ConflictError{Field: "email", Message: "email exists"}
+		}
+		return &CreateUserResponse{ID: 1, Name: req.Name, Email: req.Email}, nil
+	}, WithErrors(NotFoundError{}, ConflictError{})) // Both error types declared
+
+	// Test NotFoundError (declared)
+	t.Run("NotFound", func(t *testing.T) {
+		reqBody := CreateUserRequest{
+			Name:  "notfound",
+			Email: "test@example.com",
+		}
+		body, _ := json.Marshal(reqBody)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/test", bytes.NewReader(body)))
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		var errResp NotFoundError
+		if err := json.NewDecoder(recorder.Body).Decode(&errResp); err != nil {
+			t.Fatalf("failed to decode error response: %v", err)
+		}
+
+		if errResp.Resource != "user" {
+			t.Errorf("expected resource 'user', got '%s'", errResp.Resource)
+		}
+	})
+
+	// Test ConflictError (declared)
+	t.Run("Conflict", func(t *testing.T) {
+		reqBody := CreateUserRequest{
+			Name:  "conflict",
+			Email: "test@example.com",
+		}
+		body, _ := json.Marshal(reqBody)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/test", bytes.NewReader(body)))
+
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("expected status 409, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		var errResp ConflictError
+		if err := json.NewDecoder(recorder.Body).Decode(&errResp); err != nil {
+			t.Fatalf("failed to decode error response: %v", err)
+		}
+
+		if errResp.Field != "email" {
+			t.Errorf("expected field 'email', got '%s'", errResp.Field)
+		}
+	})
+}
+
+// Test explicitly enabling strict error types
+func TestStrictErrorTypesExplicitlyEnabled(t *testing.T) {
+	trueVal := true
+	config := &Config{
+		StrictErrorTypes: &trueVal,
+	}
+	router := NewWithConfig(config)
+
+	// Handler that returns undeclared error type
+	POST(router, "/test", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+		if req.Name == "trigger" {
+			return nil, NotFoundError{Resource: "user", Message: "user not found"}
+		}
+		return &CreateUserResponse{ID: 1, Name: req.Name, Email: req.Email}, nil
+	}, WithErrors(ConflictError{})) // Only ConflictError declared
+
+	reqBody := CreateUserRequest{
+		Name:  "trigger",
+		Email: "test@example.com",
+	}
+	body, _ := json.Marshal(reqBody)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/test", bytes.NewReader(body)))
+
+	// Should return 500 because error type not declared and strict mode is explicitly on
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 (strict mode), got %d", recorder.Code)
+	}
+
+	if !bytes.Contains(recorder.Body.Bytes(), []byte("undeclared_error_type")) {
+		t.Errorf("expected 'undeclared_error_type' in response, got: %s", recorder.Body.String())
+	}
+}
+
+// Test custom error handler can intercept undeclared error types
+func TestStrictErrorTypesCustomHandler(t *testing.T) {
+	var capturedErrorKind ErrorKind
+
+	config := &Config{
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			// Extract ErrorKind
+			var sproutErr *Error
+			if errors.As(err, &sproutErr) {
+				capturedErrorKind = sproutErr.Kind
+			}
+
+			// Custom handling for undeclared errors
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway) // Use 502 to distinguish custom handling
+			json.NewEncoder(w).Encode(map[string]string{
+				"custom_handling": "true",
+				"error_kind":      string(capturedErrorKind),
+			})
+		},
+	}
+
+	router := NewWithConfig(config)
+
+	// Handler that returns undeclared error type
+	POST(router, "/test", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+		if req.Name == "trigger" {
+			return nil, NotFoundError{Resource: "user", Message: "user not found"}
+		}
+		return &CreateUserResponse{ID: 1, Name: req.Name, Email: req.Email}, nil
+	}, WithErrors(ConflictError{})) // Only ConflictError declared
+
+	reqBody := CreateUserRequest{
+		Name:  "trigger",
+		Email: "test@example.com",
+	}
+	body, _ := json.Marshal(reqBody)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/test", bytes.NewReader(body)))
+
+	// Verify custom handler was invoked
+	if recorder.Code != http.StatusBadGateway {
+		t.Errorf("expected status 502 (custom handler), got %d", recorder.Code)
+	}
+
+	// Verify error kind was captured
+	if capturedErrorKind != ErrorKindUndeclaredError {
+		t.Errorf("expected ErrorKindUndeclaredError, got %s", capturedErrorKind)
+	}
+
+	// Verify custom response body
+	var resp map[string]string
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp["custom_handling"] != "true" {
+		t.Errorf("expected custom_handling 'true', got '%s'", resp["custom_handling"])
+	}
+
+	if resp["error_kind"] != "undeclared_error_type" {
+		t.Errorf("expected error_kind 'undeclared_error_type', got '%s'", resp["error_kind"])
+	}
+}
+
+// Test base path functionality
+func TestBasePath(t *testing.T) {
+	config := &Config{
+		BasePath: "/api/v1",
+	}
+	router := NewWithConfig(config)
+
+	POST(router, "/users", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+		return &CreateUserResponse{
+			ID:    123,
+			Name:  req.Name,
+			Email: req.Email,
+		}, nil
+	})
+
+	// Request should be made to /api/v1/users, not /users
+	reqBody := CreateUserRequest{
+		Name:  "John Doe",
+		Email: "john@example.com",
+	}
+	body, _ := json.Marshal(reqBody)
+
+	// Request to base path should work
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/api/v1/users", bytes.NewReader(body)))
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected status OK for /api/v1/users, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	// Request to route without base path should NOT work
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/users", bytes.NewReader(body)))
+
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for /users (no base path), got %d", recorder.Code)
+	}
+}
+
+// Test base path with trailing slash
+func TestBasePathWithTrailingSlash(t *testing.T) {
+	config := &Config{
+		BasePath: "/api/v1/", // Trailing slash should be handled
+	}
+	router := NewWithConfig(config)
+
+	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "success"}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/v1/users", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected status OK, got %d", recorder.Code)
+	}
+}
+
+// Test base path without leading slash
+func TestBasePathWithoutLeadingSlash(t *testing.T) {
+	config := &Config{
+		BasePath: "api/v1", // Missing leading slash should be handled
+	}
+	router := NewWithConfig(config)
+
+	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "success"}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/v1/users", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected status OK, got %d", recorder.Code)
+	}
+}
+
+// Test empty base path
+func TestEmptyBasePath(t *testing.T) {
+	config := &Config{
+		BasePath: "", // Empty base path should work like New()
+	}
+	router := NewWithConfig(config)
+
+	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "success"}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/users", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected status OK, got %d", recorder.Code)
+	}
+}
+
+// Test base path with path parameters
+func TestBasePathWithPathParams(t *testing.T) {
+	config := &Config{
+		BasePath: "/api/v1",
+	}
+	router := NewWithConfig(config)
+
+	type GetUserByIDRequest struct {
+		UserID string `path:"id" validate:"required"`
+	}
+
+	GET(router, "/users/:id", func(ctx context.Context, req *GetUserByIDRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "User ID: " + req.UserID}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/v1/users/123", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected status OK, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var resp HelloResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Message != "User ID: 123" {
+		t.Errorf("expected message 'User ID: 123', got '%s'", resp.Message)
+	}
+}
+
+// Test multiple routes with base path
+func TestMultipleRoutesWithBasePath(t *testing.T) {
+	config := &Config{
+		BasePath: "/api/v1",
+	}
+	router := NewWithConfig(config)
+
+	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "users"}, nil
+	})
+
+	POST(router, "/users", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+		return &CreateUserResponse{
+			ID:    1,
+			Name:  req.Name,
+			Email: req.Email,
+		}, nil
+	})
+
+	GET(router, "/items", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "items"}, nil
+	})
+
+	// Test GET /api/v1/users
+	t.Run("GET /users", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/v1/users", nil))
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("expected status OK, got %d", recorder.Code)
+		}
+
+		var resp HelloResponse
+		json.NewDecoder(recorder.Body).Decode(&resp)
+		if resp.Message != "users" {
+			t.Errorf("expected 'users', got '%s'", resp.Message)
+		}
+	})
+
+	// Test POST /api/v1/users
+	t.Run("POST /users", func(t *testing.T) {
+		reqBody := CreateUserRequest{Name: "John", Email: "john@example.com"}
+		body, _ := json.Marshal(reqBody)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/api/v1/users", bytes.NewReader(body)))
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("expected status OK, got %d", recorder.Code)
+		}
+	})
+
+	// Test GET /api/v1/items
+	t.Run("GET /items", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/v1/items", nil))
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("expected status OK, got %d", recorder.Code)
+		}
+
+		var resp HelloResponse
+		json.NewDecoder(recorder.Body).Decode(&resp)
+		if resp.Message != "items" {
+			t.Errorf("expected 'items', got '%s'", resp.Message)
+		}
+	})
+}
+
+func TestNestedRouterMountsPrefix(t *testing.T) {
+	router := New()
+	auth := router.Mount("/auth", nil)
+
+	GET(auth, "/login", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "auth-login"}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/auth/login", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %d", recorder.Code)
+	}
+
+	var resp HelloResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Message != "auth-login" {
+		t.Errorf("expected message 'auth-login', got '%s'", resp.Message)
+	}
+
+	// Without prefix the route should not be found.
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/login", nil))
+
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for missing prefix, got %d", recorder.Code)
+	}
+}
+
+func TestNestedRouterWithParentBasePath(t *testing.T) {
+	router := NewWithConfig(&Config{
+		BasePath: "/api",
+	})
+
+	auth := router.Mount("/auth", nil)
+
+	GET(auth, "/login", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "auth-login"}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/auth/login", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status OK, got %d", recorder.Code)
+	}
+
+	var resp HelloResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Message != "auth-login" {
+		t.Errorf("expected message 'auth-login', got '%s'", resp.Message)
+	}
+
+	// Requests missing either prefix should be 404.
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/auth/login", nil))
+
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 without base path, got %d", recorder.Code)
+	}
+}
+
+func TestNestedRouterInheritsErrorHandler(t *testing.T) {
+	var handled bool
+
+	router := NewWithConfig(&Config{
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			handled = true
+			w.WriteHeader(599)
+			w.Write([]byte("custom error"))
+		},
+	})
+
+	auth := router.Mount("/auth", nil)
+
+	type AuthRequest struct {
+		Token string `header:"Authorization" validate:"required"`
+	}
+
+	GET(auth, "/login", func(ctx context.Context, req *AuthRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "should not reach"}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/auth/login", nil))
+
+	if !handled {
+		t.Fatalf("expected parent error handler to be invoked")
+	}
+
+	if recorder.Code != 599 {
+		t.Fatalf("expected status 599 from custom error handler, got %d", recorder.Code)
+	}
+
+	if !bytes.Contains(recorder.Body.Bytes(), []byte("custom error")) {
+		t.Errorf("expected custom error body, got %s", recorder.Body.String())
+	}
+}
+
+func TestNestedRouterOverridesErrorHandler(t *testing.T) {
+	var parentCalled bool
+	var childCalled bool
+
+	router := NewWithConfig(&Config{
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			parentCalled = true
+			w.WriteHeader(597)
+			w.Write([]byte("parent error"))
+		},
+	})
+
+	child := router.Mount("/child", &Config{
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			childCalled = true
+			w.WriteHeader(598)
+			w.Write([]byte("child error"))
+		},
+	})
+
+	type ChildRequest struct {
+		Token string `header:"Authorization" validate:"required"`
+	}
+
+	GET(child, "/secure", func(ctx context.Context, req *ChildRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "should not reach"}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/child/secure", nil))
+
+	if parentCalled {
+		t.Fatalf("expected parent error handler to be skipped")
+	}
+
+	if !childCalled {
+		t.Fatalf("expected child error handler to be invoked")
+	}
+
+	if recorder.Code != 598 {
+		t.Fatalf("expected status 598 from child error handler, got %d", recorder.Code)
+	}
+}
+
+func TestNestedRouterOverridesStrictFlag(t *testing.T) {
+	router := New()
+
+	strictFalse := false
+	child := router.Mount("/loose", &Config{
+		StrictErrorTypes: &strictFalse,
+	})
+
+	GET(child, "/test", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, &TeapotError{Msg: "teapot"}
+	}, WithErrors(&Error{}))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/loose/test", nil))
+
+	if recorder.Code != 418 {
+		t.Fatalf("expected status 418 from custom error, got %d", recorder.Code)
+	}
+
+	var resp map[string]string
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp["message"] != "teapot" {
+		t.Errorf("expected message 'teapot', got %s", resp["message"])
+	}
+}
+
+// Test 404 Not Found with default error handler
+func TestNotFoundDefaultHandler(t *testing.T) {
+	router := New()
+
+	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "users"}, nil
+	})
+
+	// Request to non-existent route
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/nonexistent", nil))
+
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", recorder.Code)
+	}
+
+	// Should contain error message
+	if !bytes.Contains(recorder.Body.Bytes(), []byte("not_found")) {
+		t.Errorf("expected 'not_found' in response, got: %s", recorder.Body.String())
+	}
+
+	if !bytes.Contains(recorder.Body.Bytes(), []byte("GET /nonexistent")) {
+		t.Errorf("expected route info in response, got: %s", recorder.Body.String())
+	}
+}
+
+// Test 405 Method Not Allowed with default error handler
+func TestMethodNotAllowedDefaultHandler(t *testing.T) {
+	router := New()
+	router.HandleMethodNotAllowed = true // Enable 405 responses
+
+	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "users"}, nil
+	})
+
+	// Request with wrong method
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/users", nil))
+
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", recorder.Code)
+	}
+
+	// Should contain error message
+	if !bytes.Contains(recorder.Body.Bytes(), []byte("method_not_allowed")) {
+		t.Errorf("expected 'method_not_allowed' in response, got: %s", recorder.Body.String())
+	}
+}
+
+// Test 404 with custom error handler
+func TestNotFoundCustomHandler(t *testing.T) {
+	var capturedKind ErrorKind
+
+	config := &Config{
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			var sproutErr *Error
+			if errors.As(err, &sproutErr) {
+				capturedKind = sproutErr.Kind
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{
+					"error":   "custom_not_found",
+					"message": sproutErr.Message,
+					"path":    r.URL.Path,
+				})
+			}
 		},
-		{
-			name: "ValidationError",
-			setup: func(s *Sprout) {
-				POST(s, "/users", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
-					return &CreateUserResponse{
-						ID:    1,
-						Name:  req.Name,
-						Email: req.Email,
-					}, nil
+	}
+
+	router := NewWithConfig(config)
+
+	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "users"}, nil
+	})
+
+	// Request to non-existent route
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/nonexistent", nil))
+
+	// Verify error kind was captured
+	if capturedKind != ErrorKindNotFound {
+		t.Errorf("expected ErrorKindNotFound, got %s", capturedKind)
+	}
+
+	// Verify custom response
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", recorder.Code)
+	}
+
+	var resp map[string]string
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp["error"] != "custom_not_found" {
+		t.Errorf("expected 'custom_not_found', got '%s'", resp["error"])
+	}
+
+	if resp["path"] != "/nonexistent" {
+		t.Errorf("expected path '/nonexistent', got '%s'", resp["path"])
+	}
+}
+
+// Test 405 with custom error handler
+func TestMethodNotAllowedCustomHandler(t *testing.T) {
+	var capturedKind ErrorKind
+
+	config := &Config{
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			var sproutErr *Error
+			if errors.As(err, &sproutErr) {
+				capturedKind = sproutErr.Kind
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				json.NewEncoder(w).Encode(map[string]string{
+					"error":  "custom_method_not_allowed",
+					"method": r.Method,
+					"path":   r.URL.Path,
 				})
-			},
-			request: func() *http.Request {
-				// Invalid body (name too short)
-				reqBody := CreateUserRequest{
-					Name:  "Jo",
-					Email: "john@example.com",
+			}
+		},
+	}
+
+	router := NewWithConfig(config)
+	router.HandleMethodNotAllowed = true // Enable 405 responses
+
+	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "users"}, nil
+	})
+
+	// Request with wrong method
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/users", nil))
+
+	// Verify error kind was captured
+	if capturedKind != ErrorKindMethodNotAllowed {
+		t.Errorf("expected ErrorKindMethodNotAllowed, got %s", capturedKind)
+	}
+
+	// Verify custom response
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", recorder.Code)
+	}
+
+	var resp map[string]string
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp["error"] != "custom_method_not_allowed" {
+		t.Errorf("expected 'custom_method_not_allowed', got '%s'", resp["error"])
+	}
+
+	if resp["method"] != "POST" {
+		t.Errorf("expected method 'POST', got '%s'", resp["method"])
+	}
+}
+
+// separateHeaderWriter keeps its own header map and copies it to the
+// underlying writer when the status is written.
+type separateHeaderWriter struct {
+	http.ResponseWriter
+	header http.Header
+}
+
+func (w *separateHeaderWriter) Header() http.Header { return w.header }
+
+func (w *separateHeaderWriter) WriteHeader(status int) {
+	for name, values := range w.header {
+		w.ResponseWriter.Header()[name] = values
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *separateHeaderWriter) Write(b []byte) (int, error) {
+	return w.ResponseWriter.Write(b)
+}
+
+func TestMethodNotAllowedAllowHeader(t *testing.T) {
+	register := func(router *Sprout) {
+		router.HandleMethodNotAllowed = true
+		GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+			return &HelloResponse{Message: "users"}, nil
+		})
+		DELETE(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+			return nil, nil
+		})
+	}
+	serve := func(router *Sprout) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", nil))
+		return rec
+	}
+
+	t.Run("default handler", func(t *testing.T) {
+		router := New()
+		register(router)
+
+		rec := serve(router)
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("expected status 405, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("Allow"); got != "DELETE, GET, OPTIONS" {
+			t.Fatalf("expected Allow header listing registered methods, got %q", got)
+		}
+	})
+
+	t.Run("custom handler receives the methods", func(t *testing.T) {
+		var allowed *MethodNotAllowedError
+		router := NewWithConfig(&Config{
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				if !errors.As(err, &allowed) {
+					t.Errorf("expected *MethodNotAllowedError, got %v", err)
 				}
-				body, _ := json.Marshal(reqBody)
-				return httptest.NewRequest("POST", "/users", bytes.NewReader(body))
+				w.WriteHeader(http.StatusMethodNotAllowed)
 			},
-			expectedKind:  ErrorKindValidation,
-			expectedError: "request validation failed",
+		})
+		register(router)
+
+		rec := serve(router)
+		if allowed == nil || allowed.Method != http.MethodPost || strings.Join(allowed.Allowed, ",") != "DELETE,GET,OPTIONS" {
+			t.Fatalf("unexpected method details: %+v", allowed)
+		}
+		if got := rec.Header().Get("Allow"); got != "DELETE, GET, OPTIONS" {
+			t.Fatalf("expected Allow header with custom handler, got %q", got)
+		}
+	})
+
+	t.Run("middleware swapping the writer", func(t *testing.T) {
+		router := New()
+		router.Use(func(w http.ResponseWriter, r *http.Request, next Next) {
+			continueWithWriter(next, &separateHeaderWriter{ResponseWriter: httptest.NewRecorder(), header: http.Header{}}, r)
+		})
+		var inner http.Header
+		router.Use(func(w http.ResponseWriter, r *http.Request, next Next) {
+			inner = w.Header()
+			next(nil)
+		})
+		register(router)
+
+		serve(router)
+		if got := inner.Get("Allow"); got != "DELETE, GET, OPTIONS" {
+			t.Fatalf("expected Allow header on the swapped writer, got %q", got)
+		}
+	})
+}
+
+func TestFallbackErrorMeta(t *testing.T) {
+	var captured *Error
+	router := NewWithConfig(&Config{
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			captured = nil
+			errors.As(err, &captured)
+			w.WriteHeader(http.StatusTeapot)
 		},
-		{
-			name: "ResponseValidationError",
-			setup: func(s *Sprout) {
-				GET(s, "/invalid-response", func(ctx context.Context, req *EmptyRequest) (*CreateUserResponse, error) {
-					// Return response with invalid ID (must be > 0)
-					return &CreateUserResponse{
-						ID:    -1, // Invalid!
-						Name:  "Test",
-						Email: "test@example.com",
-					}, nil
+	})
+	router.HandleMethodNotAllowed = true
+	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "users"}, nil
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+	if captured == nil || captured.Kind != ErrorKindNotFound {
+		t.Fatalf("expected not found error, got %v", captured)
+	}
+	if captured.Meta["method"] != http.MethodGet || captured.Meta["path"] != "/nowhere" {
+		t.Fatalf("unexpected 404 meta: %v", captured.Meta)
+	}
+	if _, ok := captured.Meta["allowed"]; ok {
+		t.Fatalf("did not expect allowed methods on a 404")
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/users", nil))
+	if captured == nil || captured.Kind != ErrorKindMethodNotAllowed {
+		t.Fatalf("expected method not allowed error, got %v", captured)
+	}
+	allowed, _ := captured.Meta["allowed"].([]string)
+	if captured.Meta["method"] != http.MethodPut || captured.Meta["path"] != "/users" || strings.Join(allowed, ",") != "GET,OPTIONS" {
+		t.Fatalf("unexpected 405 meta: %v", captured.Meta)
+	}
+}
+
+// Test that all error kinds go through same handler
+func TestConsistentErrorHandling(t *testing.T) {
+	errorKinds := []ErrorKind{}
+
+	config := &Config{
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			var sproutErr *Error
+			if errors.As(err, &sproutErr) {
+				errorKinds = append(errorKinds, sproutErr.Kind)
+
+				w.Header().Set("Content-Type", "application/json")
+				status := http.StatusInternalServerError
+				switch sproutErr.Kind {
+				case ErrorKindParse, ErrorKindValidation:
+					status = http.StatusBadRequest
+				case ErrorKindNotFound:
+					status = http.StatusNotFound
+				case ErrorKindMethodNotAllowed:
+					status = http.StatusMethodNotAllowed
+				}
+				w.WriteHeader(status)
+				json.NewEncoder(w).Encode(map[string]string{
+					"kind":    string(sproutErr.Kind),
+					"message": sproutErr.Message,
 				})
-			},
-			request: func() *http.Request {
-				return httptest.NewRequest("GET", "/invalid-response", nil)
-			},
-			expectedKind:  ErrorKindResponseValidation,
-			expectedError: "response validation failed",
-		},
-		{
-			name: "ErrorValidationError",
-			setup: func(s *Sprout) {
-				GET(s, "/invalid-error", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
-					// Return error with missing required field
-					return nil, NotFoundError{
-						Resource: "user",
-						Message:  "", // Invalid! Message is required
-					}
-				}, WithErrors(NotFoundError{}))
-			},
-			request: func() *http.Request {
-				return httptest.NewRequest("GET", "/invalid-error", nil)
-			},
-			expectedKind:  ErrorKindErrorValidation,
-			expectedError: "error response validation failed",
+			}
 		},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			var capturedError error
+	router := NewWithConfig(config)
+	router.HandleMethodNotAllowed = true
 
-			config := &Config{
-				ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
-					capturedError = err
-					w.WriteHeader(http.StatusTeapot)
-				},
-			}
+	type BadRequest struct {
+		Page int `query:"page" validate:"required,gte=1"`
+	}
 
-			router := NewWithConfig(config)
-			tt.setup(router)
+	GET(router, "/test", func(ctx context.Context, req *BadRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
+	})
 
-			recorder := httptest.NewRecorder()
-			router.ServeHTTP(recorder, tt.request())
+	// Test 404 - goes through ErrorHandler
+	t.Run("404 NotFound", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/nonexistent", nil))
 
-			if capturedError == nil {
-				t.Fatal("expected error handler to be called")
-			}
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", recorder.Code)
+		}
 
-			// Extract Error using errors.As
-			var sproutErr *Error
-			if !errors.As(capturedError, &sproutErr) {
-				t.Fatalf("expected *Error, got %T", capturedError)
-			}
+		if recorder.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("expected JSON content type")
+		}
+	})
 
-			if sproutErr.Kind != tt.expectedKind {
-				t.Errorf("expected kind %s, got %s", tt.expectedKind, sproutErr.Kind)
-			}
+	// Test 405 - goes through ErrorHandler
+	t.Run("405 MethodNotAllowed", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/test", nil))
 
-			if !bytes.Contains([]byte(sproutErr.Message), []byte(tt.expectedError)) {
-				t.Errorf("expected error message to contain '%s', got '%s'", tt.expectedError, sproutErr.Message)
+		if recorder.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected 405, got %d", recorder.Code)
+		}
+
+		if recorder.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("expected JSON content type")
+		}
+	})
+
+	// Test 400 Validation - goes through ErrorHandler
+	t.Run("400 Validation", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/test", nil))
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", recorder.Code)
+		}
+
+		if recorder.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("expected JSON content type")
+		}
+	})
+
+	// Verify all went through the same handler
+	if len(errorKinds) != 3 {
+		t.Errorf("expected 3 errors captured, got %d", len(errorKinds))
+	}
+
+	expectedKinds := map[ErrorKind]bool{
+		ErrorKindNotFound:         true,
+		ErrorKindMethodNotAllowed: true,
+		ErrorKindValidation:       true,
+	}
+
+	for _, kind := range errorKinds {
+		if !expectedKinds[kind] {
+			t.Errorf("unexpected error kind: %s", kind)
+		}
+	}
+}
+
+// Test nil response handling with empty struct
+func TestNilResponseWithEmptyStruct(t *testing.T) {
+	router := New()
+
+	// Empty response type with no required fields
+	type EmptyResponse struct{}
+
+	// Handler returns nil, should be converted to empty struct and serialized as {}
+	DELETE(router, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*EmptyResponse, error) {
+		return nil, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("DELETE", "/users/123", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	// Should serialize as empty JSON object {}
+	var result map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to unmarshal JSON: %v", err)
+	}
+
+	if len(result) != 0 {
+		t.Errorf("expected empty JSON object {}, got %v", result)
+	}
+}
+
+// Test nil response with 204 No Content
+func TestNilResponseWithNoContent(t *testing.T) {
+	router := New()
+
+	// Empty response type with 204 status
+	type NoContentResponse struct {
+		_ struct{} `http:"status=204"`
+	}
+
+	// Handler returns nil, should serialize to {} with 204 status
+	DELETE(router, "/items/:id", func(ctx context.Context, req *EmptyRequest) (*NoContentResponse, error) {
+		return nil, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("DELETE", "/items/456", nil))
+
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	// 204 responses must not include a body
+	if recorder.Body.Len() != 0 {
+		t.Fatalf("expected empty body for 204 response, got %q", recorder.Body.String())
+	}
+}
+
+// Test nil response fails validation when response has required fields
+func TestNilResponseWithRequiredFields(t *testing.T) {
+	router := New()
+
+	// Response type with required field
+	type UserResponse struct {
+		ID int `json:"id" validate:"required,gt=0"`
+	}
+
+	// Handler returns nil, but response type has required fields
+	GET(router, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*UserResponse, error) {
+		return nil, nil // This should fail validation!
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/users/123", nil))
+
+	// Should return 500 because validation failed
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 (validation failed), got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	// Should contain validation error message
+	if !bytes.Contains(recorder.Body.Bytes(), []byte("response validation failed")) {
+		t.Errorf("expected validation error message, got: %s", recorder.Body.String())
+	}
+}
+
+// Test nil response works with optional fields (omitempty)
+func TestNilResponseWithOptionalFields(t *testing.T) {
+	router := New()
+
+	// Response type with only optional fields
+	type OptionalResponse struct {
+		Name  string `json:"name,omitempty"`
+		Email string `json:"email,omitempty"`
+	}
+
+	// Handler returns nil, all fields are optional so it should work
+	GET(router, "/optional", func(ctx context.Context, req *EmptyRequest) (*OptionalResponse, error) {
+		return nil, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/optional", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	// Should serialize as empty JSON object {} (omitempty skips zero values)
+	var result map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to unmarshal JSON: %v", err)
+	}
+
+	if len(result) != 0 {
+		t.Errorf("expected empty JSON object {}, got %v", result)
+	}
+}
+
+func TestNilIsNotFound(t *testing.T) {
+	type EmptyResponse struct{}
+
+	register := func(router *Sprout) {
+		GET(router, "/users/:id", func(ctx context.Context, req *tracedUserRequest) (*HelloResponse, error) {
+			if req.ID == "42" {
+				return &HelloResponse{Message: "found"}, nil
 			}
+			return nil, nil
+		})
+		HEAD(router, "/users/:id", func(ctx context.Context, req *tracedUserRequest) (*EmptyResponse, error) {
+			return nil, nil
+		})
+		DELETE(router, "/users/:id", func(ctx context.Context, req *tracedUserRequest) (*EmptyResponse, error) {
+			return nil, nil
+		})
+	}
+	serve := func(router *Sprout, method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	t.Run("default error", func(t *testing.T) {
+		router := NewWithConfig(&Config{NilIsNotFound: true})
+		register(router)
+
+		if rec := serve(router, http.MethodGet, "/users/42"); rec.Code != http.StatusOK {
+			t.Fatalf("expected non-nil responses to be sent, got %d", rec.Code)
+		}
+		rec := serve(router, http.MethodGet, "/users/7")
+		if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "resource not found") {
+			t.Fatalf("expected 404 for a nil response, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if rec := serve(router, http.MethodHead, "/users/7"); rec.Code != http.StatusNotFound {
+			t.Fatalf("expected 404 for HEAD, got %d", rec.Code)
+		}
+		if rec := serve(router, http.MethodDelete, "/users/7"); rec.Code != http.StatusOK {
+			t.Fatalf("expected unsafe methods to keep the empty response, got %d", rec.Code)
+		}
+	})
 
-			// Verify custom status code was used
-			if recorder.Code != http.StatusTeapot {
-				t.Errorf("expected status 418 (custom handler), got %d", recorder.Code)
-			}
+	t.Run("typed error", func(t *testing.T) {
+		router := NewWithConfig(&Config{
+			NilIsNotFound:    true,
+			NilNotFoundError: &NotFoundError{Resource: "user", Message: "no such user"},
 		})
-	}
-}
+		api := router.Mount("/api", nil)
+		register(api)
 
-// Test default error handling (no custom handler)
-func TestDefaultErrorHandling(t *testing.T) {
-	router := New() // No custom config
+		rec := serve(router, http.MethodGet, "/api/users/7")
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if body := strings.TrimSpace(rec.Body.String()); body != `{"message":"no such user","resource":"user"}` {
+			t.Fatalf("expected the configured typed body, got %s", body)
+		}
+	})
 
-	// Test handler that triggers validation error
-	POST(router, "/test", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
-		return &CreateUserResponse{
-			ID:    1,
-			Name:  req.Name,
-			Email: req.Email,
-		}, nil
+	t.Run("disabled by default", func(t *testing.T) {
+		router := New()
+		register(router)
+		if rec := serve(router, http.MethodGet, "/users/7"); rec.Code != http.StatusInternalServerError {
+			t.Fatalf("expected the empty response to fail validation as before, got %d", rec.Code)
+		}
 	})
+}
 
-	// Invalid request (name too short)
-	reqBody := CreateUserRequest{
-		Name:  "Jo",
-		Email: "john@example.com",
+func TestSproutRegisterCustomTypeFunc(t *testing.T) {
+	router := New()
+
+	type customWrapper struct {
+		Value string
 	}
-	body, _ := json.Marshal(reqBody)
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/test", bytes.NewReader(body)))
+	var called int
+	router.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		called++
+		if !field.IsValid() {
+			return nil
+		}
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				return nil
+			}
+			field = field.Elem()
+		}
+		switch v := field.Interface().(type) {
+		case customWrapper:
+			return v.Value
+		default:
+			return nil
+		}
+	}, customWrapper{}, (*customWrapper)(nil))
 
-	// Default handler should return 400 for validation errors
-	if recorder.Code != http.StatusBadRequest {
-		t.Errorf("expected status 400, got %d", recorder.Code)
+	if err := router.validate.Var(&customWrapper{Value: "bar"}, "eq=bar"); err != nil {
+		t.Fatalf("expected validation to pass, got error: %v", err)
 	}
 
-	// Default handler returns plain text error
-	if !bytes.Contains(recorder.Body.Bytes(), []byte("validation_error")) {
-		t.Logf("Response body: %s", recorder.Body.String())
+	if called == 0 {
+		t.Fatalf("expected custom type function to be called")
 	}
 }
 
-// Test unwrapping Error
-func TestErrorUnwrap(t *testing.T) {
-	underlyingErr := errors.New("underlying error")
-	sproutErr := &Error{
-		Kind:    ErrorKindParse,
-		Message: "parse failed",
-		Err:     underlyingErr,
+func TestSproutRegisterValidation(t *testing.T) {
+	router := New()
+
+	var called bool
+	if err := router.RegisterValidation("is-foo", func(fl validator.FieldLevel) bool {
+		called = true
+		return fl.Field().String() == "foo"
+	}); err != nil {
+		t.Fatalf("failed to register custom validation: %v", err)
 	}
 
-	unwrapped := sproutErr.Unwrap()
-	if unwrapped != underlyingErr {
-		t.Errorf("expected unwrapped error to be %v, got %v", underlyingErr, unwrapped)
+	type payload struct {
+		Value string `validate:"is-foo"`
 	}
-}
 
-// Test Error string formatting
-func TestErrorString(t *testing.T) {
-	tests := []struct {
-		name     string
-		err      *Error
-		expected string
-	}{
-		{
-			name: "WithUnderlyingError",
-			err: &Error{
-				Kind:    ErrorKindValidation,
-				Message: "validation failed",
-				Err:     errors.New("field 'name' is required"),
-			},
-			expected: "validation_error: validation failed: field 'name' is required",
-		},
-		{
-			name: "WithoutUnderlyingError",
-			err: &Error{
-				Kind:    ErrorKindParse,
-				Message: "parse failed",
-			},
-			expected: "parse_error: parse failed",
-		},
+	err := router.validate.Struct(&payload{Value: "bar"})
+	if err == nil {
+		t.Fatalf("expected validation error for custom validator")
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := tt.err.Error()
-			if result != tt.expected {
-				t.Errorf("expected '%s', got '%s'", tt.expected, result)
-			}
-		})
+	if !called {
+		t.Fatalf("expected custom validation to be invoked")
 	}
 }
 
-// Test strict error type checking (default behavior)
-func TestStrictErrorTypesDefault(t *testing.T) {
-	router := New() // Default: strict = true
+type optionalSearchRequest struct {
+	Tenant string `header:"X-Tenant" validate:"required"`
+	Query  string `json:"query" validate:"required"`
+	Limit  int    `json:"limit" validate:"min=1"`
+}
 
-	// Handler that returns undeclared error type
-	POST(router, "/test", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
-		if req.Name == "trigger" {
-			// Return NotFoundError, but only ConflictError is declared
-			return nil, NotFoundError{Resource: "user", Message: "user not found"}
+type optionalSearchResponse struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit"`
+}
+
+func TestWithOptionalBody(t *testing.T) {
+	router := New()
+
+	POST(router, "/search", func(ctx context.Context, req *optionalSearchRequest) (*optionalSearchResponse, error) {
+		return &optionalSearchResponse{Query: req.Query, Limit: req.Limit}, nil
+	}, WithOptionalBody())
+
+	t.Run("missing body skips body validation", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/search", nil)
+		req.Header.Set("X-Tenant", "acme")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
 		}
-		return &CreateUserResponse{ID: 1, Name: req.Name, Email: req.Email}, nil
-	}, WithErrors(ConflictError{})) // Only ConflictError declared, NOT NotFoundError
+	})
 
-	reqBody := CreateUserRequest{
-		Name:  "trigger",
-		Email: "test@example.com",
-	}
-	body, _ := json.Marshal(reqBody)
+	t.Run("parameters are still validated", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/search", nil))
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/test", bytes.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400 for missing header, got %d", rec.Code)
+		}
+	})
 
-	// Should return 500 because error type not declared and strict mode is on
-	if recorder.Code != http.StatusInternalServerError {
-		t.Errorf("expected status 500 (strict mode), got %d", recorder.Code)
-	}
+	t.Run("present body is validated", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/search", strings.NewReader(`{"limit":5}`))
+		req.Header.Set("X-Tenant", "acme")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
 
-	if !bytes.Contains(recorder.Body.Bytes(), []byte("undeclared_error_type")) {
-		t.Errorf("expected 'undeclared_error_type' in response, got: %s", recorder.Body.String())
-	}
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400 for body missing query, got %d", rec.Code)
+		}
+	})
 }
 
-// Test strict error type checking disabled
-func TestStrictErrorTypesDisabled(t *testing.T) {
-	falseVal := false
-	config := &Config{
-		StrictErrorTypes: &falseVal,
-	}
-	router := NewWithConfig(config)
+type userV1 struct {
+	Name string `json:"name"`
+}
 
-	// Handler that returns undeclared error type
-	POST(router, "/test", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
-		if req.Name == "trigger" {
-			// Return NotFoundError, but only ConflictError is declared
-			return nil, NotFoundError{Resource: "user", Message: "user not found"}
-		}
-		return &CreateUserResponse{ID: 1, Name: req.Name, Email: req.Email}, nil
-	}, WithErrors(ConflictError{})) // Only ConflictError declared
+type userV2 struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
 
-	reqBody := CreateUserRequest{
-		Name:  "trigger",
-		Email: "test@example.com",
-	}
-	body, _ := json.Marshal(reqBody)
+func TestAcceptVersionedHandlers(t *testing.T) {
+	router := New()
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/test", bytes.NewReader(body)))
+	GET(router, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*userV1, error) {
+		return &userV1{Name: "Ada Lovelace"}, nil
+	})
+	GET(router, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*userV2, error) {
+		return &userV2{FirstName: "Ada", LastName: "Lovelace"}, nil
+	}, WithProduces("application/vnd.myapi.v2+json"))
+
+	cases := []struct {
+		name        string
+		accept      string
+		contentType string
+		field       string
+	}{
+		{name: "no accept header", accept: "", contentType: "application/json", field: "name"},
+		{name: "wildcard", accept: "*/*", contentType: "application/json", field: "name"},
+		{name: "explicit default", accept: "application/json", contentType: "application/json", field: "name"},
+		{name: "v2", accept: "application/vnd.myapi.v2+json", contentType: "application/vnd.myapi.v2+json", field: "first_name"},
+		{name: "quality ordering", accept: "application/json;q=0.5, application/vnd.myapi.v2+json", contentType: "application/vnd.myapi.v2+json", field: "first_name"},
+		{name: "unknown falls back", accept: "application/vnd.myapi.v9+json", contentType: "application/json", field: "name"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
 
-	// Should return 404 (error's status code) because strict mode is off
-	// The undeclared error is allowed with just a warning
-	if recorder.Code != http.StatusNotFound {
-		t.Errorf("expected status 404 (error's status), got %d: %s", recorder.Code, recorder.Body.String())
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != tc.contentType {
+				t.Fatalf("expected content type %q, got %q", tc.contentType, ct)
+			}
+			if vary := rec.Header().Get("Vary"); vary != "Accept" {
+				t.Fatalf("expected Vary: Accept, got %q", vary)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if _, ok := body[tc.field]; !ok {
+				t.Fatalf("expected field %q in body, got %v", tc.field, body)
+			}
+		})
 	}
+}
 
-	// Should still get valid error response
-	var errResp NotFoundError
-	if err := json.NewDecoder(recorder.Body).Decode(&errResp); err != nil {
-		t.Fatalf("failed to decode error response: %v", err)
-	}
+func TestAcceptVersionedHandlersDuplicatePanics(t *testing.T) {
+	router := New()
 
-	if errResp.Resource != "user" {
-		t.Errorf("expected resource 'user', got '%s'", errResp.Resource)
-	}
+	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*userV1, error) {
+		return &userV1{}, nil
+	})
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected duplicate media type registration to panic")
+		}
+	}()
+	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*userV2, error) {
+		return &userV2{}, nil
+	})
 }
 
-// Test declared errors work in strict mode
-func TestStrictErrorTypesDeclared(t *testing.T) {
-	router := New() // Default: strict = true
+type variedUserResponse struct {
+	Vary string `header:"Vary"`
+	Name string `json:"name"`
+}
 
-	// Handler with properly declared error types
-	POST(router, "/test", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
-		if req.Name == "notfound" {
-			return nil, NotFoundError{Resource: "user", Message: "user not found"}
+func TestVaryHeaderMerging(t *testing.T) {
+	router := NewWithConfig(&Config{
+		DefaultResponseHeaders: map[string]string{"Vary": "Origin"},
+	})
+
+	GET(router, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*variedUserResponse, error) {
+		return &variedUserResponse{Vary: "Cookie, accept", Name: "Ada Lovelace"}, nil
+	})
+	GET(router, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*userV2, error) {
+		return &userV2{FirstName: "Ada", LastName: "Lovelace"}, nil
+	}, WithProduces("application/vnd.myapi.v2+json"))
+	GET(router, "/single", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "hi"}, nil
+	})
+
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
 		}
-		if req.Name == "conflict" {
-			return nil, ConflictError{Field: "email", Message: "email exists"}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("default and field values are merged", func(t *testing.T) {
+		rec := serve("/users/1", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
 		}
-		return &CreateUserResponse{ID: 1, Name: req.Name, Email: req.Email}, nil
-	}, WithErrors(NotFoundError{}, ConflictError{})) // Both error types declared
+		if got := rec.Header().Values("Vary"); !reflect.DeepEqual(got, []string{"Accept", "Origin", "Cookie"}) {
+			t.Fatalf("expected merged Vary values, got %q", got)
+		}
+	})
 
-	// Test NotFoundError (declared)
-	t.Run("NotFound", func(t *testing.T) {
-		reqBody := CreateUserRequest{
-			Name:  "notfound",
-			Email: "test@example.com",
+	t.Run("negotiated variant keeps default", func(t *testing.T) {
+		rec := serve("/users/1", "application/vnd.myapi.v2+json")
+		if got := rec.Header().Values("Vary"); !reflect.DeepEqual(got, []string{"Accept", "Origin"}) {
+			t.Fatalf("expected Vary: Accept and Origin, got %q", got)
 		}
-		body, _ := json.Marshal(reqBody)
+	})
+
+	t.Run("single variant does not vary on accept", func(t *testing.T) {
+		rec := serve("/single", "application/json")
+		if got := rec.Header().Values("Vary"); !reflect.DeepEqual(got, []string{"Origin"}) {
+			t.Fatalf("expected only the default Vary value, got %q", got)
+		}
+	})
+}
+
+func TestDefaultStatusByMethod(t *testing.T) {
+	router := NewWithConfig(&Config{
+		DefaultStatusByMethod: map[string]int{
+			http.MethodPost:   http.StatusCreated,
+			http.MethodDelete: http.StatusNoContent,
+		},
+	})
+
+	POST(router, "/items", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "created"}, nil
+	})
+	POST(router, "/jobs", func(ctx context.Context, req *EmptyRequest) (*AcceptedResponse, error) {
+		return &AcceptedResponse{JobID: "1", Message: "queued"}, nil
+	})
+	DELETE(router, "/items/:id", func(ctx context.Context, req *EmptyRequest) (*struct{}, error) {
+		return nil, nil
+	})
+	GET(router, "/items", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "list"}, nil
+	})
 
-		recorder := httptest.NewRecorder()
-		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/test", bytes.NewReader(body)))
+	api := router.Mount("/api", nil)
+	POST(api, "/items", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "created"}, nil
+	})
 
-		if recorder.Code != http.StatusNotFound {
-			t.Errorf("expected status 404, got %d: %s", recorder.Code, recorder.Body.String())
+	cases := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodPost, "/items", http.StatusCreated},
+		{http.MethodPost, "/jobs", http.StatusAccepted},
+		{http.MethodDelete, "/items/1", http.StatusNoContent},
+		{http.MethodGet, "/items", http.StatusOK},
+		{http.MethodPost, "/api/items", http.StatusCreated},
+	}
+
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != tc.status {
+			t.Fatalf("%s %s: expected status %d, got %d", tc.method, tc.path, tc.status, rec.Code)
 		}
+	}
+}
 
-		var errResp NotFoundError
-		if err := json.NewDecoder(recorder.Body).Decode(&errResp); err != nil {
-			t.Fatalf("failed to decode error response: %v", err)
-		}
+type MovedError struct {
+	_        struct{} `http:"status=301"`
+	Location string   `header:"Location" validate:"required"`
+}
 
-		if errResp.Resource != "user" {
-			t.Errorf("expected resource 'user', got '%s'", errResp.Resource)
-		}
-	})
+func (e *MovedError) Error() string { return "moved to " + e.Location }
 
-	// Test ConflictError (declared)
-	t.Run("Conflict", func(t *testing.T) {
-		reqBody := CreateUserRequest{
-			Name:  "conflict",
-			Email: "test@example.com",
-		}
-		body, _ := json.Marshal(reqBody)
+type FoundError struct {
+	_        struct{} `http:"status=302"`
+	Location string   `header:"Location" validate:"required"`
+}
 
-		recorder := httptest.NewRecorder()
-		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/test", bytes.NewReader(body)))
+func (e *FoundError) Error() string { return "found at " + e.Location }
 
-		if recorder.Code != http.StatusConflict {
-			t.Errorf("expected status 409, got %d: %s", recorder.Code, recorder.Body.String())
-		}
+type TemporaryRedirectError struct {
+	_        struct{} `http:"status=307"`
+	Location string   `header:"Location" validate:"required"`
+}
 
-		var errResp ConflictError
-		if err := json.NewDecoder(recorder.Body).Decode(&errResp); err != nil {
-			t.Fatalf("failed to decode error response: %v", err)
-		}
+func (e *TemporaryRedirectError) Error() string { return "temporarily at " + e.Location }
 
-		if errResp.Field != "email" {
-			t.Errorf("expected field 'email', got '%s'", errResp.Field)
-		}
-	})
+type PermanentRedirectError struct {
+	_        struct{} `http:"status=308"`
+	Location string   `header:"Location" validate:"required"`
+	Reason   string   `json:"reason,omitempty"`
 }
 
-// Test explicitly enabling strict error types
-func TestStrictErrorTypesExplicitlyEnabled(t *testing.T) {
-	trueVal := true
-	config := &Config{
-		StrictErrorTypes: &trueVal,
-	}
-	router := NewWithConfig(config)
+func (e *PermanentRedirectError) Error() string { return "permanently at " + e.Location }
 
-	// Handler that returns undeclared error type
-	POST(router, "/test", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
-		if req.Name == "trigger" {
-			return nil, NotFoundError{Resource: "user", Message: "user not found"}
-		}
-		return &CreateUserResponse{ID: 1, Name: req.Name, Email: req.Email}, nil
-	}, WithErrors(ConflictError{})) // Only ConflictError declared
+func TestTypedRedirectErrors(t *testing.T) {
+	router := NewWithConfig(&Config{ErrorEnvelope: NewProblemDetails})
 
-	reqBody := CreateUserRequest{
-		Name:  "trigger",
-		Email: "test@example.com",
+	errs := map[string]error{
+		"/301": &MovedError{Location: "/new/301"},
+		"/302": &FoundError{Location: "/new/302"},
+		"/307": &TemporaryRedirectError{Location: "/new/307"},
+		"/308": &PermanentRedirectError{Location: "/new/308"},
+	}
+	for path, err := range errs {
+		err := err
+		GET(router, path, func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+			return nil, err
+		}, WithErrors(&MovedError{}, &FoundError{}, &TemporaryRedirectError{}, &PermanentRedirectError{}))
 	}
-	body, _ := json.Marshal(reqBody)
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/test", bytes.NewReader(body)))
+	for _, status := range []int{301, 302, 307, 308} {
+		t.Run(strconv.Itoa(status), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+strconv.Itoa(status), nil))
 
-	// Should return 500 because error type not declared and strict mode is explicitly on
-	if recorder.Code != http.StatusInternalServerError {
-		t.Errorf("expected status 500 (strict mode), got %d", recorder.Code)
+			if rec.Code != status {
+				t.Fatalf("expected status %d, got %d", status, rec.Code)
+			}
+			if loc := rec.Header().Get("Location"); loc != "/new/"+strconv.Itoa(status) {
+				t.Fatalf("expected Location header, got %q", loc)
+			}
+			if rec.Body.Len() != 0 {
+				t.Fatalf("expected empty body, got %q", rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "" {
+				t.Fatalf("expected no Content-Type without a body, got %q", ct)
+			}
+		})
 	}
+}
 
-	if !bytes.Contains(recorder.Body.Bytes(), []byte("undeclared_error_type")) {
-		t.Errorf("expected 'undeclared_error_type' in response, got: %s", recorder.Body.String())
+func TestTypedRedirectErrorWithBody(t *testing.T) {
+	router := New()
+
+	GET(router, "/old", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, &PermanentRedirectError{Location: "/new", Reason: "renamed"}
+	}, WithErrors(&PermanentRedirectError{}))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/old", nil))
+
+	if rec.Code != http.StatusPermanentRedirect {
+		t.Fatalf("expected status 308, got %d", rec.Code)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/new" {
+		t.Fatalf("expected Location header, got %q", loc)
+	}
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"reason":"renamed"}` {
+		t.Fatalf("expected body fields to be written, got %q", body)
 	}
 }
 
-// Test custom error handler can intercept undeclared error types
-func TestStrictErrorTypesCustomHandler(t *testing.T) {
-	var capturedErrorKind ErrorKind
+type cachedHeaderResponse struct {
+	CacheControl string `header:"Cache-Control"`
+	Message      string `json:"message"`
+}
 
-	config := &Config{
-		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
-			// Extract ErrorKind
-			var sproutErr *Error
-			if errors.As(err, &sproutErr) {
-				capturedErrorKind = sproutErr.Kind
-			}
+func TestWithCache(t *testing.T) {
+	router := NewWithConfig(&Config{
+		DefaultResponseHeaders: map[string]string{"Cache-Control": "no-cache"},
+	})
 
-			// Custom handling for undeclared errors
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadGateway) // Use 502 to distinguish custom handling
-			json.NewEncoder(w).Encode(map[string]string{
-				"custom_handling": "true",
-				"error_kind":      string(capturedErrorKind),
-			})
-		},
+	hello := func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "ok"}, nil
 	}
 
-	router := NewWithConfig(config)
+	GET(router, "/public", hello, WithCache(5*time.Minute))
+	GET(router, "/private", hello, WithCache(90*time.Second, CachePrivate()))
+	GET(router, "/nostore", hello, WithCache(time.Hour, CacheNoStore()))
+	HEAD(router, "/public", hello, WithCache(time.Minute))
+	POST(router, "/public", hello, WithCache(time.Minute))
+	GET(router, "/override", func(ctx context.Context, req *EmptyRequest) (*cachedHeaderResponse, error) {
+		return &cachedHeaderResponse{CacheControl: "max-age=1", Message: "ok"}, nil
+	}, WithCache(time.Minute))
+	GET(router, "/middleware", hello, WithCache(time.Minute), WithMiddleware(func(w http.ResponseWriter, r *http.Request, next Next) {
+		w.Header().Set("Cache-Control", "private, max-age=10")
+		next(nil)
+	}))
+	GET(router, "/failing", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, &TeapotError{Msg: "nope"}
+	}, WithCache(time.Minute), WithErrors(&TeapotError{}))
+
+	cases := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/public", "public, max-age=300"},
+		{http.MethodGet, "/private", "private, max-age=90"},
+		{http.MethodGet, "/nostore", "no-store"},
+		{http.MethodHead, "/public", "public, max-age=60"},
+		{http.MethodPost, "/public", "no-cache"},
+		{http.MethodGet, "/override", "max-age=1"},
+		{http.MethodGet, "/middleware", "private, max-age=10"},
+		{http.MethodGet, "/failing", "no-cache"},
+	}
+
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if got := rec.Header().Get("Cache-Control"); got != tc.want {
+			t.Fatalf("%s %s: expected Cache-Control %q, got %q", tc.method, tc.path, tc.want, got)
+		}
+	}
+}
 
-	// Handler that returns undeclared error type
-	POST(router, "/test", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
-		if req.Name == "trigger" {
-			return nil, NotFoundError{Resource: "user", Message: "user not found"}
+type proxyFilters struct {
+	Region string `query:"region"`
+}
+
+type proxyRequest struct {
+	Page    int `query:"page"`
+	Filters proxyFilters
+	Rest    url.Values `sprout:"queryrest"`
+	Name    string     `json:"name"`
+}
+
+type proxyResponse struct {
+	Page   int                 `json:"page"`
+	Region string              `json:"region"`
+	Rest   map[string][]string `json:"rest"`
+}
+
+func TestQueryRest(t *testing.T) {
+	router := New()
+
+	GET(router, "/proxy", func(ctx context.Context, req *proxyRequest) (*proxyResponse, error) {
+		return &proxyResponse{Page: req.Page, Region: req.Filters.Region, Rest: req.Rest}, nil
+	})
+
+	t.Run("collects unbound keys", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/proxy?page=2&region=eu&tag=a&tag=b&sort=name", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
 		}
-		return &CreateUserResponse{ID: 1, Name: req.Name, Email: req.Email}, nil
-	}, WithErrors(ConflictError{})) // Only ConflictError declared
+		var resp proxyResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Page != 2 || resp.Region != "eu" {
+			t.Fatalf("expected explicit params to bind, got %+v", resp)
+		}
+		want := map[string][]string{"tag": {"a", "b"}, "sort": {"name"}}
+		if !reflect.DeepEqual(resp.Rest, want) {
+			t.Fatalf("expected rest %v, got %v", want, resp.Rest)
+		}
+	})
 
-	reqBody := CreateUserRequest{
-		Name:  "trigger",
-		Email: "test@example.com",
-	}
-	body, _ := json.Marshal(reqBody)
+	t.Run("nil without unbound keys", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/proxy?page=1", nil))
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/test", bytes.NewReader(body)))
+		if body := rec.Body.String(); !strings.Contains(body, `"rest":null`) {
+			t.Fatalf("expected rest to stay nil, got %s", body)
+		}
+	})
+}
 
-	// Verify custom handler was invoked
-	if recorder.Code != http.StatusBadGateway {
-		t.Errorf("expected status 502 (custom handler), got %d", recorder.Code)
+type gatewayRequest struct {
+	Rest     string `path:"rest"`
+	FullPath string `sprout:"fullpath"`
+	PathRest string `sprout:"pathrest"`
+	Name     string `json:"name"`
+}
+
+type gatewayResponse struct {
+	Rest     string `json:"rest"`
+	FullPath string `json:"full_path"`
+	PathRest string `json:"path_rest"`
+}
+
+func TestPathFields(t *testing.T) {
+	router := NewWithConfig(&Config{BasePath: "/api"})
+	gateway := router.Mount("/gateway", nil)
+
+	handler := func(ctx context.Context, req *gatewayRequest) (*gatewayResponse, error) {
+		return &gatewayResponse{Rest: req.Rest, FullPath: req.FullPath, PathRest: req.PathRest}, nil
 	}
+	GET(gateway, "/*rest", handler)
+	POST(router, "/echo", handler)
 
-	// Verify error kind was captured
-	if capturedErrorKind != ErrorKindUndeclaredError {
-		t.Errorf("expected ErrorKindUndeclaredError, got %s", capturedErrorKind)
+	serve := func(method, path, body string) gatewayResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp gatewayResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
 	}
 
-	// Verify custom response body
-	var resp map[string]string
-	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
+	resp := serve(http.MethodGet, "/api/gateway/users/42?x=1", "")
+	if resp.FullPath != "/api/gateway/users/42" {
+		t.Fatalf("expected full request path, got %q", resp.FullPath)
+	}
+	if resp.PathRest != "/users/42" || resp.Rest != "/users/42" {
+		t.Fatalf("expected path after mount prefix, got %+v", resp)
 	}
 
-	if resp["custom_handling"] != "true" {
-		t.Errorf("expected custom_handling 'true', got '%s'", resp["custom_handling"])
+	resp = serve(http.MethodGet, "/api/gateway/", "")
+	if resp.PathRest != "/" {
+		t.Fatalf("expected / for the mount root, got %q", resp.PathRest)
 	}
 
-	if resp["error_kind"] != "undeclared_error_type" {
-		t.Errorf("expected error_kind 'undeclared_error_type', got '%s'", resp["error_kind"])
+	resp = serve(http.MethodPost, "/api/echo", `{"name":"x","FullPath":"/spoofed"}`)
+	if resp.FullPath != "/api/echo" || resp.PathRest != "/echo" {
+		t.Fatalf("expected path fields bound from the URL only, got %+v", resp)
 	}
 }
 
-// Test base path functionality
-func TestBasePath(t *testing.T) {
-	config := &Config{
-		BasePath: "/api/v1",
+func TestStopOnFirstValidationError(t *testing.T) {
+	type twoFieldResponse struct {
+		A string `json:"a" validate:"required"`
+		B string `json:"b" validate:"required"`
 	}
-	router := NewWithConfig(config)
+
+	var captured []error
+	router := NewWithConfig(&Config{
+		StopOnFirstValidationError: true,
+		RequestValidationError: func(errs validator.ValidationErrors) error {
+			fields := make([]string, 0, len(errs))
+			for _, fe := range errs {
+				fields = append(fields, fe.Field())
+			}
+			return &FieldValidationError{Code: "INVALID_REQUEST", Fields: fields}
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			captured = append(captured, err)
+			w.WriteHeader(http.StatusInternalServerError)
+		},
+	})
 
 	POST(router, "/users", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
-		return &CreateUserResponse{
-			ID:    123,
-			Name:  req.Name,
-			Email: req.Email,
-		}, nil
+		return &CreateUserResponse{ID: 1, Name: req.Name, Email: req.Email}, nil
+	})
+	GET(router, "/broken", func(ctx context.Context, req *EmptyRequest) (*twoFieldResponse, error) {
+		return &twoFieldResponse{}, nil
 	})
 
-	// Request should be made to /api/v1/users, not /users
-	reqBody := CreateUserRequest{
-		Name:  "John Doe",
-		Email: "john@example.com",
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"Jo","email":"nope"}`)))
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body FieldValidationError
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if len(body.Fields) != 1 {
+		t.Fatalf("expected only the first failing field, got %v", body.Fields)
 	}
-	body, _ := json.Marshal(reqBody)
 
-	// Request to base path should work
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/api/v1/users", bytes.NewReader(body)))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/broken", nil))
 
-	if recorder.Code != http.StatusOK {
-		t.Errorf("expected status OK for /api/v1/users, got %d: %s", recorder.Code, recorder.Body.String())
+	if len(captured) != 1 {
+		t.Fatalf("expected response validation error to reach the handler, got %v", captured)
+	}
+	var validationErrs validator.ValidationErrors
+	if !errors.As(captured[0], &validationErrs) || len(validationErrs) != 1 {
+		t.Fatalf("expected a single response validation error, got %v", captured[0])
 	}
+}
 
-	// Request to route without base path should NOT work
-	recorder = httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/users", bytes.NewReader(body)))
+type renderRequest struct {
+	Name     string `path:"name"`
+	Template string `sprout:"textbody" validate:"required,max=64"`
+}
 
-	if recorder.Code != http.StatusNotFound {
-		t.Errorf("expected status 404 for /users (no base path), got %d", recorder.Code)
-	}
+type renderResponse struct {
+	Cache string `header:"Cache-Control"`
+	Text  string `sprout:"textbody" validate:"required"`
 }
 
-// Test base path with trailing slash
-func TestBasePathWithTrailingSlash(t *testing.T) {
-	config := &Config{
-		BasePath: "/api/v1/", // Trailing slash should be handled
-	}
-	router := NewWithConfig(config)
+func TestTextPlainBodies(t *testing.T) {
+	router := New()
 
-	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
-		return &HelloResponse{Message: "success"}, nil
+	POST(router, "/render/:name", func(ctx context.Context, req *renderRequest) (*renderResponse, error) {
+		return &renderResponse{Cache: "no-cache", Text: strings.ReplaceAll(req.Template, "{{name}}", req.Name)}, nil
+	})
+	GET(router, "/empty", func(ctx context.Context, req *EmptyRequest) (*renderResponse, error) {
+		return &renderResponse{}, nil
 	})
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/v1/users", nil))
+	t.Run("round trip", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/render/ada", strings.NewReader("Hello, {{name}}!"))
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
 
-	if recorder.Code != http.StatusOK {
-		t.Errorf("expected status OK, got %d", recorder.Code)
-	}
-}
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if body := rec.Body.String(); body != "Hello, ada!" {
+			t.Fatalf("expected raw text body, got %q", body)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+			t.Fatalf("expected text/plain content type, got %q", ct)
+		}
+		if cc := rec.Header().Get("Cache-Control"); cc != "no-cache" {
+			t.Fatalf("expected header fields to still apply, got %q", cc)
+		}
+	})
 
-// Test base path without leading slash
-func TestBasePathWithoutLeadingSlash(t *testing.T) {
-	config := &Config{
-		BasePath: "api/v1", // Missing leading slash should be handled
-	}
-	router := NewWithConfig(config)
+	t.Run("request validation runs", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/render/ada", strings.NewReader(strings.Repeat("x", 65)))
+		req.Header.Set("Content-Type", "text/plain")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
 
-	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
-		return &HelloResponse{Message: "success"}, nil
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rec.Code)
+		}
 	})
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/v1/users", nil))
+	t.Run("other content types are not bound", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/render/ada", strings.NewReader(`{"template":"x"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
 
-	if recorder.Code != http.StatusOK {
-		t.Errorf("expected status OK, got %d", recorder.Code)
-	}
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400 for missing text body, got %d", rec.Code)
+		}
+	})
+
+	t.Run("response validation runs", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/empty", nil))
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("expected status 500, got %d", rec.Code)
+		}
+	})
 }
 
-// Test empty base path
-func TestEmptyBasePath(t *testing.T) {
-	config := &Config{
-		BasePath: "", // Empty base path should work like New()
-	}
-	router := NewWithConfig(config)
+type deepFilterRequest struct {
+	Filter map[string]string   `query:"filter"`
+	Limits map[string]int      `query:"limit"`
+	Rest   map[string][]string `sprout:"queryrest"`
+}
+
+type deepFilterResponse struct {
+	Filter map[string]string   `json:"filter"`
+	Limits map[string]int      `json:"limits"`
+	Rest   map[string][]string `json:"rest"`
+}
+
+func TestDeepObjectQueryParameters(t *testing.T) {
+	router := New()
 
-	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
-		return &HelloResponse{Message: "success"}, nil
+	GET(router, "/users", func(ctx context.Context, req *deepFilterRequest) (*deepFilterResponse, error) {
+		return &deepFilterResponse{Filter: req.Filter, Limits: req.Limits, Rest: req.Rest}, nil
 	})
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/users", nil))
-
-	if recorder.Code != http.StatusOK {
-		t.Errorf("expected status OK, got %d", recorder.Code)
+	serve := func(target string) (*httptest.ResponseRecorder, deepFilterResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var resp deepFilterResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return rec, resp
 	}
-}
 
-// Test base path with path parameters
-func TestBasePathWithPathParams(t *testing.T) {
-	config := &Config{
-		BasePath: "/api/v1",
-	}
-	router := NewWithConfig(config)
+	t.Run("bracket entries fill the map", func(t *testing.T) {
+		rec, resp := serve("/users?filter%5Bstatus%5D=active&filter[role]=admin&filter[empty]=&limit[page]=20&sort=name")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		want := map[string]string{"status": "active", "role": "admin", "empty": ""}
+		if !reflect.DeepEqual(resp.Filter, want) {
+			t.Fatalf("expected filter %v, got %v", want, resp.Filter)
+		}
+		if resp.Limits["page"] != 20 {
+			t.Fatalf("expected typed map values, got %v", resp.Limits)
+		}
+		if !reflect.DeepEqual(resp.Rest, map[string][]string{"sort": {"name"}}) {
+			t.Fatalf("expected only unbound keys in queryrest, got %v", resp.Rest)
+		}
+	})
 
-	type GetUserByIDRequest struct {
-		UserID string `path:"id" validate:"required"`
-	}
+	t.Run("missing entries leave the map nil", func(t *testing.T) {
+		rec, resp := serve("/users?filter=plain&filter[]=x&filter[a][b]=y")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if resp.Filter != nil {
+			t.Fatalf("expected nil filter, got %v", resp.Filter)
+		}
+	})
 
-	GET(router, "/users/:id", func(ctx context.Context, req *GetUserByIDRequest) (*HelloResponse, error) {
-		return &HelloResponse{Message: "User ID: " + req.UserID}, nil
+	t.Run("invalid values are parse errors", func(t *testing.T) {
+		rec, _ := serve("/users?limit[page]=many")
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), "limit[page]") {
+			t.Fatalf("expected error to name the parameter, got %s", rec.Body.String())
+		}
 	})
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/v1/users/123", nil))
+	t.Run("first invalid key is deterministic", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			rec, _ := serve("/users?limit[size]=big&limit[page]=many&limit[offset]=far")
+			if !strings.Contains(rec.Body.String(), "limit[offset]") {
+				t.Fatalf("expected the error to name the first key in order, got %s", rec.Body.String())
+			}
+		}
+	})
+}
 
-	if recorder.Code != http.StatusOK {
-		t.Errorf("expected status OK, got %d: %s", recorder.Code, recorder.Body.String())
-	}
+type envelopeUserRequest struct {
+	Tenant string `header:"X-Tenant"`
+	Name   string `json:"name" validate:"required"`
+}
 
-	var resp HelloResponse
-	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
+type envelopeUserResponse struct {
+	Location string `header:"Location"`
+	Name     string `json:"name"`
+	Tenant   string `json:"tenant"`
+}
+
+func TestEnvelopeKeys(t *testing.T) {
+	router := NewWithConfig(&Config{RequestEnvelopeKey: "data", ResponseEnvelopeKey: "data"})
+	api := router.Mount("/api", nil)
+
+	handler := func(ctx context.Context, req *envelopeUserRequest) (*envelopeUserResponse, error) {
+		return &envelopeUserResponse{Location: "/users/1", Name: req.Name, Tenant: req.Tenant}, nil
 	}
+	POST(router, "/users", handler)
+	POST(api, "/users", handler)
+	GET(router, "/text", func(ctx context.Context, req *EmptyRequest) (*renderResponse, error) {
+		return &renderResponse{Text: "plain"}, nil
+	})
 
-	if resp.Message != "User ID: 123" {
-		t.Errorf("expected message 'User ID: 123', got '%s'", resp.Message)
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Tenant", "acme")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
 	}
-}
 
-// Test multiple routes with base path
-func TestMultipleRoutesWithBasePath(t *testing.T) {
-	config := &Config{
-		BasePath: "/api/v1",
+	for _, path := range []string{"/users", "/api/users"} {
+		t.Run("round trip "+path, func(t *testing.T) {
+			rec := post(path, `{"data":{"name":"Ada"}}`)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			want := `{"data":{"name":"Ada","tenant":"acme"}}`
+			if body := strings.TrimSpace(rec.Body.String()); body != want {
+				t.Fatalf("expected %s, got %s", want, body)
+			}
+			if loc := rec.Header().Get("Location"); loc != "/users/1" {
+				t.Fatalf("expected header fields to stay outside the envelope, got %q", loc)
+			}
+		})
 	}
-	router := NewWithConfig(config)
 
-	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
-		return &HelloResponse{Message: "users"}, nil
+	t.Run("missing key is a parse error", func(t *testing.T) {
+		rec := post("/users", `{"name":"Ada"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), `wrapped in "data"`) {
+			t.Fatalf("expected envelope error message, got %s", rec.Body.String())
+		}
 	})
 
-	POST(router, "/users", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
-		return &CreateUserResponse{
-			ID:    1,
-			Name:  req.Name,
-			Email: req.Email,
-		}, nil
+	t.Run("inner body is validated", func(t *testing.T) {
+		rec := post("/users", `{"data":{}}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rec.Code)
+		}
+		if strings.Contains(rec.Body.String(), `"data"`) {
+			t.Fatalf("expected error responses to stay unwrapped, got %s", rec.Body.String())
+		}
 	})
 
-	GET(router, "/items", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
-		return &HelloResponse{Message: "items"}, nil
+	t.Run("text responses are not wrapped", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/text", nil))
+		if body := rec.Body.String(); body != "plain" {
+			t.Fatalf("expected raw text body, got %q", body)
+		}
 	})
+}
 
-	// Test GET /api/v1/users
-	t.Run("GET /users", func(t *testing.T) {
-		recorder := httptest.NewRecorder()
-		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/v1/users", nil))
+type responseMeta struct {
+	Status int `json:"status"`
+}
 
-		if recorder.Code != http.StatusOK {
-			t.Errorf("expected status OK, got %d", recorder.Code)
-		}
+type responseEnvelope struct {
+	Version string       `header:"X-API-Version"`
+	Data    any          `json:"data"`
+	Meta    responseMeta `json:"meta"`
+}
 
-		var resp HelloResponse
-		json.NewDecoder(recorder.Body).Decode(&resp)
-		if resp.Message != "users" {
-			t.Errorf("expected 'users', got '%s'", resp.Message)
-		}
+func TestResponseEnvelope(t *testing.T) {
+	router := NewWithConfig(&Config{
+		DefaultStatusByMethod: map[string]int{http.MethodPost: http.StatusCreated},
+		ResponseEnvelope: func(body any, status int) any {
+			return responseEnvelope{Version: "2", Data: body, Meta: responseMeta{Status: status}}
+		},
 	})
 
-	// Test POST /api/v1/users
-	t.Run("POST /users", func(t *testing.T) {
-		reqBody := CreateUserRequest{Name: "John", Email: "john@example.com"}
-		body, _ := json.Marshal(reqBody)
+	POST(router, "/users", func(ctx context.Context, req *envelopeUserRequest) (*envelopeUserResponse, error) {
+		return &envelopeUserResponse{Location: "/users/1", Name: req.Name}, nil
+	}, WithErrors(ConflictError{}))
+	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*ListUsersEnvelope, error) {
+		return &ListUsersEnvelope{Users: []ListUsersResponse{{ID: 1, Email: "ada@example.com"}}}, nil
+	})
+	GET(router, "/conflict", func(ctx context.Context, req *EmptyRequest) (*envelopeUserResponse, error) {
+		return nil, ConflictError{Field: "name", Message: "taken"}
+	}, WithErrors(ConflictError{}))
 
-		recorder := httptest.NewRecorder()
-		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/api/v1/users", bytes.NewReader(body)))
+	t.Run("success bodies are wrapped", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"Ada"}`))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
 
-		if recorder.Code != http.StatusOK {
-			t.Errorf("expected status OK, got %d", recorder.Code)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		want := `{"data":{"name":"Ada","tenant":""},"meta":{"status":201}}`
+		if body := strings.TrimSpace(rec.Body.String()); body != want {
+			t.Fatalf("expected %s, got %s", want, body)
+		}
+		if rec.Header().Get("Location") != "/users/1" || rec.Header().Get("X-API-Version") != "2" {
+			t.Fatalf("expected DTO and envelope headers, got %v", rec.Header())
 		}
 	})
 
-	// Test GET /api/v1/items
-	t.Run("GET /items", func(t *testing.T) {
-		recorder := httptest.NewRecorder()
-		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/v1/items", nil))
+	t.Run("unwrapped bodies are wrapped", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
 
-		if recorder.Code != http.StatusOK {
-			t.Errorf("expected status OK, got %d", recorder.Code)
+		want := `{"data":[{"id":1,"email":"ada@example.com"}],"meta":{"status":200}}`
+		if body := strings.TrimSpace(rec.Body.String()); body != want {
+			t.Fatalf("expected %s, got %s", want, body)
 		}
+	})
 
-		var resp HelloResponse
-		json.NewDecoder(recorder.Body).Decode(&resp)
-		if resp.Message != "items" {
-			t.Errorf("expected 'items', got '%s'", resp.Message)
+	t.Run("errors are not wrapped", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/conflict", nil))
+
+		if rec.Code != http.StatusConflict || strings.Contains(rec.Body.String(), `"data"`) {
+			t.Fatalf("expected unwrapped 409, got %d: %s", rec.Code, rec.Body.String())
 		}
 	})
 }
 
-func TestNestedRouterMountsPrefix(t *testing.T) {
+type ticketStatus string
+
+type ticketPriority int
+
+type ticketListRequest struct {
+	Queue    ticketStatus   `path:"queue" validate:"oneof=open closed"`
+	Status   ticketStatus   `query:"status" validate:"omitempty,oneof=open closed"`
+	Priority ticketPriority `query:"priority" validate:"omitempty,oneof=1 2 3"`
+}
+
+type ticketListResponse struct {
+	Queue    ticketStatus   `json:"queue"`
+	Status   ticketStatus   `json:"status"`
+	Priority ticketPriority `json:"priority"`
+}
+
+func TestEnumTypedParameters(t *testing.T) {
 	router := New()
-	auth := router.Mount("/auth", nil)
 
-	GET(auth, "/login", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
-		return &HelloResponse{Message: "auth-login"}, nil
+	GET(router, "/queues/:queue/tickets", func(ctx context.Context, req *ticketListRequest) (*ticketListResponse, error) {
+		return &ticketListResponse{Queue: req.Queue, Status: req.Status, Priority: req.Priority}, nil
 	})
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/auth/login", nil))
-
-	if recorder.Code != http.StatusOK {
-		t.Fatalf("expected status OK, got %d", recorder.Code)
+	tests := []struct {
+		target string
+		status int
+		body   string
+	}{
+		{"/queues/open/tickets?status=closed&priority=2", http.StatusOK, `{"priority":2,"queue":"open","status":"closed"}`},
+		{"/queues/open/tickets", http.StatusOK, `{"priority":0,"queue":"open","status":""}`},
+		{"/queues/pending/tickets", http.StatusBadRequest, ""},
+		{"/queues/open/tickets?status=pending", http.StatusBadRequest, ""},
+		{"/queues/open/tickets?priority=5", http.StatusBadRequest, ""},
 	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
 
-	var resp HelloResponse
-	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
+		if rec.Code != tt.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.target, tt.status, rec.Code, rec.Body.String())
+		}
+		if tt.body != "" && strings.TrimSpace(rec.Body.String()) != tt.body {
+			t.Fatalf("%s: expected %s, got %s", tt.target, tt.body, rec.Body.String())
+		}
 	}
+}
 
-	if resp.Message != "auth-login" {
-		t.Errorf("expected message 'auth-login', got '%s'", resp.Message)
-	}
+type clearableFilters struct {
+	Owner string `query:"owner" validate:"required"`
+}
 
-	// Without prefix the route should not be found.
-	recorder = httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/login", nil))
+type clearableRequest struct {
+	Name    string `query:"name" validate:"required"`
+	Limit   int    `query:"limit" validate:"required,max=50"`
+	Note    string `header:"X-Note" validate:"required"`
+	Filters clearableFilters
+}
 
-	if recorder.Code != http.StatusNotFound {
-		t.Errorf("expected status 404 for missing prefix, got %d", recorder.Code)
-	}
+type clearableResponse struct {
+	Name  string `json:"name"`
+	Limit int    `json:"limit"`
 }
 
-func TestNestedRouterWithParentBasePath(t *testing.T) {
-	router := NewWithConfig(&Config{
-		BasePath: "/api",
+func TestTreatEmptyAsPresent(t *testing.T) {
+	handler := func(ctx context.Context, req *clearableRequest) (*clearableResponse, error) {
+		return &clearableResponse{Name: req.Name, Limit: req.Limit}, nil
+	}
+	serve := func(router *Sprout, target string, note *string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if note != nil {
+			req.Header.Set("X-Note", *note)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	empty := ""
+
+	t.Run("default skips empty values", func(t *testing.T) {
+		router := New()
+		GET(router, "/items", handler)
+
+		if rec := serve(router, "/items?name=&limit=&owner=", &empty); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rec.Code)
+		}
 	})
 
-	auth := router.Mount("/auth", nil)
+	t.Run("opt-in accepts explicitly empty values", func(t *testing.T) {
+		router := NewWithConfig(&Config{TreatEmptyAsPresent: true})
+		GET(router, "/items", handler)
 
-	GET(auth, "/login", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
-		return &HelloResponse{Message: "auth-login"}, nil
+		rec := serve(router, "/items?name=&limit=&owner=", &empty)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if body := strings.TrimSpace(rec.Body.String()); body != `{"limit":0,"name":""}` {
+			t.Fatalf("expected zero values, got %s", body)
+		}
 	})
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/auth/login", nil))
+	t.Run("opt-in still rejects absent values", func(t *testing.T) {
+		router := NewWithConfig(&Config{TreatEmptyAsPresent: true})
+		GET(router, "/items", handler)
 
-	if recorder.Code != http.StatusOK {
-		t.Fatalf("expected status OK, got %d", recorder.Code)
-	}
+		if rec := serve(router, "/items?name=&limit=&owner=", nil); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400 for missing header, got %d", rec.Code)
+		}
+		if rec := serve(router, "/items?name=&limit=", &empty); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400 for missing grouped parameter, got %d", rec.Code)
+		}
+		if rec := serve(router, "/items?name=&limit=90&owner=", &empty); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected non-empty values to be validated, got %d", rec.Code)
+		}
+	})
+}
 
-	var resp HelloResponse
-	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
-	}
+type strictClearableRequest struct {
+	Limit  int     `query:"limit" validate:"required,min=1,max=50"`
+	Status string  `query:"status" validate:"oneof=open closed"`
+	Cursor *string `query:"cursor"`
+	Page   *int    `query:"page"`
+}
 
-	if resp.Message != "auth-login" {
-		t.Errorf("expected message 'auth-login', got '%s'", resp.Message)
+func TestTreatEmptyAsPresentKeepsOtherRules(t *testing.T) {
+	router := NewWithConfig(&Config{TreatEmptyAsPresent: true})
+	var got *strictClearableRequest
+	GET(router, "/items", func(ctx context.Context, req *strictClearableRequest) (*HelloResponse, error) {
+		got = req
+		return &HelloResponse{Message: "ok"}, nil
+	})
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
 	}
 
-	// Requests missing either prefix should be 404.
-	recorder = httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/auth/login", nil))
+	if rec := serve("/items?limit=&status=open"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected min=1 to reject an empty limit, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve("/items?limit=5&status="); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected oneof to reject an empty status, got %d: %s", rec.Code, rec.Body.String())
+	}
 
-	if recorder.Code != http.StatusNotFound {
-		t.Errorf("expected status 404 without base path, got %d", recorder.Code)
+	rec := serve("/items?limit=5&status=open&cursor=")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got.Cursor == nil || *got.Cursor != "" {
+		t.Fatalf("expected an empty cursor to point to the zero value, got %v", got.Cursor)
+	}
+	if got.Page != nil {
+		t.Fatalf("expected an absent page to stay nil, got %v", *got.Page)
 	}
 }
 
-func TestNestedRouterInheritsErrorHandler(t *testing.T) {
-	var handled bool
-
-	router := NewWithConfig(&Config{
-		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
-			handled = true
-			w.WriteHeader(599)
-			w.Write([]byte("custom error"))
-		},
-	})
+type sliceQueryRequest struct {
+	Tags    []string  `query:"tag"`
+	IDs     []int     `query:"ids" validate:"omitempty,max=3"`
+	Weights []float64 `query:"weight"`
+	Flags   []bool    `query:"flag"`
+}
 
-	auth := router.Mount("/auth", nil)
+type sliceQueryResponse struct {
+	Tags    []string  `json:"tags"`
+	IDs     []int     `json:"ids"`
+	Weights []float64 `json:"weights"`
+	Flags   []bool    `json:"flags"`
+}
 
-	type AuthRequest struct {
-		Token string `header:"Authorization" validate:"required"`
-	}
+func TestSliceQueryParameters(t *testing.T) {
+	router := New()
 
-	GET(auth, "/login", func(ctx context.Context, req *AuthRequest) (*HelloResponse, error) {
-		return &HelloResponse{Message: "should not reach"}, nil
+	GET(router, "/search", func(ctx context.Context, req *sliceQueryRequest) (*sliceQueryResponse, error) {
+		return &sliceQueryResponse{Tags: req.Tags, IDs: req.IDs, Weights: req.Weights, Flags: req.Flags}, nil
 	})
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/auth/login", nil))
-
-	if !handled {
-		t.Fatalf("expected parent error handler to be invoked")
-	}
-
-	if recorder.Code != 599 {
-		t.Fatalf("expected status 599 from custom error handler, got %d", recorder.Code)
+	tests := []struct {
+		name   string
+		target string
+		status int
+		body   string
+	}{
+		{"repeated", "/search?tag=a&tag=b&tag=c&flag=true&flag=false", http.StatusOK,
+			`{"flags":[true,false],"ids":null,"tags":["a","b","c"],"weights":null}`},
+		{"comma separated", "/search?ids=1,2,3&weight=0.5,1.5", http.StatusOK,
+			`{"flags":null,"ids":[1,2,3],"tags":null,"weights":[0.5,1.5]}`},
+		{"empty", "/search?tag=&ids=", http.StatusOK,
+			`{"flags":null,"ids":null,"tags":null,"weights":null}`},
+		{"invalid element", "/search?ids=1,x", http.StatusBadRequest, ""},
+		{"validated", "/search?ids=1,2,3,4", http.StatusBadRequest, ""},
 	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
 
-	if !bytes.Contains(recorder.Body.Bytes(), []byte("custom error")) {
-		t.Errorf("expected custom error body, got %s", recorder.Body.String())
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.body != "" && strings.TrimSpace(rec.Body.String()) != tt.body {
+				t.Fatalf("expected %s, got %s", tt.body, rec.Body.String())
+			}
+		})
 	}
 }
 
-func TestNestedRouterOverridesErrorHandler(t *testing.T) {
-	var parentCalled bool
-	var childCalled bool
+type legacyResponse struct {
+	ID    string `json:"id" validate:"required"`
+	Email string `json:"email" validate:"omitempty,email"`
+}
 
+func TestResponseValidationMode(t *testing.T) {
+	var warnings []string
 	router := NewWithConfig(&Config{
-		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
-			parentCalled = true
-			w.WriteHeader(597)
-			w.Write([]byte("parent error"))
-		},
-	})
-
-	child := router.Mount("/child", &Config{
-		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
-			childCalled = true
-			w.WriteHeader(598)
-			w.Write([]byte("child error"))
+		ResponseValidationWarning: func(r *http.Request, route string, err error) {
+			warnings = append(warnings, r.Method+" "+route+": "+err.Error())
 		},
 	})
-
-	type ChildRequest struct {
-		Token string `header:"Authorization" validate:"required"`
+	handler := func(ctx context.Context, req *EmptyRequest) (*legacyResponse, error) {
+		return &legacyResponse{Email: "not-an-email"}, nil
 	}
+	GET(router, "/strict", handler)
+	GET(router, "/legacy/:id", handler, WithResponseValidationMode(ResponseValidationWarn))
 
-	GET(child, "/secure", func(ctx context.Context, req *ChildRequest) (*HelloResponse, error) {
-		return &HelloResponse{Message: "should not reach"}, nil
-	})
+	legacy := router.Mount("/v0", &Config{ResponseValidationMode: ResponseValidationWarn})
+	GET(legacy, "/users", handler)
+	GET(legacy, "/checked", handler, WithResponseValidationMode(ResponseValidationStrict))
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/child/secure", nil))
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
 
-	if parentCalled {
-		t.Fatalf("expected parent error handler to be skipped")
+	if rec := serve("/strict"); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected strict route to fail with 500, got %d", rec.Code)
+	}
+	if rec := serve("/v0/checked"); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected route option to override mount default, got %d", rec.Code)
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("expected no warnings for strict routes, got %v", warnings)
 	}
 
-	if !childCalled {
-		t.Fatalf("expected child error handler to be invoked")
+	rec := serve("/legacy/1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected warn route to respond 200, got %d", rec.Code)
+	}
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"email":"not-an-email","id":""}` {
+		t.Fatalf("expected the response to be sent unchanged, got %s", body)
+	}
+	if rec := serve("/v0/users"); rec.Code != http.StatusOK {
+		t.Fatalf("expected mount default to warn, got %d", rec.Code)
 	}
 
-	if recorder.Code != 598 {
-		t.Fatalf("expected status 598 from child error handler, got %d", recorder.Code)
+	if len(warnings) != 2 {
+		t.Fatalf("expected two warnings, got %v", warnings)
+	}
+	if !strings.HasPrefix(warnings[0], "GET /legacy/:id: ") || !strings.Contains(warnings[0], "'email'") {
+		t.Fatalf("expected warning with route pattern and validation error, got %q", warnings[0])
 	}
 }
 
-func TestNestedRouterOverridesStrictFlag(t *testing.T) {
-	router := New()
+func TestResponseValidationWarnLogs(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(previous)
 
-	strictFalse := false
-	child := router.Mount("/loose", &Config{
-		StrictErrorTypes: &strictFalse,
+	router := NewWithConfig(&Config{ResponseValidationMode: ResponseValidationWarn})
+	GET(router, "/legacy", func(ctx context.Context, req *EmptyRequest) (*legacyResponse, error) {
+		return &legacyResponse{}, nil
 	})
 
-	GET(child, "/test", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
-		return nil, &TeapotError{Msg: "teapot"}
-	}, WithErrors(&Error{}))
-
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/loose/test", nil))
-
-	if recorder.Code != 418 {
-		t.Fatalf("expected status 418 from custom error, got %d", recorder.Code)
-	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/legacy", nil))
 
-	var resp map[string]string
-	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
 	}
-
-	if resp["message"] != "teapot" {
-		t.Errorf("expected message 'teapot', got %s", resp["message"])
+	out := logs.String()
+	if !strings.Contains(out, "level=WARN") || !strings.Contains(out, `msg="sprout: response validation failed"`) ||
+		!strings.Contains(out, "route=/legacy") {
+		t.Fatalf("expected a structured warning, got %q", out)
 	}
 }
 
-// Test 404 Not Found with default error handler
-func TestNotFoundDefaultHandler(t *testing.T) {
+func TestWithEnabled(t *testing.T) {
 	router := New()
 
-	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
-		return &HelloResponse{Message: "users"}, nil
-	})
+	GET(router, "/beta", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "beta"}, nil
+	}, WithEnabled(false))
+	GET(router, "/beta", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "stable"}, nil
+	}, WithEnabled(true))
+	POST(router, "/hidden", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "hidden"}, nil
+	}, WithEnabled(false))
 
-	// Request to non-existent route
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/nonexistent", nil))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/beta", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "stable") {
+		t.Fatalf("expected the enabled handler to own the path, got %d: %s", rec.Code, rec.Body.String())
+	}
 
-	if recorder.Code != http.StatusNotFound {
-		t.Errorf("expected status 404, got %d", recorder.Code)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/hidden", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected disabled route to be absent, got %d", rec.Code)
 	}
 
-	// Should contain error message
-	if !bytes.Contains(recorder.Body.Bytes(), []byte("not_found")) {
-		t.Errorf("expected 'not_found' in response, got: %s", recorder.Body.String())
+	if routes := router.Routes(); len(routes) != 1 {
+		t.Fatalf("expected only the enabled route to be registered, got %+v", routes)
+	}
+	spec, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
 	}
+	if strings.Contains(string(spec), "/hidden") {
+		t.Fatalf("expected disabled route to be undocumented")
+	}
+}
 
-	if !bytes.Contains(recorder.Body.Bytes(), []byte("GET /nonexistent")) {
-		t.Errorf("expected route info in response, got: %s", recorder.Body.String())
+type currencyCode struct {
+	code string
+}
+
+func (c *currencyCode) UnmarshalText(text []byte) error {
+	if len(text) != 3 || strings.ToUpper(string(text)) != string(text) {
+		return fmt.Errorf("invalid currency code %q", text)
 	}
+	c.code = string(text)
+	return nil
 }
 
-// Test 405 Method Not Allowed with default error handler
-func TestMethodNotAllowedDefaultHandler(t *testing.T) {
+type entityID string
+
+func (id *entityID) UnmarshalText(text []byte) error {
+	*id = entityID(strings.TrimPrefix(string(text), "eid-"))
+	return nil
+}
+
+type priceRequest struct {
+	ID       entityID       `path:"id"`
+	Currency currencyCode   `query:"currency"`
+	Accept   []currencyCode `query:"accept"`
+	Region   currencyCode   `header:"X-Region"`
+}
+
+type priceResponse struct {
+	ID       string   `json:"id"`
+	Currency string   `json:"currency"`
+	Accept   []string `json:"accept"`
+	Region   string   `json:"region"`
+}
+
+func TestTextUnmarshalerParameters(t *testing.T) {
 	router := New()
-	router.HandleMethodNotAllowed = true // Enable 405 responses
 
-	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
-		return &HelloResponse{Message: "users"}, nil
+	GET(router, "/prices/:id", func(ctx context.Context, req *priceRequest) (*priceResponse, error) {
+		resp := &priceResponse{ID: string(req.ID), Currency: req.Currency.code, Region: req.Region.code}
+		for _, c := range req.Accept {
+			resp.Accept = append(resp.Accept, c.code)
+		}
+		return resp, nil
 	})
 
-	// Request with wrong method
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/users", nil))
+	t.Run("binds through UnmarshalText", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/prices/eid-42?currency=EUR&accept=USD,GBP", nil)
+		req.Header.Set("X-Region", "EEA")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
 
-	if recorder.Code != http.StatusMethodNotAllowed {
-		t.Errorf("expected status 405, got %d", recorder.Code)
-	}
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		want := `{"accept":["USD","GBP"],"currency":"EUR","id":"42","region":"EEA"}`
+		if body := strings.TrimSpace(rec.Body.String()); body != want {
+			t.Fatalf("expected %s, got %s", want, body)
+		}
+	})
 
-	// Should contain error message
-	if !bytes.Contains(recorder.Body.Bytes(), []byte("method_not_allowed")) {
-		t.Errorf("expected 'method_not_allowed' in response, got: %s", recorder.Body.String())
+	t.Run("errors are parse errors naming the parameter", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/prices/1?currency=euro", nil))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), "invalid query parameter 'currency'") {
+			t.Fatalf("expected parameter name in error, got %s", rec.Body.String())
+		}
+	})
+}
+
+type lineItem struct {
+	SKU      string
+	Quantity int
+}
+
+func (li *lineItem) UnmarshalText(text []byte) error {
+	sku, qty, ok := strings.Cut(string(text), ":")
+	if !ok || sku == "" {
+		return fmt.Errorf("expected sku:quantity, got %q", text)
+	}
+	n, err := strconv.Atoi(qty)
+	if err != nil {
+		return err
 	}
+	li.SKU, li.Quantity = sku, n
+	return nil
 }
 
-// Test 404 with custom error handler
-func TestNotFoundCustomHandler(t *testing.T) {
-	var capturedKind ErrorKind
+type quoteRequest struct {
+	Items []lineItem `query:"item" validate:"required,max=3"`
+	Notes []string   `query:"note" sprout:"nosplit"`
+}
 
-	config := &Config{
-		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
-			var sproutErr *Error
-			if errors.As(err, &sproutErr) {
-				capturedKind = sproutErr.Kind
+type quoteResponse struct {
+	Items []string `json:"items"`
+	Notes []string `json:"notes"`
+}
 
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusNotFound)
-				json.NewEncoder(w).Encode(map[string]string{
-					"error":   "custom_not_found",
-					"message": sproutErr.Message,
-					"path":    r.URL.Path,
-				})
+func TestTextUnmarshalerSliceParameters(t *testing.T) {
+	router := New()
+
+	GET(router, "/quotes", func(ctx context.Context, req *quoteRequest) (*quoteResponse, error) {
+		resp := &quoteResponse{Notes: req.Notes}
+		for _, item := range req.Items {
+			resp.Items = append(resp.Items, fmt.Sprintf("%s x%d", item.SKU, item.Quantity))
+		}
+		return resp, nil
+	})
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+		want   string
+	}{
+		{"repeated keys", "/quotes?item=a:1&item=b:2", http.StatusOK, `{"items":["a x1","b x2"],"notes":null}`},
+		{"comma-separated value", "/quotes?item=a:1,b:2", http.StatusOK, `{"items":["a x1","b x2"],"notes":null}`},
+		{"nosplit keeps commas", "/quotes?item=a:1&note=fragile,+keep+upright", http.StatusOK, `{"items":["a x1"],"notes":["fragile, keep upright"]}`},
+		{"slice rules count elements", "/quotes?item=a:1&item=b:1&item=c:1&item=d:1", http.StatusBadRequest, ""},
+		{"missing items", "/quotes", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(tt.target)
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
 			}
-		},
+			if body := strings.TrimSpace(rec.Body.String()); tt.want != "" && body != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, body)
+			}
+		})
 	}
 
-	router := NewWithConfig(config)
+	t.Run("element errors name the index", func(t *testing.T) {
+		var parseErr *ParseParameterError
+		router := NewWithConfig(&Config{
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				errors.As(err, &parseErr)
+				var sproutErr *Error
+				if errors.As(err, &sproutErr) {
+					http.Error(w, sproutErr.Message, http.StatusBadRequest)
+				}
+			},
+		})
+		GET(router, "/quotes", func(ctx context.Context, req *quoteRequest) (*quoteResponse, error) {
+			return &quoteResponse{}, nil
+		})
 
-	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
-		return &HelloResponse{Message: "users"}, nil
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/quotes?item=a:1&item=b&item=c:3", nil))
+
+		if got := strings.TrimSpace(rec.Body.String()); got != "invalid query parameter 'item' at index 1" {
+			t.Fatalf("expected index in error message, got %q", got)
+		}
+		if parseErr == nil || parseErr.Parameter != "item" || parseErr.Value != "b" {
+			t.Fatalf("expected ParseParameterError for the failing element, got %#v", parseErr)
+		}
 	})
+}
 
-	// Request to non-existent route
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/nonexistent", nil))
+type strictAddress struct {
+	City string `json:"city"`
+}
 
-	// Verify error kind was captured
-	if capturedKind != ErrorKindNotFound {
-		t.Errorf("expected ErrorKindNotFound, got %s", capturedKind)
-	}
+type strictAudit struct {
+	Reason string `json:"reason"`
+}
 
-	// Verify custom response
-	if recorder.Code != http.StatusNotFound {
-		t.Errorf("expected status 404, got %d", recorder.Code)
-	}
+type strictUserRequest struct {
+	strictAudit
+	Tenant  string          `header:"X-Tenant"`
+	Name    string          `json:"name" validate:"required"`
+	Address strictAddress   `json:"address"`
+	Payload json.RawMessage `json:"payload"`
+}
 
-	var resp map[string]string
-	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
+func TestDisallowUnknownFields(t *testing.T) {
+	handler := func(ctx context.Context, req *strictUserRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: req.Name + " " + req.Reason}, nil
 	}
-
-	if resp["error"] != "custom_not_found" {
-		t.Errorf("expected 'custom_not_found', got '%s'", resp["error"])
+	post := func(router *Sprout, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
 	}
 
-	if resp["path"] != "/nonexistent" {
-		t.Errorf("expected path '/nonexistent', got '%s'", resp["path"])
+	lenient := New()
+	POST(lenient, "/users", handler)
+	if rec := post(lenient, `{"name":"Ada","extra":true}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected unknown fields to be ignored by default, got %d", rec.Code)
 	}
-}
-
-// Test 405 with custom error handler
-func TestMethodNotAllowedCustomHandler(t *testing.T) {
-	var capturedKind ErrorKind
 
-	config := &Config{
-		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
-			var sproutErr *Error
-			if errors.As(err, &sproutErr) {
-				capturedKind = sproutErr.Kind
+	strict := NewWithConfig(&Config{DisallowUnknownFields: true})
+	POST(strict, "/users", handler)
 
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusMethodNotAllowed)
-				json.NewEncoder(w).Encode(map[string]string{
-					"error":  "custom_method_not_allowed",
-					"method": r.Method,
-					"path":   r.URL.Path,
-				})
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		field  string
+	}{
+		{"known fields", `{"name":"Ada","reason":"import","address":{"city":"Paris"},"payload":{"anything":[1]}}`, http.StatusOK, ""},
+		{"case-insensitive match", `{"Name":"Ada"}`, http.StatusOK, ""},
+		{"unknown top-level field", `{"name":"Ada","extra":true}`, http.StatusBadRequest, "extra"},
+		{"unknown nested field", `{"name":"Ada","address":{"zip":"75001"}}`, http.StatusBadRequest, "zip"},
+		{"routing field in body", `{"name":"Ada","Tenant":"acme"}`, http.StatusBadRequest, "Tenant"},
+		{"trailing data", `{"name":"Ada"} {}`, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post(strict, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
 			}
-		},
+			if tt.field != "" && !strings.Contains(rec.Body.String(), `unknown field "`+tt.field+`"`) {
+				t.Fatalf("expected error naming %q, got %s", tt.field, rec.Body.String())
+			}
+		})
 	}
+}
 
-	router := NewWithConfig(config)
-	router.HandleMethodNotAllowed = true // Enable 405 responses
+type dynamicAPIError struct {
+	_       struct{} `http:"status=400"`
+	Status  int      `json:"-"`
+	Code    string   `json:"code" validate:"required"`
+	Message string   `json:"message"`
+}
 
-	GET(router, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
-		return &HelloResponse{Message: "users"}, nil
-	})
+func (e dynamicAPIError) Error() string {
+	return e.Message
+}
 
-	// Request with wrong method
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("POST", "/users", nil))
+func (e dynamicAPIError) StatusCode() int {
+	return e.Status
+}
 
-	// Verify error kind was captured
-	if capturedKind != ErrorKindMethodNotAllowed {
-		t.Errorf("expected ErrorKindMethodNotAllowed, got %s", capturedKind)
-	}
+func TestStatusCoderErrors(t *testing.T) {
+	router := New()
 
-	// Verify custom response
-	if recorder.Code != http.StatusMethodNotAllowed {
-		t.Errorf("expected status 405, got %d", recorder.Code)
-	}
+	GET(router, "/items/:status", func(ctx context.Context, req *struct {
+		Status int `path:"status"`
+	}) (*HelloResponse, error) {
+		return nil, dynamicAPIError{Status: req.Status, Code: "item_error", Message: "failed"}
+	}, WithErrors(dynamicAPIError{}))
 
-	var resp map[string]string
-	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/items/404", http.StatusNotFound},
+		{"/items/409", http.StatusConflict},
+		{"/items/0", http.StatusBadRequest},   // falls back to the tag
+		{"/items/200", http.StatusBadRequest}, // success statuses are not errors
+		{"/items/101", http.StatusBadRequest},
+		{"/items/999", http.StatusBadRequest}, // out of range
 	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
 
-	if resp["error"] != "custom_method_not_allowed" {
-		t.Errorf("expected 'custom_method_not_allowed', got '%s'", resp["error"])
+		if rec.Code != tt.status {
+			t.Fatalf("%s: expected status %d, got %d", tt.path, tt.status, rec.Code)
+		}
+		if body := strings.TrimSpace(rec.Body.String()); body != `{"code":"item_error","message":"failed"}` {
+			t.Fatalf("%s: unexpected body %s", tt.path, body)
+		}
 	}
 
-	if resp["method"] != "POST" {
-		t.Errorf("expected method 'POST', got '%s'", resp["method"])
+	spec, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	if !strings.Contains(string(spec), `"400"`) {
+		t.Fatalf("expected the tag status to be documented")
 	}
 }
 
-// Test that all error kinds go through same handler
-func TestConsistentErrorHandling(t *testing.T) {
-	errorKinds := []ErrorKind{}
+type rateLimitedError struct {
+	_          struct{}      `http:"status=429"`
+	Wait       time.Duration `json:"-"`
+	RetryField string        `header:"Retry-After" json:"-"`
+	Message    string        `json:"message"`
+}
 
-	config := &Config{
-		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
-			var sproutErr *Error
-			if errors.As(err, &sproutErr) {
-				errorKinds = append(errorKinds, sproutErr.Kind)
+func (e rateLimitedError) Error() string             { return e.Message }
+func (e rateLimitedError) RetryAfter() time.Duration { return e.Wait }
 
-				w.Header().Set("Content-Type", "application/json")
-				status := http.StatusInternalServerError
-				switch sproutErr.Kind {
-				case ErrorKindParse, ErrorKindValidation:
-					status = http.StatusBadRequest
-				case ErrorKindNotFound:
-					status = http.StatusNotFound
-				case ErrorKindMethodNotAllowed:
-					status = http.StatusMethodNotAllowed
-				}
-				w.WriteHeader(status)
-				json.NewEncoder(w).Encode(map[string]string{
-					"kind":    string(sproutErr.Kind),
-					"message": sproutErr.Message,
-				})
-			}
-		},
+func TestRetryAfterErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      rateLimitedError
+		expected string
+	}{
+		{"whole seconds", rateLimitedError{Wait: 30 * time.Second}, "30"},
+		{"rounds up", rateLimitedError{Wait: 1500 * time.Millisecond}, "2"},
+		{"overrides header field", rateLimitedError{Wait: 5 * time.Second, RetryField: "120"}, "5"},
+		{"non-positive keeps header field", rateLimitedError{RetryField: "120"}, "120"},
+		{"non-positive without field", rateLimitedError{Wait: -time.Second}, ""},
 	}
 
-	router := NewWithConfig(config)
-	router.HandleMethodNotAllowed = true
-
-	type BadRequest struct {
-		Page int `query:"page" validate:"required,gte=1"`
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := New()
+			GET(router, "/limited", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+				err := tt.err
+				err.Message = "slow down"
+				return nil, err
+			}, WithErrors(rateLimitedError{}))
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/limited", nil))
+
+			if rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("expected status 429, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.expected {
+				t.Fatalf("expected Retry-After %q, got %q", tt.expected, got)
+			}
+		})
 	}
+}
 
-	GET(router, "/test", func(ctx context.Context, req *BadRequest) (*HelloResponse, error) {
-		return &HelloResponse{Message: "ok"}, nil
-	})
-
-	// Test 404 - goes through ErrorHandler
-	t.Run("404 NotFound", func(t *testing.T) {
-		recorder := httptest.NewRecorder()
-		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/nonexistent", nil))
-
-		if recorder.Code != http.StatusNotFound {
-			t.Errorf("expected 404, got %d", recorder.Code)
+func TestStatusForKind(t *testing.T) {
+	register := func(router *Sprout) {
+		POST(router, "/users", func(ctx context.Context, req *CreateUserRequest) (*CreateUserResponse, error) {
+			return &CreateUserResponse{ID: 1, Name: req.Name, Email: req.Email}, nil
+		})
+	}
+	serve := func(router *Sprout, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	const invalid = `{"name":"al","email":"not-an-email"}`
+	const malformed = `{"name":`
+
+	t.Run("default", func(t *testing.T) {
+		router := New()
+		register(router)
+		if rec := serve(router, invalid); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected validation errors to default to 400, got %d", rec.Code)
 		}
-
-		if recorder.Header().Get("Content-Type") != "application/json" {
-			t.Errorf("expected JSON content type")
+		if rec := serve(router, malformed); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected parse errors to default to 400, got %d", rec.Code)
 		}
 	})
 
-	// Test 405 - goes through ErrorHandler
-	t.Run("405 MethodNotAllowed", func(t *testing.T) {
-		recorder := httptest.NewRecorder()
-		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/test", nil))
-
-		if recorder.Code != http.StatusMethodNotAllowed {
-			t.Errorf("expected 405, got %d", recorder.Code)
+	t.Run("validation as 422", func(t *testing.T) {
+		router := NewWithConfig(&Config{StatusForKind: map[ErrorKind]int{ErrorKindValidation: http.StatusUnprocessableEntity}})
+		register(router)
+		if rec := serve(router, invalid); rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("expected status 422, got %d: %s", rec.Code, rec.Body.String())
 		}
-
-		if recorder.Header().Get("Content-Type") != "application/json" {
-			t.Errorf("expected JSON content type")
+		if rec := serve(router, malformed); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected parse errors to stay 400, got %d", rec.Code)
 		}
 	})
 
-	// Test 400 Validation - goes through ErrorHandler
-	t.Run("400 Validation", func(t *testing.T) {
-		recorder := httptest.NewRecorder()
-		router.ServeHTTP(recorder, httptest.NewRequest("GET", "/test", nil))
+	t.Run("problem details", func(t *testing.T) {
+		router := NewWithConfig(&Config{
+			ErrorEnvelope: NewProblemDetails,
+			StatusForKind: map[ErrorKind]int{ErrorKindValidation: http.StatusUnprocessableEntity},
+		})
+		api := router.Mount("/api", nil)
+		register(api)
 
-		if recorder.Code != http.StatusBadRequest {
-			t.Errorf("expected 400, got %d", recorder.Code)
-		}
+		req := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(invalid))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
 
-		if recorder.Header().Get("Content-Type") != "application/json" {
-			t.Errorf("expected JSON content type")
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("expected mounted router to inherit 422, got %d", rec.Code)
+		}
+		var problem map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+			t.Fatalf("failed to decode problem: %v", err)
+		}
+		if problem["status"] != float64(http.StatusUnprocessableEntity) || problem["title"] != "Unprocessable Entity" {
+			t.Fatalf("expected problem document to report 422, got %v", problem)
 		}
 	})
+}
 
-	// Verify all went through the same handler
-	if len(errorKinds) != 3 {
-		t.Errorf("expected 3 errors captured, got %d", len(errorKinds))
-	}
-
-	expectedKinds := map[ErrorKind]bool{
-		ErrorKindNotFound:         true,
-		ErrorKindMethodNotAllowed: true,
-		ErrorKindValidation:       true,
-	}
+type csvTags []string
 
-	for _, kind := range errorKinds {
-		if !expectedKinds[kind] {
-			t.Errorf("unexpected error kind: %s", kind)
-		}
+func (c *csvTags) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
 	}
+	*c = strings.Split(raw, ",")
+	return nil
 }
 
-// Test nil response handling with empty struct
-func TestNilResponseWithEmptyStruct(t *testing.T) {
-	router := New()
-
-	// Empty response type with no required fields
-	type EmptyResponse struct{}
-
-	// Handler returns nil, should be converted to empty struct and serialized as {}
-	DELETE(router, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*EmptyResponse, error) {
-		return nil, nil
-	})
-
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("DELETE", "/users/123", nil))
+type legacyOrderRequest struct {
+	ID      string  `path:"id"`
+	DryRun  bool    `query:"dry_run"`
+	Tenant  string  `header:"X-Tenant" validate:"required"`
+	Product string  `json:"product" validate:"required"`
+	Tags    csvTags `json:"tags"`
+}
 
-	if recorder.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+// UnmarshalJSON accepts the legacy {"item": ...} spelling of product. Like
+// most custom decoders it replaces the whole value.
+func (r *legacyOrderRequest) UnmarshalJSON(data []byte) error {
+	type plain legacyOrderRequest
+	var body struct {
+		plain
+		Item string `json:"item"`
 	}
-
-	// Should serialize as empty JSON object {}
-	var result map[string]interface{}
-	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
-		t.Fatalf("failed to unmarshal JSON: %v", err)
+	if err := json.Unmarshal(data, &body); err != nil {
+		return err
 	}
-
-	if len(result) != 0 {
-		t.Errorf("expected empty JSON object {}, got %v", result)
+	*r = legacyOrderRequest(body.plain)
+	if r.Product == "" {
+		r.Product = body.Item
 	}
+	return nil
 }
 
-// Test nil response with 204 No Content
-func TestNilResponseWithNoContent(t *testing.T) {
-	router := New()
+func TestCustomJSONUnmarshalers(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		router := NewWithConfig(&Config{DisallowUnknownFields: strict})
+		var got legacyOrderRequest
+		POST(router, "/orders/:id", func(ctx context.Context, req *legacyOrderRequest) (*HelloResponse, error) {
+			got = *req
+			return &HelloResponse{Message: req.Product}, nil
+		})
 
-	// Empty response type with 204 status
-	type NoContentResponse struct {
-		_ struct{} `http:"status=204"`
+		req := httptest.NewRequest(http.MethodPost, "/orders/o-1?dry_run=true", strings.NewReader(`{"item":"lamp","tags":"home,light"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Tenant", "acme")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("strict=%v: expected status 200, got %d: %s", strict, rec.Code, rec.Body.String())
+		}
+		if got.Product != "lamp" || strings.Join(got.Tags, "|") != "home|light" {
+			t.Fatalf("strict=%v: expected custom body decoding, got %+v", strict, got)
+		}
+		if got.ID != "o-1" || !got.DryRun || got.Tenant != "acme" {
+			t.Fatalf("strict=%v: expected parameters bound alongside the custom decoder, got %+v", strict, got)
+		}
 	}
+}
 
-	// Handler returns nil, should serialize to {} with 204 status
-	DELETE(router, "/items/:id", func(ctx context.Context, req *EmptyRequest) (*NoContentResponse, error) {
-		return nil, nil
-	})
+type linkedUserResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	ETag string `header:"ETag"`
+}
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("DELETE", "/items/456", nil))
+func TestResponseDecorator(t *testing.T) {
+	var patterns []string
+	router := NewWithConfig(&Config{
+		ResponseEnvelopeKey: "data",
+		ResponseDecorator: func(ctx context.Context, routePattern string, body map[string]any) map[string]any {
+			patterns = append(patterns, routePattern)
+			if HTTPRequest(ctx) == nil {
+				t.Fatalf("expected request in decorator context")
+			}
+			body["_links"] = map[string]any{"self": map[string]string{"href": "/users/" + body["id"].(string)}}
+			return body
+		},
+	})
+	api := router.Mount("/api", nil)
+	GET(api, "/users/:id", func(ctx context.Context, req *tracedUserRequest) (*linkedUserResponse, error) {
+		return &linkedUserResponse{ID: req.ID, Name: "Ada", ETag: `"u1"`}, nil
+	})
+	GET(api, "/users", func(ctx context.Context, req *EmptyRequest) (*[]HelloResponse, error) {
+		return &[]HelloResponse{{Message: "Ada"}}, nil
+	})
 
-	if recorder.Code != http.StatusNoContent {
-		t.Errorf("expected status 204, got %d: %s", recorder.Code, recorder.Body.String())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users/7", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
 	}
-
-	// 204 responses must not include a body
-	if recorder.Body.Len() != 0 {
-		t.Fatalf("expected empty body for 204 response, got %q", recorder.Body.String())
+	expected := `{"data":{"_links":{"self":{"href":"/users/7"}},"id":"7","name":"Ada"}}`
+	if body := strings.TrimSpace(rec.Body.String()); body != expected {
+		t.Fatalf("expected decorated body inside the envelope %s, got %s", expected, body)
+	}
+	if rec.Header().Get("ETag") != `"u1"` {
+		t.Fatalf("expected header fields to still apply")
+	}
+	if len(patterns) != 1 || patterns[0] != "/api/users/:id" {
+		t.Fatalf("expected full route pattern, got %v", patterns)
 	}
-}
-
-// Test nil response fails validation when response has required fields
-func TestNilResponseWithRequiredFields(t *testing.T) {
-	router := New()
 
-	// Response type with required field
-	type UserResponse struct {
-		ID int `json:"id" validate:"required,gt=0"`
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"data":[{"message":"Ada"}]}` {
+		t.Fatalf("did not expect slice bodies to be decorated, got %s", body)
+	}
+	if len(patterns) != 1 {
+		t.Fatalf("expected decorator to be skipped for slices, got %v", patterns)
 	}
+}
 
-	// Handler returns nil, but response type has required fields
-	GET(router, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*UserResponse, error) {
-		return nil, nil // This should fail validation!
+func TestHandlerDecorator(t *testing.T) {
+	var calls []string
+	cached := map[string]*HelloResponse{}
+	router := NewWithConfig(&Config{
+		HandlerDecorator: func(routePattern string, next AnyHandle) AnyHandle {
+			return func(ctx context.Context, req any) (any, error) {
+				calls = append(calls, routePattern)
+				if r, ok := req.(*tracedUserRequest); ok {
+					if resp, ok := cached[r.ID]; ok {
+						return resp, nil
+					}
+					resp, err := next(ctx, req)
+					if err == nil {
+						cached[r.ID] = resp.(*HelloResponse)
+					}
+					return resp, err
+				}
+				return next(ctx, req)
+			}
+		},
 	})
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/users/123", nil))
+	var handled int
+	GET(router, "/users/:id", func(ctx context.Context, req *tracedUserRequest) (*HelloResponse, error) {
+		handled++
+		return &HelloResponse{Message: "user " + req.ID}, nil
+	})
+	api := router.Mount("/api", nil)
+	GET(api, "/fail", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return nil, &TeapotError{Msg: "short and stout"}
+	}, WithErrors(&TeapotError{}))
 
-	// Should return 500 because validation failed
-	if recorder.Code != http.StatusInternalServerError {
-		t.Errorf("expected status 500 (validation failed), got %d: %s", recorder.Code, recorder.Body.String())
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
 	}
 
-	// Should contain validation error message
-	if !bytes.Contains(recorder.Body.Bytes(), []byte("response validation failed")) {
-		t.Errorf("expected validation error message, got: %s", recorder.Body.String())
+	for i := 0; i < 2; i++ {
+		rec := serve("/users/7")
+		if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"message":"user 7"}` {
+			t.Fatalf("unexpected response %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	if handled != 1 {
+		t.Fatalf("expected the decorator to serve the second call from cache, handler ran %d times", handled)
 	}
-}
-
-// Test nil response works with optional fields (omitempty)
-func TestNilResponseWithOptionalFields(t *testing.T) {
-	router := New()
 
-	// Response type with only optional fields
-	type OptionalResponse struct {
-		Name  string `json:"name,omitempty"`
-		Email string `json:"email,omitempty"`
+	if rec := serve("/api/fail"); rec.Code != http.StatusTeapot {
+		t.Fatalf("expected typed errors to pass through the decorator, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Join(calls, ",") != "/users/:id,/users/:id,/api/fail" {
+		t.Fatalf("expected route patterns passed to the decorator, got %v", calls)
 	}
 
-	// Handler returns nil, all fields are optional so it should work
-	GET(router, "/optional", func(ctx context.Context, req *EmptyRequest) (*OptionalResponse, error) {
-		return nil, nil
+	t.Run("wrong response type", func(t *testing.T) {
+		strict := false
+		router := NewWithConfig(&Config{
+			StrictErrorTypes: &strict,
+			HandlerDecorator: func(routePattern string, next AnyHandle) AnyHandle {
+				return func(ctx context.Context, req any) (any, error) {
+					return &CreateUserResponse{}, nil
+				}
+			},
+		})
+		GET(router, "/hello", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+			return &HelloResponse{Message: "hi"}, nil
+		})
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hello", nil))
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("expected status 500 for a mismatched response type, got %d", rec.Code)
+		}
 	})
+}
 
-	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/optional", nil))
+type defaultedListRequest struct {
+	Page   int      `query:"page" default:"1" validate:"gte=1"`
+	Limit  *int     `query:"limit" default:"20" validate:"omitempty,lte=100"`
+	Tags   []string `query:"tag" default:"new,hot"`
+	Locale string   `header:"Accept-Language" default:"en"`
+	Theme  string   `cookie:"theme" default:"light"`
+}
 
-	if recorder.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+type defaultedListResponse struct {
+	Page   int      `json:"page"`
+	Limit  int      `json:"limit"`
+	Tags   []string `json:"tags"`
+	Locale string   `json:"locale"`
+	Theme  string   `json:"theme"`
+}
+
+func TestDefaultParameterValues(t *testing.T) {
+	handler := func(ctx context.Context, req *defaultedListRequest) (*defaultedListResponse, error) {
+		resp := &defaultedListResponse{Page: req.Page, Tags: req.Tags, Locale: req.Locale, Theme: req.Theme}
+		if req.Limit != nil {
+			resp.Limit = *req.Limit
+		}
+		return resp, nil
 	}
 
-	// Should serialize as empty JSON object {} (omitempty skips zero values)
-	var result map[string]interface{}
-	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
-		t.Fatalf("failed to unmarshal JSON: %v", err)
+	tests := []struct {
+		name    string
+		config  *Config
+		target  string
+		headers map[string]string
+		status  int
+		body    string
+	}{
+		{"missing values use defaults", nil, "/items", nil, http.StatusOK,
+			`{"limit":20,"locale":"en","page":1,"tags":["new","hot"],"theme":"light"}`},
+		{"sent values win", nil, "/items?page=3&limit=5&tag=old",
+			map[string]string{"Accept-Language": "de", "Cookie": "theme=dark"}, http.StatusOK,
+			`{"limit":5,"locale":"de","page":3,"tags":["old"],"theme":"dark"}`},
+		{"empty values use defaults", nil, "/items?page=&limit=&tag=",
+			map[string]string{"Accept-Language": "", "Cookie": "theme="}, http.StatusOK,
+			`{"limit":20,"locale":"en","page":1,"tags":["new","hot"],"theme":"light"}`},
+		{"empty values use defaults when treated as present", &Config{TreatEmptyAsPresent: true}, "/items?page=&limit=",
+			nil, http.StatusOK,
+			`{"limit":20,"locale":"en","page":1,"tags":["new","hot"],"theme":"light"}`},
+		{"sent values are validated", nil, "/items?page=0", nil, http.StatusBadRequest, ""},
 	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewWithConfig(tt.config)
+			GET(router, "/items", handler)
 
-	if len(result) != 0 {
-		t.Errorf("expected empty JSON object {}, got %v", result)
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.body != "" && strings.TrimSpace(rec.Body.String()) != tt.body {
+				t.Fatalf("expected %s, got %s", tt.body, rec.Body.String())
+			}
+		})
 	}
 }
 
-func TestSproutRegisterCustomTypeFunc(t *testing.T) {
+func TestHardTimeout(t *testing.T) {
 	router := New()
 
-	type customWrapper struct {
-		Value string
-	}
+	finished := make(chan error, 1)
+	GET(router, "/slow", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		<-ctx.Done()
+		finished <- ctx.Err()
+		return &HelloResponse{Message: "too late"}, nil
+	}, WithHardTimeout(20*time.Millisecond))
+	GET(router, "/fast", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "in time"}, nil
+	}, WithHardTimeout(time.Second))
+	GET(router, "/panic", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		panic("boom")
+	}, WithHardTimeout(time.Second))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	t.Run("slow handler gets 503", func(t *testing.T) {
+		rec := serve("/slow")
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected status 503, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if err := <-finished; !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected handler context to hit its deadline, got %v", err)
+		}
+		if strings.Contains(rec.Body.String(), "too late") {
+			t.Fatalf("expected late result to be discarded, got %s", rec.Body.String())
+		}
+	})
 
-	var called int
-	router.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
-		called++
-		if !field.IsValid() {
-			return nil
+	t.Run("fast handler responds normally", func(t *testing.T) {
+		rec := serve("/fast")
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "in time") {
+			t.Fatalf("expected 200 with handler body, got %d: %s", rec.Code, rec.Body.String())
 		}
-		if field.Kind() == reflect.Ptr {
-			if field.IsNil() {
-				return nil
+	})
+
+	t.Run("panics reach the serving goroutine", func(t *testing.T) {
+		defer func() {
+			if recovered := recover(); recovered != "boom" {
+				t.Fatalf("expected handler panic to be re-raised, got %v", recovered)
 			}
-			field = field.Elem()
+		}()
+		serve("/panic")
+	})
+
+	t.Run("late raw writes are dropped", func(t *testing.T) {
+		router := New()
+		wrote := make(chan struct{})
+		GET(router, "/raw", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+			defer close(wrote)
+			w := ResponseWriter(ctx)
+			<-ctx.Done()
+			for i := 0; i < 100; i++ {
+				w.Header().Set("X-Late", "1")
+				_, _ = w.Write([]byte("late"))
+			}
+			return nil, ErrResponseWritten
+		}, WithHardTimeout(10*time.Millisecond))
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/raw", nil))
+		<-wrote
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected status 503, got %d: %s", rec.Code, rec.Body.String())
 		}
-		switch v := field.Interface().(type) {
-		case customWrapper:
-			return v.Value
-		default:
-			return nil
+		if rec.Header().Get("X-Late") != "" || strings.Contains(rec.Body.String(), "late") {
+			t.Fatalf("expected late writes to be dropped, got %v %q", rec.Header(), rec.Body.String())
 		}
-	}, customWrapper{}, (*customWrapper)(nil))
+	})
 
-	if err := router.validate.Var(&customWrapper{Value: "bar"}, "eq=bar"); err != nil {
-		t.Fatalf("expected validation to pass, got error: %v", err)
-	}
+	t.Run("headers set in time are kept", func(t *testing.T) {
+		router := New()
+		GET(router, "/headers", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+			ResponseWriter(ctx).Header().Set("X-Handler", "1")
+			return &HelloResponse{Message: "in time"}, nil
+		}, WithHardTimeout(time.Second))
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/headers", nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("X-Handler") != "1" {
+			t.Fatalf("expected 200 with the handler's header, got %d %v", rec.Code, rec.Header())
+		}
+	})
 
-	if called == 0 {
-		t.Fatalf("expected custom type function to be called")
-	}
+	t.Run("status follows StatusForKind", func(t *testing.T) {
+		router := NewWithConfig(&Config{StatusForKind: map[ErrorKind]int{ErrorKindTimeout: http.StatusGatewayTimeout}})
+		GET(router, "/slow", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+			<-ctx.Done()
+			return nil, nil
+		}, WithHardTimeout(time.Millisecond))
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Fatalf("expected status 504, got %d", rec.Code)
+		}
+	})
 }
 
-func TestSproutRegisterValidation(t *testing.T) {
+type batchItemResult struct {
+	ID     string `json:"id" validate:"required"`
+	Status int    `json:"status" validate:"gte=100,lte=599"`
+	Error  string `json:"error,omitempty"`
+}
+
+type batchDeleteRequest struct {
+	IDs []string `json:"ids" validate:"required,min=1"`
+}
+
+func TestMultiStatus(t *testing.T) {
 	router := New()
 
-	var called bool
-	if err := router.RegisterValidation("is-foo", func(fl validator.FieldLevel) bool {
-		called = true
-		return fl.Field().String() == "foo"
-	}); err != nil {
-		t.Fatalf("failed to register custom validation: %v", err)
-	}
+	POST(router, "/users/batch-delete", func(ctx context.Context, req *batchDeleteRequest) (*MultiStatus[batchItemResult], error) {
+		resp := &MultiStatus[batchItemResult]{}
+		for _, id := range req.IDs {
+			switch id {
+			case "missing":
+				resp.Results = append(resp.Results, batchItemResult{ID: id, Status: http.StatusNotFound, Error: "no such user"})
+			case "broken":
+				resp.Results = append(resp.Results, batchItemResult{ID: id})
+			default:
+				resp.Results = append(resp.Results, batchItemResult{ID: id, Status: http.StatusNoContent})
+			}
+		}
+		return resp, nil
+	})
 
-	type payload struct {
-		Value string `validate:"is-foo"`
+	serve := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/users/batch-delete", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
 	}
 
-	err := router.validate.Struct(&payload{Value: "bar"})
-	if err == nil {
-		t.Fatalf("expected validation error for custom validator")
-	}
+	t.Run("per-item outcomes", func(t *testing.T) {
+		rec := serve(`{"ids":["1","missing"]}`)
+		if rec.Code != http.StatusMultiStatus {
+			t.Fatalf("expected status 207, got %d: %s", rec.Code, rec.Body.String())
+		}
+		want := `[{"id":"1","status":204},{"id":"missing","status":404,"error":"no such user"}]`
+		if body := strings.TrimSpace(rec.Body.String()); body != want {
+			t.Fatalf("expected %s, got %s", want, body)
+		}
+	})
 
-	if !called {
-		t.Fatalf("expected custom validation to be invoked")
-	}
+	t.Run("malformed item fails response validation", func(t *testing.T) {
+		if rec := serve(`{"ids":["1","broken"]}`); rec.Code != http.StatusInternalServerError {
+			t.Fatalf("expected status 500, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
 }
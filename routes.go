@@ -0,0 +1,134 @@
+package sprout
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// RouteInfo describes a registered route for introspection, e.g. to assert
+// API contracts in tests.
+type RouteInfo struct {
+	// Method is the HTTP method the route was registered for.
+	Method string
+	// Path is the full httprouter path, including any BasePath and mount prefixes.
+	Path string
+	// ContentType is the media type of the route's success response.
+	ContentType string
+	// RequestType and ResponseType are the handler's request and response DTO
+	// types. For streaming routes ResponseType is the item type.
+	RequestType  reflect.Type
+	ResponseType reflect.Type
+	// Errors lists the error types declared with WithErrors, in declaration order.
+	Errors []DeclaredError
+	// Internal reports whether the route was registered WithInternal.
+	Internal bool
+}
+
+// DeclaredError is an error type declared for a route with WithErrors.
+type DeclaredError struct {
+	// Type is the declared error type; pointer declarations are dereferenced.
+	Type reflect.Type
+	// Status is the HTTP status the error is written with, taken from its
+	// `http:"status=..."` tag and defaulting to 500.
+	Status int
+}
+
+// Routes returns the routes registered on s and on routers mounted below it,
+// in registration order.
+func (s *Sprout) Routes() []RouteInfo {
+	s.registry.mu.RLock()
+	entries := append([]*routeEntry(nil), s.registry.entries...)
+	s.registry.mu.RUnlock()
+
+	var routes []RouteInfo
+	for _, entry := range entries {
+		if !entry.owner.descendsFrom(s) {
+			continue
+		}
+		routes = append(routes, entry.info())
+	}
+	return routes
+}
+
+// RouteTypes returns the request and response DTO types and the declared
+// errors of the route registered on s, or below it, for method and the full
+// path pattern (e.g. "/api/users/:id"). When several handlers share the method
+// and path, the default variant is reported. ok is false if no route matches.
+func (s *Sprout) RouteTypes(method, path string) (reqType, respType reflect.Type, errs []DeclaredError, ok bool) {
+	s.registry.mu.RLock()
+	defer s.registry.mu.RUnlock()
+
+	for _, entry := range s.registry.entries {
+		if entry.method != strings.ToUpper(method) || entry.path != path || !entry.owner.descendsFrom(s) {
+			continue
+		}
+		info := entry.info()
+		return info.RequestType, info.ResponseType, info.Errors, true
+	}
+	return nil, nil, nil, false
+}
+
+func (entry *routeEntry) info() RouteInfo {
+	info := RouteInfo{
+		Method:       entry.method,
+		Path:         entry.path,
+		ContentType:  entry.config.responseContentType(),
+		RequestType:  entry.reqType,
+		ResponseType: entry.respType,
+		Internal:     entry.config.internal,
+	}
+	for _, errType := range entry.config.expectedErrors {
+		info.Errors = append(info.Errors, DeclaredError{
+			Type:   errType,
+			Status: extractStatusCode(errType, http.StatusInternalServerError),
+		})
+	}
+	return info
+}
+
+// DiffErrors compares the route's declared errors with expected. missing lists
+// expected types the route does not declare; unexpected lists declared types
+// absent from expected. Both are nil when the sets match.
+func (r RouteInfo) DiffErrors(expected ...error) (missing, unexpected []reflect.Type) {
+	declared := make(map[reflect.Type]bool, len(r.Errors))
+	for _, declaredErr := range r.Errors {
+		declared[declaredErr.Type] = true
+	}
+
+	want := make(map[reflect.Type]bool, len(expected))
+	for _, err := range expected {
+		errType := derefType(reflect.TypeOf(err))
+		if errType == nil || want[errType] {
+			continue
+		}
+		want[errType] = true
+		if !declared[errType] {
+			missing = append(missing, errType)
+		}
+	}
+
+	for _, declaredErr := range r.Errors {
+		if !want[declaredErr.Type] {
+			unexpected = append(unexpected, declaredErr.Type)
+			want[declaredErr.Type] = true // report duplicates once
+		}
+	}
+	return missing, unexpected
+}
+
+func (r *routerRegistry) addEntry(entry *routeEntry) {
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	r.mu.Unlock()
+}
+
+// descendsFrom reports whether s is ancestor or is mounted below it.
+func (s *Sprout) descendsFrom(ancestor *Sprout) bool {
+	for current := s; current != nil; current = current.parent {
+		if current == ancestor {
+			return true
+		}
+	}
+	return false
+}
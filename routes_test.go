@@ -0,0 +1,124 @@
+package sprout
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRoutesDeclaredErrors(t *testing.T) {
+	router := New()
+
+	GET(router, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{}, nil
+	}, WithErrors(NotFoundError{}, &TeapotError{}))
+
+	api := router.Mount("/api", nil)
+	POST(api, "/users", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{}, nil
+	}, WithErrors(ConflictError{}), WithProduces("application/hal+json"))
+
+	routes := router.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d: %+v", len(routes), routes)
+	}
+
+	get := routes[0]
+	if get.Method != http.MethodGet || get.Path != "/users/:id" || get.ContentType != "application/json" {
+		t.Fatalf("unexpected first route: %+v", get)
+	}
+	if len(get.Errors) != 2 {
+		t.Fatalf("expected 2 declared errors, got %+v", get.Errors)
+	}
+	if get.Errors[0].Type != reflect.TypeOf(NotFoundError{}) || get.Errors[0].Status != http.StatusNotFound {
+		t.Fatalf("unexpected first declared error: %+v", get.Errors[0])
+	}
+	if get.Errors[1].Type != reflect.TypeOf(TeapotError{}) || get.Errors[1].Status != http.StatusTeapot {
+		t.Fatalf("expected pointer declarations to be dereferenced, got %+v", get.Errors[1])
+	}
+
+	post := routes[1]
+	if post.Method != http.MethodPost || post.Path != "/api/users" || post.ContentType != "application/hal+json" {
+		t.Fatalf("unexpected second route: %+v", post)
+	}
+
+	mounted := api.Routes()
+	if len(mounted) != 1 || mounted[0].Path != "/api/users" {
+		t.Fatalf("expected mount to list only its own routes, got %+v", mounted)
+	}
+}
+
+func TestRouteInfoDiffErrors(t *testing.T) {
+	router := New()
+
+	GET(router, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{}, nil
+	}, WithErrors(NotFoundError{}, &TeapotError{}))
+
+	route := router.Routes()[0]
+
+	missing, unexpected := route.DiffErrors(&NotFoundError{}, &TeapotError{})
+	if missing != nil || unexpected != nil {
+		t.Fatalf("expected matching sets, got missing=%v unexpected=%v", missing, unexpected)
+	}
+
+	missing, unexpected = route.DiffErrors(NotFoundError{}, ConflictError{})
+	if len(missing) != 1 || missing[0] != reflect.TypeOf(ConflictError{}) {
+		t.Fatalf("expected ConflictError to be missing, got %v", missing)
+	}
+	if len(unexpected) != 1 || unexpected[0] != reflect.TypeOf(TeapotError{}) {
+		t.Fatalf("expected TeapotError to be unexpected, got %v", unexpected)
+	}
+}
+
+func TestRoutesReportInternal(t *testing.T) {
+	router := New()
+
+	GET(router, "/public", func(ctx context.Context, req *EmptyRequest) (*EmptyRequest, error) {
+		return nil, nil
+	})
+	GET(router, "/internal", func(ctx context.Context, req *EmptyRequest) (*EmptyRequest, error) {
+		return nil, nil
+	}, WithInternal())
+
+	routes := router.Routes()
+	if len(routes) != 2 || routes[0].Internal || !routes[1].Internal {
+		t.Fatalf("expected only the second route to be internal, got %+v", routes)
+	}
+}
+
+func TestRouteTypes(t *testing.T) {
+	router := New()
+	api := router.Mount("/api", nil)
+
+	GET(api, "/users/:id", func(ctx context.Context, req *tracedUserRequest) (*HelloResponse, error) {
+		return &HelloResponse{}, nil
+	}, WithErrors(NotFoundError{}))
+	GET(api, "/users/:id", func(ctx context.Context, req *EmptyRequest) (*ListUsersEnvelope, error) {
+		return &ListUsersEnvelope{}, nil
+	}, WithProduces("application/vnd.v2+json"))
+
+	reqType, respType, errs, ok := router.RouteTypes("get", "/api/users/:id")
+	if !ok {
+		t.Fatalf("expected route to be found")
+	}
+	if reqType != reflect.TypeOf(tracedUserRequest{}) || respType != reflect.TypeOf(HelloResponse{}) {
+		t.Fatalf("expected default variant types, got %v and %v", reqType, respType)
+	}
+	if len(errs) != 1 || errs[0].Type != reflect.TypeOf(NotFoundError{}) || errs[0].Status != http.StatusNotFound {
+		t.Fatalf("unexpected declared errors: %+v", errs)
+	}
+
+	if _, _, _, ok := router.RouteTypes(http.MethodGet, "/users/:id"); ok {
+		t.Fatalf("expected lookup to require the full path")
+	}
+	if _, _, _, ok := router.Mount("/other", nil).RouteTypes(http.MethodGet, "/api/users/:id"); ok {
+		t.Fatalf("expected lookup to be limited to routes below the router")
+	}
+
+	routes := router.Routes()
+	if routes[1].RequestType != reflect.TypeOf(EmptyRequest{}) || routes[1].ResponseType != reflect.TypeOf(ListUsersEnvelope{}) {
+		t.Fatalf("expected Routes to report each variant's types, got %+v", routes[1])
+	}
+}
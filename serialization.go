@@ -1,9 +1,18 @@
 package sprout
 
 import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 type jsonTagInfo struct {
@@ -59,6 +68,393 @@ func isUnwrapField(field reflect.StructField) bool {
 	return hasSproutOption(field, "unwrap")
 }
 
+// isParameterGroupField reports whether field is a named struct, without a json
+// tag, whose fields bind path, query, header, cookie or claim values. Such
+// fields group related parameters and are never decoded from the request body.
+func isParameterGroupField(field reflect.StructField) bool {
+	if field.Anonymous || !field.IsExported() || field.Tag.Get("json") != "" {
+		return false
+	}
+	if field.Type.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < field.Type.NumField(); i++ {
+		nested := field.Type.Field(i)
+		if nested.Tag.Get("path") != "" || nested.Tag.Get("query") != "" || nested.Tag.Get("header") != "" ||
+			nested.Tag.Get("cookie") != "" || nested.Tag.Get("claim") != "" {
+			return true
+		}
+		if isParameterGroupField(nested) {
+			return true
+		}
+	}
+	return false
+}
+
+// isQueryRestField reports whether field collects the query parameters no
+// `query:` tag binds.
+func isQueryRestField(field reflect.StructField) bool {
+	return hasSproutOption(field, "queryrest")
+}
+
+// isFullPathField reports whether field receives the whole request path.
+func isFullPathField(field reflect.StructField) bool {
+	return hasSproutOption(field, "fullpath")
+}
+
+// isPathRestField reports whether field receives the request path after the
+// router's base path.
+func isPathRestField(field reflect.StructField) bool {
+	return hasSproutOption(field, "pathrest")
+}
+
+// isDeepObjectField reports whether field binds bracketed query entries such as
+// filter[status]=active into a map keyed by strings.
+func isDeepObjectField(field reflect.StructField) bool {
+	return field.Tag.Get("query") != "" && field.Type.Kind() == reflect.Map &&
+		field.Type.Key().Kind() == reflect.String
+}
+
+// isQuerySliceField reports whether field binds repeated or comma-separated
+// query values into a slice.
+func isQuerySliceField(field reflect.StructField) bool {
+	return field.Tag.Get("query") != "" && field.Type.Kind() == reflect.Slice
+}
+
+// isTextBodyField reports whether field carries a text/plain request or
+// response body.
+func isTextBodyField(field reflect.StructField) bool {
+	return hasSproutOption(field, "textbody") && field.Type.Kind() == reflect.String
+}
+
+// textBodyField returns the `sprout:"textbody"` string field of t, if any.
+func textBodyField(t reflect.Type) (reflect.StructField, bool) {
+	t = derefType(t)
+	if t == nil || t.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for _, field := range exportedFields(t) {
+		if isTextBodyField(field) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// isEncodedBodyField reports whether field carries an already serialized, and
+// usually compressed, response body.
+func isEncodedBodyField(field reflect.StructField) bool {
+	return hasSproutOption(field, "encodedbody") && field.Type == reflect.TypeOf([]byte(nil))
+}
+
+// encodedBodyField returns the `sprout:"encodedbody"` []byte field of t, if any.
+func encodedBodyField(t reflect.Type) (reflect.StructField, bool) {
+	t = derefType(t)
+	if t == nil || t.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for _, field := range exportedFields(t) {
+		if isEncodedBodyField(field) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// isFormField reports whether field binds a form field of a urlencoded
+// request body and is documented as one of a urlencoded or multipart body.
+func isFormField(field reflect.StructField) bool {
+	return field.Tag.Get("form") != ""
+}
+
+// isFileField reports whether field is documented as a file part of a
+// multipart request body.
+func isFileField(field reflect.StructField) bool {
+	return field.Tag.Get("file") != ""
+}
+
+// unwrapRequestEnvelope returns the raw JSON stored under key in body.
+func unwrapRequestEnvelope(body []byte, key string) ([]byte, error) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, err
+	}
+	inner, ok := envelope[key]
+	if !ok {
+		return nil, fmt.Errorf("missing envelope key %q", key)
+	}
+	return inner, nil
+}
+
+// decodeRequestJSON decodes a request body into dst, a pointer to the request
+// DTO. In strict mode keys no body field declares are rejected: top-level keys
+// are checked against the DTO's JSON fields, so keys matching routing fields
+// fail as well, and nested objects are checked by the decoder.
+func decodeRequestJSON(body []byte, dst any, strict bool) error {
+	if !strict {
+		return json.Unmarshal(body, dst)
+	}
+
+	// A type with its own UnmarshalJSON defines which keys it accepts.
+	var keys map[string]json.RawMessage
+	if _, custom := dst.(json.Unmarshaler); !custom && json.Unmarshal(body, &keys) == nil {
+		names := jsonBodyFieldNames(reflect.TypeOf(dst).Elem())
+		for key := range keys {
+			if !hasFoldedName(names, key) {
+				return fmt.Errorf("json: unknown field %q", key)
+			}
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("json: invalid data after top-level value")
+	}
+	return nil
+}
+
+// jsonBodyFieldNames returns the JSON names of t's body fields, flattening
+// embedded structs the way encoding/json does.
+func jsonBodyFieldNames(t reflect.Type) []string {
+	var names []string
+	if t.Kind() != reflect.Struct {
+		return names
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Tag.Get("json") == "" && derefType(field.Type).Kind() == reflect.Struct {
+			names = append(names, jsonBodyFieldNames(derefType(field.Type))...)
+			continue
+		}
+		if !field.IsExported() || shouldExcludeFromRequestJSON(field) {
+			continue
+		}
+		if name := parseJSONTag(field).Name; name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// hasFoldedName reports whether key matches one of names, ignoring case as
+// encoding/json does when decoding.
+func hasFoldedName(names []string, key string) bool {
+	for _, name := range names {
+		if strings.EqualFold(name, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeXML writes p to buf as an XML document, indented when pretty is set.
+func encodeXML(buf *bytes.Buffer, p xmlPayload, pretty bool) error {
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(buf)
+	if pretty {
+		enc.Indent("", "  ")
+	}
+	var err error
+	if p.root != "" {
+		err = enc.EncodeElement(p.value, xml.StartElement{Name: xml.Name{Local: p.root}})
+	} else {
+		err = enc.Encode(p.value)
+	}
+	if err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	return nil
+}
+
+// textPayload is a response body written verbatim instead of as JSON.
+type textPayload string
+
+// encodedPayload is a pre-encoded response body, written as is once its
+// Content-Encoding has been negotiated.
+type encodedPayload []byte
+
+const xmlContentType = "application/xml"
+
+// xmlPayload is a response body encoded with encoding/xml. root names the
+// document element when value has no XMLName field.
+type xmlPayload struct {
+	value any
+	root  string
+}
+
+// isXMLField reports whether field is part of a response's XML body.
+func isXMLField(field reflect.StructField) bool {
+	if !field.IsExported() || field.Anonymous {
+		return false
+	}
+	if field.Name == "XMLName" {
+		return true
+	}
+	tag, ok := field.Tag.Lookup("xml")
+	return ok && tag != "-"
+}
+
+// hasXMLTags reports whether t, a struct or pointer to one, declares an XML
+// shape through `xml:` tags.
+func hasXMLTags(t reflect.Type) bool {
+	t = derefType(t)
+	if t == nil || t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if isXMLField(t.Field(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// toXMLPayload copies the `xml:` tagged fields of resp into a struct holding
+// only those fields, so routing and metadata fields never reach the XML body.
+func toXMLPayload(resp any) xmlPayload {
+	v := reflect.Indirect(reflect.ValueOf(resp))
+	t := v.Type()
+
+	var fields []reflect.StructField
+	var index [][]int
+	root := t.Name()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !isXMLField(field) {
+			continue
+		}
+		if field.Name == "XMLName" {
+			root = ""
+		}
+		fields = append(fields, reflect.StructField{
+			Name: field.Name,
+			Type: field.Type,
+			Tag:  reflect.StructTag(`xml:"` + field.Tag.Get("xml") + `"`),
+		})
+		index = append(index, field.Index)
+	}
+	if i := strings.IndexByte(root, '['); i >= 0 {
+		root = root[:i]
+	}
+
+	out := reflect.New(reflect.StructOf(fields)).Elem()
+	for i, idx := range index {
+		out.Field(i).Set(v.FieldByIndex(idx))
+	}
+	return xmlPayload{value: out.Interface(), root: root}
+}
+
+func isSensitiveField(field reflect.StructField) bool {
+	return hasSproutOption(field, "sensitive")
+}
+
+// isReadOnlyField reports whether field is only sent by the server, such as a
+// generated ID.
+func isReadOnlyField(field reflect.StructField) bool {
+	return hasSproutOption(field, "readonly")
+}
+
+// isWriteOnlyField reports whether field is only sent by the client, such as a
+// password on a type that is also returned.
+func isWriteOnlyField(field reflect.StructField) bool {
+	return hasSproutOption(field, "writeonly")
+}
+
+// isDeprecatedField reports whether field is documented as deprecated.
+func isDeprecatedField(field reflect.StructField) bool {
+	return hasSproutOption(field, "deprecated")
+}
+
+var sensitiveFieldsCache sync.Map // reflect.Type -> []string
+
+// SensitiveFields reports the JSON names of fields tagged `sprout:"sensitive"`
+// in v's type. Nested struct fields are reported as dotted paths (for example
+// "credentials.password"), with "*" standing for any slice element or map
+// value ("keys.*.secret"); embedded structs are flattened like encoding/json.
+// v may be a struct, a pointer to a struct or a reflect.Type. The result is
+// sorted and belongs to the caller.
+func SensitiveFields(v any) []string {
+	t, ok := v.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(v)
+	}
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	if cached, ok := sensitiveFieldsCache.Load(t); ok {
+		return append([]string(nil), cached.([]string)...)
+	}
+
+	var names []string
+	collectSensitiveFields(t, "", map[reflect.Type]bool{}, &names)
+	sort.Strings(names)
+
+	cached, _ := sensitiveFieldsCache.LoadOrStore(t, names)
+	return append([]string(nil), cached.([]string)...)
+}
+
+func collectSensitiveFields(t reflect.Type, prefix string, visiting map[reflect.Type]bool, names *[]string) {
+	if visiting[t] {
+		return
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		if prefix == "" && shouldExcludeFromJSON(field) {
+			continue
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && field.Tag.Get("json") == "" && fieldType.Kind() == reflect.Struct {
+			collectSensitiveFields(fieldType, prefix, visiting, names)
+			continue
+		}
+
+		name := parseJSONTag(field).Name
+		if name == "" {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		if isSensitiveField(field) {
+			*names = append(*names, name)
+			continue
+		}
+
+		for fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Array || fieldType.Kind() == reflect.Map {
+			fieldType = derefType(fieldType.Elem())
+			name += ".*"
+		}
+		if fieldType.Kind() == reflect.Struct {
+			collectSensitiveFields(fieldType, name, visiting, names)
+		}
+	}
+}
+
 // extractStatusCode reads the HTTP status code from struct tags.
 // Looks for a field with `http:"status=XXX"` tag.
 // Returns defaultCode if no status tag is found.
@@ -127,8 +523,98 @@ func extractHeaders(v reflect.Value) map[string]string {
 	return headers
 }
 
+// cookieTagName returns the cookie name of a `cookie:` tag. On response
+// fields the name may be followed by attributes, as in "session,httponly".
+func cookieTagName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("cookie"), ",")
+	return strings.TrimSpace(name)
+}
+
+// extractCookies builds the cookies set by `cookie:` fields of a response or
+// error struct. String fields become the cookie value, configured by the tag's
+// attributes: httponly, secure, path=, domain=, maxage= and samesite=. A
+// *http.Cookie field is sent as is, named by the tag when it has no name.
+// Empty strings and nil cookies are skipped.
+func extractCookies(v reflect.Value) []*http.Cookie {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var cookies []*http.Cookie
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldValue := v.Field(i)
+		name := cookieTagName(field)
+		if name == "" || !field.IsExported() {
+			continue
+		}
+
+		switch value := fieldValue.Interface().(type) {
+		case string:
+			if value == "" {
+				continue
+			}
+			cookie := &http.Cookie{Name: name, Value: value}
+			applyCookieAttributes(cookie, field.Tag.Get("cookie"))
+			cookies = append(cookies, cookie)
+		case *http.Cookie:
+			if value == nil {
+				continue
+			}
+			cookie := *value
+			if cookie.Name == "" {
+				cookie.Name = name
+			}
+			cookies = append(cookies, &cookie)
+		}
+	}
+
+	return cookies
+}
+
+// applyCookieAttributes sets the attributes listed after the name in a
+// `cookie:` tag. Unknown attributes are ignored.
+func applyCookieAttributes(cookie *http.Cookie, tag string) {
+	parts := strings.Split(tag, ",")
+	for _, part := range parts[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch strings.ToLower(key) {
+		case "httponly":
+			cookie.HttpOnly = true
+		case "secure":
+			cookie.Secure = true
+		case "path":
+			cookie.Path = value
+		case "domain":
+			cookie.Domain = value
+		case "maxage":
+			if seconds, err := strconv.Atoi(value); err == nil {
+				cookie.MaxAge = seconds
+			}
+		case "samesite":
+			switch strings.ToLower(value) {
+			case "lax":
+				cookie.SameSite = http.SameSiteLaxMode
+			case "strict":
+				cookie.SameSite = http.SameSiteStrictMode
+			case "none":
+				cookie.SameSite = http.SameSiteNoneMode
+			}
+		}
+	}
+}
+
 // shouldExcludeFromJSON checks if a field should be excluded from JSON serialization.
-// Fields with path, query, header, or http tags are excluded.
+// Fields with path, query, header, cookie, or http tags are excluded, as are
+// queryrest, fullpath, pathrest and textbody fields.
 func shouldExcludeFromJSON(field reflect.StructField) bool {
 	// Check if field has json:"-" tag explicitly
 	if jsonTag := field.Tag.Get("json"); jsonTag == "-" {
@@ -145,13 +631,33 @@ func shouldExcludeFromJSON(field reflect.StructField) bool {
 	if field.Tag.Get("header") != "" {
 		return true
 	}
+	if field.Tag.Get("cookie") != "" {
+		return true
+	}
+	if field.Tag.Get("claim") != "" {
+		return true
+	}
+	if field.Name == "XMLName" && field.Type == reflect.TypeOf(xml.Name{}) {
+		return true
+	}
 	if field.Tag.Get("http") != "" {
 		return true
 	}
+	if isQueryRestField(field) || isFullPathField(field) || isPathRestField(field) ||
+		isTextBodyField(field) || isEncodedBodyField(field) || isFormField(field) || isFileField(field) {
+		return true
+	}
 
 	return false
 }
 
+// shouldExcludeFromRequestJSON is shouldExcludeFromJSON for request bodies,
+// which additionally leave out parameter groups. Responses serialize them as
+// ordinary nested objects.
+func shouldExcludeFromRequestJSON(field reflect.StructField) bool {
+	return shouldExcludeFromJSON(field) || isParameterGroupField(field)
+}
+
 // toJSONMap converts a struct to a map, excluding top-level fields with routing tags.
 // Anonymous embedded structs are flattened to match standard JSON encoding behavior.
 // Nested objects are included as-is (routing tags only matter at the top level).
@@ -179,6 +685,11 @@ func toJSONMap(v interface{}) map[string]interface{} {
 		// Process these BEFORE exclusion checks because embedded structs may have
 		// http tags (for status codes) but we still want to flatten their fields
 		if field.Anonymous && fieldValue.Kind() == reflect.Struct {
+			// An embedded json.Marshaler contributes the object it encodes to
+			if mergeMarshaledFields(result, fieldValue) {
+				continue
+			}
+
 			// Recursively flatten embedded struct fields into result
 			// Process embedded struct fields directly without calling Interface()
 			// to handle unexported embedded types
@@ -230,6 +741,58 @@ func toJSONMap(v interface{}) map[string]interface{} {
 	return result
 }
 
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// implementsJSONMarshaler reports whether t implements json.Marshaler itself,
+// rather than through a method promoted from an embedded field. Such types
+// are encoded by their MarshalJSON instead of field by field.
+func implementsJSONMarshaler(t reflect.Type) bool {
+	if t == nil || !t.Implements(jsonMarshalerType) {
+		return false
+	}
+	st := derefType(t)
+	if st.Kind() != reflect.Struct {
+		return true
+	}
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		if field.Anonymous && (field.Type.Implements(jsonMarshalerType) ||
+			reflect.PointerTo(field.Type).Implements(jsonMarshalerType)) {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeMarshaledFields adds the keys of the JSON object an embedded
+// json.Marshaler encodes to result. It reports false, leaving result alone,
+// when v is no marshaler or does not encode an object.
+func mergeMarshaledFields(result map[string]interface{}, v reflect.Value) bool {
+	var marshaler json.Marshaler
+	switch {
+	case v.CanAddr() && v.Addr().CanInterface() && v.Addr().Type().Implements(jsonMarshalerType):
+		marshaler, _ = v.Addr().Interface().(json.Marshaler)
+	case v.CanInterface() && v.Type().Implements(jsonMarshalerType):
+		marshaler, _ = v.Interface().(json.Marshaler)
+	}
+	if marshaler == nil {
+		return false
+	}
+
+	data, err := marshaler.MarshalJSON()
+	if err != nil {
+		return false
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return false
+	}
+	for name, value := range fields {
+		result[name] = value
+	}
+	return true
+}
+
 func unwrapJSONFieldValue(v reflect.Value) (interface{}, bool) {
 	if !v.IsValid() {
 		return nil, false
@@ -3,6 +3,7 @@ package sprout
 import (
 	"context"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -457,3 +458,319 @@ func TestParseErrorStructuredData(t *testing.T) {
 		}
 	})
 }
+
+type testCredentials struct {
+	Password string `json:"password" sprout:"sensitive"`
+	Token    string `json:"token,omitempty" sprout:"sensitive"`
+}
+
+type testSensitiveBase struct {
+	Secret string `json:"secret" sprout:"sensitive"`
+}
+
+type testSensitiveRequest struct {
+	testSensitiveBase
+	Name        string                     `json:"name"`
+	Credentials testCredentials            `json:"credentials"`
+	Backup      *testCredentials           `json:"backup,omitempty"`
+	History     []*testCredentials         `json:"history"`
+	Keys        map[string]testCredentials `json:"keys"`
+	Session     string                     `header:"X-Session" sprout:"sensitive"`
+	Ignored     string                     `json:"-" sprout:"sensitive"`
+}
+
+func TestSensitiveFields(t *testing.T) {
+	expected := []string{
+		"backup.password",
+		"backup.token",
+		"credentials.password",
+		"credentials.token",
+		"history.*.password",
+		"history.*.token",
+		"keys.*.password",
+		"keys.*.token",
+		"secret",
+	}
+
+	for _, input := range []any{
+		testSensitiveRequest{},
+		&testSensitiveRequest{},
+		reflect.TypeOf(testSensitiveRequest{}),
+	} {
+		got := SensitiveFields(input)
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("SensitiveFields(%T) = %v, want %v", input, got, expected)
+		}
+	}
+
+	mutated := SensitiveFields(testSensitiveRequest{})
+	mutated[0] = "changed"
+	_ = append(mutated[:1], "appended")
+	if got := SensitiveFields(testSensitiveRequest{}); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected callers not to share the cached result, got %v", got)
+	}
+
+	if got := SensitiveFields(HelloResponse{}); len(got) != 0 {
+		t.Fatalf("expected no sensitive fields, got %v", got)
+	}
+	if got := SensitiveFields("not a struct"); got != nil {
+		t.Fatalf("expected nil for non-struct input, got %v", got)
+	}
+	if got := SensitiveFields(nil); got != nil {
+		t.Fatalf("expected nil for nil input, got %v", got)
+	}
+}
+
+type xmlBookResponse struct {
+	XMLName  xml.Name `xml:"book"`
+	ETag     string   `header:"ETag"`
+	ID       int      `json:"id" xml:"id,attr"`
+	Title    string   `json:"title" xml:"title"`
+	Internal string   `json:"internal"`
+}
+
+type xmlAuthorResponse struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func TestXMLResponses(t *testing.T) {
+	router := NewWithConfig(&Config{EnableXML: true})
+	GET(router, "/book", func(ctx context.Context, req *EmptyRequest) (*xmlBookResponse, error) {
+		return &xmlBookResponse{ETag: `"v1"`, ID: 7, Title: "Dune", Internal: "json only"}, nil
+	})
+	GET(router, "/author", func(ctx context.Context, req *EmptyRequest) (*xmlAuthorResponse, error) {
+		return &xmlAuthorResponse{Name: "Herbert"}, nil
+	})
+	GET(router, "/hello", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "hi"}, nil
+	})
+
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("xml preferred", func(t *testing.T) {
+		rec := serve("/book", "application/xml, application/json;q=0.5")
+		if ct := rec.Header().Get("Content-Type"); ct != "application/xml" {
+			t.Fatalf("expected application/xml, got %q", ct)
+		}
+		if rec.Header().Get("ETag") != `"v1"` || rec.Header().Get("Vary") != "Accept" {
+			t.Fatalf("expected header fields and Vary to be set, got %v", rec.Header())
+		}
+		expected := xml.Header + `<book id="7"><title>Dune</title></book>` + "\n"
+		if rec.Body.String() != expected {
+			t.Fatalf("expected %q, got %q", expected, rec.Body.String())
+		}
+	})
+
+	t.Run("root named after type", func(t *testing.T) {
+		rec := serve("/author", "text/xml")
+		expected := xml.Header + `<xmlAuthorResponse><name>Herbert</name></xmlAuthorResponse>` + "\n"
+		if rec.Body.String() != expected {
+			t.Fatalf("expected %q, got %q", expected, rec.Body.String())
+		}
+	})
+
+	t.Run("json by default", func(t *testing.T) {
+		for _, accept := range []string{"", "*/*", "application/json, application/xml;q=0.9"} {
+			rec := serve("/book", accept)
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Fatalf("Accept %q: expected application/json, got %q", accept, ct)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != `{"id":7,"internal":"json only","title":"Dune"}` {
+				t.Fatalf("Accept %q: unexpected body %s", accept, body)
+			}
+		}
+	})
+
+	t.Run("types without xml tags stay json", func(t *testing.T) {
+		rec := serve("/hello", "application/xml")
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Fatalf("expected application/json, got %q", ct)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		plain := New()
+		GET(plain, "/book", func(ctx context.Context, req *EmptyRequest) (*xmlBookResponse, error) {
+			return &xmlBookResponse{ID: 7, Title: "Dune"}, nil
+		})
+		req := httptest.NewRequest(http.MethodGet, "/book", nil)
+		req.Header.Set("Accept", "application/xml")
+		rec := httptest.NewRecorder()
+		plain.ServeHTTP(rec, req)
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Fatalf("expected XML to be opt-in, got %q", ct)
+		}
+	})
+}
+
+type xmlNotFoundError struct {
+	_       struct{} `http:"status=404"`
+	XMLName xml.Name `xml:"error"`
+	Code    string   `json:"code" xml:"code,attr"`
+	Message string   `json:"message" xml:"message"`
+}
+
+func (e xmlNotFoundError) Error() string { return e.Message }
+
+func TestXMLErrors(t *testing.T) {
+	router := NewWithConfig(&Config{EnableXML: true})
+	GET(router, "/book", func(ctx context.Context, req *EmptyRequest) (*xmlBookResponse, error) {
+		return nil, xmlNotFoundError{Code: "book_missing", Message: "no such book"}
+	}, WithErrors(xmlNotFoundError{}))
+	GET(router, "/teapot", func(ctx context.Context, req *EmptyRequest) (*xmlBookResponse, error) {
+		return nil, &TeapotError{Msg: "short and stout"}
+	}, WithErrors(&TeapotError{}))
+
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("xml client", func(t *testing.T) {
+		rec := serve("/book", "application/xml")
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404, got %d", rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/xml" {
+			t.Fatalf("expected application/xml, got %q", ct)
+		}
+		if rec.Header().Get("Vary") != "Accept" {
+			t.Fatalf("expected Vary: Accept, got %v", rec.Header())
+		}
+		expected := xml.Header + `<error code="book_missing"><message>no such book</message></error>` + "\n"
+		if rec.Body.String() != expected {
+			t.Fatalf("expected %q, got %q", expected, rec.Body.String())
+		}
+	})
+
+	t.Run("json client", func(t *testing.T) {
+		rec := serve("/book", "application/json")
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Fatalf("expected application/json, got %q", ct)
+		}
+		if body := strings.TrimSpace(rec.Body.String()); body != `{"code":"book_missing","message":"no such book"}` {
+			t.Fatalf("unexpected body %s", body)
+		}
+	})
+
+	t.Run("errors without xml tags stay json", func(t *testing.T) {
+		rec := serve("/teapot", "application/xml")
+		if rec.Code != http.StatusTeapot {
+			t.Fatalf("expected status 418, got %d", rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Fatalf("expected application/json, got %q", ct)
+		}
+	})
+}
+
+type shapeRequest struct {
+	Kind string `path:"kind"`
+}
+
+type shapeResponse struct {
+	Kind   string  `json:"-"`
+	Radius float64 `json:"-"`
+	Width  float64 `json:"-"`
+	ETag   string  `header:"ETag"`
+}
+
+func (s shapeResponse) MarshalJSON() ([]byte, error) {
+	if s.Kind == "circle" {
+		return json.Marshal(map[string]any{"type": "circle", "radius": s.Radius})
+	}
+	return json.Marshal(map[string]any{"type": "square", "side": s.Width})
+}
+
+type auditStamp struct {
+	by string
+}
+
+func (a *auditStamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"audited_by": a.by})
+}
+
+type auditedShapeResponse struct {
+	*auditStamp
+	Name string `json:"name"`
+}
+
+type Audit struct {
+	By string
+}
+
+func (a Audit) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"audited_by": strings.ToUpper(a.By)})
+}
+
+type embeddedAuditResponse struct {
+	Audit
+	Name   string `json:"name"`
+	Status int    `header:"X-Status"`
+}
+
+func TestCustomJSONMarshalers(t *testing.T) {
+	router := New()
+	GET(router, "/shapes/:kind", func(ctx context.Context, req *shapeRequest) (*shapeResponse, error) {
+		return &shapeResponse{Kind: req.Kind, Radius: 2, Width: 3, ETag: `"s1"`}, nil
+	})
+	GET(router, "/audited", func(ctx context.Context, req *EmptyRequest) (*embeddedAuditResponse, error) {
+		return &embeddedAuditResponse{Audit: Audit{By: "ada"}, Name: "report", Status: 1}, nil
+	})
+	NDJSON(router, http.MethodGet, "/shapes", func(ctx context.Context, req *EmptyRequest, send func(*shapeResponse) error) error {
+		if err := send(&shapeResponse{Kind: "circle", Radius: 1}); err != nil {
+			return err
+		}
+		return send(&shapeResponse{Kind: "square", Width: 4})
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := serve("/shapes/circle")
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"radius":2,"type":"circle"}` {
+		t.Fatalf("expected MarshalJSON output, got %s", body)
+	}
+	if rec.Header().Get("ETag") != `"s1"` {
+		t.Fatalf("expected header fields to still apply, got %v", rec.Header())
+	}
+	if body := strings.TrimSpace(serve("/shapes/square").Body.String()); body != `{"side":3,"type":"square"}` {
+		t.Fatalf("expected MarshalJSON output, got %s", body)
+	}
+
+	rec = serve("/audited")
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"audited_by":"ADA","name":"report"}` {
+		t.Fatalf("expected embedded marshaler merged with outer fields, got %s", body)
+	}
+
+	lines := strings.Split(strings.TrimSpace(serve("/shapes").Body.String()), "\n")
+	if len(lines) != 2 || lines[0] != `{"radius":1,"type":"circle"}` || lines[1] != `{"side":4,"type":"square"}` {
+		t.Fatalf("expected MarshalJSON output for streamed items, got %q", lines)
+	}
+}
+
+func TestImplementsJSONMarshaler(t *testing.T) {
+	if !implementsJSONMarshaler(reflect.TypeOf(&shapeResponse{})) {
+		t.Fatalf("expected own MarshalJSON to be detected")
+	}
+	if implementsJSONMarshaler(reflect.TypeOf(&auditedShapeResponse{})) || implementsJSONMarshaler(reflect.TypeOf(&embeddedAuditResponse{})) {
+		t.Fatalf("did not expect promoted MarshalJSON to take over the whole response")
+	}
+	if implementsJSONMarshaler(reflect.TypeOf(&HelloResponse{})) {
+		t.Fatalf("did not expect plain structs to be marshalers")
+	}
+}
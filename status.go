@@ -0,0 +1,34 @@
+package sprout
+
+import "context"
+
+type responseStatusContextKey struct{}
+
+// responseStatus holds the status a handler chose with WithStatus.
+type responseStatus struct {
+	code int
+}
+
+// WithStatus sets the success status of the current response from inside a
+// handler, for routes whose status depends on the outcome, such as 201 when a
+// resource was created and 200 when it already existed. It takes precedence
+// over the response type's status tag and the route's default status.
+//
+// Only 2xx codes are accepted; errors go through the usual error pipeline.
+// The call is ignored for other codes and outside a typed handler. Document
+// the extra statuses with WithResponses.
+func WithStatus(ctx context.Context, code int) {
+	if code < 200 || code > 299 {
+		return
+	}
+	if status, ok := ctx.Value(responseStatusContextKey{}).(*responseStatus); ok {
+		status.code = code
+	}
+}
+
+// withResponseStatus returns a context that WithStatus can record a status
+// on, along with the slot it records into.
+func withResponseStatus(ctx context.Context) (context.Context, *responseStatus) {
+	status := &responseStatus{}
+	return context.WithValue(ctx, responseStatusContextKey{}, status), status
+}
@@ -0,0 +1,85 @@
+package sprout
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type upsertUserRequest struct {
+	ID string `path:"id"`
+}
+
+func TestWithStatus(t *testing.T) {
+	router := NewWithConfig(&Config{
+		ResponseEnvelope: func(body any, status int) any {
+			return map[string]any{"status": status, "data": body}
+		},
+	})
+
+	existing := map[string]bool{"1": true}
+	PUT(router, "/users/:id", func(ctx context.Context, req *upsertUserRequest) (*HelloResponse, error) {
+		if !existing[req.ID] {
+			existing[req.ID] = true
+			WithStatus(ctx, http.StatusCreated)
+		}
+		return &HelloResponse{Message: req.ID}, nil
+	}, WithResponses(http.StatusCreated))
+	POST(router, "/jobs", func(ctx context.Context, req *EmptyRequest) (*AcceptedResponse, error) {
+		WithStatus(ctx, http.StatusOK)
+		return &AcceptedResponse{JobID: "1", Message: "done"}, nil
+	})
+	GET(router, "/ignored", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		WithStatus(ctx, http.StatusNotFound)
+		return &HelloResponse{Message: "hi"}, nil
+	})
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	t.Run("default status", func(t *testing.T) {
+		if rec := serve(http.MethodPut, "/users/1"); rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("chosen status", func(t *testing.T) {
+		rec := serve(http.MethodPut, "/users/2")
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if !strings.Contains(rec.Body.String(), `"status":201`) {
+			t.Fatalf("expected envelope to see the chosen status, got %s", rec.Body.String())
+		}
+	})
+
+	t.Run("overrides status tag", func(t *testing.T) {
+		if rec := serve(http.MethodPost, "/jobs"); rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("non-success codes are ignored", func(t *testing.T) {
+		if rec := serve(http.MethodGet, "/ignored"); rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("outside a handler", func(t *testing.T) {
+		WithStatus(context.Background(), http.StatusCreated)
+	})
+}
+
+func TestWithResponsesPanicsOnErrorStatus(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected WithResponses to panic on a non-2xx status")
+		}
+	}()
+	WithResponses(http.StatusNotFound)
+}
@@ -0,0 +1,300 @@
+package sprout
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+// errStreamClosed is returned by send functions invoked after the handler returned.
+var errStreamClosed = errors.New("sprout: stream closed")
+
+// NDJSONHandle is a streaming handler. Each call to send writes one item as a
+// single line of compact JSON and flushes it to the client. send is not safe
+// for concurrent use and must not be called after the handler returns.
+type NDJSONHandle[Req, Item any] func(ctx context.Context, req *Req, send func(*Item) error) error
+
+// NDJSON registers a handler that streams newline-delimited JSON
+// (application/x-ndjson). Requests are bound and validated exactly like
+// regular routes, and every item is validated before it is written.
+//
+// The success status (200 unless Config.DefaultStatusByMethod says otherwise)
+// and headers are committed when the first item is sent. Until then, errors
+// returned by the handler (or reported by send) produce a regular error
+// response. Once streaming has started the status can no longer change, so a
+// failure simply ends the stream.
+func NDJSON[Req, Item any](s *Sprout, method, path string, h NDJSONHandle[Req, Item], opts ...RouteOption) {
+	cfg := newRouteConfig(opts)
+	cfg.successContentType = ndjsonContentType
+	s.registerRoute(method, path, typeOf[Req](), typeOf[Item](), cfg, func(entry *routeEntry) Middleware {
+		return wrapNDJSON(entry, h, cfg)
+	})
+}
+
+func wrapNDJSON[Req, Item any](entry *routeEntry, handle NDJSONHandle[Req, Item], cfg *routeConfig) Middleware {
+	return func(w http.ResponseWriter, req *http.Request, next Next) {
+		s := entry.owner
+		ctx := withHTTPRequest(req.Context(), req)
+
+		reqDTO, ok := bindRequest[Req](s, w, req, cfg)
+		if !ok {
+			return
+		}
+
+		stream := &ndjsonStream{owner: s, entry: entry, w: w, req: req, status: cfg.successStatus()}
+		err := handle(ctx, reqDTO, func(item *Item) error {
+			if item == nil {
+				item = new(Item)
+			}
+			return stream.send(item)
+		})
+		stream.closed = true
+
+		if stream.started {
+			// Headers are committed; there is no way left to report the failure.
+			return
+		}
+
+		if stream.failure != nil {
+			handleError(s, w, req, stream.failure)
+			return
+		}
+
+		if err != nil {
+			handleHandlerError(s, w, req, next, cfg, err)
+			return
+		}
+
+		stream.start()
+	}
+}
+
+// ndjsonStream writes items of an NDJSON response as they are produced.
+type ndjsonStream struct {
+	owner   *Sprout
+	entry   *routeEntry
+	w       http.ResponseWriter
+	req     *http.Request
+	status  int
+	started bool
+	closed  bool
+
+	// failure records a sprout error raised by send before the stream started,
+	// so it is reported with its own kind rather than as a handler error.
+	failure *Error
+}
+
+func (st *ndjsonStream) send(item any) error {
+	if st.closed {
+		return errStreamClosed
+	}
+	if st.failure != nil {
+		return st.failure
+	}
+	if err := st.req.Context().Err(); err != nil {
+		return err
+	}
+
+	if err := st.owner.checkResponse(st.req, st.entry, item); err != nil {
+		return st.fail(err)
+	}
+
+	line, err := json.Marshal(prepareResponseBody(item))
+	if err != nil {
+		return st.fail(&Error{
+			Kind:    ErrorKindSerialization,
+			Message: "failed to encode response",
+			Err:     err,
+		})
+	}
+
+	st.start()
+	if !shouldWriteBody(st.req.Method, st.status) {
+		return nil
+	}
+
+	if _, err := st.w.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := http.NewResponseController(st.w).Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// fail records err if nothing has been written yet and returns it to the handler.
+func (st *ndjsonStream) fail(err *Error) error {
+	if !st.started {
+		st.failure = err
+	}
+	return err
+}
+
+func (st *ndjsonStream) start() {
+	if st.started {
+		return
+	}
+	st.started = true
+
+	st.owner.applyDefaultHeaders(st.w)
+
+	if st.w.Header().Get("Content-Type") == "" {
+		st.w.Header().Set("Content-Type", ndjsonContentType)
+	}
+	st.w.WriteHeader(st.status)
+}
+
+const eventStreamContentType = "text/event-stream"
+
+// EventStream sends Server-Sent Events to the client of an SSE route. It is
+// not safe for concurrent use and must not be used after the handler returns.
+type EventStream interface {
+	// Send writes one event and flushes it. event names the event type and
+	// may be empty for the default "message" type. String and []byte data is
+	// sent as is; anything else is encoded as JSON like a response body.
+	// Multi-line data is split over several data lines. After the client has
+	// gone away Send returns the request context's error.
+	Send(event string, data any) error
+
+	// Flush commits the response headers, if no event has been sent yet, and
+	// flushes anything buffered to the client.
+	Flush()
+}
+
+// SSEHandle is a Server-Sent Events handler. The response stays open until it
+// returns; ctx is cancelled when the client disconnects.
+type SSEHandle[Req any] func(ctx context.Context, req *Req, stream EventStream) error
+
+// SSE registers a GET handler that streams Server-Sent Events
+// (text/event-stream). Requests are bound and validated exactly like regular
+// routes. The response is sent with Cache-Control: no-cache and
+// X-Accel-Buffering: no so proxies pass events through unbuffered.
+//
+// The status (always 200) and headers are committed by the first Send or
+// Flush. Until then, an error returned by the handler produces a regular
+// error response; afterwards it simply ends the stream.
+func SSE[Req any](s *Sprout, path string, h SSEHandle[Req], opts ...RouteOption) {
+	cfg := newRouteConfig(opts)
+	cfg.successContentType = eventStreamContentType
+	s.registerRoute(http.MethodGet, path, typeOf[Req](), typeOf[string](), cfg, func(entry *routeEntry) Middleware {
+		return wrapSSE(entry, h, cfg)
+	})
+}
+
+func wrapSSE[Req any](entry *routeEntry, handle SSEHandle[Req], cfg *routeConfig) Middleware {
+	return func(w http.ResponseWriter, req *http.Request, next Next) {
+		s := entry.owner
+		ctx := withHTTPRequest(req.Context(), req)
+
+		reqDTO, ok := bindRequest[Req](s, w, req, cfg)
+		if !ok {
+			return
+		}
+
+		stream := &sseStream{owner: s, w: w, req: req}
+		err := handle(ctx, reqDTO, stream)
+		stream.closed = true
+
+		if stream.started {
+			// Headers are committed; there is no way left to report the failure.
+			return
+		}
+
+		if err != nil {
+			handleHandlerError(s, w, req, next, cfg, err)
+			return
+		}
+
+		stream.start()
+	}
+}
+
+// sseStream is the EventStream of an SSE route.
+type sseStream struct {
+	owner   *Sprout
+	w       http.ResponseWriter
+	req     *http.Request
+	started bool
+	closed  bool
+}
+
+func (st *sseStream) Send(event string, data any) error {
+	if st.closed {
+		return errStreamClosed
+	}
+	if err := st.req.Context().Err(); err != nil {
+		return err
+	}
+	if strings.ContainsAny(event, "\r\n") {
+		return fmt.Errorf("sprout: invalid event name %q", event)
+	}
+
+	var payload []byte
+	switch value := data.(type) {
+	case string:
+		payload = []byte(value)
+	case []byte:
+		payload = value
+	default:
+		encoded, err := json.Marshal(prepareResponseBody(data))
+		if err != nil {
+			return err
+		}
+		payload = encoded
+	}
+
+	var buf bytes.Buffer
+	if event != "" {
+		buf.WriteString("event: " + event + "\n")
+	}
+	// SSE clients end lines at CRLF, LF or a lone CR, so all three split data.
+	normalized := strings.ReplaceAll(strings.ReplaceAll(string(payload), "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(normalized, "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteByte('\n')
+
+	st.start()
+	if _, err := st.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return st.flush()
+}
+
+func (st *sseStream) Flush() {
+	if st.closed {
+		return
+	}
+	st.start()
+	_ = st.flush()
+}
+
+func (st *sseStream) flush() error {
+	if err := http.NewResponseController(st.w).Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+func (st *sseStream) start() {
+	if st.started {
+		return
+	}
+	st.started = true
+
+	st.owner.applyDefaultHeaders(st.w)
+
+	header := st.w.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", eventStreamContentType)
+	}
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	st.w.WriteHeader(http.StatusOK)
+}
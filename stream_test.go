@@ -0,0 +1,347 @@
+package sprout
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+type exportRequest struct {
+	Limit int `query:"limit" validate:"required,min=1"`
+}
+
+type exportLine struct {
+	ID   int    `json:"id"`
+	Name string `json:"name" validate:"required"`
+}
+
+type flushCountingRecorder struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (r *flushCountingRecorder) Flush() {
+	r.flushes++
+	r.ResponseRecorder.Flush()
+}
+
+func TestNDJSONStreamsLines(t *testing.T) {
+	router := New()
+
+	NDJSON(router, http.MethodGet, "/export", func(ctx context.Context, req *exportRequest, send func(*exportLine) error) error {
+		for i := 1; i <= req.Limit; i++ {
+			if err := send(&exportLine{ID: i, Name: "item"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/export?limit=3", nil)
+	rec := &flushCountingRecorder{ResponseRecorder: httptest.NewRecorder()}
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("expected application/x-ndjson content type, got %q", ct)
+	}
+	if rec.flushes != 3 {
+		t.Fatalf("expected a flush per line, got %d flushes", rec.flushes)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(rec.Body.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %q", len(lines), rec.Body.String())
+	}
+	for i, line := range lines {
+		var item exportLine
+		if err := json.Unmarshal([]byte(line), &item); err != nil {
+			t.Fatalf("line %d is not valid JSON: %v", i, err)
+		}
+		if item.ID != i+1 {
+			t.Fatalf("expected id %d on line %d, got %d", i+1, i, item.ID)
+		}
+	}
+}
+
+func TestNDJSONEmptyStream(t *testing.T) {
+	router := New()
+
+	NDJSON(router, http.MethodGet, "/export", func(ctx context.Context, req *EmptyRequest, send func(*exportLine) error) error {
+		return nil
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/export", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("expected application/x-ndjson content type, got %q", ct)
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("expected empty body, got %q", rec.Body.String())
+	}
+}
+
+func TestNDJSONErrorsBeforeFirstLine(t *testing.T) {
+	router := New()
+
+	called := false
+	NDJSON(router, http.MethodGet, "/export", func(ctx context.Context, req *exportRequest, send func(*exportLine) error) error {
+		called = true
+		return &TeapotError{Msg: "no export today"}
+	}, WithErrors(&TeapotError{}))
+
+	t.Run("request validation", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/export", nil))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rec.Code)
+		}
+		if called {
+			t.Fatalf("handler should not run when request validation fails")
+		}
+	})
+
+	t.Run("declared handler error", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/export?limit=1", nil))
+
+		if rec.Code != http.StatusTeapot {
+			t.Fatalf("expected status 418, got %d", rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Fatalf("expected application/json error content type, got %q", ct)
+		}
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("expected JSON error body: %v", err)
+		}
+		if body["message"] != "no export today" {
+			t.Fatalf("unexpected error body: %v", body)
+		}
+	})
+}
+
+func TestNDJSONInvalidFirstItem(t *testing.T) {
+	router := New()
+
+	var sendErr error
+	NDJSON(router, http.MethodGet, "/export", func(ctx context.Context, req *EmptyRequest, send func(*exportLine) error) error {
+		sendErr = send(&exportLine{ID: 1})
+		return sendErr
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/export", nil))
+
+	if sendErr == nil {
+		t.Fatalf("expected send to report the invalid item")
+	}
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), `"id"`) {
+		t.Fatalf("invalid item should not be written, got %q", rec.Body.String())
+	}
+}
+
+func TestNDJSONErrorAfterFirstLineEndsStream(t *testing.T) {
+	router := New()
+
+	NDJSON(router, http.MethodGet, "/export", func(ctx context.Context, req *EmptyRequest, send func(*exportLine) error) error {
+		if err := send(&exportLine{ID: 1, Name: "first"}); err != nil {
+			return err
+		}
+		return &TeapotError{Msg: "late failure"}
+	}, WithErrors(&TeapotError{}))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/export", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected committed status 200, got %d", rec.Code)
+	}
+	if got := rec.Body.String(); got != "{\"id\":1,\"name\":\"first\"}\n" {
+		t.Fatalf("expected only the first line, got %q", got)
+	}
+}
+
+func TestNDJSONOpenAPIContentType(t *testing.T) {
+	router := New()
+
+	NDJSON(router, http.MethodGet, "/export", func(ctx context.Context, req *exportRequest, send func(*exportLine) error) error {
+		return nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	op := doc.Paths.Value("/export").Get
+	if op == nil {
+		t.Fatalf("expected GET operation for /export")
+	}
+
+	resp := op.Responses.Value("200")
+	if resp == nil || resp.Value == nil {
+		t.Fatalf("expected 200 response in spec")
+	}
+	media := resp.Value.Content["application/x-ndjson"]
+	if media == nil || media.Schema == nil {
+		t.Fatalf("expected application/x-ndjson content, got %v", resp.Value.Content)
+	}
+	if media.Schema.Ref != "#/components/schemas/sprout_exportLine" {
+		t.Fatalf("expected line schema reference, got %q", media.Schema.Ref)
+	}
+	if op.Parameters.GetByInAndName("query", "limit") == nil {
+		t.Fatalf("expected limit query parameter")
+	}
+}
+
+type priceTick struct {
+	Symbol string  `json:"symbol"`
+	Price  float64 `json:"price"`
+}
+
+type tickerRequest struct {
+	Symbol string `query:"symbol" validate:"required"`
+}
+
+func TestSSEStreamsEvents(t *testing.T) {
+	router := New()
+	SSE(router, "/ticks", func(ctx context.Context, req *tickerRequest, stream EventStream) error {
+		if err := stream.Send("tick", &priceTick{Symbol: req.Symbol, Price: 1.5}); err != nil {
+			return err
+		}
+		if err := stream.Send("", "line one\nline two"); err != nil {
+			return err
+		}
+		if err := stream.Send("", "a\rb\r\nc"); err != nil {
+			return err
+		}
+		return stream.Send("bad\nname", "x")
+	})
+
+	rec := &flushCountingRecorder{ResponseRecorder: httptest.NewRecorder()}
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ticks?symbol=ACME", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+	if rec.Header().Get("Cache-Control") != "no-cache" || rec.Header().Get("X-Accel-Buffering") != "no" {
+		t.Fatalf("expected buffering to be disabled, got %v", rec.Header())
+	}
+	expected := "event: tick\ndata: {\"price\":1.5,\"symbol\":\"ACME\"}\n\n" +
+		"data: line one\ndata: line two\n\n" +
+		"data: a\ndata: b\ndata: c\n\n"
+	if rec.Body.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, rec.Body.String())
+	}
+	if rec.flushes < 2 {
+		t.Fatalf("expected each event to be flushed, got %d flushes", rec.flushes)
+	}
+}
+
+func TestSSEErrorsBeforeFirstEvent(t *testing.T) {
+	router := New()
+	SSE(router, "/ticks", func(ctx context.Context, req *tickerRequest, stream EventStream) error {
+		return &TeapotError{Msg: "no ticks today"}
+	}, WithErrors(&TeapotError{}))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ticks", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected request validation to run, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ticks?symbol=NOPE", nil))
+	if rec.Code != http.StatusTeapot {
+		t.Fatalf("expected handler error before streaming to be reported, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct == "text/event-stream" {
+		t.Fatalf("did not expect an event stream for an error response")
+	}
+}
+
+func TestSSEStopsWhenClientLeaves(t *testing.T) {
+	router := New()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var sendErr error
+	var stream EventStream
+	SSE(router, "/ticks", func(ctx context.Context, req *tickerRequest, s EventStream) error {
+		stream = s
+		s.Flush()
+		cancel()
+		<-ctx.Done()
+		sendErr = s.Send("tick", "late")
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ticks?symbol=ACME", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Fatalf("expected headers flushed without events, got %d: %q", rec.Code, rec.Body.String())
+	}
+	if sendErr != context.Canceled {
+		t.Fatalf("expected Send to report the cancelled context, got %v", sendErr)
+	}
+	if err := stream.Send("tick", "after return"); err != errStreamClosed {
+		t.Fatalf("expected closed stream after the handler returned, got %v", err)
+	}
+}
+
+func TestOpenAPISSE(t *testing.T) {
+	router := New()
+	SSE(router, "/ticks", func(ctx context.Context, req *tickerRequest, stream EventStream) error {
+		return nil
+	})
+
+	specBytes, err := router.OpenAPIJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal openapi json: %v", err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(specBytes)
+	if err != nil {
+		t.Fatalf("failed to parse openapi json: %v", err)
+	}
+
+	op := doc.Paths.Value("/ticks").Get
+	if op == nil {
+		t.Fatalf("expected GET operation for /ticks")
+	}
+	resp := op.Responses.Value("200")
+	if resp == nil || resp.Value == nil {
+		t.Fatalf("expected 200 response in spec")
+	}
+	media := resp.Value.Content["text/event-stream"]
+	if media == nil || media.Schema == nil || media.Schema.Value == nil || !media.Schema.Value.Type.Is("string") {
+		t.Fatalf("expected text/event-stream string content, got %v", resp.Value.Content)
+	}
+	if op.Parameters.GetByInAndName("query", "symbol") == nil {
+		t.Fatalf("expected symbol query parameter")
+	}
+}
@@ -0,0 +1,56 @@
+package sprout
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"path"
+)
+
+// swaggerUIVersion pins the swagger-ui-dist release loaded from the CDN.
+const swaggerUIVersion = "5.17.14"
+
+var swaggerUITemplate = template.Must(template.New("swagger-ui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@{{.Version}}/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@{{.Version}}/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: {{.SpecURL}}, dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`))
+
+// serveSwaggerUI writes a Swagger UI page for the document served at specPath.
+// The page sits one segment below specPath and links to it relatively, so it
+// keeps working behind proxies that strip a path prefix.
+func (d *openAPIDocument) serveSwaggerUI(w http.ResponseWriter, specPath string) {
+	title := "API"
+	if d != nil {
+		d.mu.RLock()
+		if d.doc.Info != nil && d.doc.Info.Title != "" {
+			title = d.doc.Info.Title
+		}
+		d.mu.RUnlock()
+	}
+
+	var page bytes.Buffer
+	err := swaggerUITemplate.Execute(&page, struct {
+		Title   string
+		Version string
+		SpecURL string
+	}{title, swaggerUIVersion, "../" + path.Base(specPath)})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(page.Bytes())
+}
@@ -0,0 +1,183 @@
+package sprout
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Timeout returns middleware that gives later middleware and the handler a
+// context cancelled after d. The rest of the chain runs in its own goroutine;
+// if it has not finished at the deadline, an *Error of kind ErrorKindTimeout
+// goes through the error pipeline, answering 503 Service Unavailable by
+// default (map it to 504 with StatusForKind if you prefer).
+//
+// Handlers receive the deadline through ctx and must watch ctx.Done() to stop
+// early; they cannot be interrupted. What they write after the deadline is
+// discarded, as is a late panic. If the response was already started when the
+// deadline fired, its status can no longer change; the error still reaches the
+// pipeline, so ErrorHandler can log it, and the rest of the body is dropped.
+// Register it after Recoverer so panics before the deadline are recovered.
+func Timeout(d time.Duration) Middleware {
+	return func(w http.ResponseWriter, req *http.Request, next Next) {
+		ctx, cancel := context.WithTimeout(req.Context(), d)
+		defer cancel()
+		req = req.WithContext(ctx)
+
+		tw := &timeoutWriter{ctx: ctx, w: w, header: w.Header().Clone()}
+		done := make(chan any, 1)
+		go func() {
+			var recovered any
+			defer func() { done <- recovered }()
+			defer func() { recovered = recover() }()
+			continueWithWriter(next, tw, req)
+		}()
+
+		// finish hands over the outcome of a chain that returned in time.
+		finish := func(recovered any) {
+			tw.finish()
+			if recovered != nil {
+				panic(recovered)
+			}
+		}
+		select {
+		case recovered := <-done:
+			if !tw.missedDeadline() {
+				finish(recovered)
+				return
+			}
+		case <-ctx.Done():
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				// The client went away; let the chain wind down as usual.
+				finish(<-done)
+				return
+			}
+		}
+
+		committed := tw.timeout()
+		state := chainStateFrom(req)
+		if state == nil {
+			return
+		}
+		timeoutErr := &Error{
+			Kind:    ErrorKindTimeout,
+			Message: "request timed out",
+			Err:     ctx.Err(),
+		}
+		if committed {
+			// Headers are committed; report the timeout without writing.
+			handleError(state.owner, committedWriter{w}, req, timeoutErr)
+			return
+		}
+		handleError(state.owner, w, req, timeoutErr)
+	}
+}
+
+// timeoutWriter hands the writes of a chain running under Timeout, or of a
+// handler running under WithHardTimeout, to the real writer until the
+// deadline, and drops them afterwards. Headers are kept
+// on a copy until the response is started, so the timeout response never
+// races a late handler.
+type timeoutWriter struct {
+	ctx    context.Context
+	w      http.ResponseWriter
+	header http.Header
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeaderLocked(status)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expiredLocked() {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeaderLocked(http.StatusOK)
+	return tw.w.Write(b)
+}
+
+// Flush sends what has been written so far, unless the deadline passed.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expiredLocked() {
+		return
+	}
+	tw.writeHeaderLocked(http.StatusOK)
+	_ = http.NewResponseController(tw.w).Flush()
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(status int) {
+	if tw.expiredLocked() || tw.wroteHeader {
+		return
+	}
+	tw.copyHeaderLocked()
+	tw.w.WriteHeader(status)
+	// Informational responses other than 101 precede the final status.
+	if status >= http.StatusOK || status == http.StatusSwitchingProtocols {
+		tw.wroteHeader = true
+	}
+}
+
+// copyHeaderLocked replaces the real writer's headers with the chain's copy.
+func (tw *timeoutWriter) copyHeaderLocked() {
+	dst := tw.w.Header()
+	for name := range dst {
+		if _, ok := tw.header[name]; !ok {
+			delete(dst, name)
+		}
+	}
+	for name, values := range tw.header {
+		dst[name] = slices.Clone(values)
+	}
+}
+
+// expiredLocked reports whether writes must be dropped, noting a deadline
+// that passed before the middleware got to it.
+func (tw *timeoutWriter) expiredLocked() bool {
+	if !tw.timedOut && errors.Is(tw.ctx.Err(), context.DeadlineExceeded) {
+		tw.timedOut = true
+	}
+	return tw.timedOut
+}
+
+// missedDeadline reports whether the chain tried to write after the deadline.
+func (tw *timeoutWriter) missedDeadline() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.timedOut
+}
+
+// timeout stops forwarding writes and reports whether the response had
+// already been started.
+func (tw *timeoutWriter) timeout() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.timedOut = true
+	return tw.wroteHeader
+}
+
+// finish copies the headers of a chain that returned without writing, so the
+// server still sends them.
+func (tw *timeoutWriter) finish() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.wroteHeader && !tw.timedOut {
+		tw.copyHeaderLocked()
+	}
+}
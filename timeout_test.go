@@ -0,0 +1,142 @@
+package sprout
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type timeoutRequest struct {
+	Mode string `query:"mode"`
+}
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	strict := false
+	router := NewWithConfig(&Config{StrictErrorTypes: &strict})
+	router.Use(Recoverer())
+	router.Use(func(w http.ResponseWriter, r *http.Request, next Next) {
+		w.Header().Set("X-Before", "1")
+		next(nil)
+	})
+	router.Use(Timeout(20 * time.Millisecond))
+
+	GET(router, "/work", func(ctx context.Context, req *timeoutRequest) (*HelloResponse, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Errorf("expected handler context to carry a deadline")
+		}
+		switch req.Mode {
+		case "cooperative":
+			<-ctx.Done()
+			return nil, ctx.Err()
+		case "stubborn":
+			<-release
+			ResponseWriter(ctx).Header().Set("X-Late", "1")
+			return &HelloResponse{Message: "late"}, nil
+		case "panic":
+			panic("boom")
+		}
+		ResponseWriter(ctx).Header().Set("X-Handler", "1")
+		return &HelloResponse{Message: "done"}, nil
+	})
+
+	serve := func(mode string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/work?mode="+mode, nil))
+		return rec
+	}
+
+	t.Run("in time", func(t *testing.T) {
+		rec := serve("")
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "done") {
+			t.Fatalf("expected handler response, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("X-Before") != "1" || rec.Header().Get("X-Handler") != "1" {
+			t.Fatalf("expected headers from before and after the middleware, got %v", rec.Header())
+		}
+	})
+
+	t.Run("cooperative handler", func(t *testing.T) {
+		rec := serve("cooperative")
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected status 503, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("X-Before") != "1" {
+			t.Fatalf("expected earlier headers on the timeout response")
+		}
+	})
+
+	t.Run("handler ignoring the deadline", func(t *testing.T) {
+		start := time.Now()
+		rec := serve("stubborn")
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected status 503, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("expected the timeout response at the deadline, took %v", elapsed)
+		}
+		if rec.Header().Get("X-Late") != "" || strings.Contains(rec.Body.String(), "late") {
+			t.Fatalf("did not expect the late response to be written")
+		}
+	})
+
+	t.Run("panic before the deadline", func(t *testing.T) {
+		if rec := serve("panic"); rec.Code != http.StatusInternalServerError {
+			t.Fatalf("expected status 500, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("response already started", func(t *testing.T) {
+		var reported error
+		logged := NewWithConfig(&Config{
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				reported = err
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+		})
+		logged.Use(Timeout(20 * time.Millisecond))
+		GET(logged, "/work", func(ctx context.Context, req *timeoutRequest) (*HelloResponse, error) {
+			w := ResponseWriter(ctx)
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("first"))
+			<-ctx.Done()
+			_, _ = w.Write([]byte("second"))
+			return nil, ErrResponseWritten
+		})
+
+		rec := httptest.NewRecorder()
+		logged.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/work", nil))
+
+		if rec.Code != http.StatusOK || rec.Body.String() != "first" {
+			t.Fatalf("expected the started response to be cut off, got %d: %q", rec.Code, rec.Body.String())
+		}
+		var sproutErr *Error
+		if !errors.As(reported, &sproutErr) || sproutErr.Kind != ErrorKindTimeout {
+			t.Fatalf("expected the timeout to reach ErrorHandler, got %v", reported)
+		}
+	})
+}
+
+func TestTimeoutStatusForKind(t *testing.T) {
+	router := NewWithConfig(&Config{
+		StatusForKind: map[ErrorKind]int{ErrorKindTimeout: http.StatusGatewayTimeout},
+	})
+	router.Use(Timeout(10 * time.Millisecond))
+	GET(router, "/slow", func(ctx context.Context, req *EmptyRequest) (*HelloResponse, error) {
+		<-ctx.Done()
+		return nil, &Error{Kind: ErrorKindTimeout, Message: "gave up", Err: ctx.Err()}
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected status 504, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,129 @@
+package sprout
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Attribute keys OTelMiddleware sets on server spans, following the
+// OpenTelemetry HTTP semantic conventions.
+const (
+	AttrHTTPRequestMethod      = "http.request.method"
+	AttrHTTPRoute              = "http.route"
+	AttrURLPath                = "url.path"
+	AttrHTTPResponseStatusCode = "http.response.status_code"
+	AttrErrorType              = "error.type"
+)
+
+// Tracer starts server spans for OTelMiddleware. It is the small part of an
+// OpenTelemetry tracer and propagator that Sprout needs, so adapting one is a
+// few lines and Sprout itself does not depend on the OpenTelemetry SDK.
+type Tracer interface {
+	// Start extracts any trace context carried by header, starts a span named
+	// name as its child, and returns a context holding the new span.
+	Start(ctx context.Context, name string, header http.Header) (context.Context, Span)
+	// Inject writes the trace context of ctx into header.
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	SetAttribute(key string, value any)
+	RecordError(err error)
+	End()
+}
+
+// OTelMiddleware returns middleware that traces each request with tracer.
+// Spans are named "METHOD /route/:pattern" after the matched route, or just the
+// method when no route matched. The span's context is handed to later
+// middleware and the handler, and injected into the response headers before
+// the handler runs.
+//
+// Once the chain finishes the span records the response status. Server
+// errors (5xx) and panics are also passed to RecordError, using the
+// error that went through Sprout's error pipeline when there is one. The span
+// is ended even if a later layer panics; the panic is then re-raised.
+func OTelMiddleware(tracer Tracer) Middleware {
+	return func(w http.ResponseWriter, req *http.Request, next Next) {
+		route := RoutePattern(req)
+		name := req.Method
+		if route != "" {
+			name += " " + route
+		}
+
+		ctx, span := tracer.Start(req.Context(), name, req.Header)
+		span.SetAttribute(AttrHTTPRequestMethod, req.Method)
+		span.SetAttribute(AttrURLPath, req.URL.Path)
+		if route != "" {
+			span.SetAttribute(AttrHTTPRoute, route)
+		}
+		tracer.Inject(ctx, w.Header())
+
+		rec := &statusRecorder{ResponseWriter: w}
+		req = req.WithContext(ctx)
+		defer func() {
+			recovered := recover()
+			status := rec.statusCode()
+			var err error
+			if state := chainStateFrom(req); state != nil {
+				err = state.lastErr()
+			}
+			if recovered != nil {
+				status = http.StatusInternalServerError
+				err = fmt.Errorf("panic: %v", recovered)
+			}
+
+			span.SetAttribute(AttrHTTPResponseStatusCode, status)
+			if status >= http.StatusInternalServerError {
+				span.SetAttribute(AttrErrorType, strconv.Itoa(status))
+				if err == nil {
+					err = fmt.Errorf("%d %s", status, http.StatusText(status))
+				}
+				span.RecordError(err)
+			}
+			span.End()
+
+			if recovered != nil {
+				panic(recovered)
+			}
+		}()
+
+		continueWithWriter(next, rec, req)
+	}
+}
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	// Informational responses other than 101 precede the final status.
+	if r.status == 0 && (status >= http.StatusOK || status == http.StatusSwitchingProtocols) {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// statusCode reports the written status, or 200 when nothing was written.
+func (r *statusRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
@@ -0,0 +1,185 @@
+package sprout
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type recordedSpan struct {
+	name   string
+	parent string
+	attrs  map[string]any
+	errs   []error
+	ended  bool
+}
+
+func (s *recordedSpan) SetAttribute(key string, value any) { s.attrs[key] = value }
+func (s *recordedSpan) RecordError(err error)              { s.errs = append(s.errs, err) }
+func (s *recordedSpan) End()                               { s.ended = true }
+
+type spanContextKey struct{}
+
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, header http.Header) (context.Context, Span) {
+	span := &recordedSpan{name: name, parent: header.Get("Traceparent"), attrs: map[string]any{}}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+func (t *recordingTracer) Inject(ctx context.Context, header http.Header) {
+	if span, ok := ctx.Value(spanContextKey{}).(*recordedSpan); ok {
+		header.Set("Traceresponse", "span:"+span.name)
+	}
+}
+
+type tracedUserRequest struct {
+	ID string `path:"id"`
+}
+
+func TestOTelMiddleware(t *testing.T) {
+	tracer := &recordingTracer{}
+	strict := false
+	router := NewWithConfig(&Config{StrictErrorTypes: &strict})
+	router.Use(OTelMiddleware(tracer))
+
+	GET(router, "/users/:id", func(ctx context.Context, req *tracedUserRequest) (*HelloResponse, error) {
+		if _, ok := ctx.Value(spanContextKey{}).(*recordedSpan); !ok {
+			t.Fatalf("expected span in handler context")
+		}
+		switch req.ID {
+		case "fail":
+			return nil, errors.New("database unavailable")
+		case "panic":
+			panic("boom")
+		case "missing":
+			return nil, &Error{Kind: ErrorKindNotFound, Message: "no such user"}
+		}
+		return &HelloResponse{Message: req.ID}, nil
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Traceparent", "00-parent-01")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("success", func(t *testing.T) {
+		rec := serve("/users/42")
+		span := tracer.spans[len(tracer.spans)-1]
+
+		if span.name != "GET /users/:id" {
+			t.Fatalf("expected span named by route pattern, got %q", span.name)
+		}
+		if span.parent != "00-parent-01" {
+			t.Fatalf("expected incoming trace headers passed to tracer, got %q", span.parent)
+		}
+		if got := rec.Header().Get("Traceresponse"); got != "span:GET /users/:id" {
+			t.Fatalf("expected trace context injected into response, got %q", got)
+		}
+		if span.attrs[AttrHTTPRoute] != "/users/:id" || span.attrs[AttrURLPath] != "/users/42" || span.attrs[AttrHTTPRequestMethod] != http.MethodGet {
+			t.Fatalf("unexpected request attributes: %#v", span.attrs)
+		}
+		if span.attrs[AttrHTTPResponseStatusCode] != http.StatusOK {
+			t.Fatalf("expected status attribute 200, got %#v", span.attrs[AttrHTTPResponseStatusCode])
+		}
+		if len(span.errs) != 0 || !span.ended {
+			t.Fatalf("expected ended span without errors, got %#v", span)
+		}
+	})
+
+	t.Run("client error", func(t *testing.T) {
+		serve("/users/missing")
+		span := tracer.spans[len(tracer.spans)-1]
+
+		if span.attrs[AttrHTTPResponseStatusCode] != http.StatusNotFound {
+			t.Fatalf("expected status attribute 404, got %#v", span.attrs[AttrHTTPResponseStatusCode])
+		}
+		if len(span.errs) != 0 {
+			t.Fatalf("did not expect client errors to be recorded, got %v", span.errs)
+		}
+		if _, ok := span.attrs[AttrErrorType]; ok {
+			t.Fatalf("did not expect error.type for a 404")
+		}
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		serve("/users/fail")
+		span := tracer.spans[len(tracer.spans)-1]
+
+		if span.attrs[AttrHTTPResponseStatusCode] != http.StatusInternalServerError || span.attrs[AttrErrorType] != "500" {
+			t.Fatalf("unexpected error attributes: %#v", span.attrs)
+		}
+		if len(span.errs) != 1 || span.errs[0].Error() != "database unavailable" {
+			t.Fatalf("expected handler error recorded, got %v", span.errs)
+		}
+		if !span.ended {
+			t.Fatalf("expected span to be ended")
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected panic to propagate")
+				}
+			}()
+			serve("/users/panic")
+		}()
+		span := tracer.spans[len(tracer.spans)-1]
+
+		if !span.ended || len(span.errs) != 1 {
+			t.Fatalf("expected ended span with recorded panic, got %#v", span)
+		}
+		if span.attrs[AttrHTTPResponseStatusCode] != http.StatusInternalServerError {
+			t.Fatalf("expected status attribute 500, got %#v", span.attrs[AttrHTTPResponseStatusCode])
+		}
+	})
+
+	t.Run("unmatched route", func(t *testing.T) {
+		serve("/nowhere")
+		span := tracer.spans[len(tracer.spans)-1]
+
+		if span.name != http.MethodGet {
+			t.Fatalf("expected span named by method only, got %q", span.name)
+		}
+		if _, ok := span.attrs[AttrHTTPRoute]; ok {
+			t.Fatalf("did not expect http.route without a matched route")
+		}
+		if span.attrs[AttrHTTPResponseStatusCode] != http.StatusNotFound {
+			t.Fatalf("expected status attribute 404, got %#v", span.attrs[AttrHTTPResponseStatusCode])
+		}
+	})
+}
+
+func TestRoutePattern(t *testing.T) {
+	router := New()
+	api := router.Mount("/api", nil)
+
+	var seen string
+	api.Use(func(w http.ResponseWriter, r *http.Request, next Next) {
+		seen = RoutePattern(r)
+		next(nil)
+	})
+	GET(api, "/users/:id", func(ctx context.Context, req *tracedUserRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: req.ID}, nil
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users/7", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if seen != "/api/users/:id" {
+		t.Fatalf("expected full route pattern, got %q", seen)
+	}
+}
@@ -0,0 +1,178 @@
+package sprout
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ValidationErrorBody is the body ValidationErrorFields produces:
+// {"errors": [{"field": ..., "rule": ..., "message": ...}]}.
+type ValidationErrorBody struct {
+	Errors []FieldViolation `json:"errors"`
+}
+
+// FieldViolation describes one failed validation rule.
+type FieldViolation struct {
+	// Field is the JSON path of the field, e.g. "address.city", or the Go
+	// field name for fields without a json tag such as query parameters.
+	Field string `json:"field"`
+	// Rule is the validate tag that failed, e.g. "required" or "min".
+	Rule string `json:"rule"`
+	// Message is a short English description of the failure.
+	Message string `json:"message"`
+}
+
+// ValidationErrorFields is a ready-made Config.ValidationErrorFormatter that
+// reports each failed field with its rule and an English message.
+func ValidationErrorFields(errs validator.ValidationErrors) any {
+	body := ValidationErrorBody{Errors: make([]FieldViolation, 0, len(errs))}
+	for _, fe := range errs {
+		body.Errors = append(body.Errors, FieldViolation{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Message: fieldErrorMessage(fe),
+		})
+	}
+	return body
+}
+
+// fieldPath strips the request type name from a field error's namespace.
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if i := strings.IndexByte(namespace, '.'); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+// pathParamFailure re-kinds a parse or validation failure of a path
+// parameter of t as kind, naming the parameter. Failures of query, header and
+// body fields, and every failure when kind is empty or already err's kind,
+// are returned unchanged.
+func pathParamFailure(kind ErrorKind, t reflect.Type, err *Error) *Error {
+	if kind == "" || kind == err.Kind {
+		return err
+	}
+	var parseErr *ParseParameterError
+	if errors.As(err, &parseErr) {
+		if parseErr.Source != ParameterSourcePath {
+			return err
+		}
+		return &Error{Kind: kind, Message: err.Message, Err: err.Err}
+	}
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+	for _, fe := range validationErrs {
+		if name := pathParamName(t, fe.StructNamespace()); name != "" {
+			return &Error{
+				Kind:    kind,
+				Message: fmt.Sprintf("invalid path parameter '%s'", name),
+				Err:     err.Err,
+			}
+		}
+	}
+	return err
+}
+
+// pathParamName returns the path tag of the field of t at a validator
+// struct namespace such as "Request.Group.ID", or "" if it is not a path
+// parameter.
+func pathParamName(t reflect.Type, namespace string) string {
+	parts := strings.Split(namespace, ".")
+	var field reflect.StructField
+	for _, part := range parts[1:] {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return ""
+		}
+		var ok bool
+		if field, ok = t.FieldByName(part); !ok {
+			return ""
+		}
+		t = field.Type
+	}
+	if len(parts) < 2 {
+		return ""
+	}
+	return field.Tag.Get("path")
+}
+
+// fieldErrorMessage describes the common validator rules in plain English,
+// falling back to naming the rule.
+func fieldErrorMessage(fe validator.FieldError) string {
+	param := fe.Param()
+	size := "be"
+	switch fe.Kind() {
+	case reflect.String:
+		size = "have a length of"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		size = "contain"
+	}
+	items := ""
+	if fe.Kind() == reflect.String {
+		items = " characters"
+	} else if size == "contain" {
+		items = " items"
+	}
+
+	switch fe.Tag() {
+	case "required", "required_if", "required_unless", "required_with", "required_without":
+		return "is required"
+	case "min", "gte":
+		return fmt.Sprintf("must %s at least %s%s", size, param, items)
+	case "max", "lte":
+		return fmt.Sprintf("must %s at most %s%s", size, param, items)
+	case "gt":
+		return fmt.Sprintf("must %s more than %s%s", size, param, items)
+	case "lt":
+		return fmt.Sprintf("must %s less than %s%s", size, param, items)
+	case "len":
+		return fmt.Sprintf("must %s exactly %s%s", size, param, items)
+	case "eq":
+		return fmt.Sprintf("must equal %s", param)
+	case "ne":
+		return fmt.Sprintf("must not equal %s", param)
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.Join(strings.Fields(param), ", "))
+	case "email":
+		return "must be a valid email address"
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "uuid", "uuid4":
+		return "must be a valid UUID"
+	default:
+		return fmt.Sprintf("failed the '%s' rule", fe.Tag())
+	}
+}
+
+// writeFormattedValidationError writes a request validation failure through
+// Config.ValidationErrorFormatter. It reports false when no formatter applies
+// or its result cannot be encoded.
+func writeFormattedValidationError(s *Sprout, w http.ResponseWriter, r *http.Request, status int, err error) bool {
+	if s.config.ValidationErrorFormatter == nil {
+		return false
+	}
+	var sproutErr *Error
+	if !errors.As(err, &sproutErr) || sproutErr.Kind != ErrorKindValidation {
+		return false
+	}
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return false
+	}
+
+	body := s.config.ValidationErrorFormatter(validationErrs)
+	if body == nil {
+		return false
+	}
+	return writeErrorBody(s, w, r, status, body)
+}
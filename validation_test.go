@@ -0,0 +1,202 @@
+package sprout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type signupAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type formattedSignupRequest struct {
+	Page    int           `query:"page" validate:"omitempty,min=1"`
+	Name    string        `json:"name" validate:"required,min=3"`
+	Email   string        `json:"email" validate:"required,email"`
+	Plan    string        `json:"plan" validate:"oneof=free pro"`
+	Tags    []string      `json:"tags" validate:"max=2"`
+	Address signupAddress `json:"address"`
+}
+
+func TestValidationErrorFormatter(t *testing.T) {
+	serve := func(router *Sprout, body string) *httptest.ResponseRecorder {
+		POST(router, "/signup", func(ctx context.Context, req *formattedSignupRequest) (*HelloResponse, error) {
+			return &HelloResponse{Message: req.Name}, nil
+		})
+		req := httptest.NewRequest(http.MethodPost, "/signup?page=-1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	const invalid = `{"name":"al","email":"nope","plan":"gold","tags":["a","b","c"],"address":{}}`
+
+	t.Run("field list", func(t *testing.T) {
+		rec := serve(NewWithConfig(&Config{ValidationErrorFormatter: ValidationErrorFields}), invalid)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Fatalf("expected application/json, got %q", ct)
+		}
+
+		var body ValidationErrorBody
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to decode body %s: %v", rec.Body.String(), err)
+		}
+		expected := []FieldViolation{
+			{Field: "Page", Rule: "min", Message: "must be at least 1"},
+			{Field: "name", Rule: "min", Message: "must have a length of at least 3 characters"},
+			{Field: "email", Rule: "email", Message: "must be a valid email address"},
+			{Field: "plan", Rule: "oneof", Message: "must be one of: free, pro"},
+			{Field: "tags", Rule: "max", Message: "must contain at most 2 items"},
+			{Field: "address.city", Rule: "required", Message: "is required"},
+		}
+		if !reflect.DeepEqual(body.Errors, expected) {
+			t.Fatalf("expected %+v, got %+v", expected, body.Errors)
+		}
+	})
+
+	t.Run("custom shape with status override", func(t *testing.T) {
+		router := NewWithConfig(&Config{
+			StatusForKind: map[ErrorKind]int{ErrorKindValidation: http.StatusUnprocessableEntity},
+			ErrorEnvelope: NewProblemDetails,
+			ValidationErrorFormatter: func(errs validator.ValidationErrors) any {
+				fields := map[string]string{}
+				for _, fe := range errs {
+					fields[fe.Field()] = fe.Tag()
+				}
+				return map[string]any{"invalid": fields}
+			},
+		})
+		rec := serve(router, `{"name":"alice","email":"a@example.com","plan":"pro"}`)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("expected status 422, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Fatalf("expected formatter to take precedence over the envelope, got %q", ct)
+		}
+		if body := strings.TrimSpace(rec.Body.String()); body != `{"invalid":{"Page":"min","city":"required"}}` {
+			t.Fatalf("unexpected body %s", body)
+		}
+	})
+
+	t.Run("falls back without errors to format", func(t *testing.T) {
+		router := NewWithConfig(&Config{ValidationErrorFormatter: func(validator.ValidationErrors) any { return nil }})
+		rec := serve(router, invalid)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "request validation failed") {
+			t.Fatalf("expected default response, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+type wireNameLine struct {
+	SKU string `json:"sku" validate:"required"`
+}
+
+type wireNameOrder struct {
+	Customer struct {
+		Email string `json:"email,omitempty" validate:"required,email"`
+	} `json:"customer"`
+	Lines    []wireNameLine `json:"lines" validate:"dive"`
+	Secret   string         `json:"-" validate:"required"`
+	Untagged string         `validate:"required"`
+}
+
+func TestValidationErrorsUseJSONNames(t *testing.T) {
+	router := New()
+	var order wireNameOrder
+	order.Lines = []wireNameLine{{}}
+	err := router.validate.Struct(order)
+
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	var got []string
+	for _, fe := range errs {
+		got = append(got, fe.Namespace()+"|"+fe.Field()+"|"+fe.StructField())
+	}
+	expected := []string{
+		"wireNameOrder.customer.email|email|Email",
+		"wireNameOrder.lines[0].sku|sku|SKU",
+		"wireNameOrder.Secret|Secret|Secret",
+		"wireNameOrder.Untagged|Untagged|Untagged",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
+
+type accountScope struct {
+	AccountID string `path:"account_id" validate:"uuid4"`
+}
+
+type accountOrderRequest struct {
+	Scope accountScope
+	ID    int    `path:"id" validate:"min=1"`
+	Limit int    `query:"limit" validate:"omitempty,max=10"`
+	Note  string `json:"note" validate:"omitempty,max=5"`
+}
+
+func TestPathValidationErrorKind(t *testing.T) {
+	router := NewWithConfig(&Config{PathValidationErrorKind: ErrorKindNotFound})
+	handler := func(ctx context.Context, req *accountOrderRequest) (*HelloResponse, error) {
+		return &HelloResponse{Message: "found"}, nil
+	}
+	GET(router, "/accounts/:account_id/orders/:id", handler)
+	POST(router, "/accounts/:account_id/orders/:id", handler, WithPathValidationAs(ErrorKindValidation))
+	api := router.Mount("/api", nil)
+	GET(api, "/accounts/:account_id/orders/:id", handler)
+	plain := New()
+	GET(plain, "/accounts/:account_id/orders/:id", handler)
+	PUT(plain, "/accounts/:account_id/orders/:id", handler, WithPathValidationAs(ErrorKindNotFound))
+
+	const account = "/accounts/7f1c2b1e-4c1a-4e7a-9d3b-2f6e8a9b0c1d"
+	cases := []struct {
+		name    string
+		router  *Sprout
+		method  string
+		path    string
+		body    string
+		status  int
+		message string
+	}{
+		{name: "valid", router: router, method: http.MethodGet, path: account + "/orders/1", status: http.StatusOK},
+		{name: "malformed account", router: router, method: http.MethodGet, path: "/accounts/nope/orders/1", status: http.StatusNotFound, message: "invalid path parameter 'account_id'"},
+		{name: "failed rule", router: router, method: http.MethodGet, path: account + "/orders/0", status: http.StatusNotFound, message: "invalid path parameter 'id'"},
+		{name: "unparseable", router: router, method: http.MethodGet, path: account + "/orders/abc", status: http.StatusNotFound, message: "invalid path parameter 'id'"},
+		{name: "query stays 400", router: router, method: http.MethodGet, path: account + "/orders/1?limit=50", status: http.StatusBadRequest},
+		{name: "body stays 400", router: router, method: http.MethodPost, path: account + "/orders/1", body: `{"note":"too long"}`, status: http.StatusBadRequest},
+		{name: "route override", router: router, method: http.MethodPost, path: account + "/orders/0", status: http.StatusBadRequest},
+		{name: "inherited by mounts", router: router, method: http.MethodGet, path: "/api/accounts/nope/orders/1", status: http.StatusNotFound},
+		{name: "default is 400", router: plain, method: http.MethodGet, path: "/accounts/nope/orders/1", status: http.StatusBadRequest},
+		{name: "route opt-in", router: plain, method: http.MethodPut, path: "/accounts/nope/orders/1", status: http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if tc.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+			tc.router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("expected status %d, got %d: %s", tc.status, rec.Code, rec.Body.String())
+			}
+			if tc.message != "" && !strings.Contains(rec.Body.String(), tc.message) {
+				t.Fatalf("expected message %q, got %s", tc.message, rec.Body.String())
+			}
+		})
+	}
+}